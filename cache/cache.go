@@ -1,7 +1,9 @@
 package cache
 
 import (
+	"hash/maphash"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -11,82 +13,101 @@ import (
 // https://dev.to/ernesto27/key-value-store-in-golang-52h1
 
 type ValueStore struct {
-	mu         sync.RWMutex
-	store      map[string]string
-	expiration map[string]int64 // Stores expiration times as Unix timestamps, 0 for no expiration
+	shards [shardCount]*shard
+	seed   maphash.Seed
+	dirty  atomic.Int64 // changes since the last save
+	used   atomic.Int64 // estimated bytes taken by the keys
+	clock  atomic.Int64 // unix milliseconds, see now
+
+	// multiMu keeps a write to several shards whole in a snapshot, which
+	// copies one shard at a time: such writes hold it shared, and taking a
+	// snapshot holds it exclusively. It is taken before any shard.
+	multiMu sync.RWMutex
+
+	expiredKeys    atomic.Int64
+	staleRatio     atomic.Uint64 // float64 bits, see expireCycle
+	timeCapReached atomic.Int64
+
+	maxMemory   atomic.Int64
+	policy      atomic.Int32 // EvictionPolicy
+	evictedKeys atomic.Int64
+	evictMu     sync.Mutex // guards the fields below
+	samples     int
+	pool        []evictionCandidate
+
+	libMu      sync.RWMutex // guards the libraries; taken after any shard
+	libraries  []string     // code of the function libraries
+	libVersion uint64
 }
 
-func NewValueStore(cleanupInterval time.Duration) *ValueStore {
-	vs := &ValueStore{
-		store:      make(map[string]string),
-		expiration: make(map[string]int64),
+// NewValueStore returns an empty store that runs an active expire cycle every
+// expireInterval, a second divided by upstream's hz.
+func NewValueStore(expireInterval time.Duration) *ValueStore {
+	vs := &ValueStore{seed: maphash.MakeSeed(), samples: 5}
+	vs.clock.Store(time.Now().UnixMilli())
+	for i := range vs.shards {
+		vs.shards[i] = newShard()
 	}
-	go vs.startCleanup(cleanupInterval)
+	go vs.activeExpire(expireInterval)
 	return vs
 }
 
 // we mark zero as non expirary
 func (kv *ValueStore) Set(key, value string, ttl time.Duration) {
-	kv.mu.Lock()
-	defer kv.mu.Unlock()
-	kv.store[key] = value
+	defer kv.lockKey(key).Unlock()
+	var expireAt int64
 	if ttl > 0 {
-		kv.expiration[key] = time.Now().Add(ttl).UnixNano()
-	} else {
-		kv.expiration[key] = 0
+		expireAt = time.Now().Add(ttl).UnixNano()
 	}
+	kv.put(key, value, expireAt)
+	kv.modified(key)
 }
 
-func (kv *ValueStore) Get(key string) (string, bool) {
-	kv.mu.RLock()
-	exp, ok := kv.expiration[key]
-	kv.mu.RUnlock()
+// Get returns the string stored at key. It fails with ErrWrongType if the key
+// holds another kind of value.
+func (kv *ValueStore) Get(key string) (string, bool, error) {
+	defer kv.rlockKey(key).RUnlock()
 
-	// If the key exists and is expired
-	if ok && exp > 0 && time.Now().UnixNano() > exp {
-		// Clean up the expired key
-		kv.Delete(key)
-		return "", false
+	value, ok := kv.lookupRead(key)
+	if !ok {
+		return "", false, nil
 	}
-
-	kv.mu.RLock()
-	value, exists := kv.store[key]
-	kv.mu.RUnlock()
-	return value, exists
+	str, ok := value.(string)
+	if !ok {
+		return "", false, ErrWrongType
+	}
+	return str, true, nil
 }
 
 func (kv *ValueStore) Delete(key string) bool {
-	kv.mu.Lock()
-	defer kv.mu.Unlock()
+	defer kv.lockKey(key).Unlock()
 
-	_, exists := kv.store[key]
+	_, exists := kv.lookupWrite(key)
 	if exists {
-		delete(kv.store, key)
-		delete(kv.expiration, key)
+		kv.remove(key)
+		kv.modified(key)
 	}
 	return exists
 }
 
-func (kv *ValueStore) startCleanup(interval time.Duration) {
-	for {
-		time.Sleep(interval)
-		now := time.Now().UnixNano()
-		kv.mu.Lock()
-		for key, exp := range kv.expiration {
-			if exp > 0 && now > exp {
-				delete(kv.store, key)
-				delete(kv.expiration, key)
-			}
-		}
-		kv.mu.Unlock()
-	}
+// Exists reports whether key holds a live value of any type.
+func (kv *ValueStore) Exists(key string) bool {
+	defer kv.rlockKey(key).RUnlock()
+	_, ok := kv.lookupRead(key)
+	return ok
+}
+
+// Type returns the type of the value stored at key, or TypeNone.
+func (kv *ValueStore) Type(key string) ValueType {
+	defer kv.rlockKey(key).RUnlock()
+	value, _ := kv.lookupRead(key)
+	return typeOf(value)
 }
 
 func (kv *ValueStore) GetExpiry(key string) (time.Time, bool) {
-	kv.mu.RLock()
-	defer kv.mu.RUnlock()
+	defer kv.rlockKey(key).RUnlock()
 
-	exp, exists := kv.expiration[key]
+	exp, exists := kv.shard(key).expiration[key]
 	if !exists || exp == 0 {
 		return time.Time{}, false // No expiration set or key does not exist
 	}
@@ -94,16 +115,71 @@ func (kv *ValueStore) GetExpiry(key string) (time.Time, bool) {
 	return time.Unix(0, exp), true
 }
 
+// GetKeys returns every live key. Shards are read one at a time, so the keys
+// are not a snapshot of a single moment.
 func (kv *ValueStore) GetKeys() []string {
-	kv.mu.RLock()
-	defer kv.mu.RUnlock()
-	keys := make([]string, 0, len(kv.store))
-	now := time.Now().UnixNano()
-	for key, exp := range kv.expiration {
-		if exp > 0 && now > exp {
-			continue
+	var keys []string
+	for _, s := range kv.shards {
+		s.mu.RLock()
+		now := time.Now().UnixNano()
+		for key := range s.store {
+			if !s.isExpired(key, now) {
+				keys = append(keys, key)
+			}
 		}
-		keys = append(keys, key)
+		s.mu.RUnlock()
 	}
 	return keys
 }
+
+// lookupRead returns the value at key, treating expired keys as missing.
+// Caller must hold at least a read lock on its shard.
+func (kv *ValueStore) lookupRead(key string) (any, bool) {
+	it, ok := kv.shard(key).store[key]
+	if !ok || kv.isExpired(key) {
+		return nil, false
+	}
+	kv.touch(it)
+	return it.value, true
+}
+
+// lookupWrite is lookupRead for callers holding the write lock; expired keys
+// are removed so the caller starts from a clean slate.
+func (kv *ValueStore) lookupWrite(key string) (any, bool) {
+	it, ok := kv.shard(key).store[key]
+	if !ok {
+		return nil, false
+	}
+	if kv.isExpired(key) {
+		kv.expire(key)
+		return nil, false
+	}
+	kv.touch(it)
+	return it.value, true
+}
+
+// Flush deletes every key, and the function libraries, as a full resync
+// from a primary does before loading its dataset.
+func (kv *ValueStore) Flush() {
+	defer kv.lockAll()()
+	for _, s := range kv.shards {
+		for key := range s.store {
+			kv.remove(key)
+			kv.modified(key)
+		}
+	}
+	kv.libMu.Lock()
+	defer kv.libMu.Unlock()
+	kv.setLibraries(nil)
+}
+
+// remove deletes key and its expiration. Caller must hold the write lock of
+// its shard.
+func (kv *ValueStore) remove(key string) {
+	s := kv.shard(key)
+	if it := s.store[key]; it != nil {
+		kv.used.Add(-it.size)
+	}
+	delete(s.store, key)
+	delete(s.expiration, key)
+}
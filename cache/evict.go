@@ -0,0 +1,250 @@
+package cache
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+)
+
+// Beyond maxmemory, keys are evicted by an approximation of the policy, as
+// upstream does: rather than keep every key in order, each round samples a
+// few keys from a few shards and adds them to a small pool of the best
+// candidates seen so far, then evicts the best of the pool. The pool carries
+// over between rounds, so the approximation improves as more are sampled.
+
+// EvictionPolicy chooses which keys are evicted beyond maxmemory.
+type EvictionPolicy int32
+
+const (
+	NoEviction EvictionPolicy = iota
+	AllKeysLRU
+	AllKeysLFU
+	AllKeysRandom
+	VolatileLRU
+	VolatileLFU
+	VolatileRandom
+	VolatileTTL
+)
+
+var policyNames = []string{
+	NoEviction:     "noeviction",
+	AllKeysLRU:     "allkeys-lru",
+	AllKeysLFU:     "allkeys-lfu",
+	AllKeysRandom:  "allkeys-random",
+	VolatileLRU:    "volatile-lru",
+	VolatileLFU:    "volatile-lfu",
+	VolatileRandom: "volatile-random",
+	VolatileTTL:    "volatile-ttl",
+}
+
+// ParseEvictionPolicy returns the policy named as maxmemory-policy names it.
+func ParseEvictionPolicy(name string) (EvictionPolicy, error) {
+	if i := slices.Index(policyNames, name); i >= 0 {
+		return EvictionPolicy(i), nil
+	}
+	return NoEviction, fmt.Errorf("unknown maxmemory policy %q", name)
+}
+
+func (p EvictionPolicy) String() string {
+	return policyNames[p]
+}
+
+// volatile reports whether the policy only evicts keys with a deadline.
+func (p EvictionPolicy) volatile() bool {
+	return p >= VolatileLRU
+}
+
+func (p EvictionPolicy) lfu() bool {
+	return p == AllKeysLFU || p == VolatileLFU
+}
+
+const (
+	evictionPoolSize = 16 // candidates kept between rounds, EVPOOL_SIZE
+	evictionShards   = 4  // shards sampled each round
+)
+
+// evictionCandidate is a key in the pool. The higher its score, the better
+// a candidate it is.
+type evictionCandidate struct {
+	key   string
+	score int64
+}
+
+// SetMaxMemory limits the memory keys may take to limit bytes, 0 for no
+// limit, evicting by policy. Each shard sampled has samples keys sampled.
+func (kv *ValueStore) SetMaxMemory(limit int64, policy EvictionPolicy, samples int) {
+	kv.evictMu.Lock()
+	defer kv.evictMu.Unlock()
+	kv.maxMemory.Store(limit)
+	kv.policy.Store(int32(policy))
+	kv.samples = max(samples, 1)
+	kv.pool = kv.pool[:0]
+}
+
+// MaxMemory returns the limit and policy SetMaxMemory set.
+func (kv *ValueStore) MaxMemory() (int64, EvictionPolicy) {
+	return kv.maxMemory.Load(), EvictionPolicy(kv.policy.Load())
+}
+
+// OverMaxMemory reports whether keys take more memory than the limit.
+func (kv *ValueStore) OverMaxMemory() bool {
+	limit := kv.maxMemory.Load()
+	return limit > 0 && kv.used.Load() > limit
+}
+
+// EvictedKeys returns how many keys have been evicted.
+func (kv *ValueStore) EvictedKeys() int64 {
+	return kv.evictedKeys.Load()
+}
+
+// Evict evicts keys until they fit in the limit, calling evicted with each,
+// and reports whether they do. It fails with noeviction, or once nothing the
+// policy may evict is left.
+func (kv *ValueStore) Evict(evicted func(key string)) bool {
+	if !kv.OverMaxMemory() {
+		return true
+	}
+	kv.evictMu.Lock()
+	defer kv.evictMu.Unlock()
+
+	policy := EvictionPolicy(kv.policy.Load())
+	if policy == NoEviction {
+		return !kv.OverMaxMemory()
+	}
+	for kv.OverMaxMemory() {
+		var key string
+		if policy == AllKeysRandom || policy == VolatileRandom {
+			var ok bool
+			if key, ok = kv.randomKey(policy.volatile()); !ok {
+				return false
+			}
+		} else {
+			if !kv.fillPool(policy) {
+				return false
+			}
+			// the best candidate is last
+			key = kv.pool[len(kv.pool)-1].key
+			kv.pool = kv.pool[:len(kv.pool)-1]
+		}
+		if kv.evictKey(key, policy.volatile()) {
+			evicted(key)
+		}
+	}
+	return true
+}
+
+// evictKey evicts key, unless it is gone or, for a volatile policy, no
+// longer has a deadline since it was sampled.
+func (kv *ValueStore) evictKey(key string, volatile bool) bool {
+	defer kv.lockKey(key).Unlock()
+	if _, ok := kv.lookupWrite(key); !ok {
+		return false
+	}
+	if volatile && kv.expireAt(key) == 0 {
+		return false
+	}
+	kv.remove(key)
+	kv.modified(key)
+	kv.evictedKeys.Add(1)
+	return true
+}
+
+// fillPool samples keys from evictionShards shards, starting at a random
+// one, into the pool. It reports whether the pool has any candidate.
+func (kv *ValueStore) fillPool(policy EvictionPolicy) bool {
+	start := rand.IntN(shardCount)
+	sampled := 0
+	for i := 0; i < shardCount && sampled < evictionShards; i++ {
+		s := kv.shards[(start+i)%shardCount]
+		s.mu.RLock()
+		if kv.sampleShard(s, policy) {
+			sampled++
+		}
+		s.mu.RUnlock()
+	}
+	return len(kv.pool) > 0
+}
+
+// sampleShard adds samples keys of s to the pool, reporting whether it had
+// any. Caller must hold its read lock and kv.evictMu.
+func (kv *ValueStore) sampleShard(s *shard, policy EvictionPolicy) bool {
+	n := 0
+	add := func(key string, it *item) {
+		n++
+		var score int64
+		switch policy {
+		case AllKeysLRU, VolatileLRU:
+			score = kv.now() - it.access.Load() // idle time
+		case AllKeysLFU, VolatileLFU:
+			score = 255 - int64(kv.lfuCounter(it))
+		case VolatileTTL:
+			score = math.MaxInt64 - s.expiration[key] // expiring soonest
+		}
+		kv.addCandidate(evictionCandidate{key: key, score: score})
+	}
+	if policy.volatile() {
+		for key := range s.expiration {
+			if n == kv.samples {
+				break
+			}
+			add(key, s.store[key])
+		}
+	} else {
+		for key, it := range s.store {
+			if n == kv.samples {
+				break
+			}
+			add(key, it)
+		}
+	}
+	return n > 0
+}
+
+// addCandidate adds c to the pool, kept in ascending order of score, unless
+// the pool is full of better candidates.
+func (kv *ValueStore) addCandidate(c evictionCandidate) {
+	if i := slices.IndexFunc(kv.pool, func(p evictionCandidate) bool { return p.key == c.key }); i >= 0 {
+		kv.pool = slices.Delete(kv.pool, i, i+1)
+	}
+	i, _ := slices.BinarySearchFunc(kv.pool, c.score, func(p evictionCandidate, score int64) int {
+		return cmp.Compare(p.score, score)
+	})
+	if len(kv.pool) == evictionPoolSize {
+		if i == 0 {
+			return
+		}
+		// drop the worst to make room
+		kv.pool = kv.pool[1:]
+		i--
+	}
+	kv.pool = slices.Insert(kv.pool, i, c)
+}
+
+// randomKey returns a key, with a deadline if volatile, from a random shard.
+func (kv *ValueStore) randomKey(volatile bool) (string, bool) {
+	start := rand.IntN(shardCount)
+	for i := 0; i < shardCount; i++ {
+		s := kv.shards[(start+i)%shardCount]
+		s.mu.RLock()
+		var key string
+		found := false
+		if volatile {
+			for key = range s.expiration {
+				found = true
+				break
+			}
+		} else {
+			for key = range s.store {
+				found = true
+				break
+			}
+		}
+		s.mu.RUnlock()
+		if found {
+			return key, true
+		}
+	}
+	return "", false
+}
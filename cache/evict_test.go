@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestUsedMemory(t *testing.T) {
+	kv := NewValueStore(time.Hour)
+	kv.Set("small", "x", 0)
+	small := kv.UsedMemory()
+	kv.Set("large", string(make([]byte, 10000)), 0)
+	if used := kv.UsedMemory(); used < small+10000 || used > small+11000 {
+		t.Errorf("UsedMemory() failed. Expected about %d bytes, got: %d", small+10000, used)
+	}
+
+	for i := 0; i < 1000; i++ {
+		kv.ListPush("list", false, "0123456789")
+		kv.HashSet("hash", strconv.Itoa(i), "0123456789")
+	}
+	if used := kv.UsedMemory(); used < small+10000+2*1000*10 {
+		t.Errorf("UsedMemory() failed. Expected collections counted by their elements, got: %d", used)
+	}
+
+	for _, key := range []string{"small", "large", "list", "hash"} {
+		kv.Delete(key)
+	}
+	if used := kv.UsedMemory(); used != 0 {
+		t.Errorf("UsedMemory() failed. Expected 0 once every key is deleted, got: %d", used)
+	}
+}
+
+// fill sets n keys, key:0 to key:n-1, with ttl, each a tick of the clock
+// after the last, and limits memory to what half of them take.
+func fill(kv *ValueStore, n int, ttl time.Duration, policy EvictionPolicy) {
+	for i := 0; i < n; i++ {
+		kv.clock.Add(1000)
+		kv.Set("key:"+strconv.Itoa(i), "value", ttl)
+	}
+	kv.SetMaxMemory(kv.UsedMemory()/2, policy, 5)
+}
+
+func countEvicted(kv *ValueStore, keys []string) int {
+	n := 0
+	for _, key := range keys {
+		if !kv.Exists(key) {
+			n++
+		}
+	}
+	return n
+}
+
+func TestEvict(t *testing.T) {
+	kv := NewValueStore(time.Hour)
+	fill(kv, 1000, 0, AllKeysLRU)
+	// the oldest half is accessed again, so the newest should go instead
+	var old, recent []string
+	for i := 0; i < 1000; i++ {
+		key := "key:" + strconv.Itoa(i)
+		if i < 500 {
+			old = append(old, key)
+		} else {
+			recent = append(recent, key)
+		}
+	}
+	kv.clock.Add(1000)
+	for _, key := range old {
+		kv.Get(key)
+	}
+
+	evicted := 0
+	if !kv.Evict(func(string) { evicted++ }) {
+		t.Fatalf("Evict() failed. Expected memory freed with allkeys-lru")
+	}
+	if kv.OverMaxMemory() || evicted < 400 || int64(evicted) != kv.EvictedKeys() {
+		t.Errorf("Evict() failed. Expected about half the keys evicted, got: %d, %d bytes used", evicted, kv.UsedMemory())
+	}
+	// sampling only approximates LRU
+	if n := countEvicted(kv, old); n > evicted/4 {
+		t.Errorf("Evict() failed. Expected mostly the idle keys evicted, got: %d of %d recently used", n, evicted)
+	}
+}
+
+func TestEvictLFU(t *testing.T) {
+	kv := NewValueStore(time.Hour)
+	fill(kv, 1000, 0, AllKeysLFU)
+	var frequent []string
+	for i := 0; i < 1000; i += 2 {
+		frequent = append(frequent, "key:"+strconv.Itoa(i))
+	}
+	for round := 0; round < 100; round++ {
+		for _, key := range frequent {
+			kv.Get(key)
+		}
+	}
+
+	evicted := 0
+	kv.Evict(func(string) { evicted++ })
+	if n := countEvicted(kv, frequent); n > evicted/4 {
+		t.Errorf("Evict() failed. Expected mostly the rarely used keys evicted, got: %d of %d frequently used", n, evicted)
+	}
+}
+
+func TestEvictVolatile(t *testing.T) {
+	kv := NewValueStore(time.Hour)
+	for i := 0; i < 100; i++ {
+		kv.Set("persistent:"+strconv.Itoa(i), "value", 0)
+		kv.Set("volatile:"+strconv.Itoa(i), "value", time.Duration(i+1)*time.Minute)
+	}
+
+	// volatile-ttl evicts the keys expiring soonest
+	kv.SetMaxMemory(kv.UsedMemory()*3/4, VolatileTTL, 5)
+	if !kv.Evict(func(string) {}) {
+		t.Fatalf("Evict() failed. Expected memory freed with volatile-ttl")
+	}
+	if kv.Exists("persistent:0") != true || kv.Exists("volatile:99") != true {
+		t.Errorf("Evict() failed. Expected persistent keys and the last to expire kept")
+	}
+
+	// with only persistent keys left there is nothing to evict
+	kv.SetMaxMemory(1, VolatileRandom, 5)
+	if kv.Evict(func(string) {}) {
+		t.Errorf("Evict() failed. Expected to fail once no key has a deadline")
+	}
+	if !kv.Exists("persistent:0") || kv.Exists("volatile:99") {
+		t.Errorf("Evict() failed. Expected every volatile key and no other evicted")
+	}
+
+	kv.SetMaxMemory(1, NoEviction, 5)
+	if kv.Evict(func(string) {}) || !kv.Exists("persistent:0") {
+		t.Errorf("Evict() failed. Expected noeviction to evict nothing")
+	}
+}
@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"math"
+	"time"
+)
+
+// Keys are removed once their deadline passes in two ways, as upstream does:
+// lazily, by the first write to touch the key, and actively, by a cycle that
+// runs hz times a second. Rather than scan every key, the cycle samples keys
+// with a deadline, a shard at a time. When more than a quarter of a sample had
+// expired, many more probably have, so the shard is sampled again. A cycle may
+// take a quarter of the interval between cycles; the next one carries on from
+// the shard where it stopped.
+
+const (
+	expireSampleKeys = 20 // keys sampled at a time, ACTIVE_EXPIRE_CYCLE_KEYS_PER_LOOP
+	expireStalePerc  = 25 // sample again while more than this percentage had expired
+	expireTimePerc   = 25 // percentage of the interval a cycle may take
+)
+
+// ExpireStats counts the keys removed for having expired, for INFO.
+type ExpireStats struct {
+	// ExpiredKeys is how many keys have expired, lazily or actively.
+	ExpiredKeys int64
+	// StalePerc estimates the percentage of keys with a deadline that have
+	// expired but not yet been removed, from recent samples.
+	StalePerc float64
+	// TimeCapReached counts cycles that stopped for running out of time.
+	TimeCapReached int64
+}
+
+func (kv *ValueStore) ExpireStats() ExpireStats {
+	return ExpireStats{
+		ExpiredKeys:    kv.expiredKeys.Load(),
+		StalePerc:      math.Float64frombits(kv.staleRatio.Load()) * 100,
+		TimeCapReached: kv.timeCapReached.Load(),
+	}
+}
+
+// activeExpire runs an expire cycle every interval, refreshing the clock
+// eviction reads first.
+func (kv *ValueStore) activeExpire(interval time.Duration) {
+	next := 0
+	for {
+		time.Sleep(interval)
+		kv.clock.Store(time.Now().UnixMilli())
+		next = kv.expireCycle(next, interval*expireTimePerc/100)
+	}
+}
+
+// expireCycle samples each shard in turn, starting from next, until all have
+// been sampled or budget is spent. It returns the shard to start the next
+// cycle from.
+func (kv *ValueStore) expireCycle(next int, budget time.Duration) int {
+	deadline := time.Now().Add(budget)
+	var sampled, expired int
+	for visited := 0; visited < shardCount; visited++ {
+		n, e, done := kv.expireShard(kv.shards[next], deadline)
+		sampled += n
+		expired += e
+		if !done {
+			kv.timeCapReached.Add(1)
+			break
+		}
+		next = (next + 1) % shardCount
+	}
+
+	// a moving average, as upstream keeps, so one unlucky sample does not
+	// swing the estimate
+	if sampled > 0 {
+		current := float64(expired) / float64(sampled)
+		stale := math.Float64frombits(kv.staleRatio.Load())
+		kv.staleRatio.Store(math.Float64bits(current*0.05 + stale*0.95))
+	}
+	return next
+}
+
+// expireShard samples the keys of s with a deadline, removing those that
+// have expired, until a sample is mostly live. done is false if it stopped at
+// deadline instead.
+func (kv *ValueStore) expireShard(s *shard, deadline time.Time) (sampled, expired int, done bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for {
+		now := time.Now()
+		if now.After(deadline) {
+			return sampled, expired, false
+		}
+		n, e := 0, 0
+		// ranging over a map starts at a random position, which makes for a
+		// random sample
+		for key, exp := range s.expiration {
+			if n == expireSampleKeys {
+				break
+			}
+			n++
+			if now.UnixNano() > exp {
+				kv.expire(key)
+				e++
+			}
+		}
+		sampled += n
+		expired += e
+		if e*100 <= n*expireStalePerc {
+			return sampled, expired, true
+		}
+	}
+}
+
+// expire removes key, whose deadline has passed. Caller must hold the write
+// lock of its shard.
+func (kv *ValueStore) expire(key string) {
+	kv.remove(key)
+	kv.modified(key)
+	kv.expiredKeys.Add(1)
+}
@@ -0,0 +1,62 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// keysWithDeadline counts the keys held with a deadline, expired or not.
+func keysWithDeadline(kv *ValueStore) int {
+	n := 0
+	for _, s := range kv.shards {
+		s.mu.RLock()
+		n += len(s.expiration)
+		s.mu.RUnlock()
+	}
+	return n
+}
+
+func TestExpireCycle(t *testing.T) {
+	kv := NewValueStore(time.Hour)
+	for i := 0; i < 5000; i++ {
+		kv.Set("expiring:"+strconv.Itoa(i), "value", time.Millisecond)
+	}
+	for i := 0; i < 1000; i++ {
+		kv.Set("live:"+strconv.Itoa(i), "value", time.Hour)
+		kv.Set("persistent:"+strconv.Itoa(i), "value", 0)
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	// with most of the sampled keys expired each shard is sampled until
+	// nearly all are gone, in a single cycle
+	kv.expireCycle(0, time.Minute)
+	if n := keysWithDeadline(kv); n > 1000+shardCount*expireSampleKeys {
+		t.Errorf("expireCycle() failed. Expected nearly all expired keys removed, got: %d keys with a deadline", n)
+	}
+	stats := kv.ExpireStats()
+	if stats.ExpiredKeys < 4000 || stats.StalePerc <= 0 {
+		t.Errorf("ExpireStats() failed. Expected thousands of expired keys and a stale percentage, got: %+v", stats)
+	}
+	if len(kv.GetKeys()) != 2000 {
+		t.Errorf("expireCycle() failed. Expected the live keys kept, got: %d keys", len(kv.GetKeys()))
+	}
+
+	// out of time, a cycle stops where it is and the next carries on
+	if next := kv.expireCycle(7, 0); next != 7 || kv.ExpireStats().TimeCapReached != 1 {
+		t.Errorf("expireCycle() failed. Expected to stop at shard 7 out of time, got: %d, %+v", next, kv.ExpireStats())
+	}
+}
+
+func TestLazyExpire(t *testing.T) {
+	kv := NewValueStore(time.Hour)
+	kv.Set("key", "value", time.Millisecond)
+	time.Sleep(2 * time.Millisecond)
+
+	if kv.Exists("key") {
+		t.Errorf("Exists() failed. Expected an expired key to be missing")
+	}
+	if kv.Delete("key") || kv.ExpireStats().ExpiredKeys != 1 {
+		t.Errorf("Delete() failed. Expected the expired key counted as expired, got: %+v", kv.ExpireStats())
+	}
+}
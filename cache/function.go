@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"slices"
+)
+
+// The function libraries of FUNCTION LOAD belong to the dataset, as they do
+// upstream, so they are saved, rewritten and replicated along with the keys.
+// The store only keeps their code; the scripting engine compiles it.
+
+// Libraries returns the code of every function library, and a version that
+// changes whenever the libraries do.
+func (kv *ValueStore) Libraries() ([]string, uint64) {
+	kv.libMu.RLock()
+	defer kv.libMu.RUnlock()
+	return slices.Clone(kv.libraries), kv.libVersion
+}
+
+// UpdateLibraries replaces the libraries with those fn returns, atomically.
+// If fn fails the libraries are left as they were.
+func (kv *ValueStore) UpdateLibraries(fn func(codes []string) ([]string, error)) error {
+	kv.libMu.Lock()
+	defer kv.libMu.Unlock()
+	codes, err := fn(slices.Clone(kv.libraries))
+	if err != nil {
+		return err
+	}
+	kv.setLibraries(codes)
+	kv.changed()
+	return nil
+}
+
+// setLibraries replaces the libraries. Caller must hold kv.libMu.
+func (kv *ValueStore) setLibraries(codes []string) {
+	kv.libraries = codes
+	kv.libVersion++
+}
@@ -0,0 +1,247 @@
+package cache
+
+import (
+	"errors"
+	"math"
+	"strconv"
+)
+
+var (
+	ErrHashNotInteger = errors.New("ERR hash value is not an integer")
+	ErrHashNotFloat   = errors.New("ERR hash value is not a float")
+	ErrOverflow       = errors.New("ERR increment or decrement would overflow")
+	ErrNaN            = errors.New("ERR increment would produce NaN or Infinity")
+)
+
+// Hash maps fields to values.
+type Hash map[string]string
+
+// getHash returns the hash at key. When create is set a missing key is
+// initialised with an empty hash. Caller must hold the write lock of its shard.
+func (kv *ValueStore) getHash(key string, create bool) (Hash, error) {
+	value, ok := kv.lookupWrite(key)
+	if !ok {
+		if !create {
+			return nil, nil
+		}
+		hash := make(Hash)
+		kv.put(key, hash, 0)
+		return hash, nil
+	}
+	hash, ok := value.(Hash)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	return hash, nil
+}
+
+// readHash is getHash for callers holding only the read lock.
+func (kv *ValueStore) readHash(key string) (Hash, error) {
+	value, ok := kv.lookupRead(key)
+	if !ok {
+		return nil, nil
+	}
+	hash, ok := value.(Hash)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	return hash, nil
+}
+
+// HashSet sets field value pairs in the hash at key and returns how many
+// fields were newly created.
+func (kv *ValueStore) HashSet(key string, pairs ...string) (int, error) {
+	defer kv.lockKey(key).Unlock()
+
+	hash, err := kv.getHash(key, true)
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if _, exists := hash[pairs[i]]; !exists {
+			added++
+		}
+		hash[pairs[i]] = pairs[i+1]
+	}
+	kv.modified(key)
+	return added, nil
+}
+
+// HashSetNX sets field only if it does not exist yet.
+func (kv *ValueStore) HashSetNX(key, field, value string) (bool, error) {
+	defer kv.lockKey(key).Unlock()
+
+	hash, err := kv.getHash(key, true)
+	if err != nil {
+		return false, err
+	}
+	if _, exists := hash[field]; exists {
+		return false, nil
+	}
+	hash[field] = value
+	kv.modified(key)
+	return true, nil
+}
+
+func (kv *ValueStore) HashGet(key, field string) (string, bool, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	hash, err := kv.readHash(key)
+	if hash == nil || err != nil {
+		return "", false, err
+	}
+	value, ok := hash[field]
+	return value, ok, nil
+}
+
+// HashMGet returns the values of fields, with nil for fields that do not exist.
+func (kv *ValueStore) HashMGet(key string, fields ...string) ([]*string, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	hash, err := kv.readHash(key)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]*string, len(fields))
+	for i, field := range fields {
+		if value, ok := hash[field]; ok {
+			values[i] = &value
+		}
+	}
+	return values, nil
+}
+
+// HashDel removes fields and returns how many existed. Hashes that become
+// empty are deleted.
+func (kv *ValueStore) HashDel(key string, fields ...string) (int, error) {
+	defer kv.lockKey(key).Unlock()
+
+	hash, err := kv.getHash(key, false)
+	if hash == nil || err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, field := range fields {
+		if _, exists := hash[field]; exists {
+			delete(hash, field)
+			removed++
+		}
+	}
+	if len(hash) == 0 {
+		kv.remove(key)
+	}
+	if removed > 0 {
+		kv.modified(key)
+	}
+	return removed, nil
+}
+
+// HashGetAll returns a copy of the hash at key.
+func (kv *ValueStore) HashGetAll(key string) (Hash, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	hash, err := kv.readHash(key)
+	if err != nil {
+		return nil, err
+	}
+
+	copied := make(Hash, len(hash))
+	for field, value := range hash {
+		copied[field] = value
+	}
+	return copied, nil
+}
+
+func (kv *ValueStore) HashLen(key string) (int, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	hash, err := kv.readHash(key)
+	return len(hash), err
+}
+
+// HashIncrBy adds delta to the integer stored in field, treating a missing
+// field as 0.
+func (kv *ValueStore) HashIncrBy(key, field string, delta int64) (int64, error) {
+	defer kv.lockKey(key).Unlock()
+
+	hash, err := kv.getHash(key, true)
+	if err != nil {
+		return 0, err
+	}
+
+	var current int64
+	if value, exists := hash[field]; exists {
+		current, err = strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, ErrHashNotInteger
+		}
+	}
+	if (delta > 0 && current > math.MaxInt64-delta) || (delta < 0 && current < math.MinInt64-delta) {
+		return 0, ErrOverflow
+	}
+
+	current += delta
+	hash[field] = strconv.FormatInt(current, 10)
+	kv.modified(key)
+	return current, nil
+}
+
+// HashIncrByFloat adds delta to the number stored in field, treating a
+// missing field as 0.
+func (kv *ValueStore) HashIncrByFloat(key, field string, delta float64) (float64, error) {
+	defer kv.lockKey(key).Unlock()
+
+	hash, err := kv.getHash(key, true)
+	if err != nil {
+		return 0, err
+	}
+
+	var current float64
+	if value, exists := hash[field]; exists {
+		current, err = strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(current) || math.IsInf(current, 0) {
+			return 0, ErrHashNotFloat
+		}
+	}
+
+	current += delta
+	if math.IsNaN(current) || math.IsInf(current, 0) {
+		return 0, ErrNaN
+	}
+	hash[field] = FormatFloat(current)
+	kv.modified(key)
+	return current, nil
+}
+
+// HashScan returns a page of field value pairs starting at cursor; see
+// scanPage for the cursor semantics.
+func (kv *ValueStore) HashScan(key string, cursor uint64, match string, count int) (uint64, []string, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	hash, err := kv.readHash(key)
+	if hash == nil || err != nil {
+		return 0, []string{}, err
+	}
+
+	fields := make([]string, 0, len(hash))
+	for field := range hash {
+		fields = append(fields, field)
+	}
+
+	next, page := scanPage(fields, cursor, match, count)
+	pairs := make([]string, 0, len(page)*2)
+	for _, field := range page {
+		pairs = append(pairs, field, hash[field])
+	}
+	return next, pairs, nil
+}
+
+// FormatFloat formats f the way upstream replies with floating point values:
+// as short as possible, without an exponent.
+func FormatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
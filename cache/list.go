@@ -0,0 +1,357 @@
+package cache
+
+// List is a double ended queue of strings backed by a ring buffer, so pushes
+// and pops at either end are O(1) and indexing does not walk the list.
+type List struct {
+	items []string
+	head  int
+	size  int
+}
+
+func NewList() *List {
+	return &List{}
+}
+
+func (l *List) Len() int {
+	return l.size
+}
+
+// At returns the element at index i, which must be in [0, Len()).
+func (l *List) At(i int) string {
+	return l.items[(l.head+i)%len(l.items)]
+}
+
+func (l *List) set(i int, value string) {
+	l.items[(l.head+i)%len(l.items)] = value
+}
+
+func (l *List) grow() {
+	if l.size < len(l.items) {
+		return
+	}
+	capacity := len(l.items) * 2
+	if capacity == 0 {
+		capacity = 8
+	}
+	items := make([]string, capacity)
+	for i := 0; i < l.size; i++ {
+		items[i] = l.At(i)
+	}
+	l.items = items
+	l.head = 0
+}
+
+func (l *List) PushFront(value string) {
+	l.grow()
+	l.head = (l.head - 1 + len(l.items)) % len(l.items)
+	l.items[l.head] = value
+	l.size++
+}
+
+func (l *List) PushBack(value string) {
+	l.grow()
+	l.items[(l.head+l.size)%len(l.items)] = value
+	l.size++
+}
+
+// PopFront removes and returns the first element. The list must not be empty.
+func (l *List) PopFront() string {
+	value := l.items[l.head]
+	l.items[l.head] = ""
+	l.head = (l.head + 1) % len(l.items)
+	l.size--
+	return value
+}
+
+// PopBack removes and returns the last element. The list must not be empty.
+func (l *List) PopBack() string {
+	i := (l.head + l.size - 1) % len(l.items)
+	value := l.items[i]
+	l.items[i] = ""
+	l.size--
+	return value
+}
+
+// Range returns the elements between start and stop inclusive, using the
+// upstream LRANGE index rules: negative indexes count from the tail and out
+// of range indexes are clamped.
+func (l *List) Range(start, stop int) []string {
+	start, stop, ok := normalizeRange(start, stop, l.size)
+	if !ok {
+		return []string{}
+	}
+	values := make([]string, 0, stop-start+1)
+	for i := start; i <= stop; i++ {
+		values = append(values, l.At(i))
+	}
+	return values
+}
+
+// Values returns a copy of every element, head first.
+func (l *List) Values() []string {
+	return l.Range(0, -1)
+}
+
+// replace swaps the contents of the list for values.
+func (l *List) replace(values []string) {
+	l.items = values
+	l.head = 0
+	l.size = len(values)
+}
+
+// normalizeRange converts inclusive start and stop indexes, possibly negative,
+// into offsets within a sequence of the given length. ok is false when the
+// range is empty.
+func normalizeRange(start, stop, length int) (int, int, bool) {
+	if start < 0 {
+		start += length
+	}
+	if stop < 0 {
+		stop += length
+	}
+	if start < 0 {
+		start = 0
+	}
+	if stop >= length {
+		stop = length - 1
+	}
+	if start > stop || start >= length {
+		return 0, 0, false
+	}
+	return start, stop, true
+}
+
+// getList returns the list at key. When create is set a missing key is
+// initialised with an empty list. Caller must hold the write lock of its shard.
+func (kv *ValueStore) getList(key string, create bool) (*List, error) {
+	value, ok := kv.lookupWrite(key)
+	if !ok {
+		if !create {
+			return nil, nil
+		}
+		list := NewList()
+		kv.put(key, list, 0)
+		return list, nil
+	}
+	list, ok := value.(*List)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	return list, nil
+}
+
+// readList is getList for callers holding only the read lock.
+func (kv *ValueStore) readList(key string) (*List, error) {
+	value, ok := kv.lookupRead(key)
+	if !ok {
+		return nil, nil
+	}
+	list, ok := value.(*List)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	return list, nil
+}
+
+// ListPush adds values to the head (front) or tail of the list at key,
+// creating it if needed, and returns the new length.
+func (kv *ValueStore) ListPush(key string, front bool, values ...string) (int, error) {
+	defer kv.lockKey(key).Unlock()
+
+	list, err := kv.getList(key, true)
+	if err != nil {
+		return 0, err
+	}
+	for _, value := range values {
+		if front {
+			list.PushFront(value)
+		} else {
+			list.PushBack(value)
+		}
+	}
+	kv.modified(key)
+	return list.Len(), nil
+}
+
+// ListPop removes up to count elements from the head (front) or tail of the
+// list at key. A nil slice means the key does not exist. Lists that become
+// empty are deleted.
+func (kv *ValueStore) ListPop(key string, front bool, count int) ([]string, error) {
+	defer kv.lockKey(key).Unlock()
+
+	list, err := kv.getList(key, false)
+	if list == nil || err != nil {
+		return nil, err
+	}
+
+	values := make([]string, 0, min(count, list.Len()))
+	for len(values) < count && list.Len() > 0 {
+		if front {
+			values = append(values, list.PopFront())
+		} else {
+			values = append(values, list.PopBack())
+		}
+	}
+	if list.Len() == 0 {
+		kv.remove(key)
+	}
+	if len(values) > 0 {
+		kv.modified(key)
+	}
+	return values, nil
+}
+
+func (kv *ValueStore) ListLen(key string) (int, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	list, err := kv.readList(key)
+	if list == nil || err != nil {
+		return 0, err
+	}
+	return list.Len(), nil
+}
+
+func (kv *ValueStore) ListRange(key string, start, stop int) ([]string, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	list, err := kv.readList(key)
+	if err != nil {
+		return nil, err
+	}
+	if list == nil {
+		return []string{}, nil
+	}
+	return list.Range(start, stop), nil
+}
+
+// ListIndex returns the element at index, which may be negative to count
+// from the tail.
+func (kv *ValueStore) ListIndex(key string, index int) (string, bool, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	list, err := kv.readList(key)
+	if list == nil || err != nil {
+		return "", false, err
+	}
+	if index < 0 {
+		index += list.Len()
+	}
+	if index < 0 || index >= list.Len() {
+		return "", false, nil
+	}
+	return list.At(index), true, nil
+}
+
+func (kv *ValueStore) ListSet(key string, index int, value string) error {
+	defer kv.lockKey(key).Unlock()
+
+	list, err := kv.getList(key, false)
+	if err != nil {
+		return err
+	}
+	if list == nil {
+		return ErrNoSuchKey
+	}
+	if index < 0 {
+		index += list.Len()
+	}
+	if index < 0 || index >= list.Len() {
+		return ErrOutOfRange
+	}
+	list.set(index, value)
+	kv.modified(key)
+	return nil
+}
+
+// ListRem removes elements equal to value. A positive count removes the first
+// count matches from the head, a negative count the first matches from the
+// tail and zero removes them all. It returns the number removed.
+func (kv *ValueStore) ListRem(key string, count int, value string) (int, error) {
+	defer kv.lockKey(key).Unlock()
+
+	list, err := kv.getList(key, false)
+	if list == nil || err != nil {
+		return 0, err
+	}
+
+	values := list.Values()
+	remove := make([]bool, len(values))
+	removed := 0
+	if count >= 0 {
+		for i := 0; i < len(values) && (count == 0 || removed < count); i++ {
+			if values[i] == value {
+				remove[i] = true
+				removed++
+			}
+		}
+	} else {
+		for i := len(values) - 1; i >= 0 && removed < -count; i-- {
+			if values[i] == value {
+				remove[i] = true
+				removed++
+			}
+		}
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+
+	kept := make([]string, 0, len(values)-removed)
+	for i, v := range values {
+		if !remove[i] {
+			kept = append(kept, v)
+		}
+	}
+	list.replace(kept)
+	if list.Len() == 0 {
+		kv.remove(key)
+	}
+	kv.modified(key)
+	return removed, nil
+}
+
+// ListTrim keeps only the elements between start and stop inclusive.
+func (kv *ValueStore) ListTrim(key string, start, stop int) error {
+	defer kv.lockKey(key).Unlock()
+
+	list, err := kv.getList(key, false)
+	if list == nil || err != nil {
+		return err
+	}
+	length := list.Len()
+	list.replace(list.Range(start, stop))
+	if list.Len() == 0 {
+		kv.remove(key)
+	}
+	if list.Len() != length {
+		kv.modified(key)
+	}
+	return nil
+}
+
+// ListInsert inserts value before or after the first occurrence of pivot. It
+// returns the new length, -1 if pivot was not found or 0 if the key does not
+// exist.
+func (kv *ValueStore) ListInsert(key string, before bool, pivot, value string) (int, error) {
+	defer kv.lockKey(key).Unlock()
+
+	list, err := kv.getList(key, false)
+	if list == nil || err != nil {
+		return 0, err
+	}
+
+	values := list.Values()
+	for i, v := range values {
+		if v != pivot {
+			continue
+		}
+		if !before {
+			i++
+		}
+		values = append(values[:i], append([]string{value}, values[i:]...)...)
+		list.replace(values)
+		kv.modified(key)
+		return list.Len(), nil
+	}
+	return -1, nil
+}
@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestListWrapsAround(t *testing.T) {
+	l := NewList()
+	for _, v := range []string{"c", "d", "e"} {
+		l.PushBack(v)
+	}
+	l.PushFront("b")
+	l.PushFront("a")
+	l.PopFront()
+
+	// push enough to force the ring buffer to grow while wrapped
+	for _, v := range []string{"f", "g", "h", "i", "j", "k"} {
+		l.PushBack(v)
+	}
+
+	expected := []string{"b", "c", "d", "e", "f", "g", "h", "i", "j", "k"}
+	if actual := l.Values(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("List Values() failed. Expected: %v, got: %v", expected, actual)
+	}
+	if l.Len() != len(expected) {
+		t.Errorf("List Len() failed. Expected: %d, got: %d", len(expected), l.Len())
+	}
+	if back := l.PopBack(); back != "k" {
+		t.Errorf("List PopBack() failed. Expected: %q, got: %q", "k", back)
+	}
+}
+
+func TestListRange(t *testing.T) {
+	l := NewList()
+	for _, v := range []string{"a", "b", "c", "d"} {
+		l.PushBack(v)
+	}
+
+	tests := []struct {
+		start, stop int
+		expected    []string
+	}{
+		{0, -1, []string{"a", "b", "c", "d"}},
+		{1, 2, []string{"b", "c"}},
+		{-2, -1, []string{"c", "d"}},
+		{-100, 100, []string{"a", "b", "c", "d"}},
+		{3, 1, []string{}},
+		{5, 10, []string{}},
+	}
+	for _, tt := range tests {
+		if actual := l.Range(tt.start, tt.stop); !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("List Range(%d, %d) failed. Expected: %v, got: %v", tt.start, tt.stop, tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package cache
+
+import (
+	"math/rand/v2"
+	"sync/atomic"
+)
+
+// Memory is accounted per key, by an estimate of what the key and its value
+// take, refreshed whenever the key is modified. Estimating a collection
+// samples a few of its elements, as upstream's MEMORY USAGE does, so the cost
+// of a write does not grow with the size of the value it changes.
+
+// item is a value in a shard, with what eviction needs to know of it.
+type item struct {
+	value any
+	size  int64 // estimated bytes, guarded by the shard's write lock
+
+	// set under the read lock too, so atomic
+	access atomic.Int64  // clock when it was last accessed, for LRU
+	lfu    atomic.Uint32 // minutes << 8 | counter, for LFU
+}
+
+const (
+	sizeSamples = 5 // elements sampled to estimate a collection
+
+	keyOverhead     = 96 // map entries, item and string header of a key
+	stringOverhead  = 16
+	elementOverhead = 32 // per element of a collection, beyond its strings
+	zsetOverhead    = 64 // per member of a sorted set, for its skiplist node
+)
+
+// estimateSize returns about how many bytes key and value take.
+func estimateSize(key string, value any) int64 {
+	size := keyOverhead + len(key)
+	switch v := value.(type) {
+	case string:
+		size += stringOverhead + len(v)
+	case *List:
+		size += 16*len(v.items) + v.size*(stringOverhead+sampleLen(v.size, func(i int) int {
+			return len(v.At(i))
+		}))
+	case Hash:
+		size += len(v) * (elementOverhead + sampleMap(v, func(field, value string) int {
+			return len(field) + len(value)
+		}))
+	case Set:
+		size += len(v) * (elementOverhead + sampleMap(v, func(member string, _ struct{}) int {
+			return len(member)
+		}))
+	case *ZSet:
+		size += v.Len() * (elementOverhead + zsetOverhead + sampleMap(v.dict, func(member string, _ float64) int {
+			return len(member)
+		}))
+	case *Stream:
+		size += len(v.entries) * (elementOverhead + sampleLen(len(v.entries), func(i int) int {
+			n := 0
+			for _, field := range v.entries[i].Fields {
+				n += stringOverhead + len(field)
+			}
+			return n
+		}))
+	}
+	return int64(size)
+}
+
+// sampleLen returns the average of length over up to sizeSamples of n
+// indexes, spread across them.
+func sampleLen(n int, length func(i int) int) int {
+	if n == 0 {
+		return 0
+	}
+	samples := min(n, sizeSamples)
+	total := 0
+	for i := 0; i < samples; i++ {
+		total += length(i * n / samples)
+	}
+	return total / samples
+}
+
+// sampleMap is sampleLen for the first entries a range over m returns, which
+// start at a random one.
+func sampleMap[K comparable, V any](m map[K]V, length func(K, V) int) int {
+	samples, total := 0, 0
+	for k, v := range m {
+		if samples == sizeSamples {
+			break
+		}
+		total += length(k, v)
+		samples++
+	}
+	if samples == 0 {
+		return 0
+	}
+	return total / samples
+}
+
+// account refreshes the size of key after a change. Caller must hold the
+// write lock of its shard.
+func (kv *ValueStore) account(key string) {
+	it := kv.shard(key).store[key]
+	if it == nil {
+		return
+	}
+	size := estimateSize(key, it.value)
+	kv.used.Add(size - it.size)
+	it.size = size
+}
+
+// UsedMemory returns the estimated bytes taken by every key.
+func (kv *ValueStore) UsedMemory() int64 {
+	return kv.used.Load()
+}
+
+// The clock eviction reads is refreshed by the expire cycle rather than read
+// on every access, as upstream's LRU clock is; reading the time costs more
+// than a lookup.
+
+// now returns the clock in milliseconds.
+func (kv *ValueStore) now() int64 {
+	return kv.clock.Load()
+}
+
+// LFU counters, after upstream: a counter grows logarithmically with the
+// accesses, starting from lfuInitVal so new keys are not evicted first, and
+// loses one for every minute the key goes without one.
+const (
+	lfuInitVal   = 5
+	lfuLogFactor = 10
+)
+
+// touch records an access to it.
+func (kv *ValueStore) touch(it *item) {
+	now := kv.now()
+	if it.access.Load() != now {
+		it.access.Store(now)
+	}
+	if EvictionPolicy(kv.policy.Load()).lfu() {
+		counter := lfuIncr(kv.lfuCounter(it))
+		it.lfu.Store(uint32(kv.minutes())<<8 | uint32(counter))
+	}
+}
+
+// minutes returns the clock in minutes, wrapping at 16 bits.
+func (kv *ValueStore) minutes() uint16 {
+	return uint16(kv.now() / 60000)
+}
+
+// lfuCounter returns the counter of it, decayed for the minutes since its
+// last access.
+func (kv *ValueStore) lfuCounter(it *item) uint8 {
+	lfu := it.lfu.Load()
+	counter := uint8(lfu)
+	elapsed := kv.minutes() - uint16(lfu>>8)
+	if uint32(elapsed) >= uint32(counter) {
+		return 0
+	}
+	return counter - uint8(elapsed)
+}
+
+func lfuIncr(counter uint8) uint8 {
+	if counter == 255 {
+		return counter
+	}
+	base := max(float64(counter)-lfuInitVal, 0)
+	if rand.Float64() < 1/(base*lfuLogFactor+1) {
+		counter++
+	}
+	return counter
+}
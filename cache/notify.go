@@ -0,0 +1,48 @@
+package cache
+
+// Clients blocked on keys, such as XREAD BLOCK, register a channel here and
+// are signalled when a write makes new data available. Signals only say
+// "look again": the waiter re-reads under the normal locks.
+
+// WaitKeys registers interest in keys and returns a channel that receives a
+// value whenever one of them is signalled, plus a function to unregister.
+// Register before the first read so nothing written in between is missed.
+func (kv *ValueStore) WaitKeys(keys ...string) (<-chan struct{}, func()) {
+	wake := make(chan struct{}, 1)
+
+	unlock := kv.lockKeys(keys...)
+	for _, key := range keys {
+		s := kv.shard(key)
+		if s.waiters == nil {
+			s.waiters = make(map[string]map[chan struct{}]struct{})
+		}
+		if s.waiters[key] == nil {
+			s.waiters[key] = make(map[chan struct{}]struct{})
+		}
+		s.waiters[key][wake] = struct{}{}
+	}
+	unlock()
+
+	cancel := func() {
+		defer kv.lockKeys(keys...)()
+		for _, key := range keys {
+			s := kv.shard(key)
+			delete(s.waiters[key], wake)
+			if len(s.waiters[key]) == 0 {
+				delete(s.waiters, key)
+			}
+		}
+	}
+	return wake, cancel
+}
+
+// signalKey wakes every client waiting on key without blocking the writer.
+// Caller must hold the write lock of its shard.
+func (kv *ValueStore) signalKey(key string) {
+	for wake := range kv.shard(key).waiters[key] {
+		select {
+		case wake <- struct{}{}:
+		default: // already has a pending wake up
+		}
+	}
+}
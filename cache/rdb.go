@@ -0,0 +1,670 @@
+package cache
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/rdb"
+)
+
+// WriteRDB writes the snapshot to w as an RDB file.
+func (s *Snapshot) WriteRDB(w io.Writer) error {
+	enc := rdb.NewWriter(w)
+	enc.Header()
+	enc.Aux("redis-ver", "7.2.0") // the newest release that reads this version
+	enc.Aux("redis-bits", "64")
+	enc.Aux("ctime", strconv.FormatInt(time.Now().Unix(), 10))
+	enc.Aux("aof-base", "0")
+	for _, code := range s.libraries {
+		enc.Function(code)
+	}
+
+	expires := 0
+	for _, k := range s.keys {
+		if k.expireAt > 0 {
+			expires++
+		}
+	}
+	enc.SelectDB(0)
+	enc.ResizeDB(len(s.keys), expires)
+
+	for _, k := range s.keys {
+		if k.expireAt > 0 {
+			enc.ExpireAt(k.expireAt / int64(time.Millisecond))
+		}
+		writeValue(enc, k.key, k.value)
+	}
+	return enc.Close()
+}
+
+func writeValue(enc *rdb.Writer, key string, value any) {
+	switch v := value.(type) {
+	case string:
+		enc.Key(rdb.TypeString, key)
+		enc.String(v)
+	case *List:
+		enc.Key(rdb.TypeList, key)
+		enc.Length(uint64(v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			enc.String(v.At(i))
+		}
+	case Set:
+		enc.Key(rdb.TypeSet, key)
+		enc.Length(uint64(len(v)))
+		for member := range v {
+			enc.String(member)
+		}
+	case Hash:
+		enc.Key(rdb.TypeHash, key)
+		enc.Length(uint64(len(v)))
+		for field, value := range v {
+			enc.String(field)
+			enc.String(value)
+		}
+	case []ZMember:
+		enc.Key(rdb.TypeZSet2, key)
+		enc.Length(uint64(len(v)))
+		for _, m := range v {
+			enc.String(m.Member)
+			enc.Double(m.Score)
+		}
+	case *Stream:
+		enc.Key(rdb.TypeStreamListpacks3, key)
+		writeStream(enc, v)
+	}
+}
+
+func encodeStreamID(id StreamID) []byte {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b, id.Ms)
+	binary.BigEndian.PutUint64(b[8:], id.Seq)
+	return b
+}
+
+func writeStreamID(enc *rdb.Writer, id StreamID) {
+	enc.Length(id.Ms)
+	enc.Length(id.Seq)
+}
+
+// writeStream writes a stream the way upstream lays it out in memory: nodes
+// of up to streamNodeEntries entries, each a listpack keyed by its first ID,
+// followed by the stream's metadata and consumer groups.
+func writeStream(enc *rdb.Writer, s *Stream) {
+	nodes := (len(s.entries) + streamNodeEntries - 1) / streamNodeEntries
+	enc.Length(uint64(nodes))
+	for start := 0; start < len(s.entries); start += streamNodeEntries {
+		end := min(start+streamNodeEntries, len(s.entries))
+		master := s.entries[start]
+		enc.String(string(encodeStreamID(master.ID)))
+		enc.String(string(streamListpack(master, s.entries[start:end])))
+	}
+
+	enc.Length(uint64(len(s.entries)))
+	writeStreamID(enc, s.lastID)
+	writeStreamID(enc, s.first())
+	writeStreamID(enc, s.maxDeletedID)
+	enc.Length(s.entriesAdded)
+
+	names := make([]string, 0, len(s.groups))
+	for name := range s.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	enc.Length(uint64(len(names)))
+	for _, name := range names {
+		g := s.groups[name]
+		enc.String(name)
+		writeStreamID(enc, g.lastID)
+		enc.Length(uint64(g.entriesRead)) // -1 wraps, as it does upstream
+
+		enc.Length(uint64(len(g.pel)))
+		for _, p := range g.pel {
+			enc.Raw(encodeStreamID(p.id))
+			enc.Millis(p.deliveryTime)
+			enc.Length(uint64(p.deliveryCount))
+		}
+
+		consumers := make([]string, 0, len(g.consumers))
+		for name := range g.consumers {
+			consumers = append(consumers, name)
+		}
+		sort.Strings(consumers)
+		enc.Length(uint64(len(consumers)))
+		for _, name := range consumers {
+			c := g.consumers[name]
+			enc.String(name)
+			enc.Millis(c.seenTime)
+			enc.Millis(c.activeTime)
+
+			ids := make([]StreamID, 0, len(c.pending))
+			for id := range c.pending {
+				ids = append(ids, id)
+			}
+			sort.Slice(ids, func(i, j int) bool { return ids[i].Compare(ids[j]) < 0 })
+			enc.Length(uint64(len(ids)))
+			for _, id := range ids {
+				enc.Raw(encodeStreamID(id))
+			}
+		}
+	}
+}
+
+// streamListpack encodes one stream node. The master entry carries the field
+// names of the first entry; entries with the same names store only values.
+func streamListpack(master StreamEntry, entries []StreamEntry) []byte {
+	var lp rdb.Listpack
+	masterFields := len(master.Fields) / 2
+	lp.AppendInt(int64(len(entries)))
+	lp.AppendInt(0) // deleted entries
+	lp.AppendInt(int64(masterFields))
+	for i := 0; i < len(master.Fields); i += 2 {
+		lp.AppendString(master.Fields[i])
+	}
+	lp.AppendInt(0) // master entry terminator
+
+	for _, e := range entries {
+		fields := len(e.Fields) / 2
+		same := fields == masterFields
+		for i := 0; same && i < len(e.Fields); i += 2 {
+			same = e.Fields[i] == master.Fields[i]
+		}
+
+		flags := int64(0)
+		if same {
+			flags = rdb.StreamItemSameFields
+		}
+		lp.AppendInt(flags)
+		lp.AppendInt(int64(e.ID.Ms - master.ID.Ms))
+		lp.AppendInt(int64(e.ID.Seq - master.ID.Seq))
+		count := int64(3 + fields)
+		if same {
+			for i := 1; i < len(e.Fields); i += 2 {
+				lp.AppendString(e.Fields[i])
+			}
+		} else {
+			lp.AppendInt(int64(fields))
+			for _, f := range e.Fields {
+				lp.AppendString(f)
+			}
+			count += int64(fields) + 1
+		}
+		lp.AppendInt(count)
+	}
+	return lp.Bytes()
+}
+
+// LoadRDB reads an RDB file into the store, replacing keys it already holds.
+// Keys that have expired are skipped, as are keys of databases other than 0
+// since the store has only one.
+func (kv *ValueStore) LoadRDB(r io.Reader) error {
+	dec := rdb.NewReader(r)
+	version, err := dec.Header()
+	if err != nil {
+		return err
+	}
+
+	defer kv.lockAll()()
+	kv.libMu.Lock()
+	defer kv.libMu.Unlock()
+
+	db := uint64(0)
+	expireAt := int64(0)
+	for {
+		op, err := dec.Byte()
+		if err != nil {
+			return err
+		}
+
+		switch op {
+		case rdb.OpEOF:
+			return dec.Checksum(version)
+		case rdb.OpAux:
+			if _, err := dec.String(); err != nil {
+				return err
+			}
+			if _, err := dec.String(); err != nil {
+				return err
+			}
+		case rdb.OpSelectDB:
+			if db, err = dec.Length(); err != nil {
+				return err
+			}
+		case rdb.OpResizeDB:
+			if _, err := dec.Length(); err != nil {
+				return err
+			}
+			if _, err := dec.Length(); err != nil {
+				return err
+			}
+		case rdb.OpExpireTimeMs:
+			ms, err := dec.Millis()
+			if err != nil {
+				return err
+			}
+			expireAt = ms * int64(time.Millisecond)
+		case rdb.OpExpireTime:
+			sec, err := dec.Seconds()
+			if err != nil {
+				return err
+			}
+			expireAt = sec * int64(time.Second)
+		case rdb.OpIdle:
+			if _, err := dec.Length(); err != nil {
+				return err
+			}
+		case rdb.OpFreq:
+			if _, err := dec.Byte(); err != nil {
+				return err
+			}
+		case rdb.OpFunction2:
+			code, err := dec.String()
+			if err != nil {
+				return err
+			}
+			kv.setLibraries(append(kv.libraries, code))
+		default:
+			key, err := dec.String()
+			if err != nil {
+				return err
+			}
+			value, err := readValue(dec, op)
+			if err != nil {
+				return fmt.Errorf("loading key %q: %w", key, err)
+			}
+			if db == 0 && (expireAt == 0 || expireAt > time.Now().UnixNano()) {
+				kv.put(key, value, expireAt)
+				kv.account(key)
+			}
+			expireAt = 0
+		}
+	}
+}
+
+var errUnsupportedType = errors.New("rdb: unsupported value type")
+
+func readValue(dec *rdb.Reader, valueType byte) (any, error) {
+	switch valueType {
+	case rdb.TypeString:
+		return dec.String()
+
+	case rdb.TypeList:
+		values, err := readStrings(dec, 1)
+		if err != nil {
+			return nil, err
+		}
+		return &List{items: values, size: len(values)}, nil
+
+	case rdb.TypeListQuicklist2:
+		nodes, err := dec.Length()
+		if err != nil {
+			return nil, err
+		}
+		var values []string
+		for i := uint64(0); i < nodes; i++ {
+			container, err := dec.Length()
+			if err != nil {
+				return nil, err
+			}
+			blob, err := dec.String()
+			if err != nil {
+				return nil, err
+			}
+			if container == rdb.QuicklistPlain {
+				values = append(values, blob)
+				continue
+			}
+			entries, err := rdb.ParseListpack([]byte(blob))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, entries...)
+		}
+		return &List{items: values, size: len(values)}, nil
+
+	case rdb.TypeSet, rdb.TypeSetIntset, rdb.TypeSetListpack:
+		var members []string
+		var err error
+		switch valueType {
+		case rdb.TypeSet:
+			members, err = readStrings(dec, 1)
+		case rdb.TypeSetIntset:
+			members, err = readBlob(dec, rdb.ParseIntset)
+		default:
+			members, err = readBlob(dec, rdb.ParseListpack)
+		}
+		if err != nil {
+			return nil, err
+		}
+		set := make(Set, len(members))
+		for _, member := range members {
+			set[member] = struct{}{}
+		}
+		return set, nil
+
+	case rdb.TypeHash, rdb.TypeHashListpack:
+		var pairs []string
+		var err error
+		if valueType == rdb.TypeHash {
+			pairs, err = readStrings(dec, 2)
+		} else {
+			pairs, err = readBlob(dec, rdb.ParseListpack)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(pairs)%2 != 0 {
+			return nil, rdb.ErrCorrupt
+		}
+		hash := make(Hash, len(pairs)/2)
+		for i := 0; i < len(pairs); i += 2 {
+			hash[pairs[i]] = pairs[i+1]
+		}
+		return hash, nil
+
+	case rdb.TypeZSet, rdb.TypeZSet2:
+		n, err := dec.Length()
+		if err != nil {
+			return nil, err
+		}
+		zset := NewZSet()
+		for i := uint64(0); i < n; i++ {
+			member, err := dec.String()
+			if err != nil {
+				return nil, err
+			}
+			var score float64
+			if valueType == rdb.TypeZSet2 {
+				score, err = dec.Double()
+			} else {
+				score, err = dec.OldDouble()
+			}
+			if err != nil {
+				return nil, err
+			}
+			zset.Add(score, member)
+		}
+		return zset, nil
+
+	case rdb.TypeZSetListpack:
+		pairs, err := readBlob(dec, rdb.ParseListpack)
+		if err != nil {
+			return nil, err
+		}
+		if len(pairs)%2 != 0 {
+			return nil, rdb.ErrCorrupt
+		}
+		zset := NewZSet()
+		for i := 0; i < len(pairs); i += 2 {
+			score, err := strconv.ParseFloat(pairs[i+1], 64)
+			if err != nil {
+				return nil, rdb.ErrCorrupt
+			}
+			zset.Add(score, pairs[i])
+		}
+		return zset, nil
+
+	case rdb.TypeStreamListpacks, rdb.TypeStreamListpacks2, rdb.TypeStreamListpacks3:
+		return readStream(dec, valueType)
+	}
+	return nil, fmt.Errorf("%w %d", errUnsupportedType, valueType)
+}
+
+// readStrings reads a length followed by length*per strings.
+func readStrings(dec *rdb.Reader, per uint64) ([]string, error) {
+	n, err := dec.Length()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, 0, min(n*per, 1<<16))
+	for i := uint64(0); i < n*per; i++ {
+		value, err := dec.String()
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// readBlob reads a string holding an encoded aggregate and parses it.
+func readBlob(dec *rdb.Reader, parse func([]byte) ([]string, error)) ([]string, error) {
+	blob, err := dec.String()
+	if err != nil {
+		return nil, err
+	}
+	return parse([]byte(blob))
+}
+
+func readStreamID(dec *rdb.Reader) (StreamID, error) {
+	ms, err := dec.Length()
+	if err != nil {
+		return StreamID{}, err
+	}
+	seq, err := dec.Length()
+	return StreamID{ms, seq}, err
+}
+
+func readRawStreamID(dec *rdb.Reader) (StreamID, error) {
+	b := make([]byte, 16)
+	if err := dec.Raw(b); err != nil {
+		return StreamID{}, err
+	}
+	return StreamID{binary.BigEndian.Uint64(b), binary.BigEndian.Uint64(b[8:])}, nil
+}
+
+func readStream(dec *rdb.Reader, valueType byte) (*Stream, error) {
+	s := NewStream()
+	nodes, err := dec.Length()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < nodes; i++ {
+		key, err := dec.String()
+		if err != nil {
+			return nil, err
+		}
+		if len(key) != 16 {
+			return nil, rdb.ErrCorrupt
+		}
+		master := StreamID{binary.BigEndian.Uint64([]byte(key)), binary.BigEndian.Uint64([]byte(key[8:]))}
+		lp, err := readBlob(dec, rdb.ParseListpack)
+		if err != nil {
+			return nil, err
+		}
+		if s.entries, err = parseStreamNode(s.entries, master, lp); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := dec.Length(); err != nil { // length, implied by the entries
+		return nil, err
+	}
+	if s.lastID, err = readStreamID(dec); err != nil {
+		return nil, err
+	}
+	if valueType >= rdb.TypeStreamListpacks2 {
+		if _, err := readStreamID(dec); err != nil { // first ID, implied too
+			return nil, err
+		}
+		if s.maxDeletedID, err = readStreamID(dec); err != nil {
+			return nil, err
+		}
+		if s.entriesAdded, err = dec.Length(); err != nil {
+			return nil, err
+		}
+	} else {
+		s.entriesAdded = uint64(len(s.entries))
+	}
+
+	groups, err := dec.Length()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < groups; i++ {
+		name, err := dec.String()
+		if err != nil {
+			return nil, err
+		}
+		g, err := readStreamGroup(dec, valueType)
+		if err != nil {
+			return nil, err
+		}
+		if s.groups == nil {
+			s.groups = make(map[string]*streamGroup)
+		}
+		s.groups[name] = g
+	}
+	return s, nil
+}
+
+// parseStreamNode appends the live entries of one stream listpack.
+func parseStreamNode(entries []StreamEntry, master StreamID, lp []string) ([]StreamEntry, error) {
+	ints := func(values ...string) ([]uint64, bool) {
+		out := make([]uint64, len(values))
+		for i, v := range values {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, false
+			}
+			out[i] = uint64(n)
+		}
+		return out, true
+	}
+
+	if len(lp) < 4 {
+		return nil, rdb.ErrCorrupt
+	}
+	header, ok := ints(lp[0], lp[1], lp[2])
+	if !ok || uint64(len(lp)) < 4+header[2] {
+		return nil, rdb.ErrCorrupt
+	}
+	masterFields := lp[3 : 3+header[2]]
+	p := lp[4+header[2]:]
+
+	for len(p) > 0 {
+		if len(p) < 3 {
+			return nil, rdb.ErrCorrupt
+		}
+		head, ok := ints(p[0], p[1], p[2])
+		if !ok {
+			return nil, rdb.ErrCorrupt
+		}
+		flags := head[0]
+		id := StreamID{master.Ms + head[1], master.Seq + head[2]}
+		p = p[3:]
+
+		var fields []string
+		if flags&rdb.StreamItemSameFields != 0 {
+			if len(p) < len(masterFields) {
+				return nil, rdb.ErrCorrupt
+			}
+			fields = make([]string, 0, 2*len(masterFields))
+			for i, name := range masterFields {
+				fields = append(fields, name, p[i])
+			}
+			p = p[len(masterFields):]
+		} else {
+			if len(p) < 1 {
+				return nil, rdb.ErrCorrupt
+			}
+			n, ok := ints(p[0])
+			if !ok || uint64(len(p)) < 1+2*n[0] {
+				return nil, rdb.ErrCorrupt
+			}
+			fields = append([]string(nil), p[1:1+2*n[0]]...)
+			p = p[1+2*n[0]:]
+		}
+		if len(p) < 1 {
+			return nil, rdb.ErrCorrupt
+		}
+		p = p[1:] // lp-count
+
+		if flags&rdb.StreamItemDeleted == 0 {
+			entries = append(entries, StreamEntry{ID: id, Fields: fields})
+		}
+	}
+	return entries, nil
+}
+
+func readStreamGroup(dec *rdb.Reader, valueType byte) (*streamGroup, error) {
+	g := &streamGroup{entriesRead: -1, consumers: make(map[string]*streamConsumer)}
+	var err error
+	if g.lastID, err = readStreamID(dec); err != nil {
+		return nil, err
+	}
+	if valueType >= rdb.TypeStreamListpacks2 {
+		entriesRead, err := dec.Length()
+		if err != nil {
+			return nil, err
+		}
+		g.entriesRead = int64(entriesRead)
+	}
+
+	pending, err := dec.Length()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < pending; i++ {
+		p := &pendingEntry{}
+		if p.id, err = readRawStreamID(dec); err != nil {
+			return nil, err
+		}
+		if p.deliveryTime, err = dec.Millis(); err != nil {
+			return nil, err
+		}
+		count, err := dec.Length()
+		if err != nil {
+			return nil, err
+		}
+		p.deliveryCount = int64(count)
+		g.pel = append(g.pel, p)
+	}
+
+	consumers, err := dec.Length()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < consumers; i++ {
+		c := &streamConsumer{activeTime: -1, pending: make(map[StreamID]*pendingEntry)}
+		if c.name, err = dec.String(); err != nil {
+			return nil, err
+		}
+		if c.seenTime, err = dec.Millis(); err != nil {
+			return nil, err
+		}
+		if valueType >= rdb.TypeStreamListpacks3 {
+			if c.activeTime, err = dec.Millis(); err != nil {
+				return nil, err
+			}
+		}
+		owned, err := dec.Length()
+		if err != nil {
+			return nil, err
+		}
+		for j := uint64(0); j < owned; j++ {
+			id, err := readRawStreamID(dec)
+			if err != nil {
+				return nil, err
+			}
+			p := g.findPending(id)
+			if p == nil || p.consumer != nil {
+				return nil, rdb.ErrCorrupt
+			}
+			p.consumer = c
+			c.pending[id] = p
+		}
+		g.consumers[c.name] = c
+	}
+
+	for _, p := range g.pel {
+		if p.consumer == nil {
+			return nil, rdb.ErrCorrupt
+		}
+	}
+	return g, nil
+}
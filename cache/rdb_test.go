@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// sortedKeys returns a snapshot's keys in a stable order with expiries cut to
+// the millisecond precision RDB stores.
+func sortedKeys(s *Snapshot) []snapshotKey {
+	keys := append([]snapshotKey(nil), s.keys...)
+	sort.Slice(keys, func(i, j int) bool { return keys[i].key < keys[j].key })
+	for i := range keys {
+		keys[i].expireAt -= keys[i].expireAt % int64(time.Millisecond)
+		if zset, ok := keys[i].value.([]ZMember); ok {
+			sort.Slice(zset, func(a, b int) bool { return zset[a].Member < zset[b].Member })
+		}
+	}
+	return keys
+}
+
+func TestRDBRoundTrip(t *testing.T) {
+	kv := NewValueStore(time.Minute)
+	kv.Set("string", "hello", 0)
+	kv.Set("integer", "-12345", time.Hour)
+	kv.Set("expired", "gone", time.Millisecond)
+	kv.ListPush("list", false, "a", "b", "c")
+	kv.HashSet("hash", "f1", "v1", "f2", "2")
+	kv.SetAdd("set", "x", "y", "1")
+	kv.ZAdd("zset", ZAddFlags{}, ZMember{Member: "m1", Score: 1.5}, ZMember{Member: "m2", Score: -3})
+
+	// enough entries for several stream nodes, with changing field names
+	for i := 1; i <= 250; i++ {
+		fields := []string{"f", strconv.Itoa(i)}
+		if i%7 == 0 {
+			fields = []string{"other", "x", "f", "y"}
+		}
+		kv.XAdd("stream", StreamIDSpec{ID: StreamID{uint64(i / 3), uint64(i % 3)}}, fields, false, StreamTrim{})
+	}
+	kv.XDel("stream", StreamID{10, 0})
+	kv.XGroupCreate("stream", "g1", GroupPosition{}, false)
+	kv.XGroupCreate("stream", "g2", GroupPosition{Last: true}, false)
+	kv.XReadGroup("g1", "alice", []string{"stream"}, []GroupReadID{{New: true}}, 3, false)
+	kv.XReadGroup("g1", "bob", []string{"stream"}, []GroupReadID{{New: true}}, 2, false)
+	kv.XGroupCreateConsumer("stream", "g1", "idle")
+	kv.XAdd("empty", StreamIDSpec{Auto: true}, []string{"f", "v"}, false, StreamTrim{})
+	kv.XTrim("empty", StreamTrim{Strategy: TrimMaxLen})
+	libraries := []string{"#!lua name=a\nredis.register_function('f', function() end)", "#!lua name=b\n"}
+	kv.UpdateLibraries(func([]string) ([]string, error) { return libraries, nil })
+	time.Sleep(2 * time.Millisecond)
+
+	before := kv.Snapshot()
+	var buf bytes.Buffer
+	if err := before.WriteRDB(&buf); err != nil {
+		t.Fatalf("WriteRDB() failed: %s", err)
+	}
+
+	loaded := NewValueStore(time.Minute)
+	if err := loaded.LoadRDB(&buf); err != nil {
+		t.Fatalf("LoadRDB() failed: %s", err)
+	}
+	after := loaded.Snapshot()
+
+	if before.Len() != 8 {
+		t.Errorf("Snapshot() failed. Expected: 8 keys, got: %d", before.Len())
+	}
+	if !reflect.DeepEqual(sortedKeys(before), sortedKeys(after)) {
+		t.Errorf("LoadRDB() failed. Expected the loaded store to match the saved one")
+	}
+	if codes, _ := loaded.Libraries(); !reflect.DeepEqual(codes, libraries) {
+		t.Errorf("LoadRDB() failed. Expected libraries: %q, got: %q", libraries, codes)
+	}
+	if loaded.Dirty() != 0 {
+		t.Errorf("LoadRDB() failed. Expected loading to leave no changes to save, got: %d", loaded.Dirty())
+	}
+}
+
+func TestRDBChecksum(t *testing.T) {
+	kv := NewValueStore(time.Minute)
+	kv.Set("key", "value", 0)
+
+	var buf bytes.Buffer
+	kv.Snapshot().WriteRDB(&buf)
+	corrupt := buf.Bytes()
+	corrupt[len(corrupt)-12] ^= 0xff // inside the value, before EOF and checksum
+
+	if err := NewValueStore(time.Minute).LoadRDB(bytes.NewReader(corrupt)); err == nil {
+		t.Error("LoadRDB() failed. Expected a corrupted file to be rejected")
+	}
+}
@@ -0,0 +1,63 @@
+package cache
+
+import (
+	"hash/fnv"
+	"sort"
+
+	"github.com/Ryan-DL/go-redis-server/glob"
+)
+
+// Cursors for the SCAN family are positions in the order of the FNV-1a hash
+// of each name. Unlike an offset into a sorted slice that order does not shift
+// when names are added or removed, so as upstream guarantees, an element
+// present for the whole of an iteration is always returned at least once.
+
+func scanHash(name string) uint64 {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	return uint64(h.Sum32())
+}
+
+// scanPage returns up to count names starting at cursor, filtered by match,
+// along with the cursor to continue from. A returned cursor of 0 means the
+// iteration is complete. Names sharing a hash are always returned together so
+// none are skipped at a page boundary.
+func scanPage(names []string, cursor uint64, match string, count int) (uint64, []string) {
+	type entry struct {
+		name string
+		hash uint64
+	}
+
+	entries := make([]entry, 0, len(names))
+	for _, name := range names {
+		if h := scanHash(name); h >= cursor {
+			entries = append(entries, entry{name, h})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].hash != entries[j].hash {
+			return entries[i].hash < entries[j].hash
+		}
+		return entries[i].name < entries[j].name
+	})
+
+	if count < 1 {
+		count = 1
+	}
+
+	page := make([]string, 0, min(count, len(entries)))
+	i := 0
+	for ; i < len(entries); i++ {
+		if i >= count && entries[i].hash != entries[i-1].hash {
+			break
+		}
+		if match == "" || glob.Match(match, entries[i].name) {
+			page = append(page, entries[i].name)
+		}
+	}
+
+	if i == len(entries) {
+		return 0, page
+	}
+	return entries[i].hash, page
+}
@@ -0,0 +1,284 @@
+package cache
+
+// Set is an unordered collection of unique strings.
+type Set map[string]struct{}
+
+// SetOp selects the algebra applied by SetCombine and SetCombineStore.
+type SetOp int
+
+const (
+	SetUnion SetOp = iota
+	SetInter
+	SetDiff
+)
+
+// Members returns the elements of the set in no particular order.
+func (s Set) Members() []string {
+	members := make([]string, 0, len(s))
+	for member := range s {
+		members = append(members, member)
+	}
+	return members
+}
+
+// getSet returns the set at key. When create is set a missing key is
+// initialised with an empty set. Caller must hold the write lock of its shard.
+func (kv *ValueStore) getSet(key string, create bool) (Set, error) {
+	value, ok := kv.lookupWrite(key)
+	if !ok {
+		if !create {
+			return nil, nil
+		}
+		set := make(Set)
+		kv.put(key, set, 0)
+		return set, nil
+	}
+	set, ok := value.(Set)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	return set, nil
+}
+
+// readSet is getSet for callers holding only the read lock.
+func (kv *ValueStore) readSet(key string) (Set, error) {
+	value, ok := kv.lookupRead(key)
+	if !ok {
+		return nil, nil
+	}
+	set, ok := value.(Set)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	return set, nil
+}
+
+// SetAdd adds members to the set at key and returns how many were new.
+func (kv *ValueStore) SetAdd(key string, members ...string) (int, error) {
+	defer kv.lockKey(key).Unlock()
+
+	set, err := kv.getSet(key, true)
+	if err != nil {
+		return 0, err
+	}
+
+	added := 0
+	for _, member := range members {
+		if _, exists := set[member]; !exists {
+			set[member] = struct{}{}
+			added++
+		}
+	}
+	if added > 0 {
+		kv.modified(key)
+	}
+	return added, nil
+}
+
+// SetRem removes members and returns how many existed. Sets that become empty
+// are deleted.
+func (kv *ValueStore) SetRem(key string, members ...string) (int, error) {
+	defer kv.lockKey(key).Unlock()
+
+	set, err := kv.getSet(key, false)
+	if set == nil || err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, member := range members {
+		if _, exists := set[member]; exists {
+			delete(set, member)
+			removed++
+		}
+	}
+	if len(set) == 0 {
+		kv.remove(key)
+	}
+	if removed > 0 {
+		kv.modified(key)
+	}
+	return removed, nil
+}
+
+// SetMove moves member from the set at src to the set at dest. Both keys are
+// checked for the wrong type before anything is changed.
+func (kv *ValueStore) SetMove(src, dest, member string) (bool, error) {
+	defer kv.lockKeys(src, dest)()
+
+	srcSet, err := kv.getSet(src, false)
+	if err != nil {
+		return false, err
+	}
+	if _, err := kv.getSet(dest, false); err != nil {
+		return false, err
+	}
+	if _, exists := srcSet[member]; !exists {
+		return false, nil
+	}
+
+	delete(srcSet, member)
+	if len(srcSet) == 0 {
+		kv.remove(src)
+	}
+	destSet, _ := kv.getSet(dest, true)
+	destSet[member] = struct{}{}
+	kv.modified(src)
+	kv.modified(dest)
+	return true, nil
+}
+
+func (kv *ValueStore) SetMembers(key string) ([]string, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	set, err := kv.readSet(key)
+	if err != nil {
+		return nil, err
+	}
+	return set.Members(), nil
+}
+
+// SetIsMember reports, for each of members, whether it is in the set at key.
+func (kv *ValueStore) SetIsMember(key string, members ...string) ([]bool, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	set, err := kv.readSet(key)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make([]bool, len(members))
+	for i, member := range members {
+		_, found[i] = set[member]
+	}
+	return found, nil
+}
+
+func (kv *ValueStore) SetCard(key string) (int, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	set, err := kv.readSet(key)
+	return len(set), err
+}
+
+// SetPop removes and returns up to count random members. Go's map iteration
+// order is randomised, which is all the randomness SPOP needs.
+func (kv *ValueStore) SetPop(key string, count int) ([]string, error) {
+	defer kv.lockKey(key).Unlock()
+
+	set, err := kv.getSet(key, false)
+	if set == nil || err != nil {
+		return nil, err
+	}
+
+	popped := make([]string, 0, min(count, len(set)))
+	for member := range set {
+		if len(popped) >= count {
+			break
+		}
+		delete(set, member)
+		popped = append(popped, member)
+	}
+	if len(set) == 0 {
+		kv.remove(key)
+	}
+	if len(popped) > 0 {
+		kv.modified(key)
+	}
+	return popped, nil
+}
+
+// SetCombine returns the union, intersection or difference of the sets at
+// keys. Missing keys count as empty sets.
+func (kv *ValueStore) SetCombine(op SetOp, keys ...string) ([]string, error) {
+	defer kv.rlockKeys(keys...)()
+
+	result, err := kv.combineSets(op, keys)
+	if err != nil {
+		return nil, err
+	}
+	return result.Members(), nil
+}
+
+// SetCombineStore is SetCombine writing the result to dest, replacing
+// whatever it held, and returning its size. The whole operation happens under
+// one lock so other clients never observe a partial result.
+func (kv *ValueStore) SetCombineStore(op SetOp, dest string, keys ...string) (int, error) {
+	defer kv.lockKeys(append([]string{dest}, keys...)...)()
+
+	result, err := kv.combineSets(op, keys)
+	if err != nil {
+		return 0, err
+	}
+
+	kv.remove(dest)
+	if len(result) > 0 {
+		kv.put(dest, result, 0)
+	}
+	kv.modified(dest)
+	return len(result), nil
+}
+
+// combineSets computes a new set from the sets at keys. Caller must hold at
+// least the read lock.
+func (kv *ValueStore) combineSets(op SetOp, keys []string) (Set, error) {
+	sets := make([]Set, len(keys))
+	for i, key := range keys {
+		set, err := kv.readSet(key)
+		if err != nil {
+			return nil, err
+		}
+		sets[i] = set
+	}
+
+	result := make(Set)
+	switch op {
+	case SetUnion:
+		for _, set := range sets {
+			for member := range set {
+				result[member] = struct{}{}
+			}
+		}
+	case SetInter:
+		for member := range sets[0] {
+			inAll := true
+			for _, set := range sets[1:] {
+				if _, ok := set[member]; !ok {
+					inAll = false
+					break
+				}
+			}
+			if inAll {
+				result[member] = struct{}{}
+			}
+		}
+	case SetDiff:
+		for member := range sets[0] {
+			inOther := false
+			for _, set := range sets[1:] {
+				if _, ok := set[member]; ok {
+					inOther = true
+					break
+				}
+			}
+			if !inOther {
+				result[member] = struct{}{}
+			}
+		}
+	}
+	return result, nil
+}
+
+// SetScan returns a page of members starting at cursor; see scanPage for the
+// cursor semantics.
+func (kv *ValueStore) SetScan(key string, cursor uint64, match string, count int) (uint64, []string, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	set, err := kv.readSet(key)
+	if set == nil || err != nil {
+		return 0, []string{}, err
+	}
+
+	next, page := scanPage(set.Members(), cursor, match, count)
+	return next, page, nil
+}
@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"hash/maphash"
+	"slices"
+	"sync"
+	"time"
+)
+
+// The keyspace is split into shards by a hash of each key, and every shard
+// has its own lock, so clients working on different keys rarely wait for one
+// another. An operation on one key locks only that key's shard. One on several
+// keys locks each of their shards in ascending order, so two of them sharing
+// shards cannot deadlock, and one on the whole store locks every shard. A
+// write to several shards also holds the store's multiMu, so that a snapshot
+// sees all of it or none.
+
+// shardCount is how many shards the keyspace is split into. It is a power of
+// two so a key's shard is the low bits of its hash.
+const shardCount = 256
+
+type shard struct {
+	mu         sync.RWMutex
+	store      map[string]*item // values are string, *List, Hash, Set, *ZSet or *Stream
+	expiration map[string]int64 // deadlines in unix nanoseconds, of only the keys that have one
+	waiters    map[string]map[chan struct{}]struct{}
+	watchers   map[string]map[*Watch]struct{}
+}
+
+func newShard() *shard {
+	return &shard{
+		store:      make(map[string]*item),
+		expiration: make(map[string]int64),
+	}
+}
+
+// isExpired reports whether key has a deadline before now. Caller must hold
+// the shard's lock.
+func (s *shard) isExpired(key string, now int64) bool {
+	exp := s.expiration[key]
+	return exp > 0 && now > exp
+}
+
+// shardIndex returns the position of the shard holding key.
+func (kv *ValueStore) shardIndex(key string) int {
+	return int(maphash.String(kv.seed, key) & (shardCount - 1))
+}
+
+func (kv *ValueStore) shard(key string) *shard {
+	return kv.shards[kv.shardIndex(key)]
+}
+
+// lockKey write-locks the shard holding key and returns its lock, for use as
+// defer kv.lockKey(key).Unlock().
+func (kv *ValueStore) lockKey(key string) *sync.RWMutex {
+	s := kv.shard(key)
+	s.mu.Lock()
+	return &s.mu
+}
+
+// rlockKey is lockKey taking the read lock.
+func (kv *ValueStore) rlockKey(key string) *sync.RWMutex {
+	s := kv.shard(key)
+	s.mu.RLock()
+	return &s.mu
+}
+
+// lockKeys write-locks the shards holding keys, each once and in ascending
+// order, and returns the function that unlocks them.
+func (kv *ValueStore) lockKeys(keys ...string) func() {
+	return kv.lockShards(kv.shardIndexes(keys), false)
+}
+
+// rlockKeys is lockKeys taking read locks.
+func (kv *ValueStore) rlockKeys(keys ...string) func() {
+	return kv.lockShards(kv.shardIndexes(keys), true)
+}
+
+// lockAll write-locks every shard, for operations on the whole store.
+func (kv *ValueStore) lockAll() func() {
+	return kv.lockShards(allShards, false)
+}
+
+// rlockAll is lockAll taking read locks.
+func (kv *ValueStore) rlockAll() func() {
+	return kv.lockShards(allShards, true)
+}
+
+var allShards = func() []int {
+	indexes := make([]int, shardCount)
+	for i := range indexes {
+		indexes[i] = i
+	}
+	return indexes
+}()
+
+// shardIndexes returns the distinct shards holding keys, in ascending order.
+func (kv *ValueStore) shardIndexes(keys []string) []int {
+	indexes := make([]int, len(keys))
+	for i, key := range keys {
+		indexes[i] = kv.shardIndex(key)
+	}
+	slices.Sort(indexes)
+	return slices.Compact(indexes)
+}
+
+// lockShards locks the shards at indexes, which must be in ascending order.
+func (kv *ValueStore) lockShards(indexes []int, read bool) func() {
+	multi := !read && len(indexes) > 1
+	if multi {
+		kv.multiMu.RLock()
+	}
+	for _, i := range indexes {
+		if read {
+			kv.shards[i].mu.RLock()
+		} else {
+			kv.shards[i].mu.Lock()
+		}
+	}
+	return func() {
+		for _, i := range slices.Backward(indexes) {
+			if read {
+				kv.shards[i].mu.RUnlock()
+			} else {
+				kv.shards[i].mu.Unlock()
+			}
+		}
+		if multi {
+			kv.multiMu.RUnlock()
+		}
+	}
+}
+
+// expireAt returns the deadline of key, 0 if it has none. Caller must hold
+// the lock of its shard.
+func (kv *ValueStore) expireAt(key string) int64 {
+	return kv.shard(key).expiration[key]
+}
+
+// put stores value at key, expiring at expireAt or never if it is 0. Caller
+// must hold the write lock of its shard.
+func (kv *ValueStore) put(key string, value any, expireAt int64) {
+	s := kv.shard(key)
+	if it := s.store[key]; it != nil {
+		it.value = value
+	} else {
+		it = &item{value: value}
+		it.access.Store(kv.now())
+		it.lfu.Store(uint32(kv.minutes())<<8 | lfuInitVal)
+		s.store[key] = it
+	}
+	if expireAt > 0 {
+		s.expiration[key] = expireAt
+	} else {
+		delete(s.expiration, key)
+	}
+}
+
+// isExpired reports whether key has a deadline in the past. Caller must hold
+// the lock of its shard.
+func (kv *ValueStore) isExpired(key string) bool {
+	// most keys have no deadline, and reading the clock is not free
+	exp := kv.shard(key).expiration[key]
+	return exp > 0 && time.Now().UnixNano() > exp
+}
@@ -0,0 +1,153 @@
+package cache
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestShardIndexes(t *testing.T) {
+	kv := NewValueStore(time.Minute)
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = "key:" + strconv.Itoa(i%500)
+	}
+
+	indexes := kv.shardIndexes(keys)
+	if !slices.IsSorted(indexes) || len(slices.Compact(slices.Clone(indexes))) != len(indexes) {
+		t.Errorf("shardIndexes() failed. Expected distinct ascending shards, got: %v", indexes)
+	}
+	if len(indexes) < shardCount/2 {
+		t.Errorf("shardIndexes() failed. Expected 500 keys to spread over most of %d shards, got: %d", shardCount, len(indexes))
+	}
+}
+
+// TestLockKeysOrder runs operations on overlapping keys, given in every
+// order, at once; locking their shards in a fixed order keeps them from
+// deadlocking.
+func TestLockKeysOrder(t *testing.T) {
+	kv := NewValueStore(time.Minute)
+	keys := make([]string, 16)
+	for i := range keys {
+		keys[i] = "set:" + strconv.Itoa(i)
+		kv.SetAdd(keys[i], strconv.Itoa(i))
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var wg sync.WaitGroup
+		for w := 0; w < 16; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 500; i++ {
+					order := slices.Clone(keys)
+					rand.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+					kv.SetMove(order[0], order[1], strconv.Itoa(rand.IntN(len(keys))))
+					kv.SetCombine(SetUnion, order[:4]...)
+					kv.UpdateKeys(order[:3], func([]*Entry) error { return nil })
+				}
+			}()
+		}
+		wg.Wait()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(30 * time.Second):
+		t.Fatal("SetMove() failed. Expected concurrent multi-key operations to finish")
+	}
+
+	members, _ := kv.SetCombine(SetUnion, keys...)
+	if len(members) != len(keys) {
+		t.Errorf("SetMove() failed. Expected %d members across the sets, got: %d", len(keys), len(members))
+	}
+}
+
+// The benchmarks below run from every core, over benchKeys keys. Run them
+// with go test -run '^$' -bench Parallel -cpu 1,2,4,8 ./cache: as the store
+// is sharded, ns/op falls in proportion to the cores, while
+// BenchmarkGetParallelOneLock, a map behind one RWMutex as the store used to
+// be, stops improving once the cores contend for its reader count.
+const benchKeys = 10000
+
+func benchStore(b *testing.B) (*ValueStore, []string) {
+	kv := NewValueStore(time.Hour)
+	keys := make([]string, benchKeys)
+	for i := range keys {
+		keys[i] = "key:" + strconv.Itoa(i)
+		kv.Set(keys[i], "value", 0)
+	}
+	b.ResetTimer()
+	return kv, keys
+}
+
+func BenchmarkGetParallel(b *testing.B) {
+	kv, keys := benchStore(b)
+	b.RunParallel(func(pb *testing.PB) {
+		i := rand.IntN(benchKeys)
+		for pb.Next() {
+			kv.Get(keys[i])
+			if i++; i == benchKeys {
+				i = 0
+			}
+		}
+	})
+}
+
+func BenchmarkSetParallel(b *testing.B) {
+	kv, keys := benchStore(b)
+	b.RunParallel(func(pb *testing.PB) {
+		i := rand.IntN(benchKeys)
+		for pb.Next() {
+			kv.Set(keys[i], "value", 0)
+			if i++; i == benchKeys {
+				i = 0
+			}
+		}
+	})
+}
+
+// BenchmarkMixedParallel has one write for every nine reads.
+func BenchmarkMixedParallel(b *testing.B) {
+	kv, keys := benchStore(b)
+	b.RunParallel(func(pb *testing.PB) {
+		i := rand.IntN(benchKeys)
+		for n := 0; pb.Next(); n++ {
+			if n%10 == 0 {
+				kv.Set(keys[i], "value", 0)
+			} else {
+				kv.Get(keys[i])
+			}
+			if i++; i == benchKeys {
+				i = 0
+			}
+		}
+	})
+}
+
+func BenchmarkGetParallelOneLock(b *testing.B) {
+	var mu sync.RWMutex
+	store := make(map[string]any, benchKeys)
+	keys := make([]string, benchKeys)
+	for i := range keys {
+		keys[i] = "key:" + strconv.Itoa(i)
+		store[keys[i]] = "value"
+	}
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := rand.IntN(benchKeys)
+		for pb.Next() {
+			mu.RLock()
+			_ = store[keys[i]].(string)
+			mu.RUnlock()
+			if i++; i == benchKeys {
+				i = 0
+			}
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"maps"
+	"slices"
+	"time"
+)
+
+// Snapshot is a copy of the store. It is taken one shard at a time, each
+// read-locked only while its own keys are copied, so clients working on the
+// others carry on meanwhile; writing it out, the slow part, takes no lock at
+// all. A write to several shards is never seen half done, but writes to one
+// shard may land after some shards were copied: callers that need the store
+// at an exact point, to follow the snapshot with the writes after it, must
+// keep writes out while it is taken.
+type Snapshot struct {
+	keys      []snapshotKey
+	libraries []string
+	dirty     int64
+}
+
+type snapshotKey struct {
+	key      string
+	value    any   // string, *List, Hash, Set, []ZMember or *Stream
+	expireAt int64 // unix nanoseconds, 0 for no expiry
+}
+
+// Snapshot copies every live key.
+func (kv *ValueStore) Snapshot() *Snapshot {
+	kv.multiMu.Lock()
+	defer kv.multiMu.Unlock()
+
+	kv.libMu.RLock()
+	// changes made while copying may be in the snapshot or not, so they are
+	// left counted as dirty
+	s := &Snapshot{libraries: kv.libraries, dirty: kv.dirty.Load()}
+	kv.libMu.RUnlock()
+
+	now := time.Now().UnixNano()
+	for _, sh := range kv.shards {
+		sh.mu.RLock()
+		s.keys = slices.Grow(s.keys, len(sh.store))
+		for key, it := range sh.store {
+			if sh.isExpired(key, now) {
+				continue
+			}
+			s.keys = append(s.keys, snapshotKey{key: key, value: cloneValue(it.value), expireAt: sh.expiration[key]})
+		}
+		sh.mu.RUnlock()
+	}
+	return s
+}
+
+// Len returns the number of keys in the snapshot.
+func (s *Snapshot) Len() int {
+	return len(s.keys)
+}
+
+// Dirty returns how many changes have been made since the last save.
+func (kv *ValueStore) Dirty() int64 {
+	return kv.dirty.Load()
+}
+
+// Saved records that s has been persisted: the changes it contains no longer
+// count as dirty, while those made since it was taken still do.
+func (kv *ValueStore) Saved(s *Snapshot) {
+	kv.dirty.Add(-s.dirty)
+}
+
+// cloneValue copies a value so it can be read while the store changes.
+// Sorted sets are flattened to their members, which is all a snapshot needs.
+func cloneValue(value any) any {
+	switch v := value.(type) {
+	case *List:
+		values := v.Values()
+		return &List{items: values, size: len(values)}
+	case Hash:
+		return maps.Clone(v)
+	case Set:
+		return maps.Clone(v)
+	case *ZSet:
+		return v.Members()
+	case *Stream:
+		return v.clone()
+	default:
+		return value
+	}
+}
+
+// clone copies the stream and its consumer groups. Entries are never changed
+// in place, so their fields are shared.
+func (s *Stream) clone() *Stream {
+	c := *s
+	c.entries = append([]StreamEntry(nil), s.entries...)
+	c.groups = make(map[string]*streamGroup, len(s.groups))
+	for name, g := range s.groups {
+		c.groups[name] = g.clone()
+	}
+	return &c
+}
+
+func (g *streamGroup) clone() *streamGroup {
+	c := &streamGroup{
+		lastID:      g.lastID,
+		entriesRead: g.entriesRead,
+		pel:         make([]*pendingEntry, len(g.pel)),
+		consumers:   make(map[string]*streamConsumer, len(g.consumers)),
+	}
+	for name, consumer := range g.consumers {
+		c.consumers[name] = &streamConsumer{
+			name:       consumer.name,
+			seenTime:   consumer.seenTime,
+			activeTime: consumer.activeTime,
+			pending:    make(map[StreamID]*pendingEntry, len(consumer.pending)),
+		}
+	}
+	for i, p := range g.pel {
+		owner := c.consumers[p.consumer.name]
+		c.pel[i] = &pendingEntry{id: p.id, consumer: owner, deliveryTime: p.deliveryTime, deliveryCount: p.deliveryCount}
+		owner.pending[p.id] = c.pel[i]
+	}
+	return c
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+// keysInShards returns two keys, the first in a lower shard than the second.
+func keysInShards(kv *ValueStore) (string, string) {
+	first := "key:0"
+	for i := 1; ; i++ {
+		key := "key:" + strconv.Itoa(i)
+		switch {
+		case kv.shardIndex(key) > kv.shardIndex(first):
+			return first, key
+		case kv.shardIndex(key) < kv.shardIndex(first):
+			return key, first
+		}
+	}
+}
+
+func TestSnapshotLocksOneShard(t *testing.T) {
+	kv := NewValueStore(time.Minute)
+	copied, held := keysInShards(kv)
+
+	// the snapshot waits on the second shard, having copied the first
+	mu := kv.lockKey(held)
+	done := make(chan *Snapshot)
+	go func() { done <- kv.Snapshot() }()
+	time.Sleep(10 * time.Millisecond)
+
+	set := make(chan struct{})
+	go func() {
+		kv.Set(copied, "v", 0)
+		close(set)
+	}()
+	select {
+	case <-set:
+	case <-time.After(5 * time.Second):
+		t.Errorf("Set() failed. Expected not to wait for a snapshot held up on another shard")
+	}
+	mu.Unlock()
+	<-done
+	<-set
+}
+
+func TestSnapshotMultiShardWrite(t *testing.T) {
+	kv := NewValueStore(time.Minute)
+	src, dest := keysInShards(kv)
+	kv.SetAdd(src, "member")
+	// enough keys for the move to run while a snapshot is being taken
+	for i := 0; i < 50000; i++ {
+		kv.Set("filler:"+strconv.Itoa(i), "v", 0)
+	}
+
+	stop := make(chan struct{})
+	moved := make(chan struct{})
+	go func() {
+		defer close(moved)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			kv.SetMove(src, dest, "member")
+			kv.SetMove(dest, src, "member")
+		}
+	}()
+
+	for i := 0; i < 20; i++ {
+		members := 0
+		for _, k := range kv.Snapshot().keys {
+			if set, ok := k.value.(Set); ok {
+				members += len(set)
+			}
+		}
+		if members != 1 {
+			t.Fatalf("Snapshot() failed. Expected: the moved member once, got: %d times", members)
+		}
+	}
+	close(stop)
+	<-moved
+}
@@ -0,0 +1,453 @@
+package cache
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidStreamID  = errors.New("ERR Invalid stream ID specified as stream command argument")
+	ErrStreamIDTooSmall = errors.New("ERR The ID specified in XADD is equal or smaller than the target stream top item")
+	ErrStreamIDZero     = errors.New("ERR The ID specified in XADD must be greater than 0-0")
+	ErrStreamExhausted  = errors.New("ERR The stream has exhausted the last possible ID, unable to add more items")
+)
+
+// streamNodeEntries mirrors upstream's stream-node-max-entries. Entries are
+// kept in a flat slice rather than a radix tree of listpacks, but approximate
+// trimming still only evicts whole nodes worth of entries so "~" behaves like
+// it does upstream.
+const streamNodeEntries = 100
+
+// StreamID is an entry ID: a millisecond timestamp and a sequence number.
+type StreamID struct {
+	Ms, Seq uint64
+}
+
+// MaxStreamID is the largest possible ID, what "+" stands for in XRANGE.
+var MaxStreamID = StreamID{math.MaxUint64, math.MaxUint64}
+
+func (id StreamID) String() string {
+	return strconv.FormatUint(id.Ms, 10) + "-" + strconv.FormatUint(id.Seq, 10)
+}
+
+// Compare returns -1, 0 or 1 depending on whether id is smaller than, equal
+// to or greater than other.
+func (id StreamID) Compare(other StreamID) int {
+	switch {
+	case id.Ms < other.Ms:
+		return -1
+	case id.Ms > other.Ms:
+		return 1
+	case id.Seq < other.Seq:
+		return -1
+	case id.Seq > other.Seq:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Next returns the ID immediately after id. ok is false on overflow.
+func (id StreamID) Next() (StreamID, bool) {
+	switch {
+	case id.Seq < math.MaxUint64:
+		return StreamID{id.Ms, id.Seq + 1}, true
+	case id.Ms < math.MaxUint64:
+		return StreamID{id.Ms + 1, 0}, true
+	default:
+		return id, false
+	}
+}
+
+// Prev returns the ID immediately before id. ok is false on underflow.
+func (id StreamID) Prev() (StreamID, bool) {
+	switch {
+	case id.Seq > 0:
+		return StreamID{id.Ms, id.Seq - 1}, true
+	case id.Ms > 0:
+		return StreamID{id.Ms - 1, math.MaxUint64}, true
+	default:
+		return id, false
+	}
+}
+
+// ParseStreamID parses "ms-seq" or "ms". When the sequence is missing it is
+// set to defaultSeq, which lets range starts and ends default to the lowest
+// and highest IDs with that timestamp.
+func ParseStreamID(s string, defaultSeq uint64) (StreamID, error) {
+	msPart, seqPart, hasSeq := strings.Cut(s, "-")
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return StreamID{}, ErrInvalidStreamID
+	}
+	if !hasSeq {
+		return StreamID{ms, defaultSeq}, nil
+	}
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return StreamID{}, ErrInvalidStreamID
+	}
+	return StreamID{ms, seq}, nil
+}
+
+// StreamIDSpec is the ID argument of XADD: "*" (Auto), "ms-*" (AutoSeq) or an
+// explicit ID.
+type StreamIDSpec struct {
+	ID      StreamID
+	Auto    bool
+	AutoSeq bool
+}
+
+func ParseStreamIDSpec(s string) (StreamIDSpec, error) {
+	if s == "*" {
+		return StreamIDSpec{Auto: true}, nil
+	}
+	if ms, found := strings.CutSuffix(s, "-*"); found {
+		id, err := ParseStreamID(ms, 0)
+		if err != nil || strings.Contains(ms, "-") {
+			return StreamIDSpec{}, ErrInvalidStreamID
+		}
+		return StreamIDSpec{ID: id, AutoSeq: true}, nil
+	}
+	id, err := ParseStreamID(s, 0)
+	if err != nil {
+		return StreamIDSpec{}, err
+	}
+	return StreamIDSpec{ID: id}, nil
+}
+
+// StreamEntry is one entry of a stream. Fields holds field value pairs.
+type StreamEntry struct {
+	ID     StreamID
+	Fields []string
+}
+
+// TrimStrategy selects how XADD and XTRIM trim a stream.
+type TrimStrategy int
+
+const (
+	TrimNone TrimStrategy = iota
+	TrimMaxLen
+	TrimMinID
+)
+
+// StreamTrim holds the MAXLEN or MINID arguments of XADD and XTRIM. Limit
+// bounds how many entries an approximate trim may evict; 0 means no bound.
+type StreamTrim struct {
+	Strategy TrimStrategy
+	Approx   bool
+	MaxLen   int64
+	MinID    StreamID
+	Limit    int64
+}
+
+// Stream is an append-only log of entries ordered by ID.
+type Stream struct {
+	entries      []StreamEntry
+	lastID       StreamID
+	maxDeletedID StreamID
+	entriesAdded uint64
+	groups       map[string]*streamGroup
+}
+
+func NewStream() *Stream {
+	return &Stream{}
+}
+
+func (s *Stream) Len() int {
+	return len(s.entries)
+}
+
+func (s *Stream) LastID() StreamID {
+	return s.lastID
+}
+
+// nextID works out the ID of a new entry from spec, enforcing that IDs only
+// ever grow.
+func (s *Stream) nextID(spec StreamIDSpec) (StreamID, error) {
+	// nothing can follow the last possible ID, whatever was asked for
+	if s.lastID == MaxStreamID {
+		return StreamID{}, ErrStreamExhausted
+	}
+	switch {
+	case spec.Auto:
+		ms := uint64(time.Now().UnixMilli())
+		if ms > s.lastID.Ms {
+			return StreamID{ms, 0}, nil
+		}
+		id, ok := s.lastID.Next()
+		if !ok {
+			return StreamID{}, ErrStreamIDTooSmall
+		}
+		return id, nil
+	case spec.AutoSeq:
+		if spec.ID.Ms > s.lastID.Ms {
+			return StreamID{spec.ID.Ms, 0}, nil
+		}
+		if spec.ID.Ms == s.lastID.Ms && s.lastID.Seq < math.MaxUint64 {
+			return StreamID{spec.ID.Ms, s.lastID.Seq + 1}, nil
+		}
+		return StreamID{}, ErrStreamIDTooSmall
+	default:
+		if spec.ID.Compare(StreamID{}) == 0 {
+			return StreamID{}, ErrStreamIDZero
+		}
+		if spec.ID.Compare(s.lastID) <= 0 {
+			return StreamID{}, ErrStreamIDTooSmall
+		}
+		return spec.ID, nil
+	}
+}
+
+// Add appends an entry and returns its ID.
+func (s *Stream) Add(spec StreamIDSpec, fields []string) (StreamID, error) {
+	id, err := s.nextID(spec)
+	if err != nil {
+		return StreamID{}, err
+	}
+	s.entries = append(s.entries, StreamEntry{ID: id, Fields: fields})
+	s.lastID = id
+	s.entriesAdded++
+	return id, nil
+}
+
+// search returns the index of the first entry with an ID >= id.
+func (s *Stream) search(id StreamID) int {
+	return sort.Search(len(s.entries), func(i int) bool {
+		return s.entries[i].ID.Compare(id) >= 0
+	})
+}
+
+// Range returns entries with IDs between start and end inclusive, newest
+// first when rev is set. A count of 0 or less means no limit.
+func (s *Stream) Range(start, end StreamID, rev bool, count int) []StreamEntry {
+	entries := []StreamEntry{}
+	if start.Compare(end) > 0 {
+		return entries
+	}
+
+	from := s.search(start)
+	to := s.search(end)
+	if to < len(s.entries) && s.entries[to].ID.Compare(end) == 0 {
+		to++
+	}
+
+	if rev {
+		for i := to - 1; i >= from && (count <= 0 || len(entries) < count); i-- {
+			entries = append(entries, s.entries[i])
+		}
+		return entries
+	}
+	for i := from; i < to && (count <= 0 || len(entries) < count); i++ {
+		entries = append(entries, s.entries[i])
+	}
+	return entries
+}
+
+// After returns up to count entries with an ID greater than id.
+func (s *Stream) After(id StreamID, count int) []StreamEntry {
+	next, ok := id.Next()
+	if !ok {
+		return []StreamEntry{}
+	}
+	return s.Range(next, MaxStreamID, false, count)
+}
+
+// Delete removes the entries with the given IDs and returns how many existed.
+func (s *Stream) Delete(ids ...StreamID) int {
+	deleted := 0
+	for _, id := range ids {
+		i := s.search(id)
+		if i < len(s.entries) && s.entries[i].ID.Compare(id) == 0 {
+			s.entries = append(s.entries[:i], s.entries[i+1:]...)
+			if id.Compare(s.maxDeletedID) > 0 {
+				s.maxDeletedID = id
+			}
+			deleted++
+		}
+	}
+	return deleted
+}
+
+// Trim evicts entries from the head of the stream according to trim and
+// returns how many were removed.
+func (s *Stream) Trim(trim StreamTrim) int {
+	var excess int
+	switch trim.Strategy {
+	case TrimMaxLen:
+		excess = len(s.entries) - int(trim.MaxLen)
+	case TrimMinID:
+		excess = s.search(trim.MinID)
+	}
+	if excess <= 0 {
+		return 0
+	}
+
+	if trim.Approx {
+		if trim.Limit > 0 && int64(excess) > trim.Limit {
+			excess = int(trim.Limit)
+		}
+		excess -= excess % streamNodeEntries
+		if excess == 0 {
+			return 0
+		}
+	}
+
+	if last := s.entries[excess-1].ID; last.Compare(s.maxDeletedID) > 0 {
+		s.maxDeletedID = last
+	}
+	// reslice rather than copy so trimming on every XADD stays O(1); the
+	// backing array is released the next time append grows it
+	clear(s.entries[:excess])
+	s.entries = s.entries[excess:]
+	return excess
+}
+
+// getStream returns the stream at key. When create is set a missing key is
+// initialised with an empty stream. Caller must hold the write lock of its shard.
+func (kv *ValueStore) getStream(key string, create bool) (*Stream, error) {
+	value, ok := kv.lookupWrite(key)
+	if !ok {
+		if !create {
+			return nil, nil
+		}
+		stream := NewStream()
+		kv.put(key, stream, 0)
+		return stream, nil
+	}
+	stream, ok := value.(*Stream)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	return stream, nil
+}
+
+// readStream is getStream for callers holding only the read lock.
+func (kv *ValueStore) readStream(key string) (*Stream, error) {
+	value, ok := kv.lookupRead(key)
+	if !ok {
+		return nil, nil
+	}
+	stream, ok := value.(*Stream)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	return stream, nil
+}
+
+// XAdd appends an entry to the stream at key, trims it if requested and
+// wakes clients blocked reading it. With noMkStream a missing key is not
+// created and ok is false.
+func (kv *ValueStore) XAdd(key string, spec StreamIDSpec, fields []string, noMkStream bool, trim StreamTrim) (id StreamID, ok bool, err error) {
+	defer kv.lockKey(key).Unlock()
+
+	stream, err := kv.getStream(key, !noMkStream)
+	if stream == nil || err != nil {
+		return StreamID{}, false, err
+	}
+
+	id, err = stream.Add(spec, fields)
+	if err != nil {
+		if stream.entriesAdded == 0 {
+			kv.remove(key)
+		}
+		return StreamID{}, false, err
+	}
+	stream.Trim(trim)
+
+	kv.modified(key)
+	return id, true, nil
+}
+
+func (kv *ValueStore) XLen(key string) (int, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	stream, err := kv.readStream(key)
+	if stream == nil || err != nil {
+		return 0, err
+	}
+	return stream.Len(), nil
+}
+
+func (kv *ValueStore) XRange(key string, start, end StreamID, rev bool, count int) ([]StreamEntry, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	stream, err := kv.readStream(key)
+	if stream == nil || err != nil {
+		return []StreamEntry{}, err
+	}
+	return stream.Range(start, end, rev, count), nil
+}
+
+// XDel removes entries by ID. Unlike other types an emptied stream is kept,
+// as upstream does, so its last ID is not forgotten.
+func (kv *ValueStore) XDel(key string, ids ...StreamID) (int, error) {
+	defer kv.lockKey(key).Unlock()
+
+	stream, err := kv.getStream(key, false)
+	if stream == nil || err != nil {
+		return 0, err
+	}
+	deleted := stream.Delete(ids...)
+	if deleted > 0 {
+		kv.modified(key)
+	}
+	return deleted, nil
+}
+
+func (kv *ValueStore) XTrim(key string, trim StreamTrim) (int, error) {
+	defer kv.lockKey(key).Unlock()
+
+	stream, err := kv.getStream(key, false)
+	if stream == nil || err != nil {
+		return 0, err
+	}
+	removed := stream.Trim(trim)
+	if removed > 0 {
+		kv.modified(key)
+	}
+	return removed, nil
+}
+
+// XLastID returns the ID of the last entry added to the stream at key, which
+// is what "$" resolves to in XREAD.
+func (kv *ValueStore) XLastID(key string) (StreamID, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	stream, err := kv.readStream(key)
+	if stream == nil || err != nil {
+		return StreamID{}, err
+	}
+	return stream.LastID(), nil
+}
+
+// StreamReadResult holds the entries XREAD returns for one stream.
+type StreamReadResult struct {
+	Key     string
+	Entries []StreamEntry
+}
+
+// XRead returns, for each key, up to count entries with IDs greater than the
+// matching ID. Streams with nothing new are left out.
+func (kv *ValueStore) XRead(keys []string, ids []StreamID, count int) ([]StreamReadResult, error) {
+	defer kv.rlockKeys(keys...)()
+
+	var results []StreamReadResult
+	for i, key := range keys {
+		stream, err := kv.readStream(key)
+		if err != nil {
+			return nil, err
+		}
+		if stream == nil {
+			continue
+		}
+		if entries := stream.After(ids[i], count); len(entries) > 0 {
+			results = append(results, StreamReadResult{Key: key, Entries: entries})
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,751 @@
+package cache
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+var (
+	ErrBusyGroup   = errors.New("BUSYGROUP Consumer Group name already exists")
+	ErrNoGroup     = errors.New("NOGROUP No such key or consumer group")
+	ErrGroupNoKey  = errors.New("ERR The XGROUP subcommand requires the key to exist. Note that for CREATE you may want to use the MKSTREAM option to create an empty stream automatically.")
+	ErrEntriesRead = errors.New("ERR value for ENTRIESREAD must be positive or -1")
+)
+
+// NoGroupError is returned by XReadGroup to name the key whose group is
+// missing. It matches ErrNoGroup with errors.Is.
+type NoGroupError struct {
+	Key string
+}
+
+func (e *NoGroupError) Error() string {
+	return ErrNoGroup.Error()
+}
+
+func (e *NoGroupError) Is(target error) bool {
+	return target == ErrNoGroup
+}
+
+// pendingEntry is an entry of a group's pending entries list (PEL): delivered
+// to a consumer but not acknowledged yet.
+type pendingEntry struct {
+	id            StreamID
+	consumer      *streamConsumer
+	deliveryTime  int64 // unix milliseconds
+	deliveryCount int64
+}
+
+type streamConsumer struct {
+	name       string
+	seenTime   int64 // last interaction, unix milliseconds
+	activeTime int64 // last successful read or claim, -1 if never
+	pending    map[StreamID]*pendingEntry
+}
+
+// streamGroup is a consumer group. The PEL is kept sorted by ID so ranges can
+// be served with a binary search; each consumer also indexes its own entries.
+type streamGroup struct {
+	lastID      StreamID
+	entriesRead int64 // -1 when unknown
+	pel         []*pendingEntry
+	consumers   map[string]*streamConsumer
+}
+
+func nowMs() int64 {
+	return time.Now().UnixMilli()
+}
+
+// searchPEL returns the index of the first pending entry with an ID >= id.
+func (g *streamGroup) searchPEL(id StreamID) int {
+	return sort.Search(len(g.pel), func(i int) bool {
+		return g.pel[i].id.Compare(id) >= 0
+	})
+}
+
+func (g *streamGroup) findPending(id StreamID) *pendingEntry {
+	if i := g.searchPEL(id); i < len(g.pel) && g.pel[i].id == id {
+		return g.pel[i]
+	}
+	return nil
+}
+
+func (g *streamGroup) addPending(p *pendingEntry) {
+	i := g.searchPEL(p.id)
+	g.pel = append(g.pel, nil)
+	copy(g.pel[i+1:], g.pel[i:])
+	g.pel[i] = p
+	p.consumer.pending[p.id] = p
+}
+
+func (g *streamGroup) removePending(id StreamID) bool {
+	i := g.searchPEL(id)
+	if i == len(g.pel) || g.pel[i].id != id {
+		return false
+	}
+	delete(g.pel[i].consumer.pending, id)
+	g.pel = append(g.pel[:i], g.pel[i+1:]...)
+	return true
+}
+
+// consumer returns the named consumer, creating it if needed, and records the
+// interaction.
+func (g *streamGroup) consumer(name string) *streamConsumer {
+	c, ok := g.consumers[name]
+	if !ok {
+		c = &streamConsumer{name: name, activeTime: -1, pending: make(map[StreamID]*pendingEntry)}
+		g.consumers[name] = c
+	}
+	c.seenTime = nowMs()
+	return c
+}
+
+// assign moves a pending entry to consumer c.
+func (p *pendingEntry) assign(c *streamConsumer) {
+	if p.consumer == c {
+		return
+	}
+	delete(p.consumer.pending, p.id)
+	p.consumer = c
+	c.pending[p.id] = p
+}
+
+// first returns the ID of the first entry, or 0-0 if the stream is empty.
+func (s *Stream) first() StreamID {
+	if len(s.entries) == 0 {
+		return StreamID{}
+	}
+	return s.entries[0].ID
+}
+
+func (s *Stream) entry(id StreamID) (StreamEntry, bool) {
+	if i := s.search(id); i < len(s.entries) && s.entries[i].ID == id {
+		return s.entries[i], true
+	}
+	return StreamEntry{}, false
+}
+
+// hasTombstones reports whether entries were deleted from the middle of the
+// stream rather than trimmed off its head, which makes it impossible to
+// count how far into the stream an ID is.
+func (s *Stream) hasTombstones() bool {
+	return len(s.entries) > 0 && s.maxDeletedID.Compare(s.entries[0].ID) > 0
+}
+
+// entriesReadAt estimates how many entries were ever added up to and
+// including id, like upstream's streamEstimateDistanceFromFirstEverEntry. It
+// returns -1 when that cannot be known.
+func (s *Stream) entriesReadAt(id StreamID) int64 {
+	if s.entriesAdded == 0 {
+		return 0
+	}
+	if id.Compare(s.lastID) >= 0 {
+		return int64(s.entriesAdded)
+	}
+	if len(s.entries) == 0 || s.hasTombstones() {
+		return -1
+	}
+	trimmed := int64(s.entriesAdded) - int64(len(s.entries))
+	if id.Compare(s.entries[0].ID) < 0 {
+		if id.Compare(s.maxDeletedID) >= 0 {
+			return trimmed
+		}
+		return -1
+	}
+	return trimmed + int64(s.search(id))
+}
+
+// lag returns how many entries the group has yet to read, or -1 if unknown.
+func (s *Stream) lag(g *streamGroup) int64 {
+	if g.lastID.Compare(s.lastID) >= 0 || s.entriesAdded == 0 {
+		return 0
+	}
+	if g.entriesRead < 0 || s.hasTombstones() {
+		return -1
+	}
+	return int64(s.entriesAdded) - g.entriesRead
+}
+
+// GroupPosition is where a consumer group reads from: an ID, or the last ID
+// of the stream for "$". EntriesRead overrides the group's entries-read
+// counter when SetEntriesRead is true.
+type GroupPosition struct {
+	ID             StreamID
+	Last           bool
+	EntriesRead    int64
+	SetEntriesRead bool
+}
+
+func (s *Stream) setGroupPosition(g *streamGroup, pos GroupPosition) error {
+	if pos.SetEntriesRead && pos.EntriesRead < -1 {
+		return ErrEntriesRead
+	}
+	g.lastID = pos.ID
+	if pos.Last {
+		g.lastID = s.lastID
+	}
+	if pos.SetEntriesRead {
+		g.entriesRead = pos.EntriesRead
+	} else {
+		g.entriesRead = s.entriesReadAt(g.lastID)
+	}
+	return nil
+}
+
+// getGroup returns the stream at key and its named group, or ErrNoGroup if
+// either is missing. Caller must hold the write lock of its shard.
+func (kv *ValueStore) getGroup(key, group string) (*Stream, *streamGroup, error) {
+	stream, err := kv.getStream(key, false)
+	if err != nil {
+		return nil, nil, err
+	}
+	if stream == nil || stream.groups[group] == nil {
+		return nil, nil, ErrNoGroup
+	}
+	return stream, stream.groups[group], nil
+}
+
+// readGroup is getGroup for callers holding only the read lock.
+func (kv *ValueStore) readGroup(key, group string) (*Stream, *streamGroup, error) {
+	stream, err := kv.readStream(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if stream == nil || stream.groups[group] == nil {
+		return nil, nil, ErrNoGroup
+	}
+	return stream, stream.groups[group], nil
+}
+
+// XGroupCreate creates a consumer group. With mkStream a missing key is
+// created as an empty stream.
+func (kv *ValueStore) XGroupCreate(key, group string, pos GroupPosition, mkStream bool) error {
+	if pos.SetEntriesRead && pos.EntriesRead < -1 {
+		return ErrEntriesRead
+	}
+
+	defer kv.lockKey(key).Unlock()
+
+	stream, err := kv.getStream(key, mkStream)
+	if err != nil {
+		return err
+	}
+	if stream == nil {
+		return ErrGroupNoKey
+	}
+	if stream.groups[group] != nil {
+		return ErrBusyGroup
+	}
+
+	g := &streamGroup{consumers: make(map[string]*streamConsumer)}
+	if err := stream.setGroupPosition(g, pos); err != nil {
+		return err
+	}
+	if stream.groups == nil {
+		stream.groups = make(map[string]*streamGroup)
+	}
+	stream.groups[group] = g
+	kv.modified(key)
+	return nil
+}
+
+// XGroupSetID moves the last delivered ID of a group.
+func (kv *ValueStore) XGroupSetID(key, group string, pos GroupPosition) error {
+	defer kv.lockKey(key).Unlock()
+
+	stream, g, err := kv.getGroup(key, group)
+	if err != nil {
+		return err
+	}
+	if err := stream.setGroupPosition(g, pos); err != nil {
+		return err
+	}
+	kv.changed()
+	return nil
+}
+
+// XGroupDestroy deletes a group and its PEL, reporting whether it existed.
+// Clients blocked reading from it are woken so they notice.
+func (kv *ValueStore) XGroupDestroy(key, group string) (bool, error) {
+	defer kv.lockKey(key).Unlock()
+
+	stream, _, err := kv.getGroup(key, group)
+	if err == ErrNoGroup {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	delete(stream.groups, group)
+	kv.modified(key)
+	return true, nil
+}
+
+// XGroupCreateConsumer adds a consumer to a group, reporting whether it was
+// created.
+func (kv *ValueStore) XGroupCreateConsumer(key, group, consumer string) (bool, error) {
+	defer kv.lockKey(key).Unlock()
+
+	_, g, err := kv.getGroup(key, group)
+	if err != nil {
+		return false, err
+	}
+	if g.consumers[consumer] != nil {
+		return false, nil
+	}
+	g.consumer(consumer)
+	kv.changed()
+	return true, nil
+}
+
+// XGroupDelConsumer removes a consumer and its pending entries, returning
+// how many entries it had pending.
+func (kv *ValueStore) XGroupDelConsumer(key, group, consumer string) (int, error) {
+	defer kv.lockKey(key).Unlock()
+
+	_, g, err := kv.getGroup(key, group)
+	if err != nil {
+		return 0, err
+	}
+	c := g.consumers[consumer]
+	if c == nil {
+		return 0, nil
+	}
+	pending := len(c.pending)
+	for id := range c.pending {
+		g.removePending(id)
+	}
+	delete(g.consumers, consumer)
+	kv.changed()
+	return pending, nil
+}
+
+// GroupReadID is an ID argument of XREADGROUP. New stands for ">", entries
+// never delivered to the group; otherwise the consumer's own pending entries
+// after ID are read back.
+type GroupReadID struct {
+	ID  StreamID
+	New bool
+}
+
+// XReadGroup reads from streams on behalf of a consumer. New entries are
+// added to the PEL unless noAck is set. Pending entries that have since been
+// deleted from the stream are returned with nil Fields. Streams read for new
+// entries are left out when there are none.
+func (kv *ValueStore) XReadGroup(group, consumer string, keys []string, ids []GroupReadID, count int, noAck bool) ([]StreamReadResult, error) {
+	defer kv.lockKeys(keys...)()
+
+	// check every key first so a missing group doesn't leave a partial read
+	for _, key := range keys {
+		if _, _, err := kv.getGroup(key, group); err == ErrNoGroup {
+			return nil, &NoGroupError{Key: key}
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	var results []StreamReadResult
+	now := nowMs()
+	for i, key := range keys {
+		stream, g, _ := kv.getGroup(key, group)
+		c := g.consumer(consumer)
+
+		if !ids[i].New {
+			entries := []StreamEntry{}
+			for j := g.searchPEL(ids[i].ID); j < len(g.pel); j++ {
+				if count > 0 && len(entries) == count {
+					break
+				}
+				p := g.pel[j]
+				if p.consumer != c || p.id == ids[i].ID {
+					continue
+				}
+				entry, ok := stream.entry(p.id)
+				if !ok {
+					entry = StreamEntry{ID: p.id}
+				}
+				entries = append(entries, entry)
+			}
+			results = append(results, StreamReadResult{Key: key, Entries: entries})
+			continue
+		}
+
+		entries := stream.After(g.lastID, count)
+		if len(entries) == 0 {
+			continue
+		}
+		for _, entry := range entries {
+			g.lastID = entry.ID
+			if est := stream.entriesReadAt(entry.ID); est >= 0 {
+				g.entriesRead = est
+			} else if g.entriesRead >= 0 {
+				g.entriesRead++
+			}
+			if noAck {
+				continue
+			}
+			// the entry may still be pending after XGROUP SETID rewound the group
+			if p := g.findPending(entry.ID); p != nil {
+				p.assign(c)
+				p.deliveryTime = now
+				p.deliveryCount++
+				continue
+			}
+			g.addPending(&pendingEntry{id: entry.ID, consumer: c, deliveryTime: now, deliveryCount: 1})
+		}
+		c.activeTime = now
+		kv.changed()
+		results = append(results, StreamReadResult{Key: key, Entries: entries})
+	}
+	return results, nil
+}
+
+// XAck removes entries from a group's PEL and returns how many were pending.
+// A missing key or group acknowledges nothing.
+func (kv *ValueStore) XAck(key, group string, ids ...StreamID) (int, error) {
+	defer kv.lockKey(key).Unlock()
+
+	_, g, err := kv.getGroup(key, group)
+	if err == ErrNoGroup {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	acked := 0
+	for _, id := range ids {
+		if g.removePending(id) {
+			acked++
+		}
+	}
+	if acked > 0 {
+		kv.changed()
+	}
+	return acked, nil
+}
+
+// PendingSummary is the short form of XPENDING.
+type PendingSummary struct {
+	Count       int
+	First, Last StreamID
+	Consumers   []ConsumerPending
+}
+
+// ConsumerPending is the number of entries pending for one consumer.
+type ConsumerPending struct {
+	Name  string
+	Count int
+}
+
+// PendingEntry describes an entry of a PEL as reported by XPENDING.
+type PendingEntry struct {
+	ID            StreamID
+	Consumer      string
+	Idle          time.Duration
+	DeliveryCount int64
+}
+
+// XPendingSummary returns the PEL size, its ID range and the consumers with
+// pending entries, sorted by name.
+func (kv *ValueStore) XPendingSummary(key, group string) (PendingSummary, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	_, g, err := kv.readGroup(key, group)
+	if err != nil {
+		return PendingSummary{}, err
+	}
+
+	summary := PendingSummary{Count: len(g.pel)}
+	if len(g.pel) == 0 {
+		return summary, nil
+	}
+	summary.First = g.pel[0].id
+	summary.Last = g.pel[len(g.pel)-1].id
+	for _, c := range g.consumers {
+		if len(c.pending) > 0 {
+			summary.Consumers = append(summary.Consumers, ConsumerPending{Name: c.name, Count: len(c.pending)})
+		}
+	}
+	sort.Slice(summary.Consumers, func(i, j int) bool {
+		return summary.Consumers[i].Name < summary.Consumers[j].Name
+	})
+	return summary, nil
+}
+
+// XPending returns up to count PEL entries between start and end, optionally
+// only those of one consumer and idle for at least minIdle milliseconds.
+func (kv *ValueStore) XPending(key, group string, start, end StreamID, count int, consumer string, minIdle int64) ([]PendingEntry, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	_, g, err := kv.readGroup(key, group)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []PendingEntry{}
+	now := nowMs()
+	for i := g.searchPEL(start); i < len(g.pel) && len(entries) < count; i++ {
+		p := g.pel[i]
+		if p.id.Compare(end) > 0 {
+			break
+		}
+		if consumer != "" && p.consumer.name != consumer {
+			continue
+		}
+		idle := now - p.deliveryTime
+		if idle < minIdle {
+			continue
+		}
+		entries = append(entries, PendingEntry{
+			ID:            p.id,
+			Consumer:      p.consumer.name,
+			Idle:          time.Duration(idle) * time.Millisecond,
+			DeliveryCount: p.deliveryCount,
+		})
+	}
+	return entries, nil
+}
+
+// ClaimOptions holds the optional arguments of XCLAIM.
+type ClaimOptions struct {
+	// DeliveryTime is the new delivery time in unix milliseconds, from IDLE
+	// or TIME. Zero means now.
+	DeliveryTime int64
+	RetryCount   int64
+	SetRetry     bool
+	Force        bool
+	JustID       bool
+	LastID       StreamID
+}
+
+// XClaim transfers pending entries idle for at least minIdle milliseconds to
+// consumer and returns the claimed entries. Entries deleted from the stream
+// are dropped from the PEL instead. With JustID only IDs are returned, Fields
+// being nil, and delivery counts are left alone.
+func (kv *ValueStore) XClaim(key, group, consumer string, minIdle int64, ids []StreamID, opts ClaimOptions) ([]StreamEntry, error) {
+	defer kv.lockKey(key).Unlock()
+
+	stream, g, err := kv.getGroup(key, group)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.LastID.Compare(g.lastID) > 0 {
+		g.lastID = opts.LastID
+	}
+
+	now := nowMs()
+	deliveryTime := opts.DeliveryTime
+	if deliveryTime == 0 {
+		deliveryTime = now
+	}
+
+	c := g.consumer(consumer)
+	claimed := []StreamEntry{}
+	for _, id := range ids {
+		entry, exists := stream.entry(id)
+		p := g.findPending(id)
+		forced := false
+		if p == nil {
+			if !opts.Force || !exists {
+				continue
+			}
+			p = &pendingEntry{id: id, consumer: c, deliveryTime: now, deliveryCount: 1}
+			g.addPending(p)
+			forced = true
+		}
+
+		// an entry FORCE just created was never delivered, so it has no idle time
+		if !forced && minIdle > 0 && now-p.deliveryTime < minIdle {
+			continue
+		}
+		if !exists {
+			g.removePending(id)
+			continue
+		}
+
+		p.assign(c)
+		p.deliveryTime = deliveryTime
+		if opts.SetRetry {
+			p.deliveryCount = opts.RetryCount
+		} else if !opts.JustID {
+			p.deliveryCount++
+		}
+		c.activeTime = now
+
+		if opts.JustID {
+			entry.Fields = nil
+		}
+		claimed = append(claimed, entry)
+	}
+	kv.changed()
+	return claimed, nil
+}
+
+// XAutoClaim scans the PEL from start and claims up to count entries idle
+// for at least minIdle milliseconds, like XCLAIM. It returns the ID to
+// continue scanning from (0-0 when done), the claimed entries and the IDs of
+// entries that no longer exist in the stream, which are dropped from the PEL.
+func (kv *ValueStore) XAutoClaim(key, group, consumer string, minIdle int64, start StreamID, count int, justID bool) (StreamID, []StreamEntry, []StreamID, error) {
+	defer kv.lockKey(key).Unlock()
+
+	stream, g, err := kv.getGroup(key, group)
+	if err != nil {
+		return StreamID{}, nil, nil, err
+	}
+
+	now := nowMs()
+	c := g.consumer(consumer)
+	claimed := []StreamEntry{}
+	deleted := []StreamID{}
+
+	// like upstream, bound the work done on a PEL full of non-idle entries
+	attempts := count * 10
+	i := g.searchPEL(start)
+	for i < len(g.pel) && attempts > 0 && len(claimed) < count {
+		attempts--
+		p := g.pel[i]
+		if now-p.deliveryTime < minIdle {
+			i++
+			continue
+		}
+
+		entry, exists := stream.entry(p.id)
+		if !exists {
+			deleted = append(deleted, p.id)
+			g.removePending(p.id)
+			continue
+		}
+
+		p.assign(c)
+		p.deliveryTime = now
+		if !justID {
+			p.deliveryCount++
+		} else {
+			entry.Fields = nil
+		}
+		c.activeTime = now
+		claimed = append(claimed, entry)
+		i++
+	}
+
+	var next StreamID
+	if i < len(g.pel) {
+		next = g.pel[i].id
+	}
+	kv.changed()
+	return next, claimed, deleted, nil
+}
+
+// StreamInfo is the reply of XINFO STREAM.
+type StreamInfo struct {
+	Length                int
+	LastGeneratedID       StreamID
+	MaxDeletedEntryID     StreamID
+	EntriesAdded          uint64
+	RecordedFirstEntryID  StreamID
+	Groups                int
+	FirstEntry, LastEntry *StreamEntry
+}
+
+// GroupInfo describes a consumer group for XINFO GROUPS. EntriesRead and Lag
+// are -1 when unknown.
+type GroupInfo struct {
+	Name            string
+	Consumers       int
+	Pending         int
+	LastDeliveredID StreamID
+	EntriesRead     int64
+	Lag             int64
+}
+
+// ConsumerInfo describes a consumer for XINFO CONSUMERS. Inactive is -1 if
+// the consumer never read or claimed anything.
+type ConsumerInfo struct {
+	Name     string
+	Pending  int
+	Idle     time.Duration
+	Inactive time.Duration
+}
+
+func (kv *ValueStore) XInfoStream(key string) (StreamInfo, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	stream, err := kv.readStream(key)
+	if err != nil {
+		return StreamInfo{}, err
+	}
+	if stream == nil {
+		return StreamInfo{}, ErrNoSuchKey
+	}
+
+	info := StreamInfo{
+		Length:               stream.Len(),
+		LastGeneratedID:      stream.lastID,
+		MaxDeletedEntryID:    stream.maxDeletedID,
+		EntriesAdded:         stream.entriesAdded,
+		RecordedFirstEntryID: stream.first(),
+		Groups:               len(stream.groups),
+	}
+	if n := len(stream.entries); n > 0 {
+		first, last := stream.entries[0], stream.entries[n-1]
+		info.FirstEntry = &first
+		info.LastEntry = &last
+	}
+	return info, nil
+}
+
+// XInfoGroups returns the groups of the stream at key sorted by name.
+func (kv *ValueStore) XInfoGroups(key string) ([]GroupInfo, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	stream, err := kv.readStream(key)
+	if err != nil {
+		return nil, err
+	}
+	if stream == nil {
+		return nil, ErrNoSuchKey
+	}
+
+	groups := []GroupInfo{}
+	for name, g := range stream.groups {
+		groups = append(groups, GroupInfo{
+			Name:            name,
+			Consumers:       len(g.consumers),
+			Pending:         len(g.pel),
+			LastDeliveredID: g.lastID,
+			EntriesRead:     g.entriesRead,
+			Lag:             stream.lag(g),
+		})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups, nil
+}
+
+// XInfoConsumers returns the consumers of a group sorted by name.
+func (kv *ValueStore) XInfoConsumers(key, group string) ([]ConsumerInfo, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	_, g, err := kv.readGroup(key, group)
+	if err != nil {
+		return nil, err
+	}
+
+	now := nowMs()
+	consumers := []ConsumerInfo{}
+	for _, c := range g.consumers {
+		info := ConsumerInfo{
+			Name:     c.name,
+			Pending:  len(c.pending),
+			Idle:     time.Duration(now-c.seenTime) * time.Millisecond,
+			Inactive: -1,
+		}
+		if c.activeTime >= 0 {
+			info.Inactive = time.Duration(now-c.activeTime) * time.Millisecond
+		}
+		consumers = append(consumers, info)
+	}
+	sort.Slice(consumers, func(i, j int) bool { return consumers[i].Name < consumers[j].Name })
+	return consumers, nil
+}
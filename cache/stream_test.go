@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStreamTrimApprox(t *testing.T) {
+	s := NewStream()
+	for i := 0; i < 250; i++ {
+		if _, err := s.Add(StreamIDSpec{Auto: true}, []string{"f", "v"}); err != nil {
+			t.Fatalf("Stream Add() failed: %s", err)
+		}
+	}
+
+	// approximate trimming only removes whole nodes of 100 entries
+	if removed := s.Trim(StreamTrim{Strategy: TrimMaxLen, Approx: true, MaxLen: 120}); removed != 100 {
+		t.Errorf("Stream Trim(~ 120) failed. Expected to remove 100, removed %d", removed)
+	}
+	if removed := s.Trim(StreamTrim{Strategy: TrimMaxLen, MaxLen: 120}); removed != 30 {
+		t.Errorf("Stream Trim(= 120) failed. Expected to remove 30, removed %d", removed)
+	}
+	if s.Len() != 120 {
+		t.Errorf("Stream Len() failed. Expected: 120, got: %d", s.Len())
+	}
+
+	first := s.Range(StreamID{}, MaxStreamID, false, 1)[0].ID
+	if removed := s.Trim(StreamTrim{Strategy: TrimMinID, MinID: first}); removed != 0 {
+		t.Errorf("Stream Trim(MINID first) failed. Expected to remove 0, removed %d", removed)
+	}
+}
+
+func TestStreamExhausted(t *testing.T) {
+	s := NewStream()
+	if _, err := s.Add(StreamIDSpec{ID: MaxStreamID}, []string{"f", "v"}); err != nil {
+		t.Fatalf("Stream Add() failed: %s", err)
+	}
+	for _, spec := range []StreamIDSpec{{Auto: true}, {ID: StreamID{Ms: MaxStreamID.Ms}, AutoSeq: true}, {ID: StreamID{1, 1}}} {
+		if _, err := s.Add(spec, []string{"f", "v"}); err != ErrStreamExhausted {
+			t.Errorf("Stream Add(%v) failed. Expected: %v, got: %v", spec, ErrStreamExhausted, err)
+		}
+	}
+}
+
+func TestParseStreamID(t *testing.T) {
+	tests := []struct {
+		input      string
+		defaultSeq uint64
+		expected   StreamID
+		valid      bool
+	}{
+		{"1-2", 0, StreamID{1, 2}, true},
+		{"5", 0, StreamID{5, 0}, true},
+		{"5", 9, StreamID{5, 9}, true},
+		{"18446744073709551615-18446744073709551615", 0, MaxStreamID, true},
+		{"1-", 0, StreamID{}, false},
+		{"-1", 0, StreamID{}, false},
+		{"a-b", 0, StreamID{}, false},
+	}
+
+	for _, test := range tests {
+		id, err := ParseStreamID(test.input, test.defaultSeq)
+		if (err == nil) != test.valid || (test.valid && id != test.expected) {
+			t.Errorf("ParseStreamID(%q) failed. Expected: %v (valid %v), got: %v (%v)", test.input, test.expected, test.valid, id, err)
+		}
+	}
+}
+
+func TestStreamGroupClaim(t *testing.T) {
+	kv := NewValueStore(time.Minute)
+	if err := kv.XGroupCreate("s", "g", GroupPosition{}, true); err != nil {
+		t.Fatalf("XGroupCreate() failed: %s", err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, _, err := kv.XAdd("s", StreamIDSpec{Auto: true}, []string{"f", "v"}, false, StreamTrim{}); err != nil {
+			t.Fatalf("XAdd() failed: %s", err)
+		}
+	}
+
+	results, err := kv.XReadGroup("g", "alice", []string{"s"}, []GroupReadID{{New: true}}, 0, false)
+	if err != nil || len(results) != 1 || len(results[0].Entries) != 5 {
+		t.Fatalf("XReadGroup() failed. Expected 5 entries, got: %v (%v)", results, err)
+	}
+	ids := make([]StreamID, 5)
+	for i, entry := range results[0].Entries {
+		ids[i] = entry.ID
+	}
+
+	if acked, _ := kv.XAck("s", "g", ids[0], ids[0]); acked != 1 {
+		t.Errorf("XAck() failed. Expected: 1, got: %d", acked)
+	}
+	kv.XDel("s", ids[2])
+
+	// claiming two entries skips the deleted one, dropping it from the PEL
+	next, claimed, deleted, err := kv.XAutoClaim("s", "g", "bob", 0, StreamID{}, 2, false)
+	if err != nil {
+		t.Fatalf("XAutoClaim() failed: %s", err)
+	}
+	if len(claimed) != 2 || claimed[0].ID != ids[1] || claimed[1].ID != ids[3] {
+		t.Errorf("XAutoClaim() claimed unexpected entries: %v", claimed)
+	}
+	if len(deleted) != 1 || deleted[0] != ids[2] {
+		t.Errorf("XAutoClaim() failed. Expected deleted: %v, got: %v", ids[2:3], deleted)
+	}
+	if next != ids[4] {
+		t.Errorf("XAutoClaim() failed. Expected next: %v, got: %v", ids[4], next)
+	}
+
+	summary, _ := kv.XPendingSummary("s", "g")
+	expected := []ConsumerPending{{"alice", 1}, {"bob", 2}}
+	if summary.Count != 3 || !reflect.DeepEqual(summary.Consumers, expected) {
+		t.Errorf("XPendingSummary() failed. Expected: %v, got: %v", expected, summary)
+	}
+}
@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"math"
+	"time"
+)
+
+// Entry is a key as seen by a View or Update closure: its value, if any, and
+// its expiry. Changes made through Set, Delete and SetExpireAt are applied to
+// the store when an Update closure returns without error.
+type Entry struct {
+	key      string
+	value    any
+	expireAt int64 // unix nanoseconds, 0 for no expiry
+	existed  bool
+	changed  bool
+}
+
+func (e *Entry) Key() string {
+	return e.key
+}
+
+func (e *Entry) Exists() bool {
+	return e.value != nil
+}
+
+// Value returns the value at the key, or nil if there is none. Aggregates are
+// returned as stored, so a closure may modify them in place.
+func (e *Entry) Value() any {
+	return e.value
+}
+
+// String returns the value as a string. ok is false if the key does not
+// exist, and ErrWrongType is returned if it holds another type.
+func (e *Entry) String() (value string, ok bool, err error) {
+	if e.value == nil {
+		return "", false, nil
+	}
+	str, isString := e.value.(string)
+	if !isString {
+		return "", false, ErrWrongType
+	}
+	return str, true, nil
+}
+
+// ExpireAt returns when the key expires, or the zero time if it does not.
+func (e *Entry) ExpireAt() time.Time {
+	if e.expireAt == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, e.expireAt)
+}
+
+// Set replaces the value. The expiry of an existing key is kept, as INCR and
+// APPEND require; use SetExpireAt to change it.
+func (e *Entry) Set(value any) {
+	e.value = value
+	e.changed = true
+}
+
+// Deadlines are kept in unix nanoseconds, which run out in 2262. A later
+// one, which EXAT and PEXPIREAT accept as upstream does, is kept as the last
+// that fits, and one before 1970 as the first.
+var (
+	minExpireAt = time.Unix(0, 1)
+	maxExpireAt = time.Unix(0, math.MaxInt64)
+)
+
+// SetExpireAt sets when the key expires, or removes its expiry given the
+// zero time. A time that has already passed deletes the key.
+func (e *Entry) SetExpireAt(t time.Time) {
+	switch {
+	case t.IsZero():
+		e.expireAt = 0
+	case t.Before(minExpireAt):
+		e.expireAt = 1
+	case t.After(maxExpireAt):
+		e.expireAt = math.MaxInt64
+	default:
+		e.expireAt = t.UnixNano()
+	}
+	e.changed = true
+}
+
+// Delete removes the key and its expiry.
+func (e *Entry) Delete() {
+	e.value = nil
+	e.expireAt = 0
+	e.changed = true
+}
+
+// entry loads key into an Entry. Caller must hold the write lock of its
+// shard.
+func (kv *ValueStore) entry(key string) *Entry {
+	value, ok := kv.lookupWrite(key)
+	e := &Entry{key: key, existed: ok}
+	if ok {
+		e.value = value
+		e.expireAt = kv.expireAt(key)
+	}
+	return e
+}
+
+// apply writes back an entry changed by an Update closure. Caller must hold
+// the write lock of its shard.
+func (kv *ValueStore) apply(e *Entry) {
+	if !e.changed {
+		return
+	}
+	if e.value == nil || (e.expireAt > 0 && e.expireAt <= time.Now().UnixNano()) {
+		if e.existed {
+			kv.remove(e.key)
+			kv.modified(e.key)
+		}
+		return
+	}
+	kv.put(e.key, e.value, e.expireAt)
+	kv.modified(e.key)
+}
+
+// View runs fn with the read lock held, giving it a consistent snapshot of
+// key. fn must not change the entry.
+func (kv *ValueStore) View(key string, fn func(e *Entry)) {
+	defer kv.rlockKey(key).RUnlock()
+
+	value, ok := kv.lookupRead(key)
+	e := &Entry{key: key, existed: ok}
+	if ok {
+		e.value = value
+		e.expireAt = kv.expireAt(key)
+	}
+	fn(e)
+}
+
+// Update runs fn with the write lock held so it can read, modify and write
+// back key atomically. If fn returns an error nothing is written.
+func (kv *ValueStore) Update(key string, fn func(e *Entry) error) error {
+	return kv.UpdateKeys([]string{key}, func(entries []*Entry) error {
+		return fn(entries[0])
+	})
+}
+
+// UpdateKeys is Update for several keys at once. A key listed twice is given
+// to fn as the same Entry, so RENAME key key sees one key rather than two.
+// Entries are written back in order.
+func (kv *ValueStore) UpdateKeys(keys []string, fn func(entries []*Entry) error) error {
+	defer kv.lockKeys(keys...)()
+
+	entries := make([]*Entry, len(keys))
+	loaded := make(map[string]*Entry, len(keys))
+	for i, key := range keys {
+		if e, ok := loaded[key]; ok {
+			entries[i] = e
+			continue
+		}
+		entries[i] = kv.entry(key)
+		loaded[key] = entries[i]
+	}
+
+	if err := fn(entries); err != nil {
+		return err
+	}
+	for i, e := range entries {
+		if loaded[keys[i]] == e {
+			kv.apply(e)
+			delete(loaded, keys[i])
+		}
+	}
+	return nil
+}
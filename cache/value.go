@@ -0,0 +1,63 @@
+package cache
+
+import "errors"
+
+// ValueType identifies the kind of value stored at a key.
+type ValueType int
+
+const (
+	TypeNone ValueType = iota
+	TypeString
+	TypeList
+	TypeHash
+	TypeSet
+	TypeZSet
+	TypeStream
+)
+
+// String returns the name upstream uses for the type, as reported by TYPE.
+func (t ValueType) String() string {
+	switch t {
+	case TypeString:
+		return "string"
+	case TypeList:
+		return "list"
+	case TypeHash:
+		return "hash"
+	case TypeSet:
+		return "set"
+	case TypeZSet:
+		return "zset"
+	case TypeStream:
+		return "stream"
+	default:
+		return "none"
+	}
+}
+
+// Errors returned by the store. Their messages are the RESP error strings
+// upstream sends, so handlers can pass them straight to the client.
+var (
+	ErrWrongType  = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+	ErrNoSuchKey  = errors.New("ERR no such key")
+	ErrOutOfRange = errors.New("ERR index out of range")
+)
+
+func typeOf(value any) ValueType {
+	switch value.(type) {
+	case string:
+		return TypeString
+	case *List:
+		return TypeList
+	case Hash:
+		return TypeHash
+	case Set:
+		return TypeSet
+	case *ZSet:
+		return TypeZSet
+	case *Stream:
+		return TypeStream
+	default:
+		return TypeNone
+	}
+}
@@ -0,0 +1,101 @@
+package cache
+
+import (
+	"maps"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// Watch implements the optimistic locking behind WATCH. It records a set of
+// keys and becomes dirty as soon as any of them is modified, which EXEC checks
+// before running a transaction. Every write to the store goes through
+// modified, so nothing slips past a watch.
+type Watch struct {
+	kv *ValueStore
+
+	// keys maps each watched key to its expiration deadline when it was
+	// watched; a key that expires afterwards counts as modified.
+	keys  map[string]int64
+	dirty atomic.Bool
+}
+
+func (kv *ValueStore) NewWatch() *Watch {
+	return &Watch{kv: kv, keys: make(map[string]int64)}
+}
+
+// Add starts watching keys. Keys already watched keep their original state.
+func (w *Watch) Add(keys ...string) {
+	kv := w.kv
+	defer kv.lockKeys(keys...)()
+
+	for _, key := range keys {
+		if _, watched := w.keys[key]; watched {
+			continue
+		}
+		var deadline int64
+		if _, ok := kv.lookupRead(key); ok {
+			deadline = kv.expireAt(key)
+		}
+		w.keys[key] = deadline
+		s := kv.shard(key)
+		if s.watchers == nil {
+			s.watchers = make(map[string]map[*Watch]struct{})
+		}
+		if s.watchers[key] == nil {
+			s.watchers[key] = make(map[*Watch]struct{})
+		}
+		s.watchers[key][w] = struct{}{}
+	}
+}
+
+// Dirty reports whether a watched key was modified or expired since it was
+// watched.
+func (w *Watch) Dirty() bool {
+	if w.dirty.Load() {
+		return true
+	}
+	now := time.Now().UnixNano()
+	for _, deadline := range w.keys {
+		if deadline > 0 && now > deadline {
+			return true
+		}
+	}
+	return false
+}
+
+// Clear stops watching every key, as UNWATCH, EXEC and DISCARD do.
+func (w *Watch) Clear() {
+	kv := w.kv
+	keys := slices.Collect(maps.Keys(w.keys))
+	defer kv.lockKeys(keys...)()
+
+	for _, key := range keys {
+		s := kv.shard(key)
+		delete(s.watchers[key], w)
+		if len(s.watchers[key]) == 0 {
+			delete(s.watchers, key)
+		}
+	}
+	clear(w.keys)
+	w.dirty.Store(false)
+}
+
+// modified records a change to key: watches on it become dirty, clients
+// blocked on it are woken, its size is estimated again and it counts towards
+// the save rules. Caller must hold the write lock of its shard.
+func (kv *ValueStore) modified(key string) {
+	kv.changed()
+	kv.account(key)
+	for w := range kv.shard(key).watchers[key] {
+		w.dirty.Store(true)
+	}
+	kv.signalKey(key)
+}
+
+// changed counts a change towards the save rules without touching watches or
+// blocked clients, for consumer group bookkeeping which upstream persists but
+// does not treat as modifying the key.
+func (kv *ValueStore) changed() {
+	kv.dirty.Add(1)
+}
@@ -0,0 +1,37 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWatchDirty(t *testing.T) {
+	kv := NewValueStore(time.Minute)
+	kv.Set("watched", "1", 0)
+	kv.Set("other", "1", 0)
+
+	w := kv.NewWatch()
+	w.Add("watched", "missing")
+	if w.Dirty() {
+		t.Fatal("Watch Dirty() failed. Expected a fresh watch to be clean")
+	}
+
+	kv.Set("other", "2", 0)
+	kv.HashDel("missing", "field")
+	if w.Dirty() {
+		t.Error("Watch Dirty() failed. Writes that change nothing watched should not dirty it")
+	}
+
+	kv.ListPush("missing", false, "x")
+	if !w.Dirty() {
+		t.Error("Watch Dirty() failed. Expected creating a watched key to dirty it")
+	}
+
+	w.Clear()
+	kv.Set("watched", "2", 50*time.Millisecond)
+	w.Add("watched")
+	time.Sleep(60 * time.Millisecond)
+	if !w.Dirty() {
+		t.Error("Watch Dirty() failed. Expected a watched key expiring to dirty it")
+	}
+}
@@ -0,0 +1,493 @@
+package cache
+
+import (
+	"errors"
+	"math"
+	"strconv"
+)
+
+var ErrScoreNaN = errors.New("ERR resulting score is not a number (NaN)")
+
+// ZSet is a sorted set: a dict from member to score for O(1) lookups paired
+// with a skiplist for ordered access, the same layout upstream uses.
+type ZSet struct {
+	dict map[string]float64
+	zsl  *skiplist
+}
+
+// ZMember is a member of a sorted set with its score.
+type ZMember struct {
+	Member string
+	Score  float64
+}
+
+// ZAddFlags are the ZADD options that change how a member is added.
+type ZAddFlags struct {
+	NX, XX, GT, LT, Incr bool
+}
+
+// ZAddResult describes the outcome of ZAdd. Score and Applied refer to the
+// last member processed, which is the only one when Incr is set.
+type ZAddResult struct {
+	Added   int
+	Updated int
+	Score   float64
+	Applied bool
+}
+
+// Aggregate selects how ZUNIONSTORE and ZINTERSTORE combine scores.
+type Aggregate int
+
+const (
+	AggregateSum Aggregate = iota
+	AggregateMin
+	AggregateMax
+)
+
+func NewZSet() *ZSet {
+	return &ZSet{dict: make(map[string]float64), zsl: newSkiplist()}
+}
+
+func (z *ZSet) Len() int {
+	return len(z.dict)
+}
+
+func (z *ZSet) Score(member string) (float64, bool) {
+	score, ok := z.dict[member]
+	return score, ok
+}
+
+// add inserts or updates member following the upstream zsetAdd rules. It
+// returns the resulting score and whether the member was added, updated or
+// left alone.
+func (z *ZSet) add(score float64, member string, flags ZAddFlags) (newScore float64, added, updated, applied bool, err error) {
+	current, exists := z.dict[member]
+	if exists {
+		if flags.NX {
+			return current, false, false, false, nil
+		}
+		if flags.Incr {
+			score += current
+			if math.IsNaN(score) {
+				return 0, false, false, false, ErrScoreNaN
+			}
+		}
+		if (flags.LT && score >= current) || (flags.GT && score <= current) {
+			return current, false, false, false, nil
+		}
+		if score != current {
+			z.zsl.delete(current, member)
+			z.zsl.insert(score, member)
+			z.dict[member] = score
+			return score, false, true, true, nil
+		}
+		return score, false, false, true, nil
+	}
+
+	if flags.XX {
+		return 0, false, false, false, nil
+	}
+	z.zsl.insert(score, member)
+	z.dict[member] = score
+	return score, true, false, true, nil
+}
+
+// Add sets the score of member, adding it if needed.
+func (z *ZSet) Add(score float64, member string) {
+	z.add(score, member, ZAddFlags{})
+}
+
+// Remove deletes member, reporting whether it existed.
+func (z *ZSet) Remove(member string) bool {
+	score, ok := z.dict[member]
+	if !ok {
+		return false
+	}
+	z.zsl.delete(score, member)
+	delete(z.dict, member)
+	return true
+}
+
+// Rank returns the 0-based position of member, counting from the highest
+// score when rev is set.
+func (z *ZSet) Rank(member string, rev bool) (int, bool) {
+	score, ok := z.dict[member]
+	if !ok {
+		return 0, false
+	}
+	rank := z.zsl.rank(score, member)
+	if rev {
+		return z.Len() - rank, true
+	}
+	return rank - 1, true
+}
+
+// RangeByRank returns members between the start and stop ranks inclusive,
+// using the LRANGE index rules.
+func (z *ZSet) RangeByRank(start, stop int, rev bool) []ZMember {
+	start, stop, ok := normalizeRange(start, stop, z.Len())
+	if !ok {
+		return []ZMember{}
+	}
+
+	members := make([]ZMember, 0, stop-start+1)
+	var x *skiplistNode
+	if rev {
+		x = z.zsl.byRank(z.Len() - start)
+	} else {
+		x = z.zsl.byRank(start + 1)
+	}
+	for i := start; i <= stop && x != nil; i++ {
+		members = append(members, ZMember{x.member, x.score})
+		if rev {
+			x = x.backward
+		} else {
+			x = x.level[0].forward
+		}
+	}
+	return members
+}
+
+// RangeByScore returns members with a score in r, skipping offset matches and
+// returning at most count of them. A negative count means no limit.
+func (z *ZSet) RangeByScore(r ScoreRange, rev bool, offset, count int) []ZMember {
+	var x *skiplistNode
+	if rev {
+		x = z.zsl.lastInScoreRange(r)
+	} else {
+		x = z.zsl.firstInScoreRange(r)
+	}
+	return z.collect(x, rev, offset, count, func(n *skiplistNode) bool { return r.contains(n.score) })
+}
+
+// RangeByLex is RangeByScore for a lexicographical range of members. It is
+// only meaningful when every member has the same score.
+func (z *ZSet) RangeByLex(r LexRange, rev bool, offset, count int) []ZMember {
+	var x *skiplistNode
+	if rev {
+		x = z.zsl.lastInLexRange(r)
+	} else {
+		x = z.zsl.firstInLexRange(r)
+	}
+	return z.collect(x, rev, offset, count, func(n *skiplistNode) bool { return r.contains(n.member) })
+}
+
+// collect walks from x while nodes satisfy inRange, applying LIMIT semantics.
+func (z *ZSet) collect(x *skiplistNode, rev bool, offset, count int, inRange func(*skiplistNode) bool) []ZMember {
+	members := []ZMember{}
+	if offset < 0 {
+		return members
+	}
+
+	next := func(n *skiplistNode) *skiplistNode {
+		if rev {
+			return n.backward
+		}
+		return n.level[0].forward
+	}
+
+	for ; x != nil && offset > 0; offset-- {
+		x = next(x)
+	}
+	for ; x != nil && count != 0 && inRange(x); x = next(x) {
+		members = append(members, ZMember{x.member, x.score})
+		count--
+	}
+	return members
+}
+
+// Members returns every member ordered by score.
+func (z *ZSet) Members() []ZMember {
+	return z.RangeByRank(0, -1, false)
+}
+
+// FormatScore formats a sorted set score the way upstream replies with it:
+// integers without a decimal point, infinities as "inf" and "-inf".
+func FormatScore(score float64) string {
+	switch {
+	case math.IsInf(score, 1):
+		return "inf"
+	case math.IsInf(score, -1):
+		return "-inf"
+	case score == math.Trunc(score) && math.Abs(score) < 1<<53:
+		return strconv.FormatInt(int64(score), 10)
+	default:
+		return strconv.FormatFloat(score, 'g', -1, 64)
+	}
+}
+
+// getZSet returns the sorted set at key. When create is set a missing key is
+// initialised with an empty sorted set. Caller must hold the write lock of its shard.
+func (kv *ValueStore) getZSet(key string, create bool) (*ZSet, error) {
+	value, ok := kv.lookupWrite(key)
+	if !ok {
+		if !create {
+			return nil, nil
+		}
+		zset := NewZSet()
+		kv.put(key, zset, 0)
+		return zset, nil
+	}
+	zset, ok := value.(*ZSet)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	return zset, nil
+}
+
+// readZSet is getZSet for callers holding only the read lock.
+func (kv *ValueStore) readZSet(key string) (*ZSet, error) {
+	value, ok := kv.lookupRead(key)
+	if !ok {
+		return nil, nil
+	}
+	zset, ok := value.(*ZSet)
+	if !ok {
+		return nil, ErrWrongType
+	}
+	return zset, nil
+}
+
+// ZAdd adds or updates members of the sorted set at key according to flags.
+// With XX a missing key is not created.
+func (kv *ValueStore) ZAdd(key string, flags ZAddFlags, members ...ZMember) (ZAddResult, error) {
+	defer kv.lockKey(key).Unlock()
+
+	var result ZAddResult
+	zset, err := kv.getZSet(key, !flags.XX)
+	if zset == nil || err != nil {
+		return result, err
+	}
+
+	for _, m := range members {
+		score, added, updated, applied, err := zset.add(m.Score, m.Member, flags)
+		if err != nil {
+			if zset.Len() == 0 {
+				kv.remove(key)
+			}
+			return result, err
+		}
+		if added {
+			result.Added++
+		}
+		if updated {
+			result.Updated++
+		}
+		result.Score = score
+		result.Applied = applied
+	}
+
+	if zset.Len() == 0 {
+		kv.remove(key)
+	}
+	if result.Added > 0 || result.Updated > 0 {
+		kv.modified(key)
+	}
+	return result, nil
+}
+
+// ZRem removes members and returns how many existed. Sorted sets that become
+// empty are deleted.
+func (kv *ValueStore) ZRem(key string, members ...string) (int, error) {
+	defer kv.lockKey(key).Unlock()
+
+	zset, err := kv.getZSet(key, false)
+	if zset == nil || err != nil {
+		return 0, err
+	}
+
+	removed := 0
+	for _, member := range members {
+		if zset.Remove(member) {
+			removed++
+		}
+	}
+	if zset.Len() == 0 {
+		kv.remove(key)
+	}
+	if removed > 0 {
+		kv.modified(key)
+	}
+	return removed, nil
+}
+
+func (kv *ValueStore) ZCard(key string) (int, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	zset, err := kv.readZSet(key)
+	if zset == nil || err != nil {
+		return 0, err
+	}
+	return zset.Len(), nil
+}
+
+func (kv *ValueStore) ZScore(key, member string) (float64, bool, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	zset, err := kv.readZSet(key)
+	if zset == nil || err != nil {
+		return 0, false, err
+	}
+	score, ok := zset.Score(member)
+	return score, ok, nil
+}
+
+func (kv *ValueStore) ZRank(key, member string, rev bool) (int, bool, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	zset, err := kv.readZSet(key)
+	if zset == nil || err != nil {
+		return 0, false, err
+	}
+	rank, ok := zset.Rank(member, rev)
+	return rank, ok, nil
+}
+
+func (kv *ValueStore) ZRangeByRank(key string, start, stop int, rev bool) ([]ZMember, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	zset, err := kv.readZSet(key)
+	if zset == nil || err != nil {
+		return []ZMember{}, err
+	}
+	return zset.RangeByRank(start, stop, rev), nil
+}
+
+func (kv *ValueStore) ZRangeByScore(key string, r ScoreRange, rev bool, offset, count int) ([]ZMember, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	zset, err := kv.readZSet(key)
+	if zset == nil || err != nil {
+		return []ZMember{}, err
+	}
+	return zset.RangeByScore(r, rev, offset, count), nil
+}
+
+func (kv *ValueStore) ZRangeByLex(key string, r LexRange, rev bool, offset, count int) ([]ZMember, error) {
+	defer kv.rlockKey(key).RUnlock()
+
+	zset, err := kv.readZSet(key)
+	if zset == nil || err != nil {
+		return []ZMember{}, err
+	}
+	return zset.RangeByLex(r, rev, offset, count), nil
+}
+
+// ZPop removes and returns up to count members with the lowest scores, or
+// the highest when max is set.
+func (kv *ValueStore) ZPop(key string, count int, max bool) ([]ZMember, error) {
+	defer kv.lockKey(key).Unlock()
+
+	zset, err := kv.getZSet(key, false)
+	if zset == nil || err != nil || count <= 0 {
+		return []ZMember{}, err
+	}
+
+	var popped []ZMember
+	if max {
+		popped = zset.RangeByRank(0, count-1, true)
+	} else {
+		popped = zset.RangeByRank(0, count-1, false)
+	}
+	for _, m := range popped {
+		zset.Remove(m.Member)
+	}
+	if zset.Len() == 0 {
+		kv.remove(key)
+	}
+	if len(popped) > 0 {
+		kv.modified(key)
+	}
+	return popped, nil
+}
+
+// ZStore computes the union, or the intersection when inter is set, of the
+// sorted sets at keys and stores it at dest. Plain sets are accepted as input
+// with every member scoring 1. Each input's scores are multiplied by its
+// weight before being combined with aggregate. It returns the size of the
+// result.
+func (kv *ValueStore) ZStore(dest string, keys []string, weights []float64, aggregate Aggregate, inter bool) (int, error) {
+	defer kv.lockKeys(append([]string{dest}, keys...)...)()
+
+	inputs := make([]map[string]float64, len(keys))
+	for i, key := range keys {
+		value, ok := kv.lookupWrite(key)
+		if !ok {
+			continue
+		}
+		switch v := value.(type) {
+		case *ZSet:
+			inputs[i] = v.dict
+		case Set:
+			scores := make(map[string]float64, len(v))
+			for member := range v {
+				scores[member] = 1
+			}
+			inputs[i] = scores
+		default:
+			return 0, ErrWrongType
+		}
+	}
+
+	combine := func(acc, score float64) float64 {
+		switch aggregate {
+		case AggregateMin:
+			return math.Min(acc, score)
+		case AggregateMax:
+			return math.Max(acc, score)
+		default:
+			if sum := acc + score; !math.IsNaN(sum) {
+				return sum
+			}
+			return 0 // inf + -inf
+		}
+	}
+	weighted := func(i int, score float64) float64 {
+		score *= weights[i]
+		if math.IsNaN(score) {
+			return 0
+		}
+		return score
+	}
+
+	result := make(map[string]float64)
+	if inter {
+		for member, score := range inputs[0] {
+			acc := weighted(0, score)
+			inAll := true
+			for i := 1; i < len(inputs); i++ {
+				other, ok := inputs[i][member]
+				if !ok {
+					inAll = false
+					break
+				}
+				acc = combine(acc, weighted(i, other))
+			}
+			if inAll {
+				result[member] = acc
+			}
+		}
+	} else {
+		for i, input := range inputs {
+			for member, score := range input {
+				if acc, ok := result[member]; ok {
+					result[member] = combine(acc, weighted(i, score))
+				} else {
+					result[member] = weighted(i, score)
+				}
+			}
+		}
+	}
+
+	kv.remove(dest)
+	kv.modified(dest)
+	if len(result) == 0 {
+		return 0, nil
+	}
+	zset := NewZSet()
+	for member, score := range result {
+		zset.Add(score, member)
+	}
+	kv.put(dest, zset, 0)
+	return zset.Len(), nil
+}
@@ -0,0 +1,84 @@
+package cache
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// TestZSetMatchesSortedSlice checks the skiplist against a plain sorted slice
+// after a random mix of inserts, updates and removals.
+func TestZSetMatchesSortedSlice(t *testing.T) {
+	z := NewZSet()
+	reference := make(map[string]float64)
+
+	for i := 0; i < 2000; i++ {
+		member := fmt.Sprintf("m%d", rand.Intn(300))
+		if rand.Intn(4) == 0 {
+			z.Remove(member)
+			delete(reference, member)
+			continue
+		}
+		score := float64(rand.Intn(50))
+		z.Add(score, member)
+		reference[member] = score
+	}
+
+	expected := make([]ZMember, 0, len(reference))
+	for member, score := range reference {
+		expected = append(expected, ZMember{member, score})
+	}
+	sort.Slice(expected, func(i, j int) bool {
+		if expected[i].Score != expected[j].Score {
+			return expected[i].Score < expected[j].Score
+		}
+		return expected[i].Member < expected[j].Member
+	})
+
+	if actual := z.Members(); !reflect.DeepEqual(actual, expected) {
+		t.Fatalf("ZSet Members() does not match reference ordering")
+	}
+	for i, m := range expected {
+		if rank, ok := z.Rank(m.Member, false); !ok || rank != i {
+			t.Fatalf("ZSet Rank(%q) failed. Expected: %d, got: %d", m.Member, i, rank)
+		}
+		if rank, _ := z.Rank(m.Member, true); rank != len(expected)-1-i {
+			t.Fatalf("ZSet Rank(%q, rev) failed. Expected: %d, got: %d", m.Member, len(expected)-1-i, rank)
+		}
+	}
+	if actual := z.RangeByRank(10, 19, false); !reflect.DeepEqual(actual, expected[10:20]) {
+		t.Errorf("ZSet RangeByRank(10, 19) failed. Expected: %v, got: %v", expected[10:20], actual)
+	}
+}
+
+func TestZSetRangeByScore(t *testing.T) {
+	z := NewZSet()
+	for i, member := range []string{"a", "b", "c", "d", "e"} {
+		z.Add(float64(i+1), member)
+	}
+
+	tests := []struct {
+		r             ScoreRange
+		rev           bool
+		offset, count int
+		expected      []string
+	}{
+		{ScoreRange{Min: 2, Max: 4}, false, 0, -1, []string{"b", "c", "d"}},
+		{ScoreRange{Min: 2, Max: 4, MinEx: true, MaxEx: true}, false, 0, -1, []string{"c"}},
+		{ScoreRange{Min: 2, Max: 4}, true, 0, -1, []string{"d", "c", "b"}},
+		{ScoreRange{Min: 1, Max: 5}, false, 1, 2, []string{"b", "c"}},
+		{ScoreRange{Min: 6, Max: 9}, false, 0, -1, []string{}},
+		{ScoreRange{Min: 3, Max: 3, MinEx: true}, false, 0, -1, []string{}},
+	}
+	for _, tt := range tests {
+		actual := []string{}
+		for _, m := range z.RangeByScore(tt.r, tt.rev, tt.offset, tt.count) {
+			actual = append(actual, m.Member)
+		}
+		if !reflect.DeepEqual(actual, tt.expected) {
+			t.Errorf("ZSet RangeByScore(%+v, rev=%v) failed. Expected: %v, got: %v", tt.r, tt.rev, tt.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package cluster
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/persist"
+)
+
+// Upstream runs its bus on a separate port with a binary protocol. Here the
+// nodes gossip over their client ports instead: once a second each node
+// sends every other node it knows of
+//
+//	CLUSTER GOSSIP <id> <host> <port> <config-epoch> <current-epoch> <slots> [<id>@<host>:<port> ...]
+//
+// and the reply carries the receiver's own state in the same form, so both
+// ends learn from one exchange.
+
+// gossipTimeout bounds one exchange with a node.
+const gossipTimeout = time.Second
+
+// message is one node's state as gossip carries it.
+type message struct {
+	id           string
+	host         string
+	port         int
+	configEpoch  uint64
+	currentEpoch uint64
+	slots        []SlotRange
+	known        []string // other nodes as id@host:port
+}
+
+func (m message) args() []string {
+	args := []string{
+		m.id,
+		m.host,
+		strconv.Itoa(m.port),
+		strconv.FormatUint(m.configEpoch, 10),
+		strconv.FormatUint(m.currentEpoch, 10),
+		formatRanges(m.slots),
+	}
+	return append(args, m.known...)
+}
+
+func parseMessage(args []string) (message, error) {
+	if len(args) < 6 {
+		return message{}, errors.New("ERR Invalid gossip message")
+	}
+	m := message{id: args[0], host: args[1], known: args[6:]}
+	var err error
+	if m.port, err = strconv.Atoi(args[2]); err != nil {
+		return message{}, errors.New("ERR Invalid gossip message")
+	}
+	if m.configEpoch, err = strconv.ParseUint(args[3], 10, 64); err != nil {
+		return message{}, errors.New("ERR Invalid gossip message")
+	}
+	if m.currentEpoch, err = strconv.ParseUint(args[4], 10, 64); err != nil {
+		return message{}, errors.New("ERR Invalid gossip message")
+	}
+	if m.slots, err = parseRanges(args[5]); err != nil {
+		return message{}, errors.New("ERR Invalid gossip message")
+	}
+	return m, nil
+}
+
+// Gossip handles CLUSTER GOSSIP from another node and returns this node's
+// state to reply with.
+func (c *Cluster) Gossip(args []string) ([]string, error) {
+	m, err := parseMessage(args)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.update(m)
+	return c.message().args(), nil
+}
+
+// message returns this node's state. Caller must hold mu.
+func (c *Cluster) message() message {
+	m := message{
+		id:           c.myself.ID,
+		host:         c.myself.Host,
+		port:         c.myself.Port,
+		configEpoch:  c.myself.ConfigEpoch,
+		currentEpoch: c.currentEpoch,
+	}
+	var mine [Slots]bool
+	for slot, n := range c.slots {
+		mine[slot] = n == c.myself
+	}
+	m.slots = ranges(&mine)
+	for _, n := range c.nodes {
+		if n != c.myself {
+			m.known = append(m.known, n.ID+"@"+n.Addr())
+		}
+	}
+	return m
+}
+
+// update applies what a node says about itself. Where two nodes claim a
+// slot the one with the higher config epoch wins. Caller must hold mu.
+func (c *Cluster) update(m message) {
+	addr := net.JoinHostPort(m.host, strconv.Itoa(m.port))
+	delete(c.handshakes, addr)
+	if m.id == c.myself.ID {
+		return
+	}
+	c.currentEpoch = max(c.currentEpoch, m.currentEpoch)
+
+	n := c.nodes[m.id]
+	if n == nil {
+		n = &Node{ID: m.id}
+		c.nodes[m.id] = n
+		log.Printf("Cluster node %s joined at %s", m.id, addr)
+	}
+	n.Host, n.Port, n.ConfigEpoch = m.host, m.port, m.configEpoch
+	n.pongRecv = time.Now()
+	n.connected = true
+
+	var claimed [Slots]bool
+	for _, r := range m.slots {
+		for slot := r.Start; slot <= r.End; slot++ {
+			claimed[slot] = true
+		}
+	}
+	for slot, owner := range c.slots {
+		switch {
+		case claimed[slot] && owner != n && (owner == nil || owner.ConfigEpoch < n.ConfigEpoch):
+			if owner == c.myself {
+				log.Printf("Hash slot %d lost to node %s", slot, n.ID)
+				c.migrating[slot] = nil
+			}
+			if c.importing[slot] == owner {
+				c.importing[slot] = nil
+			}
+			c.slots[slot] = n
+		case !claimed[slot] && owner == n:
+			c.slots[slot] = nil
+		}
+	}
+
+	for _, k := range m.known {
+		id, nodeAddr, ok := strings.Cut(k, "@")
+		if !ok || c.nodes[id] != nil {
+			continue
+		}
+		host, portStr, err := net.SplitHostPort(nodeAddr)
+		if err != nil {
+			continue
+		}
+		port, err := strconv.Atoi(portStr)
+		if err != nil {
+			continue
+		}
+		c.nodes[id] = &Node{ID: id, Host: host, Port: port}
+	}
+}
+
+// Run gossips with every known node once a second. It never returns.
+func (c *Cluster) Run() {
+	peers := make(map[string]*peer)
+	for {
+		time.Sleep(time.Second)
+
+		c.mu.Lock()
+		msg := append([]string{"CLUSTER", "GOSSIP"}, c.message().args()...)
+		targets := make(map[string]bool, len(c.nodes)+len(c.handshakes))
+		for _, n := range c.nodes {
+			if n != c.myself {
+				targets[n.Addr()] = true
+				n.pingSent = time.Now()
+			}
+		}
+		for addr := range c.handshakes {
+			targets[addr] = true
+		}
+		c.mu.Unlock()
+
+		for addr, p := range peers {
+			if !targets[addr] {
+				p.close()
+				delete(peers, addr)
+			}
+		}
+		var wg sync.WaitGroup
+		for addr := range targets {
+			p := peers[addr]
+			if p == nil {
+				p = &peer{addr: addr}
+				peers[addr] = p
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				c.exchange(p, msg)
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+func (c *Cluster) exchange(p *peer, msg []string) {
+	reply, err := p.exchange(msg, c.Password)
+	if err == nil {
+		var m message
+		if m, err = parseMessage(reply); err == nil {
+			c.mu.Lock()
+			c.update(m)
+			c.mu.Unlock()
+			return
+		}
+	}
+
+	p.close()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, n := range c.nodes {
+		if n != c.myself && n.Addr() == p.addr && n.connected {
+			n.connected = false
+			log.Printf("Lost the cluster bus link to node %s: %v", n.ID, err)
+		}
+	}
+}
+
+// peer is a gossip connection to another node, opened on first use.
+type peer struct {
+	addr string
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (p *peer) exchange(msg []string, password string) ([]string, error) {
+	if p.conn == nil {
+		conn, err := net.DialTimeout("tcp", p.addr, gossipTimeout)
+		if err != nil {
+			return nil, err
+		}
+		p.conn, p.r = conn, bufio.NewReader(conn)
+		if password != "" {
+			p.conn.SetDeadline(time.Now().Add(gossipTimeout))
+			if _, err := p.conn.Write(persist.AppendCommand(nil, []string{"AUTH", password})); err != nil {
+				return nil, err
+			}
+			line, err := p.r.ReadString('\n')
+			if err != nil {
+				return nil, err
+			}
+			if !strings.HasPrefix(line, "+") {
+				return nil, fmt.Errorf("error reply to AUTH: %s", strings.TrimSpace(line))
+			}
+		}
+	}
+
+	p.conn.SetDeadline(time.Now().Add(gossipTimeout))
+	if _, err := p.conn.Write(persist.AppendCommand(nil, msg)); err != nil {
+		return nil, err
+	}
+	return persist.ReadCommand(p.r)
+}
+
+func (p *peer) close() {
+	if p.conn != nil {
+		p.conn.Close()
+		p.conn, p.r = nil, nil
+	}
+}
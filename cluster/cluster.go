@@ -0,0 +1,317 @@
+// Package cluster splits the keyspace across several servers by hash slot,
+// as upstream's cluster mode does. Every node knows which node serves each
+// slot and redirects clients there; nodes learn about each other by
+// gossiping over their client ports.
+package cluster
+
+import (
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrCrossSlot = errors.New("CROSSSLOT Keys in request don't hash to the same slot")
+	ErrTryAgain  = errors.New("TRYAGAIN Multiple keys request during rehashing of slot")
+	ErrDown      = errors.New("CLUSTERDOWN Hash slot not served")
+)
+
+// Node is a member of the cluster.
+type Node struct {
+	ID          string
+	Host        string
+	Port        int
+	ConfigEpoch uint64
+
+	pingSent  time.Time
+	pongRecv  time.Time
+	connected bool
+}
+
+// Addr returns the node's client address, where redirects point.
+func (n *Node) Addr() string {
+	return net.JoinHostPort(n.Host, strconv.Itoa(n.Port))
+}
+
+// Cluster is this node's view of the cluster.
+type Cluster struct {
+	// Password authenticates gossip to the other nodes, which are expected
+	// to share it.
+	Password string
+
+	mu           sync.RWMutex
+	myself       *Node
+	nodes        map[string]*Node    // by ID, including myself
+	handshakes   map[string]struct{} // addresses met but not heard from yet
+	slots        [Slots]*Node
+	migrating    [Slots]*Node // slots of ours moving to another node
+	importing    [Slots]*Node // slots of another node moving to us
+	currentEpoch uint64
+}
+
+// New returns a cluster of one node, which serves no slots until given some
+// with ADDSLOTS and learns of others with MEET. host and port are where
+// clients and other nodes reach it.
+func New(host string, port int) *Cluster {
+	b := make([]byte, 20)
+	rand.Read(b)
+	return newCluster(&Node{ID: hex.EncodeToString(b), Host: host, Port: port})
+}
+
+func newCluster(myself *Node) *Cluster {
+	return &Cluster{
+		myself:     myself,
+		nodes:      map[string]*Node{myself.ID: myself},
+		handshakes: make(map[string]struct{}),
+	}
+}
+
+// NewStatic returns a cluster laid out by spec, pairs of address and slot
+// ranges such as "127.0.0.1:7000 0-5460 127.0.0.1:7001 5461-16383". Every
+// node is given the same spec; each finds itself in it by host and port.
+// Node IDs are derived from addresses so they agree across nodes.
+func NewStatic(host string, port int, spec string) (*Cluster, error) {
+	c := newCluster(&Node{ID: staticID(net.JoinHostPort(host, strconv.Itoa(port))), Host: host, Port: port})
+
+	fields := strings.Fields(spec)
+	if len(fields)%2 != 0 {
+		return nil, fmt.Errorf("invalid cluster layout %q", spec)
+	}
+	for i := 0; i < len(fields); i += 2 {
+		nodeHost, portStr, err := net.SplitHostPort(fields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid node address %q", fields[i])
+		}
+		nodePort, err := strconv.Atoi(portStr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid node address %q", fields[i])
+		}
+		rs, err := parseRanges(fields[i+1])
+		if err != nil {
+			return nil, err
+		}
+
+		id := staticID(fields[i])
+		n, ok := c.nodes[id]
+		if !ok {
+			n = &Node{ID: id, Host: nodeHost, Port: nodePort}
+			c.nodes[id] = n
+		}
+		for _, r := range rs {
+			for slot := r.Start; slot <= r.End; slot++ {
+				if c.slots[slot] != nil {
+					return nil, fmt.Errorf("slot %d is assigned twice", slot)
+				}
+				c.slots[slot] = n
+			}
+		}
+	}
+	return c, nil
+}
+
+func staticID(addr string) string {
+	sum := sha1.Sum([]byte(addr))
+	return hex.EncodeToString(sum[:])
+}
+
+// MyID returns this node's ID.
+func (c *Cluster) MyID() string {
+	return c.myself.ID
+}
+
+// Route decides whether a command on keys runs here. It returns the keys'
+// slot, or -1 if there are none, and an error whose message is the reply
+// when the command must go elsewhere: MOVED when another node serves the
+// slot, ASK while the slot is being migrated and the keys have already left.
+// asking is set if the client sent ASKING first.
+func (c *Cluster) Route(keys []string, asking bool, exists func(key string) bool) (int, error) {
+	slot := -1
+	for _, key := range keys {
+		s := KeySlot(key)
+		if slot >= 0 && s != slot {
+			return slot, ErrCrossSlot
+		}
+		slot = s
+	}
+	if slot < 0 {
+		return -1, nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if asking && c.importing[slot] != nil {
+		return slot, nil
+	}
+	owner := c.slots[slot]
+	if owner == nil {
+		return slot, ErrDown
+	}
+	if owner != c.myself {
+		return slot, fmt.Errorf("MOVED %d %s", slot, owner.Addr())
+	}
+
+	if target := c.migrating[slot]; target != nil {
+		missing := 0
+		for _, key := range keys {
+			if !exists(key) {
+				missing++
+			}
+		}
+		if missing == len(keys) {
+			return slot, fmt.Errorf("ASK %d %s", slot, target.Addr())
+		}
+		if missing > 0 {
+			return slot, ErrTryAgain
+		}
+	}
+	return slot, nil
+}
+
+// AddSlots assigns unassigned slots to this node.
+func (c *Cluster) AddSlots(slots []int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	seen := make(map[int]bool, len(slots))
+	for _, slot := range slots {
+		if c.slots[slot] != nil {
+			return fmt.Errorf("ERR Slot %d is already busy", slot)
+		}
+		if seen[slot] {
+			return fmt.Errorf("ERR Slot %d specified multiple times", slot)
+		}
+		seen[slot] = true
+	}
+	for _, slot := range slots {
+		c.slots[slot] = c.myself
+		c.importing[slot] = nil
+	}
+	return nil
+}
+
+// DelSlots unassigns slots, whichever node serves them. Other nodes keep
+// their view until the owner stops claiming them.
+func (c *Cluster) DelSlots(slots []int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, slot := range slots {
+		if c.slots[slot] == nil {
+			return fmt.Errorf("ERR Slot %d is already unassigned", slot)
+		}
+	}
+	for _, slot := range slots {
+		c.slots[slot] = nil
+		c.migrating[slot] = nil
+		c.importing[slot] = nil
+	}
+	return nil
+}
+
+// SetSlot is CLUSTER SETSLOT: action is MIGRATING, IMPORTING, STABLE or NODE,
+// and nodeID the node it refers to. keysInSlot is how many keys this node
+// holds in the slot, which must be none to hand the slot over.
+func (c *Cluster) SetSlot(slot int, action, nodeID string, keysInSlot int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var n *Node
+	if action != "STABLE" {
+		if n = c.nodes[nodeID]; n == nil {
+			return fmt.Errorf("ERR I don't know about node %s", nodeID)
+		}
+	}
+
+	switch action {
+	case "MIGRATING":
+		if c.slots[slot] != c.myself {
+			return fmt.Errorf("ERR I'm not the owner of hash slot %d", slot)
+		}
+		if n == c.myself {
+			return errors.New("ERR I'm the target node for migration")
+		}
+		c.migrating[slot] = n
+	case "IMPORTING":
+		if c.slots[slot] == c.myself {
+			return fmt.Errorf("ERR I'm already the owner of hash slot %d", slot)
+		}
+		if n == c.myself {
+			return errors.New("ERR I'm the source node for migration")
+		}
+		c.importing[slot] = n
+	case "STABLE":
+		c.migrating[slot] = nil
+		c.importing[slot] = nil
+	case "NODE":
+		if c.slots[slot] == c.myself && n != c.myself && keysInSlot > 0 {
+			return fmt.Errorf("ERR Can't assign hashslot %d to a different node while I still hold keys for this hash slot.", slot)
+		}
+		if n != c.myself {
+			c.migrating[slot] = nil
+		}
+		// the end of an import: claim the slot with a new epoch so the
+		// claim wins over the old owner's once it is gossiped
+		if n == c.myself && c.importing[slot] != nil {
+			c.importing[slot] = nil
+			c.currentEpoch++
+			c.myself.ConfigEpoch = c.currentEpoch
+		}
+		c.slots[slot] = n
+	default:
+		return errors.New("ERR Invalid CLUSTER SETSLOT action or number of arguments. Try CLUSTER HELP")
+	}
+	return nil
+}
+
+// Meet introduces another node, which joins the cluster once it answers.
+func (c *Cluster) Meet(host string, port int) error {
+	if port <= 0 || port > 65535 || net.ParseIP(host) == nil {
+		return fmt.Errorf("ERR Invalid node address specified: %s:%d", host, port)
+	}
+	addr := net.JoinHostPort(host, strconv.Itoa(port))
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, n := range c.nodes {
+		if n.Addr() == addr {
+			return nil
+		}
+	}
+	c.handshakes[addr] = struct{}{}
+	return nil
+}
+
+// Forget removes a node from this node's view, as CLUSTER FORGET does.
+func (c *Cluster) Forget(nodeID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := c.nodes[nodeID]
+	if n == nil {
+		return fmt.Errorf("ERR Unknown node %s", nodeID)
+	}
+	if n == c.myself {
+		return errors.New("ERR I tried hard but I can't forget myself...")
+	}
+	delete(c.nodes, nodeID)
+	for slot := range c.slots {
+		if c.slots[slot] == n {
+			c.slots[slot] = nil
+		}
+		if c.migrating[slot] == n {
+			c.migrating[slot] = nil
+		}
+		if c.importing[slot] == n {
+			c.importing[slot] = nil
+		}
+	}
+	return nil
+}
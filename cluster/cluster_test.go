@@ -0,0 +1,118 @@
+package cluster
+
+import (
+	"testing"
+)
+
+func TestKeySlot(t *testing.T) {
+	if crc := crc16("123456789"); crc != 0x31C3 {
+		t.Errorf("crc16() failed. Expected: %#x, got: %#x", 0x31C3, crc)
+	}
+
+	tests := []struct {
+		key  string
+		slot int
+	}{
+		{"foo", 12182},
+		{"bar", 5061},
+		{"{user1000}.following", 3443},
+		{"{user1000}.followers", 3443},
+		{"user1000", 3443},
+		{"foo{{bar}}zap", int(crc16("{bar") % Slots)},
+	}
+	for _, tt := range tests {
+		if slot := KeySlot(tt.key); slot != tt.slot {
+			t.Errorf("KeySlot(%q) failed. Expected: %d, got: %d", tt.key, tt.slot, slot)
+		}
+	}
+	if KeySlot("foo{}{bar}") != int(crc16("foo{}{bar}")%Slots) {
+		t.Errorf("KeySlot() failed. Expected an empty hashtag to hash the whole key")
+	}
+}
+
+func TestRoute(t *testing.T) {
+	layout := "127.0.0.1:7000 0-8191 127.0.0.1:7001 8192-16383"
+	c, err := NewStatic("127.0.0.1", 7000, layout)
+	if err != nil {
+		t.Fatalf("NewStatic() failed: %v", err)
+	}
+	none := func(string) bool { return false }
+
+	// "bar" is slot 5061, ours; "foo" is 12182, on 7001
+	if slot, err := c.Route([]string{"bar"}, false, none); slot != 5061 || err != nil {
+		t.Errorf("Route() failed. Expected: 5061 <nil>, got: %d %v", slot, err)
+	}
+	if _, err := c.Route([]string{"foo"}, false, none); err == nil || err.Error() != "MOVED 12182 127.0.0.1:7001" {
+		t.Errorf("Route() failed. Expected: MOVED 12182 127.0.0.1:7001, got: %v", err)
+	}
+	if _, err := c.Route([]string{"bar", "foo"}, false, none); err != ErrCrossSlot {
+		t.Errorf("Route() failed. Expected: %v, got: %v", ErrCrossSlot, err)
+	}
+	if slot, err := c.Route(nil, false, none); slot != -1 || err != nil {
+		t.Errorf("Route() failed. Expected no slot, got: %d %v", slot, err)
+	}
+
+	// migrating 5061 away: keys still here are served, missing ones are asked for
+	other := staticID("127.0.0.1:7001")
+	if err := c.SetSlot(5061, "MIGRATING", other, 1); err != nil {
+		t.Fatalf("SetSlot() failed: %v", err)
+	}
+	exists := func(key string) bool { return key == "bar" }
+	if _, err := c.Route([]string{"bar"}, false, exists); err != nil {
+		t.Errorf("Route() failed. Expected the key to be served, got: %v", err)
+	}
+	if _, err := c.Route([]string{"{bar}x"}, false, exists); err == nil || err.Error() != "ASK 5061 127.0.0.1:7001" {
+		t.Errorf("Route() failed. Expected: ASK 5061 127.0.0.1:7001, got: %v", err)
+	}
+	if _, err := c.Route([]string{"bar", "{bar}x"}, false, exists); err != ErrTryAgain {
+		t.Errorf("Route() failed. Expected: %v, got: %v", ErrTryAgain, err)
+	}
+
+	// the other side accepts the slot only after ASKING
+	target, _ := NewStatic("127.0.0.1", 7001, layout)
+	if err := target.SetSlot(5061, "IMPORTING", c.MyID(), 0); err != nil {
+		t.Fatalf("SetSlot() failed: %v", err)
+	}
+	if _, err := target.Route([]string{"bar"}, false, none); err == nil || err.Error() != "MOVED 5061 127.0.0.1:7000" {
+		t.Errorf("Route() failed. Expected: MOVED 5061 127.0.0.1:7000, got: %v", err)
+	}
+	if _, err := target.Route([]string{"bar"}, true, none); err != nil {
+		t.Errorf("Route() failed. Expected ASKING to be served, got: %v", err)
+	}
+
+	// ending the migration on the target wins the slot over gossip
+	if err := target.SetSlot(5061, "NODE", target.MyID(), 0); err != nil {
+		t.Fatalf("SetSlot() failed: %v", err)
+	}
+	reply, err := c.Gossip(target.message().args())
+	if err != nil {
+		t.Fatalf("Gossip() failed: %v", err)
+	}
+	if _, err := c.Route([]string{"bar"}, false, none); err == nil || err.Error() != "MOVED 5061 127.0.0.1:7001" {
+		t.Errorf("Gossip() failed. Expected the slot to move, got: %v", err)
+	}
+	if m, _ := parseMessage(reply); m.id != c.MyID() {
+		t.Errorf("Gossip() failed. Expected a reply from %s, got: %s", c.MyID(), m.id)
+	}
+}
+
+func TestAddSlots(t *testing.T) {
+	c := New("127.0.0.1", 7000)
+	if err := c.AddSlots([]int{1, 2, 3}); err != nil {
+		t.Fatalf("AddSlots() failed: %v", err)
+	}
+	if err := c.AddSlots([]int{3}); err == nil || err.Error() != "ERR Slot 3 is already busy" {
+		t.Errorf("AddSlots() failed. Expected: ERR Slot 3 is already busy, got: %v", err)
+	}
+	if err := c.AddSlots([]int{4, 4}); err == nil || err.Error() != "ERR Slot 4 specified multiple times" {
+		t.Errorf("AddSlots() failed. Expected: ERR Slot 4 specified multiple times, got: %v", err)
+	}
+
+	nodes := c.Nodes()
+	if len(nodes) != 1 || len(nodes[0].Slots) != 1 || nodes[0].Slots[0] != (SlotRange{1, 3}) {
+		t.Errorf("Nodes() failed. Expected slots 1-3, got: %+v", nodes)
+	}
+	if info := c.Info(); info.OK || info.SlotsAssigned != 3 {
+		t.Errorf("Info() failed. Expected 3 slots and state fail, got: %+v", info)
+	}
+}
@@ -0,0 +1,118 @@
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Slots is the number of hash slots the keyspace is divided into.
+const Slots = 16384
+
+// crc16Table is CRC16-CCITT (XModem), polynomial 0x1021, as upstream uses
+// for key slots.
+var crc16Table = func() (t [256]uint16) {
+	for i := range t {
+		crc := uint16(i) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+		t[i] = crc
+	}
+	return t
+}()
+
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc = crc<<8 ^ crc16Table[byte(crc>>8)^s[i]]
+	}
+	return crc
+}
+
+// KeySlot returns the hash slot of key. If the key contains a non-empty
+// {hashtag}, only the tag is hashed, so related keys can share a slot.
+func KeySlot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key) % Slots)
+}
+
+// ParseSlot parses a slot number as the CLUSTER commands take it.
+func ParseSlot(s string) (int, error) {
+	slot, err := strconv.Atoi(s)
+	if err != nil || slot < 0 || slot >= Slots {
+		return 0, fmt.Errorf("ERR Invalid or out of range slot")
+	}
+	return slot, nil
+}
+
+// SlotRange is a run of consecutive slots, both ends included.
+type SlotRange struct {
+	Start, End int
+}
+
+func (r SlotRange) String() string {
+	if r.Start == r.End {
+		return strconv.Itoa(r.Start)
+	}
+	return strconv.Itoa(r.Start) + "-" + strconv.Itoa(r.End)
+}
+
+// ranges collapses a slot bitmap into ranges, in order.
+func ranges(slots *[Slots]bool) []SlotRange {
+	var rs []SlotRange
+	for slot := 0; slot < Slots; slot++ {
+		if !slots[slot] {
+			continue
+		}
+		if n := len(rs); n > 0 && rs[n-1].End == slot-1 {
+			rs[n-1].End = slot
+		} else {
+			rs = append(rs, SlotRange{slot, slot})
+		}
+	}
+	return rs
+}
+
+// parseRanges parses ranges such as "0-5460,5462", or "-" for none, as the
+// static configuration and gossip carry them.
+func parseRanges(s string) ([]SlotRange, error) {
+	if s == "-" || s == "" {
+		return nil, nil
+	}
+	var rs []SlotRange
+	for _, part := range strings.Split(s, ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := ParseSlot(first)
+		if err != nil {
+			return nil, fmt.Errorf("invalid slot range %q", part)
+		}
+		end := start
+		if isRange {
+			if end, err = ParseSlot(last); err != nil || end < start {
+				return nil, fmt.Errorf("invalid slot range %q", part)
+			}
+		}
+		rs = append(rs, SlotRange{start, end})
+	}
+	return rs, nil
+}
+
+func formatRanges(rs []SlotRange) string {
+	if len(rs) == 0 {
+		return "-"
+	}
+	parts := make([]string, len(rs))
+	for i, r := range rs {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
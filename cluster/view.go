@@ -0,0 +1,129 @@
+package cluster
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// NodeInfo describes a node for CLUSTER NODES, SLOTS and SHARDS.
+type NodeInfo struct {
+	ID          string
+	Host        string
+	Port        int
+	Myself      bool
+	Connected   bool
+	ConfigEpoch uint64
+	PingSent    time.Time
+	PongRecv    time.Time
+	Slots       []SlotRange
+
+	// slots being moved, by the ID of the node on the other end; only
+	// known for myself
+	Migrating map[int]string
+	Importing map[int]string
+}
+
+// Nodes returns every known node, myself first and the rest by ID.
+func (c *Cluster) Nodes() []NodeInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	owned := make(map[*Node]*[Slots]bool, len(c.nodes))
+	for slot, n := range c.slots {
+		if n == nil {
+			continue
+		}
+		if owned[n] == nil {
+			owned[n] = new([Slots]bool)
+		}
+		owned[n][slot] = true
+	}
+
+	infos := make([]NodeInfo, 0, len(c.nodes))
+	for _, n := range c.nodes {
+		info := NodeInfo{
+			ID:          n.ID,
+			Host:        n.Host,
+			Port:        n.Port,
+			Myself:      n == c.myself,
+			Connected:   n == c.myself || n.connected,
+			ConfigEpoch: n.ConfigEpoch,
+			PingSent:    n.pingSent,
+			PongRecv:    n.pongRecv,
+		}
+		if slots := owned[n]; slots != nil {
+			info.Slots = ranges(slots)
+		}
+		if info.Myself {
+			info.Migrating = make(map[int]string)
+			info.Importing = make(map[int]string)
+			for slot := range c.slots {
+				if m := c.migrating[slot]; m != nil {
+					info.Migrating[slot] = m.ID
+				}
+				if m := c.importing[slot]; m != nil {
+					info.Importing[slot] = m.ID
+				}
+			}
+		}
+		infos = append(infos, info)
+	}
+	slices.SortFunc(infos, func(a, b NodeInfo) int {
+		if a.Myself != b.Myself {
+			if a.Myself {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return infos
+}
+
+// Shard is a run of slots and the node serving it, a row of CLUSTER SLOTS.
+type Shard struct {
+	Range SlotRange
+	Node  NodeInfo
+}
+
+// SlotMap returns the slot ranges in order with the nodes serving them.
+func (c *Cluster) SlotMap() []Shard {
+	var shards []Shard
+	for _, n := range c.Nodes() {
+		for _, r := range n.Slots {
+			shards = append(shards, Shard{Range: r, Node: n})
+		}
+	}
+	slices.SortFunc(shards, func(a, b Shard) int {
+		return cmp.Compare(a.Range.Start, b.Range.Start)
+	})
+	return shards
+}
+
+// Info is the summary CLUSTER INFO reports.
+type Info struct {
+	OK            bool // every slot is served
+	SlotsAssigned int
+	KnownNodes    int
+	Size          int // nodes serving at least one slot
+	CurrentEpoch  uint64
+	MyEpoch       uint64
+}
+
+func (c *Cluster) Info() Info {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	info := Info{KnownNodes: len(c.nodes), CurrentEpoch: c.currentEpoch, MyEpoch: c.myself.ConfigEpoch}
+	serving := make(map[*Node]bool)
+	for _, n := range c.slots {
+		if n != nil {
+			info.SlotsAssigned++
+			serving[n] = true
+		}
+	}
+	info.Size = len(serving)
+	info.OK = info.SlotsAssigned == Slots
+	return info
+}
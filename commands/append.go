@@ -1,39 +1,31 @@
 package commands
 
 import (
-	"time"
-
+	"github.com/Ryan-DL/go-redis-server/cache"
 	"github.com/Ryan-DL/go-redis-server/response"
 )
 
 func (ch *CommandHandler) HandleAppend() {
-	if len(ch.Command) < 3 {
-		response.SendError(ch.Conn, "Not enough arguments. Expected APPEND key value")
-		return
-	}
-
 	key := ch.Command[1]
 	appendValue := ch.Command[2]
 
-	currentValue, exists := ch.MemoryStore.Get(key)
-	expiry, hasExpiry := ch.MemoryStore.GetExpiry(key)
-
-	if !exists {
-		ch.MemoryStore.Set(key, appendValue, 0) // No expiration for a new key
-		response.SendInteger(ch.Conn, len(appendValue))
+	var length int
+	err := ch.MemoryStore.Update(key, func(e *cache.Entry) error {
+		currentValue, _, err := e.String()
+		if err != nil {
+			return err
+		}
+
+		// a missing key appends to the empty string; an existing one keeps its TTL
+		newValue := currentValue + appendValue
+		e.Set(newValue)
+		length = len(newValue)
+		return nil
+	})
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
 		return
 	}
 
-	// Append the value if the key exists
-	newValue := currentValue + appendValue
-
-	// Set the updated value with the same expiration (if any)
-	if hasExpiry {
-		remainingTTL := time.Until(expiry)
-		ch.MemoryStore.Set(key, newValue, remainingTTL)
-	} else {
-		ch.MemoryStore.Set(key, newValue, 0)
-	}
-
-	response.SendInteger(ch.Conn, len(newValue))
+	response.SendInteger(ch.Conn, length)
 }
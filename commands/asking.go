@@ -0,0 +1,14 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+// HandleAsking lets the client's next command use a slot this node is
+// importing, as a client does when following an ASK redirect.
+func (ch *CommandHandler) HandleAsking() {
+	if ch.Cluster == nil {
+		response.SendError(ch.Conn, errNoCluster)
+		return
+	}
+	ch.Client.asking = true
+	response.SendSimpleString(ch.Conn, "OK")
+}
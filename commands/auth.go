@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+const errWrongPass = "WRONGPASS invalid username-password pair or user is disabled."
+
+// AUTH [username] password
+func (ch *CommandHandler) HandleAuth() {
+	if len(ch.Command) > 3 {
+		response.SendError(ch.Conn, errSyntax)
+		return
+	}
+	if ch.Password == "" {
+		response.SendError(ch.Conn, "ERR AUTH <password> called without any password configured for the default user. Are you sure your configuration is correct?")
+		return
+	}
+
+	if len(ch.Command) == 2 {
+		if !ch.authenticate("default", ch.Command[1]) {
+			response.SendError(ch.Conn, "ERR invalid password")
+			return
+		}
+	} else if !ch.authenticate(ch.Command[1], ch.Command[2]) {
+		response.SendError(ch.Conn, errWrongPass)
+		return
+	}
+	response.SendSimpleString(ch.Conn, "OK")
+}
+
+// authenticate checks a username and password, and marks the client as
+// authenticated if they match. The only user is "default", whose password
+// is the server's.
+func (ch *CommandHandler) authenticate(username, password string) bool {
+	if username != "default" || (ch.Password != "" && password != ch.Password) {
+		return false
+	}
+	if ch.Client != nil {
+		ch.Client.authenticated = true
+	}
+	return true
+}
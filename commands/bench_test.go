@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/protocol"
+	"github.com/Ryan-DL/go-redis-server/pubsub"
+	"github.com/Ryan-DL/go-redis-server/replication"
+)
+
+// discardPropagator takes every batch and drops it, so writes are ordered as
+// with an AOF attached.
+type discardPropagator struct{}
+
+func (discardPropagator) Propagate([][]string) {}
+
+// BenchmarkDispatch runs commands through Dispatch from parallel clients, as
+// the server does. "idle" propagates to a primary no replica has synced
+// with, as a server without an AOF starts out; "ordered" to a propagator
+// that orders every write. Run with -cpu 1,4,16 to see how they scale.
+func BenchmarkDispatch(b *testing.B) {
+	propagators := []struct {
+		name string
+		p    Propagator
+	}{
+		{"idle", replication.NewNode(cache.NewValueStore(time.Minute), 0, 1<<20)},
+		{"ordered", discardPropagator{}},
+	}
+	for _, command := range []string{"GET", "SET"} {
+		for _, propagator := range propagators {
+			b.Run(command+"/"+propagator.name, func(b *testing.B) {
+				store := cache.NewValueStore(time.Minute)
+				for i := 0; i < 1000; i++ {
+					store.Set("key:"+strconv.Itoa(i), "value", 0)
+				}
+				broker := pubsub.NewBroker()
+				var clients atomic.Int64
+				b.RunParallel(func(pb *testing.PB) {
+					client := NewClient(discardConn{}, broker)
+					i := int(clients.Add(1)) * 7919
+					args := []string{command, "", "value"}
+					if command == "GET" {
+						args = args[:2]
+					}
+					for pb.Next() {
+						i++
+						args[0] = command
+						args[1] = "key:" + strconv.Itoa(i%1000)
+						ch := NewCommandHandler(client, args, store)
+						ch.Client = client
+						ch.Propagator = propagator.p
+						ch.Dispatch()
+					}
+					client.Flush()
+				})
+			})
+		}
+	}
+}
+
+// BenchmarkDispatchArgs runs commands from their arguments as the protocol
+// Reader returns them, converting them to strings "each" on its own, as the
+// server used to, or "packed" together as it does now.
+func BenchmarkDispatchArgs(b *testing.B) {
+	conversions := []struct {
+		name    string
+		convert func([][]byte) []string
+	}{
+		{"each", func(args [][]byte) []string {
+			command := make([]string, len(args))
+			for i, arg := range args {
+				command[i] = string(arg)
+			}
+			return command
+		}},
+		{"packed", protocol.Strings},
+	}
+	hset := []string{"HSET", "key"}
+	for i := 0; i < 10; i++ {
+		hset = append(hset, "field:"+strconv.Itoa(i), "value")
+	}
+	for _, command := range [][]string{{"SET", "key", "value"}, hset} {
+		args := make([][]byte, len(command))
+		for i, arg := range command {
+			args[i] = []byte(arg)
+		}
+		for _, conversion := range conversions {
+			b.Run(command[0]+"/"+conversion.name, func(b *testing.B) {
+				store := cache.NewValueStore(time.Minute)
+				client := NewClient(discardConn{}, pubsub.NewBroker())
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					ch := NewCommandHandler(client, conversion.convert(args), store)
+					ch.Client = client
+					ch.Dispatch()
+				}
+				client.Flush()
+			})
+		}
+	}
+}
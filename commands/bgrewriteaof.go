@@ -0,0 +1,23 @@
+package commands
+
+import (
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+func (ch *CommandHandler) HandleBGRewriteAOF() {
+	if ch.AOF == nil {
+		response.SendError(ch.Conn, "ERR append only file is disabled")
+		return
+	}
+
+	// no write may land between the snapshot and logging the writes after it
+	writeMu.Lock()
+	err := ch.AOF.Rewrite()
+	writeMu.Unlock()
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.SendSimpleString(ch.Conn, "Background append only file rewriting started")
+}
@@ -0,0 +1,35 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+func (ch *CommandHandler) HandleBGSave() {
+	if ch.Saver == nil {
+		response.SendError(ch.Conn, errNoSaver)
+		return
+	}
+
+	schedule := false
+	if len(ch.Command) > 1 {
+		if len(ch.Command) > 2 || strings.ToUpper(ch.Command[1]) != "SCHEDULE" {
+			response.SendError(ch.Conn, errSyntax)
+			return
+		}
+		schedule = true
+	}
+
+	if schedule {
+		if ch.Saver.ScheduleBGSave() {
+			response.SendSimpleString(ch.Conn, "Background saving scheduled")
+			return
+		}
+	} else if err := ch.Saver.BGSave(); err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.SendSimpleString(ch.Conn, "Background saving started")
+}
@@ -0,0 +1,55 @@
+package commands
+
+import "time"
+
+// block waits for a signal on wake until deadline, or forever when deadline
+// is zero. It returns false if the deadline passes or the client disconnects
+// first, in which case the command should give up.
+func (ch *CommandHandler) block(wake <-chan struct{}, deadline time.Time) bool {
+	// inside a transaction blocking commands behave as if they timed out
+	if ch.inExec {
+		return false
+	}
+	// let transactions and other writers run while we wait. What they change
+	// is theirs to propagate, so start counting changes afresh on return.
+	slot := ch.execSlot()
+	execMu.RUnlock(slot)
+	if ch.writeLocked {
+		writeMu.Unlock()
+	}
+	defer func() {
+		execMu.RLock(slot)
+		if ch.writeLocked {
+			writeMu.Lock()
+			ch.dirty = ch.MemoryStore.Dirty()
+		}
+	}()
+
+	// the replies to commands pipelined before this one are not held back
+	if ch.Client != nil {
+		ch.Client.Flush()
+	}
+
+	var closed <-chan struct{}
+	if ch.WatchClose != nil {
+		var stop func()
+		closed, stop = ch.WatchClose()
+		defer stop()
+	}
+
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	select {
+	case <-wake:
+		return true
+	case <-timeout:
+		return false
+	case <-closed:
+		return false
+	}
+}
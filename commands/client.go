@@ -0,0 +1,181 @@
+package commands
+
+import (
+	"bufio"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/pubsub"
+)
+
+// Client is the per-connection state commands need beyond a single request.
+// It wraps the connection so that replies are buffered until Flush, which
+// the connection loop calls once it has run every command the client
+// pipelined, and so that, once the client has subscribed to something,
+// replies are queued behind pushed messages instead of racing them.
+type Client struct {
+	net.Conn
+
+	out *bufio.Writer // replies not yet flushed, from writers while there are any
+
+	id            int64
+	name          string // from HELLO SETNAME
+	proto         int    // the protocol version replies are sent in, 2 or 3
+	authenticated bool
+
+	broker     *pubsub.Broker
+	subscriber *pubsub.Subscriber
+
+	// transaction state: commands queued since MULTI, whether one of them
+	// was rejected, and the keys being watched
+	multi       bool
+	multiFailed bool
+	queue       [][]string
+	watch       *cache.Watch
+
+	// cluster state: whether ASKING preceded this command, and the slot
+	// the keys of the queued commands hash to, or -1
+	asking    bool
+	multiSlot int
+
+	// the port a replica listens on, from REPLCONF listening-port
+	replicaPort int
+}
+
+// clientIDs numbers clients in the order they connect.
+var clientIDs atomic.Int64
+
+func NewClient(conn net.Conn, broker *pubsub.Broker) *Client {
+	return &Client{
+		Conn:      conn,
+		id:        clientIDs.Add(1),
+		proto:     2,
+		broker:    broker,
+		multiSlot: -1,
+	}
+}
+
+// outBufferSize is how much of a client's replies is buffered; a larger
+// reply is written out as it is serialized.
+const outBufferSize = 16 * 1024
+
+// writers are the reply buffers. A client only holds one between its first
+// reply and Flush, so idle clients cost no buffer.
+var writers = sync.Pool{New: func() any { return bufio.NewWriterSize(nil, outBufferSize) }}
+
+// writer returns the client's reply buffer, taking one from the pool if it
+// has none.
+func (c *Client) writer() *bufio.Writer {
+	if c.out == nil {
+		c.out = writers.Get().(*bufio.Writer)
+		c.out.Reset(c.Conn)
+	}
+	return c.out
+}
+
+// Proto returns the protocol version the client speaks, 2 unless it switched
+// to 3 with HELLO. Replies written to the client are serialized in it.
+func (c *Client) Proto() int {
+	return c.proto
+}
+
+func (c *Client) setProto(proto int) {
+	c.proto = proto
+	if c.subscriber != nil {
+		c.subscriber.SetProto(proto)
+	}
+}
+
+// Authenticated reports whether the client has given the password, with AUTH
+// or HELLO.
+func (c *Client) Authenticated() bool {
+	return c.authenticated
+}
+
+func (c *Client) Write(p []byte) (int, error) {
+	if c.subscriber != nil {
+		return c.subscriber.Write(p)
+	}
+	return c.writer().Write(p)
+}
+
+// WriteString is Write without copying s, which replies are serialized to.
+func (c *Client) WriteString(s string) (int, error) {
+	if c.subscriber != nil {
+		return c.subscriber.Write([]byte(s))
+	}
+	return c.writer().WriteString(s)
+}
+
+// Flush sends the buffered replies. The connection loop calls it when the
+// client has nothing more pipelined, and commands before they wait, so a
+// client is never left waiting for replies that are ready.
+func (c *Client) Flush() error {
+	if c.out == nil {
+		return nil
+	}
+	err := c.out.Flush()
+	c.out.Reset(nil)
+	writers.Put(c.out)
+	c.out = nil
+	return err
+}
+
+// Subscriber returns the client's broker subscriber, creating it on first
+// use. A subscriber that falls behind has its connection closed.
+func (c *Client) Subscriber() *pubsub.Subscriber {
+	if c.subscriber == nil {
+		// the subscriber writes to the connection itself from now on, so what
+		// is buffered must go first
+		c.Flush()
+		c.subscriber = c.broker.NewSubscriber(c.Conn, func() { c.Conn.Close() })
+		c.subscriber.SetProto(c.proto)
+	}
+	return c.subscriber
+}
+
+// Subscribed reports whether the client is in subscribed mode, where only
+// commands flagged FlagSubscriber may run.
+func (c *Client) Subscribed() bool {
+	return c.subscriber != nil && c.broker.Count(c.subscriber) > 0
+}
+
+// InMulti reports whether the client is queueing commands for EXEC.
+func (c *Client) InMulti() bool {
+	return c.multi
+}
+
+// rejectQueued marks the transaction as failed after a command could not be
+// queued, so EXEC aborts it.
+func (c *Client) rejectQueued() {
+	if c.multi {
+		c.multiFailed = true
+	}
+}
+
+// endMulti leaves the transaction and stops watching keys, as EXEC and
+// DISCARD do.
+func (c *Client) endMulti() {
+	c.multi = false
+	c.multiFailed = false
+	c.queue = nil
+	c.multiSlot = -1
+	if c.watch != nil {
+		c.watch.Clear()
+	}
+}
+
+// Close unsubscribes the client, drops its watches, flushes queued replies
+// and closes the connection.
+func (c *Client) Close() error {
+	c.Flush()
+	if c.subscriber != nil {
+		c.broker.Close(c.subscriber)
+	}
+	if c.watch != nil {
+		c.watch.Clear()
+	}
+	return c.Conn.Close()
+}
@@ -0,0 +1,342 @@
+package commands
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cluster"
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// route checks that the command's keys are served here, replying with the
+// redirect if not. Inside MULTI every queued command must use the same slot.
+func (ch *CommandHandler) route(cmd *Command) bool {
+	asking := false
+	if ch.Client != nil {
+		asking = ch.Client.asking
+		if cmd.Name != "ASKING" {
+			ch.Client.asking = false
+		}
+	}
+
+	slot, err := ch.Cluster.Route(cmd.Keys(ch.Command), asking, ch.MemoryStore.Exists)
+	if err == nil && slot >= 0 && ch.Client != nil && ch.Client.InMulti() && !cmd.Has(FlagNoQueue) {
+		if ch.Client.multiSlot >= 0 && ch.Client.multiSlot != slot {
+			err = cluster.ErrCrossSlot
+		}
+		ch.Client.multiSlot = slot
+	}
+	if err != nil {
+		ch.rejectQueued()
+		response.SendError(ch.Conn, err.Error())
+		return false
+	}
+	return true
+}
+
+// keysInSlot returns the keys in slot, in order.
+func (ch *CommandHandler) keysInSlot(slot int) []string {
+	var keys []string
+	for _, key := range ch.MemoryStore.GetKeys() {
+		if cluster.KeySlot(key) == slot {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// CLUSTER INFO | MYID | NODES | SLOTS | SHARDS
+// CLUSTER ADDSLOTS slot [slot ...]
+// CLUSTER ADDSLOTSRANGE start end [start end ...]
+// CLUSTER DELSLOTS slot [slot ...]
+// CLUSTER MEET ip port
+// CLUSTER FORGET node-id
+// CLUSTER KEYSLOT key
+// CLUSTER COUNTKEYSINSLOT slot
+// CLUSTER GETKEYSINSLOT slot count
+// CLUSTER SETSLOT slot IMPORTING|MIGRATING|NODE node-id | STABLE
+// CLUSTER GOSSIP ..., exchanged between nodes, see cluster/bus.go
+func (ch *CommandHandler) HandleCluster() {
+	subcommand := strings.ToUpper(ch.Command[1])
+
+	arity := map[string]int{
+		"INFO":            2,
+		"MYID":            2,
+		"NODES":           2,
+		"SLOTS":           2,
+		"SHARDS":          2,
+		"ADDSLOTS":        -3,
+		"ADDSLOTSRANGE":   -4,
+		"DELSLOTS":        -3,
+		"MEET":            -4,
+		"FORGET":          3,
+		"KEYSLOT":         3,
+		"COUNTKEYSINSLOT": 3,
+		"GETKEYSINSLOT":   4,
+		"SETSLOT":         -4,
+		"GOSSIP":          -8,
+	}[subcommand]
+	if arity == 0 {
+		response.SendError(ch.Conn, fmt.Sprintf("ERR unknown subcommand '%s'", ch.Command[1]))
+		return
+	}
+	if (arity > 0 && len(ch.Command) != arity) || (arity < 0 && len(ch.Command) < -arity) {
+		response.SendError(ch.Conn, errWrongArgs(ch.Command[0]+"|"+subcommand))
+		return
+	}
+
+	// KEYSLOT is handy without a cluster too
+	if subcommand == "KEYSLOT" {
+		response.SendInteger(ch.Conn, cluster.KeySlot(ch.Command[2]))
+		return
+	}
+	c := ch.Cluster
+	if c == nil {
+		response.SendError(ch.Conn, errNoCluster)
+		return
+	}
+
+	switch subcommand {
+	case "INFO":
+		info := c.Info()
+		state := "fail"
+		if info.OK {
+			state = "ok"
+		}
+		var b strings.Builder
+		fmt.Fprintf(&b, "cluster_state:%s\r\n", state)
+		fmt.Fprintf(&b, "cluster_slots_assigned:%d\r\n", info.SlotsAssigned)
+		fmt.Fprintf(&b, "cluster_slots_ok:%d\r\n", info.SlotsAssigned)
+		fmt.Fprintf(&b, "cluster_slots_pfail:0\r\n")
+		fmt.Fprintf(&b, "cluster_slots_fail:0\r\n")
+		fmt.Fprintf(&b, "cluster_known_nodes:%d\r\n", info.KnownNodes)
+		fmt.Fprintf(&b, "cluster_size:%d\r\n", info.Size)
+		fmt.Fprintf(&b, "cluster_current_epoch:%d\r\n", info.CurrentEpoch)
+		fmt.Fprintf(&b, "cluster_my_epoch:%d\r\n", info.MyEpoch)
+		response.SendBulkString(ch.Conn, b.String())
+
+	case "MYID":
+		response.SendBulkString(ch.Conn, c.MyID())
+
+	case "NODES":
+		response.SendBulkString(ch.Conn, clusterNodes(c.Nodes()))
+
+	case "SLOTS":
+		shards := c.SlotMap()
+		reply := make(response.ArrayType, len(shards))
+		for i, s := range shards {
+			reply[i] = response.ArrayType{
+				response.IntegerType(s.Range.Start),
+				response.IntegerType(s.Range.End),
+				response.ArrayType{
+					response.BulkStringType(s.Node.Host),
+					response.IntegerType(s.Node.Port),
+					response.BulkStringType(s.Node.ID),
+				},
+			}
+		}
+		response.SendArray(ch.Conn, reply)
+
+	case "SHARDS":
+		var reply response.ArrayType
+		for _, n := range c.Nodes() {
+			if len(n.Slots) == 0 {
+				continue
+			}
+			slots := make(response.ArrayType, 0, 2*len(n.Slots))
+			for _, r := range n.Slots {
+				slots = append(slots, response.IntegerType(r.Start), response.IntegerType(r.End))
+			}
+			health := "online"
+			if !n.Connected {
+				health = "fail"
+			}
+			reply = append(reply, response.ArrayType{
+				response.BulkStringType("slots"), slots,
+				response.BulkStringType("nodes"), response.ArrayType{response.ArrayType{
+					response.BulkStringType("id"), response.BulkStringType(n.ID),
+					response.BulkStringType("port"), response.IntegerType(n.Port),
+					response.BulkStringType("ip"), response.BulkStringType(n.Host),
+					response.BulkStringType("endpoint"), response.BulkStringType(n.Host),
+					response.BulkStringType("role"), response.BulkStringType("master"),
+					response.BulkStringType("replication-offset"), response.IntegerType(0),
+					response.BulkStringType("health"), response.BulkStringType(health),
+				}},
+			})
+		}
+		response.SendArray(ch.Conn, reply)
+
+	case "ADDSLOTS", "DELSLOTS":
+		slots := make([]int, 0, len(ch.Command)-2)
+		for _, arg := range ch.Command[2:] {
+			slot, err := cluster.ParseSlot(arg)
+			if err != nil {
+				response.SendError(ch.Conn, err.Error())
+				return
+			}
+			slots = append(slots, slot)
+		}
+		var err error
+		if subcommand == "ADDSLOTS" {
+			err = c.AddSlots(slots)
+		} else {
+			err = c.DelSlots(slots)
+		}
+		if err != nil {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+		response.SendSimpleString(ch.Conn, "OK")
+
+	case "ADDSLOTSRANGE":
+		if len(ch.Command)%2 != 0 {
+			response.SendError(ch.Conn, errWrongArgs(ch.Command[0]+"|"+subcommand))
+			return
+		}
+		var slots []int
+		for i := 2; i < len(ch.Command); i += 2 {
+			start, err := cluster.ParseSlot(ch.Command[i])
+			if err != nil {
+				response.SendError(ch.Conn, err.Error())
+				return
+			}
+			end, err := cluster.ParseSlot(ch.Command[i+1])
+			if err != nil {
+				response.SendError(ch.Conn, err.Error())
+				return
+			}
+			if start > end {
+				response.SendError(ch.Conn, fmt.Sprintf("ERR start slot number %d is greater than end slot number %d", start, end))
+				return
+			}
+			for slot := start; slot <= end; slot++ {
+				slots = append(slots, slot)
+			}
+		}
+		if err := c.AddSlots(slots); err != nil {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+		response.SendSimpleString(ch.Conn, "OK")
+
+	case "MEET":
+		port, err := strconv.Atoi(ch.Command[3])
+		if err != nil {
+			response.SendError(ch.Conn, "ERR Invalid base port specified: "+ch.Command[3])
+			return
+		}
+		if err := c.Meet(ch.Command[2], port); err != nil {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+		response.SendSimpleString(ch.Conn, "OK")
+
+	case "FORGET":
+		if err := c.Forget(ch.Command[2]); err != nil {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+		response.SendSimpleString(ch.Conn, "OK")
+
+	case "COUNTKEYSINSLOT":
+		slot, err := cluster.ParseSlot(ch.Command[2])
+		if err != nil {
+			response.SendError(ch.Conn, "ERR Invalid slot")
+			return
+		}
+		response.SendInteger(ch.Conn, len(ch.keysInSlot(slot)))
+
+	case "GETKEYSINSLOT":
+		slot, err := cluster.ParseSlot(ch.Command[2])
+		count, countErr := strconv.Atoi(ch.Command[3])
+		if err != nil || countErr != nil || count < 0 {
+			response.SendError(ch.Conn, "ERR Invalid slot or number of keys")
+			return
+		}
+		keys := ch.keysInSlot(slot)
+		response.SendStringArray(ch.Conn, keys[:min(count, len(keys))])
+
+	case "SETSLOT":
+		slot, err := cluster.ParseSlot(ch.Command[2])
+		if err != nil {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+		action := strings.ToUpper(ch.Command[3])
+		var nodeID string
+		switch {
+		case action == "STABLE" && len(ch.Command) == 4:
+		case action != "STABLE" && len(ch.Command) == 5:
+			nodeID = ch.Command[4]
+		default:
+			response.SendError(ch.Conn, "ERR Invalid CLUSTER SETSLOT action or number of arguments. Try CLUSTER HELP")
+			return
+		}
+		if err := c.SetSlot(slot, action, nodeID, len(ch.keysInSlot(slot))); err != nil {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+		response.SendSimpleString(ch.Conn, "OK")
+
+	case "GOSSIP":
+		reply, err := c.Gossip(ch.Command[2:])
+		if err != nil {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+		response.SendStringArray(ch.Conn, reply)
+	}
+}
+
+// clusterNodes formats CLUSTER NODES, one line per node:
+// <id> <ip:port@cport> <flags> <master> <ping-sent> <pong-recv> <config-epoch> <link-state> <slot> ...
+// The bus shares the client port, so cport is the port itself.
+func clusterNodes(nodes []cluster.NodeInfo) string {
+	var b strings.Builder
+	for _, n := range nodes {
+		flags := "master"
+		if n.Myself {
+			flags = "myself,master"
+		}
+		link := "connected"
+		if !n.Connected {
+			link = "disconnected"
+		}
+		fmt.Fprintf(&b, "%s %s:%d@%d %s - %d %d %d %s",
+			n.ID, n.Host, n.Port, n.Port, flags,
+			unixMilli(n.PingSent), unixMilli(n.PongRecv),
+			n.ConfigEpoch, link)
+		for _, r := range n.Slots {
+			b.WriteString(" " + r.String())
+		}
+		for _, slot := range sortedSlots(n.Migrating) {
+			fmt.Fprintf(&b, " [%d->-%s]", slot, n.Migrating[slot])
+		}
+		for _, slot := range sortedSlots(n.Importing) {
+			fmt.Fprintf(&b, " [%d-<-%s]", slot, n.Importing[slot])
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func sortedSlots(m map[int]string) []int {
+	slots := make([]int, 0, len(m))
+	for slot := range m {
+		slots = append(slots, slot)
+	}
+	slices.Sort(slots)
+	return slots
+}
+
+// unixMilli is t in milliseconds, or 0 for the zero time.
+func unixMilli(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixMilli()
+}
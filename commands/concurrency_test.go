@@ -0,0 +1,129 @@
+package commands
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/scripting"
+)
+
+// run dispatches one command. Dispatch rewrites the name in place, so each
+// call gets its own copy of the arguments.
+func run(store *cache.ValueStore, command ...string) {
+	NewCommandHandler(discardConn{}, append([]string(nil), command...), store).Dispatch()
+}
+
+// hammer runs command from workers goroutines, rounds times each.
+func hammer(store *cache.ValueStore, workers, rounds int, command ...string) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				run(store, command...)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestConcurrentIncr(t *testing.T) {
+	store := cache.NewValueStore(time.Minute)
+	store.Set("counter", "0", time.Hour)
+
+	hammer(store, 50, 200, "INCR", "counter")
+	hammer(store, 10, 100, "DECR", "counter")
+
+	value, _, _ := store.Get("counter")
+	if value != strconv.Itoa(50*200-10*100) {
+		t.Errorf("INCR failed. Expected: %d, got: %s", 50*200-10*100, value)
+	}
+	if _, hasExpiry := store.GetExpiry("counter"); !hasExpiry {
+		t.Error("INCR failed. Expected the key to keep its TTL")
+	}
+}
+
+func TestConcurrentAppend(t *testing.T) {
+	store := cache.NewValueStore(time.Minute)
+
+	hammer(store, 20, 100, "APPEND", "log", "ab")
+
+	value, _, _ := store.Get("log")
+	if len(value) != 20*100*2 {
+		t.Errorf("APPEND failed. Expected length: %d, got: %d", 20*100*2, len(value))
+	}
+}
+
+func TestConcurrentRename(t *testing.T) {
+	store := cache.NewValueStore(time.Minute)
+	store.Set("counter", "0", 0)
+
+	// Each worker shuttles its own key between two names while the counter is
+	// incremented alongside; a key must never be lost or end up under both.
+	const workers, rounds = 20, 200
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		id := strconv.Itoa(i)
+		store.Set("src"+id, id, time.Hour)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				run(store, "RENAME", "src"+id, "dst"+id)
+				run(store, "INCR", "counter")
+				run(store, "RENAME", "dst"+id, "src"+id)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < workers; i++ {
+		id := strconv.Itoa(i)
+		value, ok, _ := store.Get("src" + id)
+		if !ok || value != id {
+			t.Errorf("RENAME failed. Expected src%s: %s, got: %q", id, id, value)
+		}
+		if store.Exists("dst" + id) {
+			t.Errorf("RENAME failed. Expected dst%s to be gone", id)
+		}
+		if _, hasExpiry := store.GetExpiry("src" + id); !hasExpiry {
+			t.Errorf("RENAME failed. Expected src%s to keep its TTL", id)
+		}
+	}
+	value, _, _ := store.Get("counter")
+	if value != strconv.Itoa(workers*rounds) {
+		t.Errorf("INCR failed. Expected: %d, got: %s", workers*rounds, value)
+	}
+}
+
+func TestConcurrentEval(t *testing.T) {
+	store := cache.NewValueStore(time.Minute)
+	scripts := scripting.NewEngine(time.Second)
+
+	// a read-modify-write that would lose updates if scripts interleaved
+	script := "local n = tonumber(redis.call('GET', KEYS[1]) or '0') redis.call('SET', KEYS[1], n + 1)"
+	const workers, rounds = 20, 50
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < rounds; j++ {
+				ch := NewCommandHandler(discardConn{}, []string{"EVAL", script, "1", "counter"}, store)
+				ch.Scripts = scripts
+				ch.Dispatch()
+				run(store, "INCR", "other")
+			}
+		}()
+	}
+	wg.Wait()
+
+	value, _, _ := store.Get("counter")
+	if value != strconv.Itoa(workers*rounds) {
+		t.Errorf("EVAL failed. Expected: %d, got: %s", workers*rounds, value)
+	}
+}
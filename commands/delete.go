@@ -5,11 +5,6 @@ import (
 )
 
 func (ch *CommandHandler) HandleDelete() {
-	if len(ch.Command) < 2 {
-		response.SendError(ch.Conn, "Not enough arguments. Expected DEL key [key ...]")
-		return
-	}
-
 	keys := ch.Command[1:]
 
 	deletedCount := 0
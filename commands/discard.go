@@ -0,0 +1,12 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+func (ch *CommandHandler) HandleDiscard() {
+	if !ch.Client.multi {
+		response.SendError(ch.Conn, "ERR DISCARD without MULTI")
+		return
+	}
+	ch.Client.endMulti()
+	response.SendSimpleString(ch.Conn, "OK")
+}
@@ -0,0 +1,28 @@
+package commands
+
+import "strings"
+
+// Error replies shared by several commands.
+const (
+	errNotInteger  = "ERR value is not an integer or out of range"
+	errNotPositive = "ERR value is out of range, must be positive"
+	errOutOfRange  = "ERR value is out of range"
+	errSyntax      = "ERR syntax error"
+	errNotFloat    = "ERR value is not a valid float"
+	errNoSaver     = "ERR persistence is disabled"
+
+	errNoReplication = "ERR replication is disabled"
+	errNoCluster     = "ERR This instance has cluster support disabled"
+	errNoScripting   = "ERR scripting is disabled"
+	errBusy          = "BUSY Redis is busy running a script. You can only call SCRIPT KILL or SHUTDOWN NOSAVE."
+	errReadOnly      = "READONLY You can't write against a read only replica."
+	errOOM           = "OOM command not allowed when used memory > 'maxmemory'."
+)
+
+func errWrongArgs(name string) string {
+	return "ERR wrong number of arguments for '" + strings.ToLower(name) + "' command"
+}
+
+func errInvalidExpire(name string) string {
+	return "ERR invalid expire time in '" + strings.ToLower(name) + "' command"
+}
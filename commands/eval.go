@@ -0,0 +1,172 @@
+package commands
+
+import (
+	"bufio"
+	"strconv"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+	"github.com/Ryan-DL/go-redis-server/scripting"
+)
+
+func (ch *CommandHandler) HandleEval() {
+	ch.eval(false, false)
+}
+
+func (ch *CommandHandler) HandleEvalSHA() {
+	ch.eval(true, false)
+}
+
+func (ch *CommandHandler) HandleEvalRO() {
+	ch.eval(false, true)
+}
+
+func (ch *CommandHandler) HandleEvalSHARO() {
+	ch.eval(true, true)
+}
+
+// evalKeys returns the keys of EVAL and the commands like it:
+// script numkeys [key ...] [arg ...].
+func evalKeys(args []string) []string {
+	numKeys, err := strconv.Atoi(args[2])
+	if err != nil || numKeys < 0 || numKeys > len(args)-3 {
+		return nil
+	}
+	return args[3 : 3+numKeys]
+}
+
+// parseKeysArgs splits "numkeys [key ...] [arg ...]", starting at args[i],
+// into KEYS and ARGV.
+func parseKeysArgs(args []string, i int) (keys, argv []string, errMsg string) {
+	numKeys, err := strconv.Atoi(args[i])
+	if err != nil {
+		return nil, nil, errNotInteger
+	}
+	if numKeys < 0 {
+		return nil, nil, "ERR Number of keys can't be negative"
+	}
+	// compared this way round, so a huge numkeys cannot overflow
+	if numKeys > len(args)-i-1 {
+		return nil, nil, "ERR Number of keys can't be greater than number of args"
+	}
+	return args[i+1 : i+1+numKeys], args[i+1+numKeys:], ""
+}
+
+// EVAL script numkeys [key ...] [arg ...]
+// EVALSHA sha1 numkeys [key ...] [arg ...]
+// and their _RO variants, which refuse to write.
+func (ch *CommandHandler) eval(bySHA, readOnly bool) {
+	if ch.Scripts == nil {
+		response.SendError(ch.Conn, errNoScripting)
+		return
+	}
+	keys, argv, errMsg := parseKeysArgs(ch.Command, 2)
+	if errMsg != "" {
+		response.SendError(ch.Conn, errMsg)
+		return
+	}
+
+	sha := ch.Command[1]
+	if !bySHA {
+		var err error
+		if sha, err = ch.Scripts.Load(ch.Command[1]); err != nil {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+	}
+
+	ch.runScript(func(batch *[][]string) response.DataType {
+		return ch.Scripts.Eval(sha, keys, argv, ch.scriptCaller(readOnly, batch))
+	})
+}
+
+// runScript runs a script atomically, as EXEC runs a transaction, and
+// propagates the writes it makes rather than the script, as one batch. The
+// script's redis.call adds them to batch, through scriptCaller.
+func (ch *CommandHandler) runScript(script func(batch *[][]string) response.DataType) {
+	if !ch.inExec {
+		// upgrade to the write lock Dispatch took for reading
+		slot := ch.execSlot()
+		execMu.RUnlock(slot)
+		execMu.Lock()
+		defer func() {
+			execMu.Unlock()
+			execMu.RLock(slot)
+		}()
+		if ch.Propagator != nil {
+			defer ch.holdReplies()()
+		}
+	}
+
+	var batch [][]string
+	reply := script(&batch)
+
+	// inside EXEC the writes join the transaction's batch
+	if ch.inExec {
+		ch.propagateAs(batch...)
+	} else if len(batch) > 0 {
+		ch.Propagator.Propagate(batch)
+	}
+	response.Send(ch.Conn, reply)
+}
+
+// scriptCaller returns the redis.call of a script, which refuses writes if
+// readOnly is set.
+func (ch *CommandHandler) scriptCaller(readOnly bool, batch *[][]string) scripting.Caller {
+	return func(args []string) response.DataType {
+		return ch.scriptCall(args, readOnly, batch)
+	}
+}
+
+// scriptCall runs a command for redis.call and returns its reply. Writes it
+// makes are added to batch.
+func (ch *CommandHandler) scriptCall(args []string, readOnly bool, batch *[][]string) response.DataType {
+	cmd, ok := Lookup(args[0])
+	if !ok {
+		return response.ErrorType("ERR Unknown Redis command called from script")
+	}
+	if !cmd.CheckArity(args) {
+		return response.ErrorType("ERR Wrong number of args calling Redis command from script")
+	}
+	if cmd.Has(FlagNoScript) {
+		return response.ErrorType("ERR This Redis command is not allowed from script")
+	}
+	if cmd.Has(FlagWrite) {
+		if readOnly {
+			return response.ErrorType("ERR Write commands are not allowed from read-only scripts.")
+		}
+		if ch.Replication != nil && ch.Replication.RefusesWrites() {
+			return response.ErrorType(errReadOnly)
+		}
+	}
+	// keys were evicted before the script started; it cannot evict more
+	if cmd.Has(FlagDenyOOM) && ch.outOfMemory() {
+		return response.ErrorType(errOOM)
+	}
+	if ch.Cluster != nil {
+		if _, err := ch.Cluster.Route(cmd.Keys(args), false, ch.MemoryStore.Exists); err != nil {
+			return response.ErrorType("ERR Script attempted to access a non local key in a cluster node script")
+		}
+	}
+
+	// run it like a queued command, capturing the reply in RESP2, which is
+	// what scripts read
+	held := &heldConn{Conn: ch.Conn, proto: 2}
+	called := *ch
+	called.Conn = held
+	called.Command = args
+	called.inExec = true
+	if cmd.Has(FlagWrite) {
+		ch.Scripts.NoteWrite()
+	}
+	if cmd.Has(FlagWrite) && ch.Propagator != nil {
+		*batch = append(*batch, called.run(cmd)...)
+	} else {
+		cmd.Handler(&called)
+	}
+
+	reply, err := response.Parse(bufio.NewReader(&held.buf))
+	if err != nil {
+		return response.ErrorType("ERR " + err.Error())
+	}
+	return reply
+}
@@ -0,0 +1,39 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/pubsub"
+	"github.com/Ryan-DL/go-redis-server/scripting"
+)
+
+func TestEvalNumKeys(t *testing.T) {
+	store := cache.NewValueStore(time.Minute)
+	scripts := scripting.NewEngine(time.Second)
+	conn := &recordConn{}
+	client := NewClient(conn, pubsub.NewBroker())
+
+	const tooMany = "-ERR Number of keys can't be greater than number of args\r\n"
+	huge := "9223372036854775807"
+	for _, command := range [][]string{
+		{"EVAL", "return 1", huge, "a"},
+		{"EVALSHA", "e0e1f9fabfc9d4800c877a703b823ac0578ff8db", huge, "a"},
+		{"FCALL", "f", huge, "a"},
+		{"FCALL_RO", "f", "9223372036854775806", "a"},
+	} {
+		conn.buf.Reset()
+		ch := NewCommandHandler(client, command, store)
+		ch.Client = client
+		ch.Scripts = scripts
+		ch.Dispatch()
+		client.Flush()
+		if got := conn.buf.String(); got != tooMany {
+			t.Errorf("%s failed. Expected: %q, got: %q", command[0], tooMany, got)
+		}
+		if keys := evalKeys(command); keys != nil {
+			t.Errorf("evalKeys() failed for %s. Expected: no keys, got: %v", command[0], keys)
+		}
+	}
+}
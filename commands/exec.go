@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"sync"
+	"unsafe"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// execMu makes transactions atomic. Every command runs holding the read lock
+// while EXEC holds the write lock, so no other client's command interleaves
+// with a transaction, as with upstream's single thread.
+var execMu execLock
+
+// execSlots is how many read locks execMu spreads its readers over.
+const execSlots = 32
+
+// execLock is a read-write lock spread over slots, each on a cache line of
+// its own. Every command takes it to read, so on a sync.RWMutex commands on
+// different cores would all contend for the one counter of readers. A reader
+// locks the slot of its client; a writer locks every slot, in order.
+type execLock struct {
+	slots [execSlots]struct {
+		sync.RWMutex
+		_ [64 - unsafe.Sizeof(sync.RWMutex{})%64]byte
+	}
+}
+
+func (l *execLock) RLock(slot int) {
+	l.slots[slot].RLock()
+}
+
+func (l *execLock) RUnlock(slot int) {
+	l.slots[slot].RUnlock()
+}
+
+func (l *execLock) Lock() {
+	for i := range l.slots {
+		l.slots[i].Lock()
+	}
+}
+
+func (l *execLock) Unlock() {
+	for i := range l.slots {
+		l.slots[i].Unlock()
+	}
+}
+
+// execSlot returns the slot of execMu the handler reads through.
+func (ch *CommandHandler) execSlot() int {
+	if ch.Client == nil {
+		return 0
+	}
+	return int(uint64(ch.Client.id) % execSlots)
+}
+
+// execReader is a sync.Locker reading execMu through one slot.
+type execReader int
+
+func (r execReader) Lock()   { execMu.RLock(int(r)) }
+func (r execReader) Unlock() { execMu.RUnlock(int(r)) }
+
+// ExecLocker returns a lock that, like a running command, keeps transactions
+// from executing while it is held. Background jobs use it to see the store
+// between commands.
+func ExecLocker() sync.Locker {
+	return execReader(0)
+}
+
+// ExclusiveLocker returns a lock that keeps every command from running while
+// it is held, as EXEC does. A replica holds it to apply what its primary
+// sends.
+func ExclusiveLocker() sync.Locker {
+	return &execMu
+}
+
+func (ch *CommandHandler) HandleExec() {
+	client := ch.Client
+	if !client.multi {
+		response.SendError(ch.Conn, "ERR EXEC without MULTI")
+		return
+	}
+
+	queue := client.queue
+	if client.multiFailed {
+		client.endMulti()
+		response.SendError(ch.Conn, "EXECABORT Transaction discarded because of previous errors.")
+		return
+	}
+
+	// upgrade to the write lock Dispatch took for reading
+	slot := ch.execSlot()
+	execMu.RUnlock(slot)
+	execMu.Lock()
+	defer func() {
+		execMu.Unlock()
+		execMu.RLock(slot)
+	}()
+
+	// checked under the write lock so no one can touch the keys before we run
+	if client.watch != nil && client.watch.Dirty() {
+		client.endMulti()
+		response.SendNullArray(ch.Conn)
+		return
+	}
+	client.endMulti()
+
+	// the whole transaction is propagated as one batch; no other command can
+	// run meanwhile, so writeMu is not needed to keep the order
+	var batch [][]string
+	if ch.Propagator != nil {
+		defer ch.holdReplies()()
+	}
+
+	response.SendArrayHeader(ch.Conn, len(queue))
+	for _, args := range queue {
+		queued := *ch
+		queued.Command = args
+		queued.inExec = true
+
+		cmd, ok := Lookup(args[0])
+		if !ok {
+			response.SendError(ch.Conn, "Unknown command: "+args[0])
+			continue
+		}
+		if ch.Propagator != nil && (cmd.Has(FlagWrite) || cmd.Has(FlagMayReplicate)) {
+			batch = append(batch, queued.run(cmd)...)
+			continue
+		}
+		cmd.Handler(&queued)
+	}
+
+	if len(batch) > 0 {
+		ch.Propagator.Propagate(batch)
+	}
+}
@@ -1,9 +1,11 @@
 package commands
 
 import (
+	"math"
 	"strconv"
 	"time"
 
+	"github.com/Ryan-DL/go-redis-server/cache"
 	"github.com/Ryan-DL/go-redis-server/response"
 )
 
@@ -12,25 +14,65 @@ import (
 // https://redis.io/docs/latest/commands/expire/
 
 func (ch *CommandHandler) HandleExpire() {
-	if len(ch.Command) < 3 {
-		response.SendError(ch.Conn, "Not enough arguments. Expected EXPIRE key seconds")
-		return
-	}
-
 	key := ch.Command[1]
-	seconds, err := strconv.Atoi(ch.Command[2])
+	seconds, err := strconv.ParseInt(ch.Command[2], 10, 64)
 	if err != nil || seconds < 0 {
 		response.SendError(ch.Conn, "Invalid seconds argument")
 		return
 	}
 
-	value, exists := ch.MemoryStore.Get(key)
-	if !exists {
+	expireAt, ok := expireDeadline(seconds, time.Second, true)
+	if !ok {
+		response.SendError(ch.Conn, errInvalidExpire("expire"))
+		return
+	}
+	if !ch.expireAt(key, expireAt) {
 		response.SendInteger(ch.Conn, 0)
 		return
 	}
 
-	ttl := time.Duration(seconds) * time.Second
-	ch.MemoryStore.Set(key, value, ttl)
 	response.SendInteger(ch.Conn, 1)
 }
+
+// expireDeadline returns the deadline n units of time away, from now if
+// relative is set or else from the epoch, for EXPIRE, PEXPIREAT and SET
+// alike. Like upstream, it reports false if the deadline in milliseconds
+// overflows; one that fits but is past what the store can hold is clamped by
+// the store.
+func expireDeadline(n int64, unit time.Duration, relative bool) (time.Time, bool) {
+	ms := n
+	if unit == time.Second {
+		if n > math.MaxInt64/1000 || n < math.MinInt64/1000 {
+			return time.Time{}, false
+		}
+		ms = n * 1000
+	}
+	if relative {
+		now := time.Now().UnixMilli()
+		if ms > math.MaxInt64-now {
+			return time.Time{}, false
+		}
+		ms += now
+	}
+	return time.UnixMilli(ms), true
+}
+
+// expireAt sets the expiry of an existing key, reporting whether it exists.
+// A time in the past deletes the key straight away, as upstream does. It is
+// propagated as PEXPIREAT so replaying it later gives the same deadline.
+func (ch *CommandHandler) expireAt(key string, t time.Time) bool {
+	t = time.UnixMilli(t.UnixMilli())
+	set := false
+	ch.MemoryStore.Update(key, func(e *cache.Entry) error {
+		if !e.Exists() {
+			return nil
+		}
+		e.SetExpireAt(t)
+		set = true
+		return nil
+	})
+	if set {
+		ch.propagateAs([]string{"PEXPIREAT", key, strconv.FormatInt(t.UnixMilli(), 10)})
+	}
+	return set
+}
@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/pubsub"
+)
+
+func TestExpireOverflow(t *testing.T) {
+	store := cache.NewValueStore(time.Minute)
+	conn := &recordConn{}
+	client := NewClient(conn, pubsub.NewBroker())
+	reply(client, conn, store, "", "SET", "k", "v")
+
+	// seconds that overflow as nanoseconds, but not as milliseconds
+	if got := reply(client, conn, store, "", "EXPIRE", "k", "9300000000"); got != ":1\r\n" {
+		t.Errorf("EXPIRE failed. Expected: :1, got: %q", got)
+	}
+	if got := expireAt(store, "k"); got.Year() < 2262 {
+		t.Errorf("EXPIRE failed. Expected a deadline in 2262 or later, got: %v", got)
+	}
+
+	if got := reply(client, conn, store, "", "PEXPIREAT", "k", "9223372036854775807"); got != ":1\r\n" || expireAt(store, "k").Year() < 2262 {
+		t.Errorf("PEXPIREAT failed. Expected: :1 and a deadline in 2262 or later, got: %q, %v", got, expireAt(store, "k"))
+	}
+
+	want := "-" + errInvalidExpire("expire") + "\r\n"
+	for _, seconds := range []string{"9223372036854775", "9223372036854775807"} {
+		if got := reply(client, conn, store, "", "EXPIRE", "k", seconds); got != want {
+			t.Errorf("EXPIRE %s failed. Expected: %q, got: %q", seconds, want, got)
+		}
+	}
+	if !store.Exists("k") {
+		t.Errorf("EXPIRE failed. Expected the key kept after an invalid expire time")
+	}
+}
@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"github.com/Ryan-DL/go-redis-server/response"
+	"github.com/Ryan-DL/go-redis-server/scripting"
+)
+
+func (ch *CommandHandler) HandleFCall() {
+	ch.fcall(false)
+}
+
+func (ch *CommandHandler) HandleFCallRO() {
+	ch.fcall(true)
+}
+
+// FCALL function numkeys [key ...] [arg ...]
+// FCALL_RO function numkeys [key ...] [arg ...], which only calls functions
+// registered with the no-writes flag.
+func (ch *CommandHandler) fcall(readOnly bool) {
+	if ch.Scripts == nil {
+		response.SendError(ch.Conn, errNoScripting)
+		return
+	}
+	keys, args, errMsg := parseKeysArgs(ch.Command, 2)
+	if errMsg != "" {
+		response.SendError(ch.Conn, errMsg)
+		return
+	}
+
+	ch.runScript(func(batch *[][]string) response.DataType {
+		// looked up under the write lock, so no FUNCTION LOAD slips in between
+		fn, ok := ch.Scripts.Function(ch.MemoryStore, ch.Command[1])
+		if !ok {
+			return response.ErrorType(scripting.ErrFunctionNotFound.Error())
+		}
+		noWrites := fn.Has("no-writes")
+		if readOnly && !noWrites {
+			return response.ErrorType("ERR Can not execute a script with write flag using *_ro command.")
+		}
+		if ch.Cluster != nil && fn.Has("no-cluster") {
+			return response.ErrorType("ERR Can not run script on cluster, 'no-cluster' flag is set.")
+		}
+		return ch.Scripts.FCall(fn, keys, args, ch.scriptCaller(noWrites, batch))
+	})
+}
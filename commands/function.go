@@ -0,0 +1,236 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Ryan-DL/go-redis-server/glob"
+	"github.com/Ryan-DL/go-redis-server/rdb"
+	"github.com/Ryan-DL/go-redis-server/response"
+	"github.com/Ryan-DL/go-redis-server/scripting"
+)
+
+// FUNCTION LOAD [REPLACE] function-code
+// FUNCTION DELETE library-name
+// FUNCTION FLUSH [ASYNC|SYNC]
+// FUNCTION LIST [LIBRARYNAME library-name-pattern] [WITHCODE]
+// FUNCTION DUMP
+// FUNCTION RESTORE serialized-value [FLUSH|APPEND|REPLACE]
+// FUNCTION KILL
+func (ch *CommandHandler) HandleFunction() {
+	subcommand := strings.ToUpper(ch.Command[1])
+
+	arity := map[string]int{
+		"LOAD":    -3,
+		"DELETE":  3,
+		"FLUSH":   -2,
+		"LIST":    -2,
+		"DUMP":    2,
+		"RESTORE": -3,
+		"KILL":    2,
+	}[subcommand]
+	if arity == 0 {
+		response.SendError(ch.Conn, fmt.Sprintf("ERR unknown subcommand '%s'", ch.Command[1]))
+		return
+	}
+	if (arity > 0 && len(ch.Command) != arity) || (arity < 0 && len(ch.Command) < -arity) {
+		response.SendError(ch.Conn, errWrongArgs(ch.Command[0]+"|"+subcommand))
+		return
+	}
+
+	if subcommand == "KILL" {
+		if ch.Scripts == nil {
+			response.SendError(ch.Conn, errNoScripting)
+			return
+		}
+		if err := ch.Scripts.Kill(); err != nil {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+		response.SendSimpleString(ch.Conn, "OK")
+		return
+	}
+
+	// FUNCTION runs even while a script is busy, but only KILL may
+	if ch.Scripts != nil && ch.Scripts.Busy() {
+		response.SendError(ch.Conn, errBusy)
+		return
+	}
+
+	// the libraries are part of the dataset, so changing them is a write
+	write := subcommand != "LIST" && subcommand != "DUMP"
+	if write && ch.Replication != nil && ch.Replication.RefusesWrites() {
+		response.SendError(ch.Conn, errReadOnly)
+		return
+	}
+	if ch.inExec {
+		ch.function(subcommand)
+		return
+	}
+
+	// Dispatch leaves execMu to the commands that may run while a script is busy
+	slot := ch.execSlot()
+	execMu.RLock(slot)
+	defer execMu.RUnlock(slot)
+	if write && ch.Propagator != nil {
+		ch.runPropagated(&Command{Handler: func(ch *CommandHandler) { ch.function(subcommand) }})
+		return
+	}
+	ch.function(subcommand)
+}
+
+func (ch *CommandHandler) function(subcommand string) {
+	switch subcommand {
+	case "LOAD":
+		replace := false
+		if len(ch.Command) > 3 {
+			if len(ch.Command) > 4 || !strings.EqualFold(ch.Command[2], "REPLACE") {
+				response.SendError(ch.Conn, "ERR Unknown option given: "+ch.Command[2])
+				return
+			}
+			replace = true
+		}
+		var name string
+		err := ch.MemoryStore.UpdateLibraries(func(codes []string) ([]string, error) {
+			var err error
+			codes, name, err = scripting.AddLibrary(codes, ch.Command[len(ch.Command)-1], replace)
+			return codes, err
+		})
+		if err != nil {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+		response.SendBulkString(ch.Conn, name)
+
+	case "DELETE":
+		err := ch.MemoryStore.UpdateLibraries(func(codes []string) ([]string, error) {
+			return scripting.DeleteLibrary(codes, ch.Command[2])
+		})
+		if err != nil {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+		response.SendSimpleString(ch.Conn, "OK")
+
+	case "FLUSH":
+		if len(ch.Command) > 3 {
+			response.SendError(ch.Conn, errSyntax)
+			return
+		}
+		if len(ch.Command) == 3 {
+			if mode := strings.ToUpper(ch.Command[2]); mode != "ASYNC" && mode != "SYNC" {
+				response.SendError(ch.Conn, "ERR FUNCTION FLUSH only supports SYNC|ASYNC option")
+				return
+			}
+		}
+		ch.MemoryStore.UpdateLibraries(func([]string) ([]string, error) { return nil, nil })
+		response.SendSimpleString(ch.Conn, "OK")
+
+	case "LIST":
+		ch.functionList()
+
+	case "DUMP":
+		codes, _ := ch.MemoryStore.Libraries()
+		response.SendBulkString(ch.Conn, string(rdb.DumpFunctions(codes)))
+
+	case "RESTORE":
+		ch.functionRestore()
+	}
+}
+
+// FUNCTION LIST [LIBRARYNAME library-name-pattern] [WITHCODE]
+func (ch *CommandHandler) functionList() {
+	if ch.Scripts == nil {
+		response.SendError(ch.Conn, errNoScripting)
+		return
+	}
+	pattern, withCode := "", false
+	for i := 2; i < len(ch.Command); i++ {
+		switch strings.ToUpper(ch.Command[i]) {
+		case "WITHCODE":
+			withCode = true
+		case "LIBRARYNAME":
+			if i+1 == len(ch.Command) {
+				response.SendError(ch.Conn, "ERR library name argument was not given")
+				return
+			}
+			i++
+			pattern = ch.Command[i]
+		default:
+			response.SendError(ch.Conn, "ERR Unknown argument "+ch.Command[i])
+			return
+		}
+	}
+
+	reply := response.ArrayType{}
+	for _, lib := range ch.Scripts.Libraries(ch.MemoryStore) {
+		if pattern != "" && !glob.Match(pattern, lib.Name) {
+			continue
+		}
+		functions := make(response.ArrayType, len(lib.Functions))
+		for i, fn := range lib.Functions {
+			var description response.DataType = response.NullBulkString{}
+			if fn.Description != "" {
+				description = response.BulkStringType(fn.Description)
+			}
+			flags := make(response.SetType, len(fn.Flags))
+			for j, flag := range fn.Flags {
+				flags[j] = response.SimpleString(flag)
+			}
+			functions[i] = response.MapType{
+				{Key: response.BulkStringType("name"), Value: response.BulkStringType(fn.Name)},
+				{Key: response.BulkStringType("description"), Value: description},
+				{Key: response.BulkStringType("flags"), Value: flags},
+			}
+		}
+		entry := response.MapType{
+			{Key: response.BulkStringType("library_name"), Value: response.BulkStringType(lib.Name)},
+			{Key: response.BulkStringType("engine"), Value: response.BulkStringType("LUA")},
+			{Key: response.BulkStringType("functions"), Value: functions},
+		}
+		if withCode {
+			entry = append(entry, response.Pair{Key: response.BulkStringType("library_code"), Value: response.BulkStringType(lib.Code)})
+		}
+		reply = append(reply, entry)
+	}
+	response.SendArray(ch.Conn, reply)
+}
+
+// FUNCTION RESTORE serialized-value [FLUSH|APPEND|REPLACE]
+func (ch *CommandHandler) functionRestore() {
+	if len(ch.Command) > 4 {
+		response.SendError(ch.Conn, errSyntax)
+		return
+	}
+	policy := "APPEND"
+	if len(ch.Command) == 4 {
+		policy = strings.ToUpper(ch.Command[3])
+		if policy != "FLUSH" && policy != "APPEND" && policy != "REPLACE" {
+			response.SendError(ch.Conn, "ERR Wrong restore policy given, value should be either FLUSH, APPEND or REPLACE.")
+			return
+		}
+	}
+	restored, err := rdb.LoadFunctions([]byte(ch.Command[2]))
+	if err != nil {
+		response.SendError(ch.Conn, "ERR payload version or checksum are wrong")
+		return
+	}
+
+	err = ch.MemoryStore.UpdateLibraries(func(codes []string) ([]string, error) {
+		if policy == "FLUSH" {
+			codes = nil
+		}
+		for _, code := range restored {
+			var err error
+			if codes, _, err = scripting.AddLibrary(codes, code, policy == "REPLACE"); err != nil {
+				return nil, err
+			}
+		}
+		return codes, nil
+	})
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+	response.SendSimpleString(ch.Conn, "OK")
+}
@@ -4,12 +4,57 @@ import (
 	"net"
 
 	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/cluster"
+	"github.com/Ryan-DL/go-redis-server/persist"
+	"github.com/Ryan-DL/go-redis-server/replication"
+	"github.com/Ryan-DL/go-redis-server/scripting"
 )
 
 type CommandHandler struct {
 	Conn        net.Conn
 	Command     []string
 	MemoryStore *cache.ValueStore
+
+	// Client holds the connection's state across commands. Conn is usually
+	// the same Client, so writes go through it.
+	Client *Client
+
+	// Password is what AUTH and HELLO check clients against, empty when the
+	// server has none.
+	Password string
+
+	// inExec is set while EXEC runs a queued command, which already holds
+	// execMu exclusively and must not block.
+	inExec bool
+
+	// WatchClose is set by the connection loop so blocking commands can tell
+	// when the client hangs up. It returns a channel closed on disconnect and
+	// a function to stop watching, which must be called before returning.
+	WatchClose func() (<-chan struct{}, func())
+
+	// Saver writes snapshots for SAVE and BGSAVE. It is nil when the server
+	// runs without persistence.
+	Saver *persist.Saver
+
+	// AOF is the append only file, nil when appendonly is off. Propagator
+	// receives every write command; it is usually the AOF.
+	AOF        *persist.AOF
+	Propagator Propagator
+
+	// Replication is this server's place as a primary or replica.
+	Replication *replication.Node
+
+	// Cluster is the slot map keys are routed by, nil outside cluster mode.
+	Cluster *cluster.Cluster
+
+	// Scripts runs and caches the Lua scripts of EVAL.
+	Scripts *scripting.Engine
+
+	// state for propagating the running command, see run
+	writeLocked bool
+	dirty       int64
+	rewritten   bool
+	propagated  [][]string
 }
 
 func NewCommandHandler(conn net.Conn, command []string, memoryStore *cache.ValueStore) *CommandHandler {
@@ -0,0 +1,15 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+func (ch *CommandHandler) HandleHDel() {
+	key := ch.Command[1]
+
+	removed, err := ch.MemoryStore.HashDel(key, ch.Command[2:]...)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.SendInteger(ch.Conn, removed)
+}
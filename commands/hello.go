@@ -0,0 +1,95 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// serverVersion is the upstream release whose protocol the server speaks,
+// as HELLO reports it.
+const serverVersion = "7.2.0"
+
+// HELLO [protover [AUTH username password] [SETNAME clientname]]
+//
+// HELLO switches the client to protocol version 2 or 3, authenticating and
+// naming it on the way, and replies with the server's details.
+func (ch *CommandHandler) HandleHello() {
+	proto := ch.Client.Proto()
+	if len(ch.Command) > 1 {
+		version, err := strconv.Atoi(ch.Command[1])
+		if err != nil {
+			response.SendError(ch.Conn, "ERR Protocol version is not an integer or out of range")
+			return
+		}
+		if version != 2 && version != 3 {
+			response.SendError(ch.Conn, "NOPROTO unsupported protocol version")
+			return
+		}
+		proto = version
+	}
+
+	var name *string
+	for i := 2; i < len(ch.Command); i++ {
+		option := strings.ToUpper(ch.Command[i])
+		switch {
+		case option == "AUTH" && i+2 < len(ch.Command):
+			if !ch.authenticate(ch.Command[i+1], ch.Command[i+2]) {
+				response.SendError(ch.Conn, errWrongPass)
+				return
+			}
+			i += 2
+		case option == "SETNAME" && i+1 < len(ch.Command):
+			if !validClientName(ch.Command[i+1]) {
+				response.SendError(ch.Conn, "ERR Client names cannot contain spaces, newlines or special characters.")
+				return
+			}
+			name = &ch.Command[i+1]
+			i++
+		default:
+			response.SendError(ch.Conn, fmt.Sprintf("ERR Syntax error in HELLO option '%s'", ch.Command[i]))
+			return
+		}
+	}
+
+	if ch.Password != "" && !ch.Client.Authenticated() {
+		response.SendError(ch.Conn, "NOAUTH HELLO must be called with the client already authenticated, otherwise the HELLO <proto> AUTH <user> <pass> option can be used to authenticate the client and select the RESP protocol version at the same time")
+		return
+	}
+
+	if name != nil {
+		ch.Client.name = *name
+	}
+	ch.Client.setProto(proto)
+
+	mode := "standalone"
+	if ch.Cluster != nil {
+		mode = "cluster"
+	}
+	role := "master"
+	if ch.Replication != nil && ch.Replication.IsReplica() {
+		role = "replica"
+	}
+	response.Send(ch.Conn, response.MapType{
+		{Key: response.BulkStringType("server"), Value: response.BulkStringType("redis")},
+		{Key: response.BulkStringType("version"), Value: response.BulkStringType(serverVersion)},
+		{Key: response.BulkStringType("proto"), Value: response.IntegerType(proto)},
+		{Key: response.BulkStringType("id"), Value: response.IntegerType(int(ch.Client.id))},
+		{Key: response.BulkStringType("mode"), Value: response.BulkStringType(mode)},
+		{Key: response.BulkStringType("role"), Value: response.BulkStringType(role)},
+		{Key: response.BulkStringType("modules"), Value: response.ArrayType{}},
+	})
+}
+
+// validClientName reports whether name may be a client's name: it must not
+// contain spaces or anything but printable ASCII.
+func validClientName(name string) bool {
+	for _, c := range name {
+		if c <= ' ' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
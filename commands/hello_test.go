@@ -0,0 +1,93 @@
+package commands
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/pubsub"
+)
+
+// recordConn keeps what is written to it.
+type recordConn struct {
+	net.Conn
+	buf bytes.Buffer
+}
+
+func (c *recordConn) Write(p []byte) (int, error) {
+	return c.buf.Write(p)
+}
+
+// reply dispatches command for client and returns what it was sent.
+func reply(client *Client, conn *recordConn, store *cache.ValueStore, password string, command ...string) string {
+	conn.buf.Reset()
+	ch := NewCommandHandler(client, command, store)
+	ch.Client = client
+	ch.Password = password
+	ch.Dispatch()
+	client.Flush()
+	return conn.buf.String()
+}
+
+func TestHelloProtocol(t *testing.T) {
+	store := cache.NewValueStore(time.Minute)
+	conn := &recordConn{}
+	client := NewClient(conn, pubsub.NewBroker())
+
+	reply(client, conn, store, "", "HSET", "h", "f", "v")
+	reply(client, conn, store, "", "ZADD", "z", "1.5", "m")
+
+	tests := []struct {
+		command []string
+		resp2   string
+		resp3   string
+	}{
+		{[]string{"HGETALL", "h"}, "*2\r\n$1\r\nf\r\n$1\r\nv\r\n", "%1\r\n$1\r\nf\r\n$1\r\nv\r\n"},
+		{[]string{"ZRANGE", "z", "0", "-1", "WITHSCORES"}, "*2\r\n$1\r\nm\r\n$3\r\n1.5\r\n", "*1\r\n*2\r\n$1\r\nm\r\n,1.5\r\n"},
+		{[]string{"ZSCORE", "z", "m"}, "$3\r\n1.5\r\n", ",1.5\r\n"},
+		{[]string{"GET", "nosuch"}, "$-1\r\n", "_\r\n"},
+	}
+	for _, tt := range tests {
+		if got := reply(client, conn, store, "", tt.command...); got != tt.resp2 {
+			t.Errorf("%s failed over RESP2. Expected: %q, got: %q", tt.command[0], tt.resp2, got)
+		}
+	}
+
+	if got := reply(client, conn, store, "", "HELLO", "4"); got != "-NOPROTO unsupported protocol version\r\n" {
+		t.Errorf("HELLO failed. Expected: NOPROTO, got: %q", got)
+	}
+	reply(client, conn, store, "", "HELLO", "3")
+	if client.Proto() != 3 {
+		t.Fatalf("HELLO failed. Expected: protocol 3, got: %d", client.Proto())
+	}
+	for _, tt := range tests {
+		if got := reply(client, conn, store, "", tt.command...); got != tt.resp3 {
+			t.Errorf("%s failed over RESP3. Expected: %q, got: %q", tt.command[0], tt.resp3, got)
+		}
+	}
+}
+
+func TestAuth(t *testing.T) {
+	store := cache.NewValueStore(time.Minute)
+	conn := &recordConn{}
+	client := NewClient(conn, pubsub.NewBroker())
+
+	tests := []struct {
+		command  []string
+		expected string
+	}{
+		{[]string{"GET", "k"}, "-NOAUTH Authentication required.\r\n"},
+		{[]string{"AUTH", "wrong"}, "-ERR invalid password\r\n"},
+		{[]string{"AUTH", "someone", "secret"}, "-" + errWrongPass + "\r\n"},
+		{[]string{"HELLO", "3", "AUTH", "default", "wrong"}, "-" + errWrongPass + "\r\n"},
+		{[]string{"AUTH", "default", "secret"}, "+OK\r\n"},
+		{[]string{"GET", "k"}, "$-1\r\n"},
+	}
+	for _, tt := range tests {
+		if got := reply(client, conn, store, "secret", tt.command...); got != tt.expected {
+			t.Errorf("%v failed. Expected: %q, got: %q", tt.command, tt.expected, got)
+		}
+	}
+}
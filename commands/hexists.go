@@ -0,0 +1,20 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+func (ch *CommandHandler) HandleHExists() {
+	key := ch.Command[1]
+	field := ch.Command[2]
+
+	_, ok, err := ch.MemoryStore.HashGet(key, field)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	if ok {
+		response.SendInteger(ch.Conn, 1)
+	} else {
+		response.SendInteger(ch.Conn, 0)
+	}
+}
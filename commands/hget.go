@@ -0,0 +1,20 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+func (ch *CommandHandler) HandleHGet() {
+	key := ch.Command[1]
+	field := ch.Command[2]
+
+	value, ok, err := ch.MemoryStore.HashGet(key, field)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+	if !ok {
+		response.SendNullString(ch.Conn)
+		return
+	}
+
+	response.SendBulkString(ch.Conn, value)
+}
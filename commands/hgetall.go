@@ -0,0 +1,20 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+func (ch *CommandHandler) HandleHGetAll() {
+	key := ch.Command[1]
+
+	hash, err := ch.MemoryStore.HashGetAll(key)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	pairs := make([]string, 0, len(hash)*2)
+	for field, value := range hash {
+		pairs = append(pairs, field, value)
+	}
+
+	response.Send(ch.Conn, response.StringMap(pairs...))
+}
@@ -0,0 +1,26 @@
+package commands
+
+import (
+	"strconv"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+func (ch *CommandHandler) HandleHIncrBy() {
+	key := ch.Command[1]
+	field := ch.Command[2]
+
+	delta, err := strconv.ParseInt(ch.Command[3], 10, 64)
+	if err != nil {
+		response.SendError(ch.Conn, errNotInteger)
+		return
+	}
+
+	newValue, err := ch.MemoryStore.HashIncrBy(key, field, delta)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.SendInteger(ch.Conn, int(newValue))
+}
@@ -0,0 +1,28 @@
+package commands
+
+import (
+	"math"
+	"strconv"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+func (ch *CommandHandler) HandleHIncrByFloat() {
+	key := ch.Command[1]
+	field := ch.Command[2]
+
+	delta, err := strconv.ParseFloat(ch.Command[3], 64)
+	if err != nil || math.IsNaN(delta) || math.IsInf(delta, 0) {
+		response.SendError(ch.Conn, errNotFloat)
+		return
+	}
+
+	newValue, err := ch.MemoryStore.HashIncrByFloat(key, field, delta)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.SendBulkString(ch.Conn, cache.FormatFloat(newValue))
+}
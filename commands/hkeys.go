@@ -0,0 +1,20 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+func (ch *CommandHandler) HandleHKeys() {
+	key := ch.Command[1]
+
+	hash, err := ch.MemoryStore.HashGetAll(key)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	fields := make([]string, 0, len(hash))
+	for field := range hash {
+		fields = append(fields, field)
+	}
+
+	response.SendStringArray(ch.Conn, fields)
+}
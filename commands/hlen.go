@@ -0,0 +1,15 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+func (ch *CommandHandler) HandleHLen() {
+	key := ch.Command[1]
+
+	length, err := ch.MemoryStore.HashLen(key)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.SendInteger(ch.Conn, length)
+}
@@ -0,0 +1,24 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+func (ch *CommandHandler) HandleHMGet() {
+	key := ch.Command[1]
+
+	values, err := ch.MemoryStore.HashMGet(key, ch.Command[2:]...)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	reply := make(response.ArrayType, len(values))
+	for i, value := range values {
+		if value == nil {
+			reply[i] = response.NullBulkString{}
+		} else {
+			reply[i] = response.BulkStringType(*value)
+		}
+	}
+
+	response.SendArray(ch.Conn, reply)
+}
@@ -0,0 +1,20 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+// HMSET is deprecated upstream in favour of HSET but clients still send it.
+func (ch *CommandHandler) HandleHMSet() {
+	if len(ch.Command)%2 != 0 {
+		response.SendError(ch.Conn, errWrongArgs(ch.Command[0]))
+		return
+	}
+
+	key := ch.Command[1]
+
+	if _, err := ch.MemoryStore.HashSet(key, ch.Command[2:]...); err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.SendSimpleString(ch.Conn, "OK")
+}
@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"math/rand"
+	"strings"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// HRANDFIELD key [count [WITHVALUES]]. A positive count returns distinct
+// fields, a negative one may return the same field several times.
+func (ch *CommandHandler) HandleHRandField() {
+	if len(ch.Command) > 4 {
+		response.SendError(ch.Conn, errSyntax)
+		return
+	}
+
+	key := ch.Command[1]
+
+	hash, err := ch.MemoryStore.HashGetAll(key)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	fields := make([]string, 0, len(hash))
+	for field := range hash {
+		fields = append(fields, field)
+	}
+
+	if len(ch.Command) == 2 {
+		if len(fields) == 0 {
+			response.SendNullString(ch.Conn)
+			return
+		}
+		response.SendBulkString(ch.Conn, fields[rand.Intn(len(fields))])
+		return
+	}
+
+	count, errMsg := parseRandCount(ch.Command[2])
+	if errMsg != "" {
+		response.SendError(ch.Conn, errMsg)
+		return
+	}
+	withValues := false
+	if len(ch.Command) == 4 {
+		if strings.ToUpper(ch.Command[3]) != "WITHVALUES" {
+			response.SendError(ch.Conn, errSyntax)
+			return
+		}
+		withValues = true
+	}
+
+	var picked []string
+	if count >= 0 {
+		rand.Shuffle(len(fields), func(i, j int) { fields[i], fields[j] = fields[j], fields[i] })
+		picked = fields[:min(count, len(fields))]
+	} else if len(fields) > 0 {
+		picked = make([]string, 0, min(-count, randPrealloc))
+		for i := 0; i < -count; i++ {
+			picked = append(picked, fields[rand.Intn(len(fields))])
+		}
+	}
+
+	if withValues {
+		reply := make(response.PairsType, len(picked))
+		for i, field := range picked {
+			reply[i] = response.Pair{Key: response.BulkStringType(field), Value: response.BulkStringType(hash[field])}
+		}
+		response.Send(ch.Conn, reply)
+		return
+	}
+	response.SendStringArray(ch.Conn, picked)
+}
@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/pubsub"
+)
+
+func TestHRandFieldCount(t *testing.T) {
+	store := cache.NewValueStore(time.Minute)
+	conn := &recordConn{}
+	client := NewClient(conn, pubsub.NewBroker())
+	reply(client, conn, store, "", "HSET", "h", "f", "v")
+
+	const outOfRange = "-" + errOutOfRange + "\r\n"
+	tests := []struct {
+		command []string
+		want    string
+	}{
+		{[]string{"HRANDFIELD", "h", "-9223372036854775807"}, outOfRange},
+		{[]string{"HRANDFIELD", "h", "-9223372036854775808", "WITHVALUES"}, outOfRange},
+		{[]string{"HRANDFIELD", "h", "-2"}, "*2\r\n$1\r\nf\r\n$1\r\nf\r\n"},
+		{[]string{"HRANDFIELD", "h", "-1", "WITHVALUES"}, "*2\r\n$1\r\nf\r\n$1\r\nv\r\n"},
+	}
+	for _, tt := range tests {
+		if got := reply(client, conn, store, "", tt.command...); got != tt.want {
+			t.Errorf("%v failed. Expected: %q, got: %q", tt.command, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// scanArgs holds the options shared by the SCAN family.
+type scanArgs struct {
+	cursor   uint64
+	match    string
+	count    int
+	noValues bool
+}
+
+// parseScanArgs parses "cursor [MATCH pattern] [COUNT count]" starting at
+// args[0]. NOVALUES is only accepted when allowNoValues is set. On failure it
+// returns the error reply to send.
+func parseScanArgs(args []string, allowNoValues bool) (scanArgs, string) {
+	parsed := scanArgs{count: 10}
+
+	cursor, err := strconv.ParseUint(args[0], 10, 64)
+	if err != nil {
+		return parsed, "ERR invalid cursor"
+	}
+	parsed.cursor = cursor
+
+	for i := 1; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i]); {
+		case option == "MATCH" && i+1 < len(args):
+			parsed.match = args[i+1]
+			i++
+		case option == "COUNT" && i+1 < len(args):
+			count, err := strconv.Atoi(args[i+1])
+			if err != nil {
+				return parsed, errNotInteger
+			}
+			if count < 1 {
+				return parsed, errSyntax
+			}
+			parsed.count = count
+			i++
+		case option == "NOVALUES" && allowNoValues:
+			parsed.noValues = true
+		default:
+			return parsed, errSyntax
+		}
+	}
+	return parsed, ""
+}
+
+// sendScanReply sends the two element reply of the SCAN family: the next
+// cursor followed by the page of elements.
+func sendScanReply(ch *CommandHandler, next uint64, elements []string) {
+	response.SendArray(ch.Conn, response.ArrayType{
+		response.BulkStringType(strconv.FormatUint(next, 10)),
+		response.BulkStrings(elements),
+	})
+}
+
+func (ch *CommandHandler) HandleHScan() {
+	key := ch.Command[1]
+
+	args, errMsg := parseScanArgs(ch.Command[2:], true)
+	if errMsg != "" {
+		response.SendError(ch.Conn, errMsg)
+		return
+	}
+
+	next, pairs, err := ch.MemoryStore.HashScan(key, args.cursor, args.match, args.count)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	if args.noValues {
+		fields := make([]string, 0, len(pairs)/2)
+		for i := 0; i < len(pairs); i += 2 {
+			fields = append(fields, pairs[i])
+		}
+		pairs = fields
+	}
+
+	sendScanReply(ch, next, pairs)
+}
@@ -0,0 +1,20 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+func (ch *CommandHandler) HandleHSet() {
+	if len(ch.Command)%2 != 0 {
+		response.SendError(ch.Conn, errWrongArgs(ch.Command[0]))
+		return
+	}
+
+	key := ch.Command[1]
+
+	added, err := ch.MemoryStore.HashSet(key, ch.Command[2:]...)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.SendInteger(ch.Conn, added)
+}
@@ -0,0 +1,21 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+func (ch *CommandHandler) HandleHSetNX() {
+	key := ch.Command[1]
+	field := ch.Command[2]
+	value := ch.Command[3]
+
+	set, err := ch.MemoryStore.HashSetNX(key, field, value)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	if set {
+		response.SendInteger(ch.Conn, 1)
+	} else {
+		response.SendInteger(ch.Conn, 0)
+	}
+}
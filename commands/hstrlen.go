@@ -0,0 +1,16 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+func (ch *CommandHandler) HandleHStrLen() {
+	key := ch.Command[1]
+	field := ch.Command[2]
+
+	value, _, err := ch.MemoryStore.HashGet(key, field)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.SendInteger(ch.Conn, len(value))
+}
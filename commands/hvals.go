@@ -0,0 +1,20 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+func (ch *CommandHandler) HandleHVals() {
+	key := ch.Command[1]
+
+	hash, err := ch.MemoryStore.HashGetAll(key)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	values := make([]string, 0, len(hash))
+	for _, value := range hash {
+		values = append(values, value)
+	}
+
+	response.SendStringArray(ch.Conn, values)
+}
@@ -1,44 +1,51 @@
 package commands
 
 import (
+	"errors"
+	"math"
 	"strconv"
-	"time"
 
+	"github.com/Ryan-DL/go-redis-server/cache"
 	"github.com/Ryan-DL/go-redis-server/response"
 )
 
 func (ch *CommandHandler) HandleIncr() {
-	if len(ch.Command) < 2 {
-		response.SendError(ch.Conn, "Not enough arguments. Expected INCR key")
-		return
-	}
+	ch.incrBy(1)
+}
 
+// incrBy adds delta to the integer at the key, creating it at 0 if missing.
+// The read and the write happen under one lock so concurrent increments are
+// never lost; the key keeps its TTL.
+func (ch *CommandHandler) incrBy(delta int64) {
 	key := ch.Command[1]
 
-	currentValue, exists := ch.MemoryStore.Get(key)
-	if !exists {
-		// create new key and initialize it to 0 and increment
-		ch.MemoryStore.Set(key, "1", 0) // no expiration for a new key
-		response.SendInteger(ch.Conn, 1)
-		return
-	}
-
-	// check we're dealing with an integer
-	currentInt, err := strconv.ParseInt(currentValue, 10, 64)
+	var newValue int64
+	err := ch.MemoryStore.Update(key, func(e *cache.Entry) error {
+		currentValue, exists, err := e.String()
+		if err != nil {
+			return err
+		}
+
+		var currentInt int64
+		if exists {
+			// check we're dealing with an integer
+			currentInt, err = strconv.ParseInt(currentValue, 10, 64)
+			if err != nil {
+				return errors.New(errNotInteger)
+			}
+		}
+		if (delta > 0 && currentInt > math.MaxInt64-delta) || (delta < 0 && currentInt < math.MinInt64-delta) {
+			return cache.ErrOverflow
+		}
+
+		newValue = currentInt + delta
+		e.Set(strconv.FormatInt(newValue, 10))
+		return nil
+	})
 	if err != nil {
-		response.SendError(ch.Conn, "ERR value is not an integer or out of range")
+		response.SendError(ch.Conn, err.Error())
 		return
 	}
 
-	newValue := currentInt + 1
-
-	expiry, hasExpiry := ch.MemoryStore.GetExpiry(key)
-	if hasExpiry {
-		remainingTTL := time.Until(expiry)
-		ch.MemoryStore.Set(key, strconv.FormatInt(newValue, 10), remainingTTL)
-	} else {
-		ch.MemoryStore.Set(key, strconv.FormatInt(newValue, 10), 0) // no expiration
-	}
-
 	response.SendInteger(ch.Conn, int(newValue))
 }
@@ -2,7 +2,10 @@ package commands
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/Ryan-DL/go-redis-server/replication"
 	"github.com/Ryan-DL/go-redis-server/response"
 )
 
@@ -15,5 +18,144 @@ total_keys: %d
 		len(ch.MemoryStore.GetKeys()),
 	)
 
-	response.SendBulkString(ch.Conn, info)
+	maxMemory, policy := ch.MemoryStore.MaxMemory()
+	info += fmt.Sprintf(`
+# Memory
+used_memory: %d
+maxmemory: %d
+maxmemory_policy: %s
+`,
+		ch.MemoryStore.UsedMemory(),
+		maxMemory,
+		policy,
+	)
+
+	if ch.Saver != nil {
+		status := ch.Saver.Status()
+		info += fmt.Sprintf(`
+# Persistence
+rdb_changes_since_last_save: %d
+rdb_bgsave_in_progress: %d
+rdb_last_save_time: %d
+rdb_last_bgsave_status: %s
+`,
+			status.Dirty,
+			boolInt(status.InProgress),
+			status.LastSave.Unix(),
+			okErr(status.LastBGSaveFail),
+		)
+	}
+
+	if ch.AOF != nil {
+		status := ch.AOF.Status()
+		info += fmt.Sprintf(`aof_enabled: 1
+aof_rewrite_in_progress: %d
+aof_last_bgrewrite_status: %s
+aof_last_write_status: %s
+`,
+			boolInt(status.Rewriting),
+			okErr(status.LastRewriteFailed),
+			okErr(status.LastWriteFailed),
+		)
+	}
+
+	expire := ch.MemoryStore.ExpireStats()
+	info += fmt.Sprintf(`
+# Stats
+expired_keys: %d
+expired_stale_perc: %.2f
+expired_time_cap_reached_count: %d
+evicted_keys: %d
+`,
+		expire.ExpiredKeys,
+		expire.StalePerc,
+		expire.TimeCapReached,
+		ch.MemoryStore.EvictedKeys(),
+	)
+
+	if ch.Replication != nil {
+		info += replicationInfo(ch.Replication.Status())
+	}
+
+	info += fmt.Sprintf(`
+# Cluster
+cluster_enabled: %d
+`,
+		boolInt(ch.Cluster != nil),
+	)
+
+	response.Send(ch.Conn, response.VerbatimStringType{Format: "txt", Text: info})
+}
+
+// replicationInfo formats the replication section with upstream's fields.
+func replicationInfo(status replication.Status) string {
+	var b strings.Builder
+	b.WriteString("\n# Replication\n")
+	if status.Replica {
+		linkStatus := "down"
+		if status.LinkUp() {
+			linkStatus = "up"
+		}
+		lastIO := -1
+		if !status.LastIO.IsZero() {
+			lastIO = int(time.Since(status.LastIO).Seconds())
+		}
+		fmt.Fprintf(&b, `role: slave
+master_host: %s
+master_port: %d
+master_link_status: %s
+master_last_io_seconds_ago: %d
+master_sync_in_progress: %d
+slave_repl_offset: %d
+slave_read_only: %d
+`,
+			status.MasterHost,
+			status.MasterPort,
+			linkStatus,
+			lastIO,
+			boolInt(status.SyncInProgress),
+			status.Offset,
+			boolInt(status.ReadOnly),
+		)
+	} else {
+		b.WriteString("role: master\n")
+	}
+
+	fmt.Fprintf(&b, "connected_slaves: %d\n", len(status.Replicas))
+	for i, r := range status.Replicas {
+		fmt.Fprintf(&b, "slave%d: ip=%s,port=%d,state=%s,offset=%d,lag=%d\n", i, r.IP, r.Port, r.State, r.Offset, int(r.Lag.Seconds()))
+	}
+	fmt.Fprintf(&b, `master_replid: %s
+master_replid2: %s
+master_repl_offset: %d
+second_repl_offset: %d
+repl_backlog_active: %d
+repl_backlog_size: %d
+repl_backlog_first_byte_offset: %d
+repl_backlog_histlen: %d
+`,
+		status.ReplID,
+		status.ReplID2,
+		status.Offset,
+		status.SecondOffset,
+		boolInt(status.BacklogActive),
+		status.BacklogSize,
+		status.BacklogFirst,
+		status.BacklogLen,
+	)
+	return b.String()
+}
+
+func okErr(failed bool) string {
+	if failed {
+		return "err"
+	}
+	return "ok"
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
 }
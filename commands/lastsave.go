@@ -0,0 +1,14 @@
+package commands
+
+import (
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+func (ch *CommandHandler) HandleLastSave() {
+	if ch.Saver == nil {
+		response.SendError(ch.Conn, errNoSaver)
+		return
+	}
+
+	response.SendInteger(ch.Conn, int(ch.Saver.LastSave().Unix()))
+}
@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"strconv"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+func (ch *CommandHandler) HandleLIndex() {
+	key := ch.Command[1]
+
+	index, err := strconv.Atoi(ch.Command[2])
+	if err != nil {
+		response.SendError(ch.Conn, errNotInteger)
+		return
+	}
+
+	value, ok, err := ch.MemoryStore.ListIndex(key, index)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+	if !ok {
+		response.SendNullString(ch.Conn)
+		return
+	}
+
+	response.SendBulkString(ch.Conn, value)
+}
@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+func (ch *CommandHandler) HandleLInsert() {
+	key := ch.Command[1]
+	pivot := ch.Command[3]
+	value := ch.Command[4]
+
+	var before bool
+	switch strings.ToUpper(ch.Command[2]) {
+	case "BEFORE":
+		before = true
+	case "AFTER":
+		before = false
+	default:
+		response.SendError(ch.Conn, errSyntax)
+		return
+	}
+
+	length, err := ch.MemoryStore.ListInsert(key, before, pivot, value)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.SendInteger(ch.Conn, length)
+}
@@ -0,0 +1,54 @@
+package commands
+
+import (
+	"strconv"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+func (ch *CommandHandler) HandleLPop() {
+	ch.pop(true)
+}
+
+// pop implements LPOP and RPOP. Without a count the reply is a single bulk
+// string, with one it is an array, matching upstream.
+func (ch *CommandHandler) pop(front bool) {
+	if len(ch.Command) > 3 {
+		response.SendError(ch.Conn, errWrongArgs(ch.Command[0]))
+		return
+	}
+
+	key := ch.Command[1]
+
+	count := 1
+	withCount := len(ch.Command) == 3
+	if withCount {
+		n, err := strconv.Atoi(ch.Command[2])
+		if err != nil || n < 0 {
+			response.SendError(ch.Conn, errNotPositive)
+			return
+		}
+		count = n
+	}
+
+	values, err := ch.MemoryStore.ListPop(key, front, count)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	if withCount {
+		if values == nil {
+			response.SendNullArray(ch.Conn)
+			return
+		}
+		response.SendStringArray(ch.Conn, values)
+		return
+	}
+
+	if len(values) == 0 {
+		response.SendNullString(ch.Conn)
+		return
+	}
+	response.SendBulkString(ch.Conn, values[0])
+}
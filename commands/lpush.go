@@ -0,0 +1,20 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+func (ch *CommandHandler) HandleLPush() {
+	ch.push(true)
+}
+
+// push implements LPUSH and RPUSH, which only differ in the end they add to.
+func (ch *CommandHandler) push(front bool) {
+	key := ch.Command[1]
+
+	length, err := ch.MemoryStore.ListPush(key, front, ch.Command[2:]...)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.SendInteger(ch.Conn, length)
+}
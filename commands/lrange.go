@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"strconv"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+func (ch *CommandHandler) HandleLRange() {
+	key := ch.Command[1]
+
+	start, err := strconv.Atoi(ch.Command[2])
+	if err != nil {
+		response.SendError(ch.Conn, errNotInteger)
+		return
+	}
+	stop, err := strconv.Atoi(ch.Command[3])
+	if err != nil {
+		response.SendError(ch.Conn, errNotInteger)
+		return
+	}
+
+	values, err := ch.MemoryStore.ListRange(key, start, stop)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.SendStringArray(ch.Conn, values)
+}
@@ -0,0 +1,26 @@
+package commands
+
+import (
+	"strconv"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+func (ch *CommandHandler) HandleLRem() {
+	key := ch.Command[1]
+	value := ch.Command[3]
+
+	count, err := strconv.Atoi(ch.Command[2])
+	if err != nil {
+		response.SendError(ch.Conn, errNotInteger)
+		return
+	}
+
+	removed, err := ch.MemoryStore.ListRem(key, count, value)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.SendInteger(ch.Conn, removed)
+}
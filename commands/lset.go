@@ -0,0 +1,25 @@
+package commands
+
+import (
+	"strconv"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+func (ch *CommandHandler) HandleLSet() {
+	key := ch.Command[1]
+	value := ch.Command[3]
+
+	index, err := strconv.Atoi(ch.Command[2])
+	if err != nil {
+		response.SendError(ch.Conn, errNotInteger)
+		return
+	}
+
+	if err := ch.MemoryStore.ListSet(key, index, value); err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.SendSimpleString(ch.Conn, "OK")
+}
@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"strconv"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+func (ch *CommandHandler) HandleLTrim() {
+	key := ch.Command[1]
+
+	start, err := strconv.Atoi(ch.Command[2])
+	if err != nil {
+		response.SendError(ch.Conn, errNotInteger)
+		return
+	}
+	stop, err := strconv.Atoi(ch.Command[3])
+	if err != nil {
+		response.SendError(ch.Conn, errNotInteger)
+		return
+	}
+
+	if err := ch.MemoryStore.ListTrim(key, start, stop); err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.SendSimpleString(ch.Conn, "OK")
+}
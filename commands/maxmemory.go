@@ -0,0 +1,59 @@
+package commands
+
+// Beyond maxmemory, keys are evicted before each command runs, as upstream
+// does, and commands that may grow memory are refused if eviction cannot make
+// room. Replicas hold whatever their primary sends them, as with upstream's
+// replica-ignore-maxmemory, and the primary's evictions reach them as DEL.
+
+// outOfMemory reports whether the store is beyond maxmemory.
+func (ch *CommandHandler) outOfMemory() bool {
+	if ch.Replication != nil && ch.Replication.IsReplica() {
+		return false
+	}
+	return ch.MemoryStore.OverMaxMemory()
+}
+
+// freeMemory evicts keys until the store is within maxmemory and reports
+// whether it is. The keys are propagated as one DEL. Caller must not hold
+// execMu.
+func (ch *CommandHandler) freeMemory() bool {
+	if !ch.outOfMemory() {
+		return true
+	}
+
+	// evicting is a write, so it waits for transactions and scripts
+	slot := ch.execSlot()
+	execMu.RLock(slot)
+	defer execMu.RUnlock(slot)
+	if ch.Propagator == nil || idle(ch.Propagator) {
+		return ch.MemoryStore.Evict(func(string) {})
+	}
+
+	writeMu.Lock()
+	defer writeMu.Unlock()
+	del := []string{"DEL"}
+	ok := ch.MemoryStore.Evict(func(key string) {
+		del = append(del, key)
+	})
+	if len(del) > 1 {
+		ch.Propagator.Propagate([][]string{del})
+	}
+	return ok
+}
+
+// deniesOOM reports whether cmd is refused beyond maxmemory: it may grow
+// memory, or it is an EXEC of a transaction with such a command queued.
+func (ch *CommandHandler) deniesOOM(cmd *Command) bool {
+	if cmd.Has(FlagDenyOOM) {
+		return true
+	}
+	if cmd.Name != "EXEC" || ch.Client == nil {
+		return false
+	}
+	for _, args := range ch.Client.queue {
+		if queued, ok := Lookup(args[0]); ok && queued.Has(FlagDenyOOM) {
+			return true
+		}
+	}
+	return false
+}
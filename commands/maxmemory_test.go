@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/pubsub"
+)
+
+// recordPropagator keeps the batches propagated to it.
+type recordPropagator struct {
+	batches [][][]string
+}
+
+func (p *recordPropagator) Propagate(batch [][]string) {
+	p.batches = append(p.batches, batch)
+}
+
+func TestMaxMemory(t *testing.T) {
+	store := cache.NewValueStore(time.Minute)
+	conn := &recordConn{}
+	client := NewClient(conn, pubsub.NewBroker())
+	reply(client, conn, store, "", "SET", "a", "1")
+	reply(client, conn, store, "", "SET", "b", "2")
+	store.SetMaxMemory(1, cache.NoEviction, 5)
+
+	const oom = "-" + errOOM + "\r\n"
+	if got := reply(client, conn, store, "", "SET", "c", "3"); got != oom {
+		t.Errorf("SET failed. Expected: %q, got: %q", oom, got)
+	}
+	if got := reply(client, conn, store, "", "GET", "a"); got != "$1\r\n1\r\n" {
+		t.Errorf("GET failed. Expected reads to run beyond maxmemory, got: %q", got)
+	}
+	if got := reply(client, conn, store, "", "DEL", "a"); got != ":1\r\n" {
+		t.Errorf("DEL failed. Expected commands that free memory to run, got: %q", got)
+	}
+
+	reply(client, conn, store, "", "MULTI")
+	reply(client, conn, store, "", "SET", "c", "3")
+	if got := reply(client, conn, store, "", "EXEC"); !strings.HasPrefix(got, "-EXECABORT") {
+		t.Errorf("EXEC failed. Expected: EXECABORT, got: %q", got)
+	}
+
+	// evicting makes room, and replicas are told with a DEL
+	propagator := &recordPropagator{}
+	store.SetMaxMemory(store.UsedMemory()-1, cache.AllKeysRandom, 5)
+	ch := NewCommandHandler(client, []string{"SET", "c", "3"}, store)
+	ch.Client = client
+	ch.Propagator = propagator
+	conn.buf.Reset()
+	ch.Dispatch()
+	client.Flush()
+	if got := conn.buf.String(); got != "+OK\r\n" {
+		t.Errorf("SET failed. Expected a key evicted to make room, got: %q", got)
+	}
+	if len(propagator.batches) != 2 || propagator.batches[0][0][0] != "DEL" || store.EvictedKeys() != 1 {
+		t.Errorf("SET failed. Expected the eviction propagated before the write, got: %v", propagator.batches)
+	}
+}
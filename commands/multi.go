@@ -0,0 +1,12 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+func (ch *CommandHandler) HandleMulti() {
+	if ch.Client.multi {
+		response.SendError(ch.Conn, "ERR MULTI calls can not be nested")
+		return
+	}
+	ch.Client.multi = true
+	response.SendSimpleString(ch.Conn, "OK")
+}
@@ -0,0 +1,31 @@
+package commands
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// PEXPIREAT key unix-time-milliseconds. Expirations are written to the AOF in
+// this form, whatever command set them.
+func (ch *CommandHandler) HandlePExpireAt() {
+	key := ch.Command[1]
+	ms, err := strconv.ParseInt(ch.Command[2], 10, 64)
+	if err != nil {
+		response.SendError(ch.Conn, errNotInteger)
+		return
+	}
+
+	expireAt, ok := expireDeadline(ms, time.Millisecond, false)
+	if !ok {
+		response.SendError(ch.Conn, errInvalidExpire("pexpireat"))
+		return
+	}
+	if !ch.expireAt(key, expireAt) {
+		response.SendInteger(ch.Conn, 0)
+		return
+	}
+
+	response.SendInteger(ch.Conn, 1)
+}
@@ -5,6 +5,17 @@ import (
 )
 
 func (ch *CommandHandler) HandlePing() {
+	// in subscribed mode replies share the connection with pushed messages,
+	// so upstream answers RESP2 clients with a message-like array instead
+	if ch.Client != nil && ch.Client.Proto() == 2 && ch.Client.Subscribed() && len(ch.Command) <= 2 {
+		message := ""
+		if len(ch.Command) == 2 {
+			message = ch.Command[1]
+		}
+		response.SendStringArray(ch.Conn, []string{"pong", message})
+		return
+	}
+
 	//If we're a PING of len one, we can return with a simple string of "PONG."
 	if len(ch.Command) == 1 {
 		response.SendSimpleString(ch.Conn, "PONG")
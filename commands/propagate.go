@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"bytes"
+	"net"
+	"sync"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// Propagator receives the write commands that changed the store, in the
+// order they were applied. A batch of more than one command comes from a
+// transaction and must be applied atomically.
+type Propagator interface {
+	Propagate(batch [][]string)
+}
+
+// Propagators sends each batch to several propagators in turn, such as the
+// AOF and the replicas.
+type Propagators []Propagator
+
+func (ps Propagators) Propagate(batch [][]string) {
+	for _, p := range ps {
+		p.Propagate(batch)
+	}
+}
+
+// idler is implemented by propagators that may have nowhere to propagate to
+// yet, as a primary no replica has synced with.
+type idler interface {
+	Idle() bool
+}
+
+// Idle reports whether every propagator is idle.
+func (ps Propagators) Idle() bool {
+	for _, p := range ps {
+		if !idle(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// idle reports whether p has nowhere to propagate to. It can only become
+// false while execMu is held exclusively, so it holds for a whole command.
+func idle(p Propagator) bool {
+	i, ok := p.(idler)
+	return ok && i.Idle()
+}
+
+// writeMu orders write commands while there is somewhere to propagate them,
+// so they are propagated in the order they hit the store. Otherwise writes
+// take no lock beyond those of the keys they touch, and reads never do.
+var writeMu sync.Mutex
+
+// propagateAs replaces what a command propagates, for commands whose effect
+// depends on the clock or randomness and so would not replay the same. No
+// arguments means nothing is propagated.
+func (ch *CommandHandler) propagateAs(cmds ...[]string) {
+	ch.rewritten = true
+	ch.propagated = append(ch.propagated, cmds...)
+}
+
+// run runs cmd and returns what it should propagate: its rewrite if it gave
+// one, otherwise the command itself if it changed the store.
+func (ch *CommandHandler) run(cmd *Command) [][]string {
+	ch.dirty = ch.MemoryStore.Dirty()
+	ch.rewritten = false
+	ch.propagated = nil
+
+	cmd.Handler(ch)
+
+	if ch.rewritten {
+		return ch.propagated
+	}
+	if ch.MemoryStore.Dirty() != ch.dirty {
+		return [][]string{ch.Command}
+	}
+	return nil
+}
+
+// runPropagated runs a write command with writeMu held and propagates its
+// effect, unless there is nowhere to propagate it.
+func (ch *CommandHandler) runPropagated(cmd *Command) {
+	if idle(ch.Propagator) {
+		cmd.Handler(ch)
+		return
+	}
+	defer ch.holdReplies()()
+
+	writeMu.Lock()
+	ch.writeLocked = true
+	defer func() {
+		ch.writeLocked = false
+		writeMu.Unlock()
+	}()
+
+	if batch := ch.run(cmd); len(batch) > 0 {
+		ch.Propagator.Propagate(batch)
+	}
+}
+
+// holdReplies buffers replies until the returned function is called, once
+// the command has been propagated, so with appendfsync always a client never
+// sees the reply to a write that is not on disk yet.
+func (ch *CommandHandler) holdReplies() (release func()) {
+	conn := ch.Conn
+	held := &heldConn{Conn: conn}
+	ch.Conn = held
+	return func() {
+		ch.Conn = conn
+		conn.Write(held.buf.Bytes())
+	}
+}
+
+// heldConn buffers replies instead of sending them. They are serialized in
+// the protocol of the connection it holds, unless proto says otherwise.
+type heldConn struct {
+	net.Conn
+	buf   bytes.Buffer
+	proto int
+}
+
+func (c *heldConn) Proto() int {
+	if c.proto != 0 {
+		return c.proto
+	}
+	return response.Proto(c.Conn)
+}
+
+func (c *heldConn) Write(p []byte) (int, error) {
+	return c.buf.Write(p)
+}
@@ -0,0 +1,6 @@
+package commands
+
+// PSUBSCRIBE pattern [pattern ...]
+func (ch *CommandHandler) HandlePSubscribe() {
+	ch.Client.broker.PSubscribe(ch.Client.Subscriber(), ch.Command[1:]...)
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"strconv"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// PSYNC replicationid offset. The connection becomes a replica: the reply is
+// a snapshot or the backlog since offset, followed by the stream of writes.
+func (ch *CommandHandler) HandlePSync() {
+	if ch.Replication == nil || ch.Client == nil {
+		response.SendError(ch.Conn, errNoReplication)
+		return
+	}
+	if ch.Client.InMulti() {
+		response.SendError(ch.Conn, "ERR Command not allowed inside a transaction")
+		return
+	}
+
+	offset, err := strconv.ParseInt(ch.Command[2], 10, 64)
+	if err != nil {
+		response.SendError(ch.Conn, errNotInteger)
+		return
+	}
+
+	status := ch.Replication.Status()
+	if status.Replica && !status.LinkUp() {
+		response.SendError(ch.Conn, "NOMASTERLINK Can't SYNC while not connected with my master")
+		return
+	}
+
+	// no write may land between the snapshot and streaming the writes after
+	// it. Replies go straight to the connection, not through the client, so
+	// it must not be subscribed.
+	ch.Client.Flush()
+	if ch.Replication.Idle() {
+		// writes take writeMu only once there is a backlog, which starts here,
+		// so until then every command is kept out
+		slot := ch.execSlot()
+		execMu.RUnlock(slot)
+		execMu.Lock()
+		defer func() {
+			execMu.Unlock()
+			execMu.RLock(slot)
+		}()
+	} else {
+		writeMu.Lock()
+		defer writeMu.Unlock()
+	}
+	ch.Replication.Sync(ch.Client.Conn, ch.Client.replicaPort, ch.Command[1], offset)
+}
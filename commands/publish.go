@@ -0,0 +1,9 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+// PUBLISH channel message
+func (ch *CommandHandler) HandlePublish() {
+	receivers := ch.Client.broker.Publish(ch.Command[1], ch.Command[2])
+	response.SendInteger(ch.Conn, receivers)
+}
@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// PUBSUB CHANNELS [pattern]
+// PUBSUB NUMSUB [channel [channel ...]]
+// PUBSUB NUMPAT
+func (ch *CommandHandler) HandlePubSub() {
+	broker := ch.Client.broker
+	subcommand := strings.ToUpper(ch.Command[1])
+
+	switch {
+	case subcommand == "CHANNELS" && len(ch.Command) <= 3:
+		pattern := ""
+		if len(ch.Command) == 3 {
+			pattern = ch.Command[2]
+		}
+		response.SendStringArray(ch.Conn, broker.Channels(pattern))
+
+	case subcommand == "NUMSUB":
+		channels := ch.Command[2:]
+		counts := broker.NumSub(channels...)
+		reply := make(response.MapType, len(channels))
+		for i, channel := range channels {
+			reply[i] = response.Pair{Key: response.BulkStringType(channel), Value: response.IntegerType(counts[i])}
+		}
+		response.Send(ch.Conn, reply)
+
+	case subcommand == "NUMPAT" && len(ch.Command) == 2:
+		response.SendInteger(ch.Conn, broker.NumPat())
+
+	case subcommand == "CHANNELS" || subcommand == "NUMPAT":
+		response.SendError(ch.Conn, errWrongArgs(ch.Command[0]+"|"+subcommand))
+
+	default:
+		response.SendError(ch.Conn, fmt.Sprintf("ERR unknown subcommand '%s'", ch.Command[1]))
+	}
+}
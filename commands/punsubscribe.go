@@ -0,0 +1,6 @@
+package commands
+
+// PUNSUBSCRIBE [pattern [pattern ...]]
+func (ch *CommandHandler) HandlePUnsubscribe() {
+	ch.Client.broker.PUnsubscribe(ch.Client.Subscriber(), ch.Command[1:]...)
+}
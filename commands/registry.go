@@ -0,0 +1,245 @@
+package commands
+
+import (
+	"maps"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// CommandFlag describes how a command behaves, mirroring the flags upstream
+// reports through COMMAND INFO.
+type CommandFlag uint32
+
+const (
+	FlagReadOnly     CommandFlag = 1 << iota // only reads from the keyspace
+	FlagWrite                                // may modify the keyspace
+	FlagAdmin                                // administrative command
+	FlagFast                                 // runs in O(1) or O(log N)
+	FlagSubscriber                           // allowed while the client is in subscribed mode
+	FlagNoQueue                              // runs straight away inside MULTI instead of being queued
+	FlagNoScript                             // may not be called from a script
+	FlagMayReplicate                         // not a write itself, but may propagate writes, as scripts do
+	FlagAllowBusy                            // may run while a script is busy, so never waits for one
+	FlagNoAuth                               // may run before the client has authenticated
+	FlagDenyOOM                              // may grow memory, so is refused beyond maxmemory
+)
+
+// Command is an entry in the command table.
+//
+// Arity follows the upstream convention: a positive value is the exact number
+// of arguments including the command name, a negative value is the minimum.
+// FirstKey, LastKey and KeyStep describe where the keys are in the argument
+// list; a negative LastKey counts back from the end and a zero FirstKey means
+// the command takes no keys. Commands whose keys cannot be described that way,
+// such as ZUNIONSTORE with its numkeys argument, set GetKeys instead.
+type Command struct {
+	Name     string
+	Arity    int
+	Flags    CommandFlag
+	FirstKey int
+	LastKey  int
+	KeyStep  int
+	GetKeys  func(args []string) []string
+	Handler  func(*CommandHandler)
+}
+
+// Has reports whether every flag in f is set on the command.
+func (c *Command) Has(f CommandFlag) bool {
+	return c.Flags&f == f
+}
+
+// CheckArity reports whether args, including the command name, satisfies the
+// command's arity.
+func (c *Command) CheckArity(args []string) bool {
+	if c.Arity >= 0 {
+		return len(args) == c.Arity
+	}
+	return len(args) >= -c.Arity
+}
+
+// Keys returns the key arguments of args according to the command's key
+// positions.
+func (c *Command) Keys(args []string) []string {
+	if c.GetKeys != nil {
+		return c.GetKeys(args)
+	}
+	if c.FirstKey <= 0 || c.FirstKey >= len(args) {
+		return nil
+	}
+
+	last := c.LastKey
+	if last < 0 {
+		last = len(args) + last
+	}
+	if last >= len(args) {
+		last = len(args) - 1
+	}
+
+	step := c.KeyStep
+	if step <= 0 {
+		step = 1
+	}
+
+	keys := make([]string, 0, (last-c.FirstKey)/step+1)
+	for i := c.FirstKey; i <= last; i += step {
+		keys = append(keys, args[i])
+	}
+	return keys
+}
+
+var (
+	// registryMu orders changes to the table. Each change copies it, so
+	// Lookup, which every command runs, reads it without a lock.
+	registryMu sync.Mutex
+	registry   atomic.Pointer[map[string]*Command]
+)
+
+// updateRegistry replaces the table with a copy changed by update. Caller
+// must hold registryMu.
+func updateRegistry(update func(table map[string]*Command)) {
+	table := make(map[string]*Command)
+	if old := registry.Load(); old != nil {
+		maps.Copy(table, *old)
+	}
+	update(table)
+	registry.Store(&table)
+}
+
+// Register adds a command to the table. Names are case-insensitive. Like
+// database/sql.Register it panics if the command has no handler or if a
+// command with the same name is already registered, so embedders find out at
+// startup rather than on the first request.
+func Register(cmd *Command) {
+	if cmd == nil || cmd.Handler == nil {
+		panic("commands: Register command or handler is nil")
+	}
+
+	name := strings.ToUpper(cmd.Name)
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if _, dup := Lookup(name); dup {
+		panic("commands: Register called twice for command " + name)
+	}
+	cmd.Name = name
+	updateRegistry(func(table map[string]*Command) {
+		table[name] = cmd
+	})
+}
+
+// Unregister removes a command from the table, disabling it. It reports
+// whether the command was registered.
+func Unregister(name string) bool {
+	name = strings.ToUpper(name)
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	_, exists := Lookup(name)
+	if exists {
+		updateRegistry(func(table map[string]*Command) {
+			delete(table, name)
+		})
+	}
+	return exists
+}
+
+// Lookup finds a command by name, ignoring case.
+func Lookup(name string) (*Command, bool) {
+	table := registry.Load()
+	if table == nil {
+		return nil, false
+	}
+	cmd, ok := (*table)[strings.ToUpper(name)]
+	return cmd, ok
+}
+
+// Dispatch looks up the command named by ch.Command[0], validates its arity
+// and runs its handler.
+func (ch *CommandHandler) Dispatch() {
+	ch.Command[0] = strings.ToUpper(ch.Command[0])
+
+	// errors before a command is queued abort the transaction with EXECABORT
+	cmd, ok := Lookup(ch.Command[0])
+	if !ok {
+		ch.rejectQueued()
+		response.SendError(ch.Conn, "Unknown command: "+ch.Command[0])
+		return
+	}
+
+	if !cmd.CheckArity(ch.Command) {
+		ch.rejectQueued()
+		response.SendError(ch.Conn, errWrongArgs(cmd.Name))
+		return
+	}
+
+	if ch.Password != "" && ch.Client != nil && !ch.Client.Authenticated() && !cmd.Has(FlagNoAuth) {
+		response.SendError(ch.Conn, "NOAUTH Authentication required.")
+		return
+	}
+
+	// RESP3 clients get pushed messages apart from replies, so they may run
+	// anything while subscribed
+	if ch.Client != nil && ch.Client.Proto() == 2 && !cmd.Has(FlagSubscriber) && ch.Client.Subscribed() {
+		response.SendError(ch.Conn, "ERR Can't execute '"+strings.ToLower(cmd.Name)+"': only (P)SUBSCRIBE / (P)UNSUBSCRIBE / PING are allowed in this context")
+		return
+	}
+
+	if ch.Cluster != nil && !ch.route(cmd) {
+		return
+	}
+
+	if ch.Scripts != nil && !cmd.Has(FlagAllowBusy) && ch.Scripts.Busy() {
+		ch.rejectQueued()
+		response.SendError(ch.Conn, errBusy)
+		return
+	}
+
+	if cmd.Has(FlagWrite) && ch.Replication != nil && ch.Replication.RefusesWrites() {
+		ch.rejectQueued()
+		response.SendError(ch.Conn, errReadOnly)
+		return
+	}
+
+	// commands that may run during a busy script cannot wait to evict
+	if !cmd.Has(FlagAllowBusy) && !ch.freeMemory() && ch.deniesOOM(cmd) {
+		// an EXEC refused discards the transaction, as upstream does
+		if cmd.Name == "EXEC" && ch.Client.InMulti() {
+			ch.Client.endMulti()
+			response.SendError(ch.Conn, "EXECABORT Transaction discarded because of: "+errOOM)
+			return
+		}
+		ch.rejectQueued()
+		response.SendError(ch.Conn, errOOM)
+		return
+	}
+
+	if ch.Client != nil && ch.Client.InMulti() && !cmd.Has(FlagNoQueue) {
+		ch.Client.queue = append(ch.Client.queue, ch.Command)
+		response.SendSimpleString(ch.Conn, "QUEUED")
+		return
+	}
+
+	// a busy script holds execMu until it is killed
+	if cmd.Has(FlagAllowBusy) {
+		cmd.Handler(ch)
+		return
+	}
+
+	slot := ch.execSlot()
+	execMu.RLock(slot)
+	defer execMu.RUnlock(slot)
+	if ch.Propagator != nil && cmd.Has(FlagWrite) {
+		ch.runPropagated(cmd)
+		return
+	}
+	cmd.Handler(ch)
+}
+
+func (ch *CommandHandler) rejectQueued() {
+	if ch.Client != nil {
+		ch.Client.rejectQueued()
+	}
+}
@@ -1,40 +1,33 @@
 package commands
 
 import (
-	"time"
-
+	"github.com/Ryan-DL/go-redis-server/cache"
 	"github.com/Ryan-DL/go-redis-server/response"
 )
 
 func (ch *CommandHandler) HandleRename() {
-	if len(ch.Command) < 3 {
-		response.SendError(ch.Conn, "Not enough arguments. Expected RENAME key newkey")
-		return
-	}
-
 	key := ch.Command[1]
 	newKey := ch.Command[2]
 
-	// retrieve the key's value and ensure it exists
-	value, exists := ch.MemoryStore.Get(key)
-	if !exists {
-		response.SendError(ch.Conn, "ERR no such key")
+	// both keys are locked together so the value and its TTL move in one step,
+	// whatever its type
+	err := ch.MemoryStore.UpdateKeys([]string{key, newKey}, func(entries []*cache.Entry) error {
+		src, dst := entries[0], entries[1]
+		if !src.Exists() {
+			return cache.ErrNoSuchKey
+		}
+		if src == dst {
+			return nil
+		}
+		dst.Set(src.Value())
+		dst.SetExpireAt(src.ExpireAt())
+		src.Delete()
+		return nil
+	})
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
 		return
 	}
 
-	expiry, hasExpiry := ch.MemoryStore.GetExpiry(key)
-
-	// rename by first deleting the newKey
-	ch.MemoryStore.Delete(newKey)
-
-	if hasExpiry {
-		remainingTTL := time.Until(expiry)
-		ch.MemoryStore.Set(newKey, value, remainingTTL)
-	} else {
-		ch.MemoryStore.Set(newKey, value, 0) // No TTL
-	}
-
-	ch.MemoryStore.Delete(key)
-
 	response.SendSimpleString(ch.Conn, "OK")
 }
@@ -0,0 +1,36 @@
+package commands
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+)
+
+// Replay applies a command read back from the append only file. Replies are
+// thrown away, as upstream does, and blocking commands never block. Only a
+// command that could not have been logged by this server is an error.
+func Replay(store *cache.ValueStore, args []string) error {
+	cmd, ok := Lookup(args[0])
+	if !ok {
+		return fmt.Errorf("unknown command '%s' reading the append only file", args[0])
+	}
+	if !cmd.CheckArity(args) {
+		return fmt.Errorf("wrong number of arguments for '%s' command reading the append only file", strings.ToLower(args[0]))
+	}
+
+	ch := NewCommandHandler(discardConn{}, args, store)
+	ch.inExec = true
+	cmd.Handler(ch)
+	return nil
+}
+
+// discardConn is a connection that throws away every reply.
+type discardConn struct {
+	net.Conn
+}
+
+func (discardConn) Write(p []byte) (int, error) {
+	return len(p), nil
+}
@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// REPLCONF option value [option value ...], sent by replicas to configure
+// their link. ACK gets no reply.
+func (ch *CommandHandler) HandleReplConf() {
+	if ch.Replication == nil || ch.Client == nil {
+		response.SendError(ch.Conn, errNoReplication)
+		return
+	}
+	if len(ch.Command)%2 == 0 {
+		response.SendError(ch.Conn, errSyntax)
+		return
+	}
+
+	for i := 1; i < len(ch.Command); i += 2 {
+		value := ch.Command[i+1]
+		switch strings.ToLower(ch.Command[i]) {
+		case "listening-port":
+			port, err := strconv.Atoi(value)
+			if err != nil {
+				response.SendError(ch.Conn, errNotInteger)
+				return
+			}
+			ch.Client.replicaPort = port
+		case "ack":
+			if offset, err := strconv.ParseInt(value, 10, 64); err == nil {
+				ch.Replication.Ack(ch.Client.Conn, offset)
+			}
+			return
+		case "getack":
+			// only a primary's, which a replica answers in its link
+			return
+		case "capa", "ip-address":
+		default:
+			response.SendError(ch.Conn, "ERR Unrecognized REPLCONF option: "+ch.Command[i])
+			return
+		}
+	}
+
+	response.SendSimpleString(ch.Conn, "OK")
+}
@@ -0,0 +1,34 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// REPLICAOF host port | NO ONE, also known as SLAVEOF.
+func (ch *CommandHandler) HandleReplicaOf() {
+	if ch.Replication == nil {
+		response.SendError(ch.Conn, errNoReplication)
+		return
+	}
+
+	if strings.EqualFold(ch.Command[1], "NO") && strings.EqualFold(ch.Command[2], "ONE") {
+		ch.Replication.ReplicaOfNoOne()
+		response.SendSimpleString(ch.Conn, "OK")
+		return
+	}
+
+	port, err := strconv.Atoi(ch.Command[2])
+	if err != nil || port < 0 || port > 65535 {
+		response.SendError(ch.Conn, "ERR Invalid master port")
+		return
+	}
+	if !ch.Replication.ReplicaOf(ch.Command[1], port) {
+		response.SendSimpleString(ch.Conn, "OK Already connected to specified master")
+		return
+	}
+
+	response.SendSimpleString(ch.Conn, "OK")
+}
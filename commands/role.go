@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"strconv"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+func (ch *CommandHandler) HandleRole() {
+	if ch.Replication == nil {
+		response.SendArray(ch.Conn, response.ArrayType{
+			response.BulkStringType("master"),
+			response.IntegerType(0),
+			response.ArrayType{},
+		})
+		return
+	}
+
+	status := ch.Replication.Status()
+	if status.Replica {
+		response.SendArray(ch.Conn, response.ArrayType{
+			response.BulkStringType("slave"),
+			response.BulkStringType(status.MasterHost),
+			response.IntegerType(status.MasterPort),
+			response.BulkStringType(status.LinkState),
+			response.IntegerType(status.Offset),
+		})
+		return
+	}
+
+	replicas := make(response.ArrayType, len(status.Replicas))
+	for i, r := range status.Replicas {
+		replicas[i] = response.BulkStrings([]string{r.IP, strconv.Itoa(r.Port), strconv.FormatInt(r.Offset, 10)})
+	}
+	response.SendArray(ch.Conn, response.ArrayType{
+		response.BulkStringType("master"),
+		response.IntegerType(status.Offset),
+		replicas,
+	})
+}
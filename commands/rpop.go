@@ -0,0 +1,5 @@
+package commands
+
+func (ch *CommandHandler) HandleRPop() {
+	ch.pop(false)
+}
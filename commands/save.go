@@ -0,0 +1,24 @@
+package commands
+
+import (
+	"github.com/Ryan-DL/go-redis-server/persist"
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+func (ch *CommandHandler) HandleSave() {
+	if ch.Saver == nil {
+		response.SendError(ch.Conn, errNoSaver)
+		return
+	}
+
+	if err := ch.Saver.Save(); err != nil {
+		if err == persist.ErrSaveInProgress {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+		response.SendError(ch.Conn, "ERR "+err.Error())
+		return
+	}
+
+	response.SendSimpleString(ch.Conn, "OK")
+}
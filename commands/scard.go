@@ -0,0 +1,15 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+func (ch *CommandHandler) HandleSCard() {
+	key := ch.Command[1]
+
+	size, err := ch.MemoryStore.SetCard(key)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.SendInteger(ch.Conn, size)
+}
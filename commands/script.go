@@ -0,0 +1,80 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// SCRIPT LOAD script
+// SCRIPT EXISTS sha1 [sha1 ...]
+// SCRIPT FLUSH [ASYNC|SYNC]
+// SCRIPT KILL
+func (ch *CommandHandler) HandleScript() {
+	subcommand := strings.ToUpper(ch.Command[1])
+
+	arity := map[string]int{
+		"LOAD":   3,
+		"EXISTS": -3,
+		"FLUSH":  -2,
+		"KILL":   2,
+	}[subcommand]
+	if arity == 0 {
+		response.SendError(ch.Conn, fmt.Sprintf("ERR unknown subcommand '%s'", ch.Command[1]))
+		return
+	}
+	if (arity > 0 && len(ch.Command) != arity) || (arity < 0 && len(ch.Command) < -arity) {
+		response.SendError(ch.Conn, errWrongArgs(ch.Command[0]+"|"+subcommand))
+		return
+	}
+	if ch.Scripts == nil {
+		response.SendError(ch.Conn, errNoScripting)
+		return
+	}
+
+	// SCRIPT runs even while a script is busy, but only KILL may
+	if subcommand != "KILL" && ch.Scripts.Busy() {
+		response.SendError(ch.Conn, errBusy)
+		return
+	}
+
+	switch subcommand {
+	case "LOAD":
+		sha, err := ch.Scripts.Load(ch.Command[2])
+		if err != nil {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+		response.SendBulkString(ch.Conn, sha)
+
+	case "EXISTS":
+		exists := ch.Scripts.Exists(ch.Command[2:]...)
+		reply := make(response.ArrayType, len(exists))
+		for i, ok := range exists {
+			reply[i] = response.IntegerType(boolInt(ok))
+		}
+		response.SendArray(ch.Conn, reply)
+
+	case "FLUSH":
+		if len(ch.Command) > 3 {
+			response.SendError(ch.Conn, errSyntax)
+			return
+		}
+		if len(ch.Command) == 3 {
+			if mode := strings.ToUpper(ch.Command[2]); mode != "ASYNC" && mode != "SYNC" {
+				response.SendError(ch.Conn, "ERR SCRIPT FLUSH only support SYNC|ASYNC option")
+				return
+			}
+		}
+		ch.Scripts.Flush()
+		response.SendSimpleString(ch.Conn, "OK")
+
+	case "KILL":
+		if err := ch.Scripts.Kill(); err != nil {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+		response.SendSimpleString(ch.Conn, "OK")
+	}
+}
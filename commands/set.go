@@ -1,40 +1,153 @@
 package commands
 
 import (
+	"errors"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/Ryan-DL/go-redis-server/cache"
 	"github.com/Ryan-DL/go-redis-server/response"
 )
 
-func (ch *CommandHandler) HandleSet() {
-	if len(ch.Command) < 3 {
-		response.SendError(ch.Conn, "Not enough arguments. Expected SET key value")
+// SET key value [NX | XX] [GET] [EX seconds | PX milliseconds |
+// EXAT unix-time-seconds | PXAT unix-time-milliseconds | KEEPTTL]
+// https://redis.io/docs/latest/commands/set/
+
+// setOptions are the options of a SET command.
+type setOptions struct {
+	nx, xx   bool
+	get      bool
+	keepTTL  bool
+	expire   string    // which of EX, PX, EXAT and PXAT was given, if any
+	expireAt time.Time // the deadline it gives, to the millisecond
+}
+
+const errSetExpire = "ERR invalid expire time in 'set' command"
+
+// parseSetOptions parses the options after the key and value. As upstream,
+// options are case insensitive and may be repeated, but NX and XX, and the
+// ways of setting the expiry, exclude one another.
+func parseSetOptions(args []string) (setOptions, string) {
+	var opts setOptions
+	for i := 0; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i]); option {
+		case "NX":
+			if opts.xx {
+				return opts, errSyntax
+			}
+			opts.nx = true
+		case "XX":
+			if opts.nx {
+				return opts, errSyntax
+			}
+			opts.xx = true
+		case "GET":
+			opts.get = true
+		case "KEEPTTL":
+			if opts.expire != "" {
+				return opts, errSyntax
+			}
+			opts.keepTTL = true
+		case "EX", "PX", "EXAT", "PXAT":
+			if opts.keepTTL || (opts.expire != "" && opts.expire != option) || i+1 == len(args) {
+				return opts, errSyntax
+			}
+			i++
+			expireAt, errMsg := parseSetExpire(option, args[i])
+			if errMsg != "" {
+				return opts, errMsg
+			}
+			opts.expire, opts.expireAt = option, expireAt
+		default:
+			return opts, errSyntax
+		}
+	}
+	return opts, ""
+}
+
+// parseSetExpire returns the deadline an EX, PX, EXAT or PXAT option gives.
+func parseSetExpire(option, arg string) (time.Time, string) {
+	n, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return time.Time{}, errNotInteger
 	}
+	if n <= 0 {
+		return time.Time{}, errSetExpire
+	}
+
+	unit := time.Millisecond
+	if option == "EX" || option == "EXAT" {
+		unit = time.Second
+	}
+	expireAt, ok := expireDeadline(n, unit, option == "EX" || option == "PX")
+	if !ok {
+		return time.Time{}, errSetExpire
+	}
+	return expireAt, ""
+}
 
+var errSetSkipped = errors.New("condition not met")
+
+func (ch *CommandHandler) HandleSet() {
 	key := ch.Command[1]
 	value := ch.Command[2]
 
-	// I'm choosing not to support other times besides seconds.
-	expiration := false
-	for _, v := range ch.Command {
-		if v == "EX" {
-			expiration = true
-		}
+	opts, errMsg := parseSetOptions(ch.Command[3:])
+	if errMsg != "" {
+		response.SendError(ch.Conn, errMsg)
+		return
 	}
 
-	if expiration {
-		last := ch.Command[len(ch.Command)-1]
-		secondsToAdd, err := strconv.ParseInt(last, 10, 64)
-		if err != nil {
-			response.SendError(ch.Conn, "Unable to parse requested expiration")
-			return
+	if !opts.nx && !opts.xx && !opts.get && !opts.keepTTL && opts.expire == "" {
+		ch.MemoryStore.Set(key, value, 0)
+		response.SendSimpleString(ch.Conn, "OK")
+		return
+	}
+
+	var old string
+	var existed bool
+	err := ch.MemoryStore.Update(key, func(e *cache.Entry) error {
+		if opts.get {
+			var err error
+			if old, existed, err = e.String(); err != nil {
+				return err
+			}
+		}
+		if (opts.nx && e.Exists()) || (opts.xx && !e.Exists()) {
+			return errSetSkipped
 		}
-		expirey := time.Duration(secondsToAdd) * time.Second
-		ch.MemoryStore.Set(key, value, expirey)
+		e.Set(value)
+		if !opts.keepTTL {
+			e.SetExpireAt(opts.expireAt)
+		}
+		return nil
+	})
+	if err != nil && err != errSetSkipped {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	if err == errSetSkipped {
+		ch.propagateAs()
 	} else {
-		ch.MemoryStore.Set(key, value, 0)
+		// a relative TTL would be counted again from whenever it is replayed,
+		// so the deadline is fixed here, to the millisecond, as upstream does
+		propagated := []string{"SET", key, value}
+		if opts.expire != "" {
+			propagated = append(propagated, "PXAT", strconv.FormatInt(opts.expireAt.UnixMilli(), 10))
+		} else if opts.keepTTL {
+			propagated = append(propagated, "KEEPTTL")
+		}
+		ch.propagateAs(propagated)
 	}
 
-	response.SendSimpleString(ch.Conn, "OK")
+	switch {
+	case opts.get && existed:
+		response.SendBulkString(ch.Conn, old)
+	case opts.get || err == errSetSkipped:
+		response.SendNullString(ch.Conn)
+	default:
+		response.SendSimpleString(ch.Conn, "OK")
+	}
 }
@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/pubsub"
+)
+
+// expireAt returns the deadline of key, the zero time for none.
+func expireAt(store *cache.ValueStore, key string) time.Time {
+	var t time.Time
+	store.View(key, func(e *cache.Entry) {
+		t = e.ExpireAt()
+	})
+	return t
+}
+
+func TestSetOptions(t *testing.T) {
+	store := cache.NewValueStore(time.Minute)
+	conn := &recordConn{}
+	client := NewClient(conn, pubsub.NewBroker())
+	reply(client, conn, store, "", "LPUSH", "list", "a")
+
+	const (
+		ok        = "+OK\r\n"
+		null      = "$-1\r\n"
+		syntax    = "-" + errSyntax + "\r\n"
+		expire    = "-" + errSetExpire + "\r\n"
+		integer   = "-" + errNotInteger + "\r\n"
+		wrongType = "-WRONGTYPE Operation against a key holding the wrong kind of value\r\n"
+	)
+	tests := []struct {
+		command []string
+		want    string
+	}{
+		{[]string{"SET", "k", "EX", "v"}, syntax},
+		{[]string{"SET", "k", "v", "EX"}, syntax},
+		{[]string{"SET", "k", "v", "NX", "XX"}, syntax},
+		{[]string{"SET", "k", "v", "EX", "10", "PX", "10"}, syntax},
+		{[]string{"SET", "k", "v", "KEEPTTL", "EX", "10"}, syntax},
+		{[]string{"SET", "k", "v", "PXAT", "10", "KEEPTTL"}, syntax},
+		{[]string{"SET", "k", "v", "BOGUS"}, syntax},
+		{[]string{"SET", "k", "v", "EX", "ten"}, integer},
+		{[]string{"SET", "k", "v", "EX", "0"}, expire},
+		{[]string{"SET", "k", "v", "PX", "-5"}, expire},
+		{[]string{"SET", "k", "v", "EX", "9223372036854775"}, expire},
+		{[]string{"SET", "far", "v", "EXAT", "99999999999"}, ok},
+		{[]string{"SET", "far", "v", "PXAT", "9223372036854775807"}, ok},
+		{[]string{"SET", "k", "v", "XX"}, null},
+		{[]string{"SET", "k", "v", "nx"}, ok},
+		{[]string{"SET", "k", "w", "NX"}, null},
+		{[]string{"SET", "k", "w", "NX", "GET"}, "$1\r\nv\r\n"},
+		{[]string{"SET", "k", "w", "xx", "get"}, "$1\r\nv\r\n"},
+		{[]string{"GET", "k"}, "$1\r\nw\r\n"},
+		{[]string{"SET", "new", "v", "GET"}, null},
+		{[]string{"SET", "list", "v", "GET"}, wrongType},
+		{[]string{"LLEN", "list"}, ":1\r\n"},
+		{[]string{"SET", "k", "v", "EX", "10", "EX", "100"}, ok},
+	}
+	for _, tt := range tests {
+		if got := reply(client, conn, store, "", tt.command...); got != tt.want {
+			t.Errorf("%v failed. Expected: %q, got: %q", tt.command, tt.want, got)
+		}
+	}
+}
+
+func TestSetExpiry(t *testing.T) {
+	store := cache.NewValueStore(time.Minute)
+	conn := &recordConn{}
+	client := NewClient(conn, pubsub.NewBroker())
+
+	deadline := time.Now().Add(time.Hour).Truncate(time.Millisecond)
+	ms := strconv.FormatInt(deadline.UnixMilli(), 10)
+	tests := []struct {
+		command []string
+		want    time.Time
+	}{
+		{[]string{"SET", "k", "v", "PXAT", ms}, deadline},
+		{[]string{"SET", "k", "v", "EXAT", strconv.FormatInt(deadline.Unix(), 10)}, deadline.Truncate(time.Second)},
+		{[]string{"SET", "k", "v", "KEEPTTL"}, deadline.Truncate(time.Second)},
+		{[]string{"SET", "k", "v"}, time.Time{}},
+	}
+	for _, tt := range tests {
+		reply(client, conn, store, "", tt.command...)
+		if got := expireAt(store, "k"); !got.Equal(tt.want) {
+			t.Errorf("%v failed. Expected: %v, got: %v", tt.command, tt.want, got)
+		}
+	}
+
+	// deadlines beyond what the store keeps are accepted, as upstream does
+	reply(client, conn, store, "", "SET", "k", "v", "EXAT", "99999999999")
+	if got := expireAt(store, "k"); got.Year() < 2262 {
+		t.Errorf("SET EXAT failed. Expected a deadline in 2262 or later, got: %v", got)
+	}
+
+	reply(client, conn, store, "", "SET", "k", "v", "PX", "1500")
+	if ttl := time.Until(expireAt(store, "k")); ttl <= time.Second || ttl > 1500*time.Millisecond {
+		t.Errorf("SET PX failed. Expected a TTL of 1.5s, got: %v", ttl)
+	}
+	reply(client, conn, store, "", "SET", "k", "v", "PXAT", "1")
+	if store.Exists("k") {
+		t.Errorf("SET PXAT failed. Expected a deadline in the past to delete the key")
+	}
+}
+
+func TestSetPropagation(t *testing.T) {
+	store := cache.NewValueStore(time.Minute)
+	conn := &recordConn{}
+	client := NewClient(conn, pubsub.NewBroker())
+
+	// a relative TTL is propagated as a deadline, so replicas agree on it
+	propagate := func(command ...string) [][][]string {
+		propagator := &recordPropagator{}
+		ch := NewCommandHandler(client, command, store)
+		ch.Client = client
+		ch.Propagator = propagator
+		ch.Dispatch()
+		client.Flush()
+		return propagator.batches
+	}
+	batches := propagate("SET", "k", "v", "EX", "100", "GET")
+	if len(batches) != 1 || len(batches[0][0]) != 5 || batches[0][0][3] != "PXAT" {
+		t.Fatalf("SET EX failed. Expected: SET k v PXAT ms, got: %v", batches)
+	}
+	if got := expireAt(store, "k").UnixMilli(); strconv.FormatInt(got, 10) != batches[0][0][4] {
+		t.Errorf("SET EX failed. Expected the deadline %d propagated, got: %v", got, batches)
+	}
+	if batches := propagate("SET", "k", "w", "NX"); len(batches) != 0 {
+		t.Errorf("SET NX failed. Expected nothing propagated when not set, got: %v", batches)
+	}
+	if batches := propagate("SET", "k", "w", "keepttl"); len(batches) != 1 || batches[0][0][3] != "KEEPTTL" {
+		t.Errorf("SET KEEPTTL failed. Expected: SET k w KEEPTTL, got: %v", batches)
+	}
+}
@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"log"
+	"os"
+	"strings"
+
+	"github.com/Ryan-DL/go-redis-server/persist"
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// exit ends the process once SHUTDOWN is ready to; tests replace it.
+var exit = os.Exit
+
+// SHUTDOWN [NOSAVE | SAVE]
+// It runs while a script is busy, so that one which has written and cannot
+// be killed can still be stopped, but then only without saving what it has
+// half done.
+func (ch *CommandHandler) HandleShutdown() {
+	save, noSave := false, false
+	for _, arg := range ch.Command[1:] {
+		switch strings.ToUpper(arg) {
+		case "SAVE":
+			save = true
+		case "NOSAVE":
+			noSave = true
+		default:
+			response.SendError(ch.Conn, errSyntax)
+			return
+		}
+	}
+	if save && noSave {
+		response.SendError(ch.Conn, errSyntax)
+		return
+	}
+	// EXEC holds the lock saving takes, so it could never run queued
+	if ch.Client != nil && ch.Client.InMulti() {
+		ch.rejectQueued()
+		response.SendError(ch.Conn, "ERR Command not allowed inside a transaction")
+		return
+	}
+	if ch.Scripts != nil && ch.Scripts.Busy() && !noSave {
+		response.SendError(ch.Conn, errBusy)
+		return
+	}
+
+	log.Printf("User requested shutdown...")
+	if err := Shutdown(ch.Saver, ch.AOF, save, noSave); err != nil {
+		response.SendError(ch.Conn, "ERR Errors trying to SHUTDOWN. Check logs.")
+		return
+	}
+	exit(0)
+}
+
+// Shutdown gets the server ready to exit, as SHUTDOWN and the signals that
+// stop it do: it saves if save rules are configured or save is set, unless
+// noSave is, and syncs the append only file. Like upstream, it fails rather
+// than lose data if the save does.
+func Shutdown(saver *persist.Saver, aof *persist.AOF, save, noSave bool) error {
+	if saver != nil && !noSave {
+		if err := saver.Shutdown(save); err != nil {
+			log.Printf("Error trying to save the DB, can't exit: %v", err)
+			return err
+		}
+	}
+	if aof != nil {
+		if err := aof.Close(); err != nil {
+			log.Printf("Error syncing the AOF file on shutdown: %v", err)
+		}
+	}
+	log.Printf("Redis is now ready to exit, bye bye...")
+	return nil
+}
@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/persist"
+	"github.com/Ryan-DL/go-redis-server/pubsub"
+	"github.com/Ryan-DL/go-redis-server/scripting"
+)
+
+func TestShutdown(t *testing.T) {
+	exited := -1
+	exit = func(code int) { exited = code }
+	t.Cleanup(func() { exit = os.Exit })
+
+	store := cache.NewValueStore(time.Minute)
+	store.Set("k", "v", 0)
+	scripts := scripting.NewEngine(10 * time.Millisecond)
+	rules, _ := persist.ParseSaveRules("3600 1")
+	path := filepath.Join(t.TempDir(), "dump.rdb")
+	saver := persist.NewSaver(store, path, rules)
+
+	conn := &recordConn{}
+	client := NewClient(conn, pubsub.NewBroker())
+	shutdown := func(command ...string) string {
+		conn.buf.Reset()
+		ch := NewCommandHandler(client, command, store)
+		ch.Client = client
+		ch.Saver = saver
+		ch.Scripts = scripts
+		ch.Dispatch()
+		client.Flush()
+		return conn.buf.String()
+	}
+
+	const syntax = "-" + errSyntax + "\r\n"
+	for _, command := range [][]string{{"SHUTDOWN", "NOW"}, {"SHUTDOWN", "SAVE", "NOSAVE"}} {
+		if got := shutdown(command...); got != syntax {
+			t.Errorf("%v failed. Expected: %q, got: %q", command, syntax, got)
+		}
+	}
+	shutdown("MULTI")
+	if got := shutdown("SHUTDOWN"); !strings.HasPrefix(got, "-ERR Command not allowed inside a transaction") {
+		t.Errorf("SHUTDOWN failed. Expected to be refused inside MULTI, got: %q", got)
+	}
+	if got := shutdown("EXEC"); !strings.HasPrefix(got, "-EXECABORT") {
+		t.Errorf("EXEC failed. Expected: EXECABORT, got: %q", got)
+	}
+
+	// a busy script only lets SHUTDOWN NOSAVE through
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ch := NewCommandHandler(discardConn{}, []string{"EVAL", "while true do end", "0"}, store)
+		ch.Scripts = scripts
+		ch.Dispatch()
+	}()
+	for !scripts.Busy() {
+		time.Sleep(time.Millisecond)
+	}
+	if got := shutdown("SHUTDOWN"); got != "-"+errBusy+"\r\n" {
+		t.Errorf("SHUTDOWN failed. Expected: BUSY, got: %q", got)
+	}
+	shutdown("SHUTDOWN", "NOSAVE")
+	if _, err := os.Stat(path); exited != 0 || err == nil {
+		t.Errorf("SHUTDOWN NOSAVE failed. Expected to exit without saving, got: exit code %d, %v", exited, err)
+	}
+	shutdown("SCRIPT", "KILL")
+	<-done
+
+	exited = -1
+	shutdown("SHUTDOWN")
+	if _, err := os.Stat(path); exited != 0 || err != nil {
+		t.Errorf("SHUTDOWN failed. Expected to save and exit, got: exit code %d, %v", exited, err)
+	}
+}
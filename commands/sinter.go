@@ -0,0 +1,35 @@
+package commands
+
+import (
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+func (ch *CommandHandler) HandleSInter() {
+	ch.combineSets(cache.SetInter)
+}
+
+// combineSets implements SINTER, SUNION and SDIFF.
+func (ch *CommandHandler) combineSets(op cache.SetOp) {
+	members, err := ch.MemoryStore.SetCombine(op, ch.Command[1:]...)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.Send(ch.Conn, response.SetType(response.BulkStrings(members)))
+}
+
+// combineSetsStore implements the STORE variants, which write the result to
+// the key given as the first argument.
+func (ch *CommandHandler) combineSetsStore(op cache.SetOp) {
+	dest := ch.Command[1]
+
+	size, err := ch.MemoryStore.SetCombineStore(op, dest, ch.Command[2:]...)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.SendInteger(ch.Conn, size)
+}
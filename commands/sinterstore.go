@@ -0,0 +1,7 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/cache"
+
+func (ch *CommandHandler) HandleSInterStore() {
+	ch.combineSetsStore(cache.SetInter)
+}
@@ -0,0 +1,20 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+func (ch *CommandHandler) HandleSIsMember() {
+	key := ch.Command[1]
+	member := ch.Command[2]
+
+	found, err := ch.MemoryStore.SetIsMember(key, member)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	if found[0] {
+		response.SendInteger(ch.Conn, 1)
+	} else {
+		response.SendInteger(ch.Conn, 0)
+	}
+}
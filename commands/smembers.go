@@ -0,0 +1,15 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+func (ch *CommandHandler) HandleSMembers() {
+	key := ch.Command[1]
+
+	members, err := ch.MemoryStore.SetMembers(key)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.Send(ch.Conn, response.SetType(response.BulkStrings(members)))
+}
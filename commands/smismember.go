@@ -0,0 +1,24 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+func (ch *CommandHandler) HandleSMIsMember() {
+	key := ch.Command[1]
+
+	found, err := ch.MemoryStore.SetIsMember(key, ch.Command[2:]...)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	reply := make(response.ArrayType, len(found))
+	for i, ok := range found {
+		if ok {
+			reply[i] = response.IntegerType(1)
+		} else {
+			reply[i] = response.IntegerType(0)
+		}
+	}
+
+	response.SendArray(ch.Conn, reply)
+}
@@ -0,0 +1,21 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+func (ch *CommandHandler) HandleSMove() {
+	src := ch.Command[1]
+	dest := ch.Command[2]
+	member := ch.Command[3]
+
+	moved, err := ch.MemoryStore.SetMove(src, dest, member)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	if moved {
+		response.SendInteger(ch.Conn, 1)
+	} else {
+		response.SendInteger(ch.Conn, 0)
+	}
+}
@@ -0,0 +1,51 @@
+package commands
+
+import (
+	"strconv"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// SPOP key [count]. Like LPOP the reply is a bulk string without a count and
+// an array with one.
+func (ch *CommandHandler) HandleSPop() {
+	if len(ch.Command) > 3 {
+		response.SendError(ch.Conn, errSyntax)
+		return
+	}
+
+	key := ch.Command[1]
+
+	count := 1
+	withCount := len(ch.Command) == 3
+	if withCount {
+		n, err := strconv.Atoi(ch.Command[2])
+		if err != nil || n < 0 {
+			response.SendError(ch.Conn, errNotPositive)
+			return
+		}
+		count = n
+	}
+
+	popped, err := ch.MemoryStore.SetPop(key, count)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+	// which members were popped is random, so propagate them by name
+	if len(popped) > 0 {
+		ch.propagateAs(append([]string{"SREM", key}, popped...))
+	} else {
+		ch.propagateAs()
+	}
+
+	if withCount {
+		response.Send(ch.Conn, response.SetType(response.BulkStrings(popped)))
+		return
+	}
+	if len(popped) == 0 {
+		response.SendNullString(ch.Conn)
+		return
+	}
+	response.SendBulkString(ch.Conn, popped[0])
+}
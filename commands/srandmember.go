@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// randPrealloc caps what is allocated up front for a negative count, which a
+// client could make as large as it likes.
+const randPrealloc = 1024
+
+// parseRandCount parses the count of SRANDMEMBER and HRANDFIELD. As upstream,
+// counts whose magnitude could overflow are refused.
+func parseRandCount(arg string) (int, string) {
+	count, err := strconv.Atoi(arg)
+	if err != nil {
+		return 0, errNotInteger
+	}
+	if count < -math.MaxInt64/2 || count > math.MaxInt64/2 {
+		return 0, errOutOfRange
+	}
+	return count, ""
+}
+
+// SRANDMEMBER key [count]. A positive count returns distinct members, a
+// negative one may return the same member several times.
+func (ch *CommandHandler) HandleSRandMember() {
+	if len(ch.Command) > 3 {
+		response.SendError(ch.Conn, errSyntax)
+		return
+	}
+
+	key := ch.Command[1]
+
+	members, err := ch.MemoryStore.SetMembers(key)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	if len(ch.Command) == 2 {
+		if len(members) == 0 {
+			response.SendNullString(ch.Conn)
+			return
+		}
+		response.SendBulkString(ch.Conn, members[rand.Intn(len(members))])
+		return
+	}
+
+	count, errMsg := parseRandCount(ch.Command[2])
+	if errMsg != "" {
+		response.SendError(ch.Conn, errMsg)
+		return
+	}
+
+	if count >= 0 {
+		rand.Shuffle(len(members), func(i, j int) { members[i], members[j] = members[j], members[i] })
+		response.SendStringArray(ch.Conn, members[:min(count, len(members))])
+		return
+	}
+
+	picked := make([]string, 0, min(-count, randPrealloc))
+	for i := 0; i < -count && len(members) > 0; i++ {
+		picked = append(picked, members[rand.Intn(len(members))])
+	}
+	response.SendStringArray(ch.Conn, picked)
+}
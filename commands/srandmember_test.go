@@ -0,0 +1,33 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/pubsub"
+)
+
+func TestSRandMemberCount(t *testing.T) {
+	store := cache.NewValueStore(time.Minute)
+	conn := &recordConn{}
+	client := NewClient(conn, pubsub.NewBroker())
+	reply(client, conn, store, "", "SADD", "s", "a")
+
+	const outOfRange = "-" + errOutOfRange + "\r\n"
+	tests := []struct {
+		count string
+		want  string
+	}{
+		{"-9223372036854775807", outOfRange},
+		{"-9223372036854775808", outOfRange},
+		{"9223372036854775807", outOfRange},
+		{"-3", "*3\r\n$1\r\na\r\n$1\r\na\r\n$1\r\na\r\n"},
+		{"3", "*1\r\n$1\r\na\r\n"},
+	}
+	for _, tt := range tests {
+		if got := reply(client, conn, store, "", "SRANDMEMBER", "s", tt.count); got != tt.want {
+			t.Errorf("SRANDMEMBER %s failed. Expected: %q, got: %q", tt.count, tt.want, got)
+		}
+	}
+}
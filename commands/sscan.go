@@ -0,0 +1,21 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+func (ch *CommandHandler) HandleSScan() {
+	key := ch.Command[1]
+
+	args, errMsg := parseScanArgs(ch.Command[2:], false)
+	if errMsg != "" {
+		response.SendError(ch.Conn, errMsg)
+		return
+	}
+
+	next, members, err := ch.MemoryStore.SetScan(key, args.cursor, args.match, args.count)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	sendScanReply(ch, next, members)
+}
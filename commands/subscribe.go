@@ -0,0 +1,6 @@
+package commands
+
+// SUBSCRIBE channel [channel ...]
+func (ch *CommandHandler) HandleSubscribe() {
+	ch.Client.broker.Subscribe(ch.Client.Subscriber(), ch.Command[1:]...)
+}
@@ -0,0 +1,152 @@
+package commands
+
+// The built in command table. Flags and key positions follow upstream.
+func init() {
+	for _, cmd := range []*Command{
+		{Name: "PING", Arity: -1, Flags: FlagFast | FlagSubscriber, Handler: (*CommandHandler).HandlePing},
+		{Name: "AUTH", Arity: -2, Flags: FlagNoScript | FlagFast | FlagNoAuth | FlagAllowBusy, Handler: (*CommandHandler).HandleAuth},
+		{Name: "HELLO", Arity: -1, Flags: FlagNoScript | FlagFast | FlagNoAuth | FlagAllowBusy, Handler: (*CommandHandler).HandleHello},
+		{Name: "INFO", Arity: -1, Flags: FlagAdmin, Handler: (*CommandHandler).HandleInfo},
+		{Name: "GET", Arity: 2, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleGet},
+		{Name: "SET", Arity: -3, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleSet},
+		{Name: "DEL", Arity: -2, Flags: FlagWrite, FirstKey: 1, LastKey: -1, KeyStep: 1, Handler: (*CommandHandler).HandleDelete},
+		{Name: "EXISTS", Arity: -2, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: -1, KeyStep: 1, Handler: (*CommandHandler).HandleExists},
+		{Name: "EXPIRE", Arity: 3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleExpire},
+		{Name: "PEXPIREAT", Arity: 3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandlePExpireAt},
+		{Name: "TTL", Arity: 2, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleTTL},
+		{Name: "RENAME", Arity: 3, Flags: FlagWrite, FirstKey: 1, LastKey: 2, KeyStep: 1, Handler: (*CommandHandler).HandleRename},
+		{Name: "APPEND", Arity: 3, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleAppend},
+		{Name: "INCR", Arity: 2, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleIncr},
+		{Name: "DECR", Arity: 2, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleDecr},
+		{Name: "TYPE", Arity: 2, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleType},
+
+		// lists
+		{Name: "LPUSH", Arity: -3, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleLPush},
+		{Name: "RPUSH", Arity: -3, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleRPush},
+		{Name: "LPOP", Arity: -2, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleLPop},
+		{Name: "RPOP", Arity: -2, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleRPop},
+		{Name: "LRANGE", Arity: 4, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleLRange},
+		{Name: "LLEN", Arity: 2, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleLLen},
+		{Name: "LINDEX", Arity: 3, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleLIndex},
+		{Name: "LSET", Arity: 4, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleLSet},
+		{Name: "LREM", Arity: 4, Flags: FlagWrite, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleLRem},
+		{Name: "LTRIM", Arity: 4, Flags: FlagWrite, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleLTrim},
+		{Name: "LINSERT", Arity: 5, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleLInsert},
+
+		// hashes
+		{Name: "HSET", Arity: -4, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleHSet},
+		{Name: "HMSET", Arity: -4, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleHMSet},
+		{Name: "HSETNX", Arity: 4, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleHSetNX},
+		{Name: "HGET", Arity: 3, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleHGet},
+		{Name: "HMGET", Arity: -3, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleHMGet},
+		{Name: "HDEL", Arity: -3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleHDel},
+		{Name: "HGETALL", Arity: 2, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleHGetAll},
+		{Name: "HKEYS", Arity: 2, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleHKeys},
+		{Name: "HVALS", Arity: 2, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleHVals},
+		{Name: "HLEN", Arity: 2, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleHLen},
+		{Name: "HEXISTS", Arity: 3, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleHExists},
+		{Name: "HSTRLEN", Arity: 3, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleHStrLen},
+		{Name: "HINCRBY", Arity: 4, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleHIncrBy},
+		{Name: "HINCRBYFLOAT", Arity: 4, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleHIncrByFloat},
+		{Name: "HRANDFIELD", Arity: -2, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleHRandField},
+		{Name: "HSCAN", Arity: -3, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleHScan},
+
+		// sets
+		{Name: "SADD", Arity: -3, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleSAdd},
+		{Name: "SREM", Arity: -3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleSRem},
+		{Name: "SMOVE", Arity: 4, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 2, KeyStep: 1, Handler: (*CommandHandler).HandleSMove},
+		{Name: "SMEMBERS", Arity: 2, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleSMembers},
+		{Name: "SISMEMBER", Arity: 3, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleSIsMember},
+		{Name: "SMISMEMBER", Arity: -3, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleSMIsMember},
+		{Name: "SCARD", Arity: 2, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleSCard},
+		{Name: "SPOP", Arity: -2, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleSPop},
+		{Name: "SRANDMEMBER", Arity: -2, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleSRandMember},
+		{Name: "SINTER", Arity: -2, Flags: FlagReadOnly, FirstKey: 1, LastKey: -1, KeyStep: 1, Handler: (*CommandHandler).HandleSInter},
+		{Name: "SUNION", Arity: -2, Flags: FlagReadOnly, FirstKey: 1, LastKey: -1, KeyStep: 1, Handler: (*CommandHandler).HandleSUnion},
+		{Name: "SDIFF", Arity: -2, Flags: FlagReadOnly, FirstKey: 1, LastKey: -1, KeyStep: 1, Handler: (*CommandHandler).HandleSDiff},
+		{Name: "SINTERSTORE", Arity: -3, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: -1, KeyStep: 1, Handler: (*CommandHandler).HandleSInterStore},
+		{Name: "SUNIONSTORE", Arity: -3, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: -1, KeyStep: 1, Handler: (*CommandHandler).HandleSUnionStore},
+		{Name: "SDIFFSTORE", Arity: -3, Flags: FlagWrite | FlagDenyOOM, FirstKey: 1, LastKey: -1, KeyStep: 1, Handler: (*CommandHandler).HandleSDiffStore},
+		{Name: "SSCAN", Arity: -3, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleSScan},
+
+		// sorted sets
+		{Name: "ZADD", Arity: -4, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleZAdd},
+		{Name: "ZINCRBY", Arity: 4, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleZIncrBy},
+		{Name: "ZREM", Arity: -3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleZRem},
+		{Name: "ZCARD", Arity: 2, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleZCard},
+		{Name: "ZSCORE", Arity: 3, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleZScore},
+		{Name: "ZRANK", Arity: -3, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleZRank},
+		{Name: "ZREVRANK", Arity: -3, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleZRevRank},
+		{Name: "ZRANGE", Arity: -4, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleZRange},
+		{Name: "ZREVRANGE", Arity: -4, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleZRevRange},
+		{Name: "ZRANGEBYSCORE", Arity: -4, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleZRangeByScore},
+		{Name: "ZREVRANGEBYSCORE", Arity: -4, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleZRevRangeByScore},
+		{Name: "ZRANGEBYLEX", Arity: -4, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleZRangeByLex},
+		{Name: "ZREVRANGEBYLEX", Arity: -4, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleZRevRangeByLex},
+		{Name: "ZPOPMIN", Arity: -2, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleZPopMin},
+		{Name: "ZPOPMAX", Arity: -2, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleZPopMax},
+		{Name: "ZUNIONSTORE", Arity: -4, Flags: FlagWrite | FlagDenyOOM, GetKeys: zstoreKeys, Handler: (*CommandHandler).HandleZUnionStore},
+		{Name: "ZINTERSTORE", Arity: -4, Flags: FlagWrite | FlagDenyOOM, GetKeys: zstoreKeys, Handler: (*CommandHandler).HandleZInterStore},
+
+		// streams
+		{Name: "XADD", Arity: -5, Flags: FlagWrite | FlagDenyOOM | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleXAdd},
+		{Name: "XRANGE", Arity: -4, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleXRange},
+		{Name: "XREVRANGE", Arity: -4, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleXRevRange},
+		{Name: "XLEN", Arity: 2, Flags: FlagReadOnly | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleXLen},
+		{Name: "XTRIM", Arity: -4, Flags: FlagWrite, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleXTrim},
+		{Name: "XDEL", Arity: -3, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleXDel},
+		{Name: "XREAD", Arity: -4, Flags: FlagReadOnly, GetKeys: xreadKeys, Handler: (*CommandHandler).HandleXRead},
+		{Name: "XGROUP", Arity: -2, Flags: FlagWrite, FirstKey: 2, LastKey: 2, KeyStep: 1, Handler: (*CommandHandler).HandleXGroup},
+		{Name: "XREADGROUP", Arity: -7, Flags: FlagWrite, GetKeys: xreadgroupKeys, Handler: (*CommandHandler).HandleXReadGroup},
+		{Name: "XACK", Arity: -4, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleXAck},
+		{Name: "XPENDING", Arity: -3, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleXPending},
+		{Name: "XCLAIM", Arity: -6, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleXClaim},
+		{Name: "XAUTOCLAIM", Arity: -6, Flags: FlagWrite | FlagFast, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: (*CommandHandler).HandleXAutoClaim},
+		{Name: "XINFO", Arity: -3, Flags: FlagReadOnly, FirstKey: 2, LastKey: 2, KeyStep: 1, Handler: (*CommandHandler).HandleXInfo},
+
+		// pub/sub
+		{Name: "SUBSCRIBE", Arity: -2, Flags: FlagSubscriber | FlagNoScript, Handler: (*CommandHandler).HandleSubscribe},
+		{Name: "UNSUBSCRIBE", Arity: -1, Flags: FlagSubscriber | FlagNoScript, Handler: (*CommandHandler).HandleUnsubscribe},
+		{Name: "PSUBSCRIBE", Arity: -2, Flags: FlagSubscriber | FlagNoScript, Handler: (*CommandHandler).HandlePSubscribe},
+		{Name: "PUNSUBSCRIBE", Arity: -1, Flags: FlagSubscriber | FlagNoScript, Handler: (*CommandHandler).HandlePUnsubscribe},
+		{Name: "PUBLISH", Arity: 3, Flags: FlagFast, Handler: (*CommandHandler).HandlePublish},
+		{Name: "PUBSUB", Arity: -2, Handler: (*CommandHandler).HandlePubSub},
+
+		// transactions
+		{Name: "MULTI", Arity: 1, Flags: FlagFast | FlagNoQueue | FlagNoScript, Handler: (*CommandHandler).HandleMulti},
+		{Name: "EXEC", Arity: 1, Flags: FlagNoQueue | FlagNoScript, Handler: (*CommandHandler).HandleExec},
+		{Name: "DISCARD", Arity: 1, Flags: FlagFast | FlagNoQueue | FlagNoScript, Handler: (*CommandHandler).HandleDiscard},
+		{Name: "WATCH", Arity: -2, Flags: FlagFast | FlagNoQueue | FlagNoScript, FirstKey: 1, LastKey: -1, KeyStep: 1, Handler: (*CommandHandler).HandleWatch},
+		{Name: "UNWATCH", Arity: 1, Flags: FlagFast | FlagNoScript, Handler: (*CommandHandler).HandleUnwatch},
+
+		// persistence
+		{Name: "SAVE", Arity: 1, Flags: FlagAdmin | FlagNoScript, Handler: (*CommandHandler).HandleSave},
+		{Name: "BGSAVE", Arity: -1, Flags: FlagAdmin | FlagNoScript, Handler: (*CommandHandler).HandleBGSave},
+		{Name: "SHUTDOWN", Arity: -1, Flags: FlagAdmin | FlagNoScript | FlagNoQueue | FlagAllowBusy, Handler: (*CommandHandler).HandleShutdown},
+		{Name: "LASTSAVE", Arity: 1, Flags: FlagFast, Handler: (*CommandHandler).HandleLastSave},
+		{Name: "BGREWRITEAOF", Arity: 1, Flags: FlagAdmin | FlagNoScript, Handler: (*CommandHandler).HandleBGRewriteAOF},
+
+		// replication
+		{Name: "REPLICAOF", Arity: 3, Flags: FlagAdmin | FlagNoScript, Handler: (*CommandHandler).HandleReplicaOf},
+		{Name: "SLAVEOF", Arity: 3, Flags: FlagAdmin | FlagNoScript, Handler: (*CommandHandler).HandleReplicaOf},
+		{Name: "PSYNC", Arity: 3, Flags: FlagAdmin | FlagNoQueue | FlagNoScript, Handler: (*CommandHandler).HandlePSync},
+		{Name: "REPLCONF", Arity: -1, Flags: FlagAdmin | FlagNoScript, Handler: (*CommandHandler).HandleReplConf},
+		{Name: "ROLE", Arity: 1, Flags: FlagFast, Handler: (*CommandHandler).HandleRole},
+
+		// cluster
+		{Name: "CLUSTER", Arity: -2, Flags: FlagAdmin, Handler: (*CommandHandler).HandleCluster},
+		{Name: "ASKING", Arity: 1, Flags: FlagFast, Handler: (*CommandHandler).HandleAsking},
+
+		// scripting
+		{Name: "EVAL", Arity: -3, Flags: FlagNoScript | FlagMayReplicate, GetKeys: evalKeys, Handler: (*CommandHandler).HandleEval},
+		{Name: "EVALSHA", Arity: -3, Flags: FlagNoScript | FlagMayReplicate, GetKeys: evalKeys, Handler: (*CommandHandler).HandleEvalSHA},
+		{Name: "EVAL_RO", Arity: -3, Flags: FlagReadOnly | FlagNoScript, GetKeys: evalKeys, Handler: (*CommandHandler).HandleEvalRO},
+		{Name: "EVALSHA_RO", Arity: -3, Flags: FlagReadOnly | FlagNoScript, GetKeys: evalKeys, Handler: (*CommandHandler).HandleEvalSHARO},
+		{Name: "SCRIPT", Arity: -2, Flags: FlagNoScript | FlagAllowBusy, Handler: (*CommandHandler).HandleScript},
+		{Name: "FCALL", Arity: -3, Flags: FlagNoScript | FlagMayReplicate, GetKeys: evalKeys, Handler: (*CommandHandler).HandleFCall},
+		{Name: "FCALL_RO", Arity: -3, Flags: FlagReadOnly | FlagNoScript, GetKeys: evalKeys, Handler: (*CommandHandler).HandleFCallRO},
+		{Name: "FUNCTION", Arity: -2, Flags: FlagNoScript | FlagMayReplicate | FlagAllowBusy, Handler: (*CommandHandler).HandleFunction},
+	} {
+		Register(cmd)
+	}
+}
@@ -0,0 +1,9 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+func (ch *CommandHandler) HandleType() {
+	key := ch.Command[1]
+
+	response.SendSimpleString(ch.Conn, ch.MemoryStore.Type(key).String())
+}
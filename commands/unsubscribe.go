@@ -0,0 +1,6 @@
+package commands
+
+// UNSUBSCRIBE [channel [channel ...]]
+func (ch *CommandHandler) HandleUnsubscribe() {
+	ch.Client.broker.Unsubscribe(ch.Client.Subscriber(), ch.Command[1:]...)
+}
@@ -0,0 +1,10 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+func (ch *CommandHandler) HandleUnwatch() {
+	if ch.Client.watch != nil {
+		ch.Client.watch.Clear()
+	}
+	response.SendSimpleString(ch.Conn, "OK")
+}
@@ -0,0 +1,16 @@
+package commands
+
+import "github.com/Ryan-DL/go-redis-server/response"
+
+// WATCH key [key ...]
+func (ch *CommandHandler) HandleWatch() {
+	if ch.Client.multi {
+		response.SendError(ch.Conn, "ERR WATCH inside MULTI is not allowed")
+		return
+	}
+	if ch.Client.watch == nil {
+		ch.Client.watch = ch.MemoryStore.NewWatch()
+	}
+	ch.Client.watch.Add(ch.Command[1:]...)
+	response.SendSimpleString(ch.Conn, "OK")
+}
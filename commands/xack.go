@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// XACK key group id [id ...]
+func (ch *CommandHandler) HandleXAck() {
+	key, group := ch.Command[1], ch.Command[2]
+
+	ids := make([]cache.StreamID, 0, len(ch.Command)-3)
+	for _, arg := range ch.Command[3:] {
+		id, err := cache.ParseStreamID(arg, 0)
+		if err != nil {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	acked, err := ch.MemoryStore.XAck(key, group, ids...)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.SendInteger(ch.Conn, acked)
+}
@@ -0,0 +1,126 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// parseStreamTrim parses "MAXLEN|MINID [=|~] threshold [LIMIT count]"
+// starting at args[i], which must be MAXLEN or MINID. It returns the index
+// just past the trim arguments, or an error reply.
+func parseStreamTrim(args []string, i int) (cache.StreamTrim, int, string) {
+	var trim cache.StreamTrim
+	if strings.ToUpper(args[i]) == "MAXLEN" {
+		trim.Strategy = cache.TrimMaxLen
+	} else {
+		trim.Strategy = cache.TrimMinID
+	}
+	i++
+
+	if i < len(args) && (args[i] == "=" || args[i] == "~") {
+		trim.Approx = args[i] == "~"
+		i++
+	}
+	if i >= len(args) {
+		return trim, i, errSyntax
+	}
+
+	if trim.Strategy == cache.TrimMaxLen {
+		maxLen, err := strconv.ParseInt(args[i], 10, 64)
+		if err != nil {
+			return trim, i, errNotInteger
+		}
+		if maxLen < 0 {
+			return trim, i, "ERR The MAXLEN argument must be >= 0."
+		}
+		trim.MaxLen = maxLen
+	} else {
+		minID, err := cache.ParseStreamID(args[i], 0)
+		if err != nil {
+			return trim, i, err.Error()
+		}
+		trim.MinID = minID
+	}
+	i++
+
+	// like upstream, approximate trimming evicts at most 100 nodes by default
+	if trim.Approx {
+		trim.Limit = 100 * 100
+	}
+	if i+1 < len(args) && strings.ToUpper(args[i]) == "LIMIT" {
+		limit, err := strconv.ParseInt(args[i+1], 10, 64)
+		if err != nil || limit < 0 {
+			return trim, i, "ERR The LIMIT argument must be >= 0."
+		}
+		if !trim.Approx {
+			return trim, i, "ERR syntax error, LIMIT cannot be used without the special ~ option"
+		}
+		trim.Limit = limit
+		i += 2
+	}
+	return trim, i, ""
+}
+
+// XADD key [NOMKSTREAM] [MAXLEN|MINID [=|~] threshold [LIMIT count]] *|id field value [field value ...]
+func (ch *CommandHandler) HandleXAdd() {
+	key := ch.Command[1]
+
+	noMkStream := false
+	var trim cache.StreamTrim
+
+	i := 2
+options:
+	for i < len(ch.Command) {
+		switch strings.ToUpper(ch.Command[i]) {
+		case "NOMKSTREAM":
+			noMkStream = true
+			i++
+		case "MAXLEN", "MINID":
+			var errMsg string
+			trim, i, errMsg = parseStreamTrim(ch.Command, i)
+			if errMsg != "" {
+				response.SendError(ch.Conn, errMsg)
+				return
+			}
+		default:
+			break options
+		}
+	}
+
+	if i >= len(ch.Command) {
+		response.SendError(ch.Conn, errSyntax)
+		return
+	}
+	fields := ch.Command[i+1:]
+	if len(fields) == 0 || len(fields)%2 != 0 {
+		response.SendError(ch.Conn, errWrongArgs(ch.Command[0]))
+		return
+	}
+
+	spec, err := cache.ParseStreamIDSpec(ch.Command[i])
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	id, ok, err := ch.MemoryStore.XAdd(key, spec, append([]string(nil), fields...), noMkStream, trim)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+	if !ok {
+		response.SendNullString(ch.Conn)
+		return
+	}
+	// generated IDs depend on the clock, so propagate the one we picked
+	if spec.Auto || spec.AutoSeq {
+		args := append([]string(nil), ch.Command...)
+		args[i] = id.String()
+		ch.propagateAs(args)
+	}
+
+	response.SendBulkString(ch.Conn, id.String())
+}
@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// XAUTOCLAIM key group consumer min-idle-time start [COUNT count] [JUSTID]
+func (ch *CommandHandler) HandleXAutoClaim() {
+	key, group, consumer := ch.Command[1], ch.Command[2], ch.Command[3]
+
+	minIdle, errMsg := parseMinIdle(ch.Command[4], "XAUTOCLAIM")
+	if errMsg != "" {
+		response.SendError(ch.Conn, errMsg)
+		return
+	}
+	start, errMsg := parseRangeStart(ch.Command[5])
+	if errMsg != "" {
+		response.SendError(ch.Conn, errMsg)
+		return
+	}
+
+	count := 100
+	justID := false
+	for i := 6; i < len(ch.Command); i++ {
+		switch option := strings.ToUpper(ch.Command[i]); {
+		case option == "COUNT" && i+1 < len(ch.Command):
+			n, err := strconv.Atoi(ch.Command[i+1])
+			if err != nil {
+				response.SendError(ch.Conn, errNotInteger)
+				return
+			}
+			// upstream scans up to ten times COUNT entries, so cap it too
+			if n < 1 || n > 1<<20 {
+				response.SendError(ch.Conn, "ERR COUNT must be > 0")
+				return
+			}
+			count = n
+			i++
+		case option == "JUSTID":
+			justID = true
+		default:
+			response.SendError(ch.Conn, errSyntax)
+			return
+		}
+	}
+
+	next, claimed, deleted, err := ch.MemoryStore.XAutoClaim(key, group, consumer, minIdle, start, count, justID)
+	if err != nil {
+		ch.sendGroupError(err, key, group)
+		return
+	}
+
+	deletedIDs := make([]string, len(deleted))
+	for i, id := range deleted {
+		deletedIDs[i] = id.String()
+	}
+
+	// entries found deleted were dropped from the pending list, which
+	// replays as acknowledging them
+	ch.propagateClaim(key, group, consumer, claimed, cache.ClaimOptions{DeliveryTime: time.Now().UnixMilli(), JustID: justID})
+	if len(deleted) > 0 {
+		ch.propagateAs(append([]string{"XACK", key, group}, deletedIDs...))
+	}
+	response.SendArray(ch.Conn, response.ArrayType{
+		response.BulkStringType(next.String()),
+		claimedReply(claimed, justID),
+		response.BulkStrings(deletedIDs),
+	})
+}
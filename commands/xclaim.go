@@ -0,0 +1,139 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// parseMinIdle parses the min-idle-time argument of XCLAIM and XAUTOCLAIM.
+// It is kept in milliseconds, which no valid value overflows. Negative values
+// are treated as 0, like upstream.
+func parseMinIdle(arg, command string) (int64, string) {
+	ms, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return 0, "ERR Invalid min-idle-time argument for " + command
+	}
+	return max(ms, 0), ""
+}
+
+// claimedReply converts claimed entries to RESP, just their IDs with JUSTID.
+func claimedReply(entries []cache.StreamEntry, justID bool) response.ArrayType {
+	if !justID {
+		return streamEntries(entries)
+	}
+	reply := make(response.ArrayType, len(entries))
+	for i, entry := range entries {
+		reply[i] = response.BulkStringType(entry.ID.String())
+	}
+	return reply
+}
+
+// XCLAIM key group consumer min-idle-time id [id ...] [IDLE ms] [TIME unix-time-milliseconds] [RETRYCOUNT count] [FORCE] [JUSTID] [LASTID lastid]
+func (ch *CommandHandler) HandleXClaim() {
+	key, group, consumer := ch.Command[1], ch.Command[2], ch.Command[3]
+
+	minIdle, errMsg := parseMinIdle(ch.Command[4], "XCLAIM")
+	if errMsg != "" {
+		response.SendError(ch.Conn, errMsg)
+		return
+	}
+
+	// IDs run until the first argument that isn't one
+	i := 5
+	var ids []cache.StreamID
+	for ; i < len(ch.Command); i++ {
+		id, err := cache.ParseStreamID(ch.Command[i], 0)
+		if err != nil {
+			break
+		}
+		ids = append(ids, id)
+	}
+
+	var opts cache.ClaimOptions
+	now := time.Now().UnixMilli()
+	for ; i < len(ch.Command); i++ {
+		option := strings.ToUpper(ch.Command[i])
+		hasValue := i+1 < len(ch.Command)
+		switch {
+		case option == "FORCE":
+			opts.Force = true
+		case option == "JUSTID":
+			opts.JustID = true
+		case (option == "IDLE" || option == "TIME" || option == "RETRYCOUNT") && hasValue:
+			n, err := strconv.ParseInt(ch.Command[i+1], 10, 64)
+			if err != nil {
+				response.SendError(ch.Conn, errNotInteger)
+				return
+			}
+			switch option {
+			case "IDLE":
+				// clamped so the subtraction cannot overflow
+				opts.DeliveryTime = now - min(max(n, 0), now)
+			case "TIME":
+				opts.DeliveryTime = n
+			case "RETRYCOUNT":
+				opts.RetryCount = n
+				opts.SetRetry = true
+			}
+			i++
+		case option == "LASTID" && hasValue:
+			id, err := cache.ParseStreamID(ch.Command[i+1], 0)
+			if err != nil {
+				response.SendError(ch.Conn, err.Error())
+				return
+			}
+			opts.LastID = id
+			i++
+		default:
+			response.SendError(ch.Conn, fmt.Sprintf("ERR Unrecognized XCLAIM option '%s'", ch.Command[i]))
+			return
+		}
+	}
+	// a delivery time in the future or before the epoch means now
+	if opts.DeliveryTime <= 0 || opts.DeliveryTime > now {
+		opts.DeliveryTime = now
+	}
+
+	claimed, err := ch.MemoryStore.XClaim(key, group, consumer, minIdle, ids, opts)
+	if err != nil {
+		ch.sendGroupError(err, key, group)
+		return
+	}
+	ch.propagateClaim(key, group, consumer, claimed, opts)
+
+	response.SendArray(ch.Conn, claimedReply(claimed, opts.JustID))
+}
+
+// propagateClaim propagates a claim as an XCLAIM of exactly the entries that
+// were claimed, with no idle check and a fixed delivery time, since which
+// entries were idle long enough depends on when it runs.
+func (ch *CommandHandler) propagateClaim(key, group, consumer string, claimed []cache.StreamEntry, opts cache.ClaimOptions) {
+	if len(claimed) == 0 {
+		ch.propagateAs()
+		return
+	}
+
+	args := []string{"XCLAIM", key, group, consumer, "0"}
+	for _, entry := range claimed {
+		args = append(args, entry.ID.String())
+	}
+	args = append(args, "TIME", strconv.FormatInt(opts.DeliveryTime, 10))
+	if opts.SetRetry {
+		args = append(args, "RETRYCOUNT", strconv.FormatInt(opts.RetryCount, 10))
+	}
+	if opts.Force {
+		args = append(args, "FORCE")
+	}
+	if opts.JustID {
+		args = append(args, "JUSTID")
+	}
+	if opts.LastID != (cache.StreamID{}) {
+		args = append(args, "LASTID", opts.LastID.String())
+	}
+	ch.propagateAs(args)
+}
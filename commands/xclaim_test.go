@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/pubsub"
+)
+
+func TestXClaimIdleOverflow(t *testing.T) {
+	store := cache.NewValueStore(time.Minute)
+	conn := &recordConn{}
+	client := NewClient(conn, pubsub.NewBroker())
+	reply(client, conn, store, "", "XADD", "xs", "1-1", "f", "v")
+	reply(client, conn, store, "", "XGROUP", "CREATE", "xs", "g", "0")
+	reply(client, conn, store, "", "XREADGROUP", "GROUP", "g", "c", "STREAMS", "xs", ">")
+
+	// a min-idle-time this long is never reached
+	const forever = "9223372036854775807"
+	tests := []struct {
+		command []string
+		want    string
+	}{
+		{[]string{"XAUTOCLAIM", "xs", "g", "c2", forever, "0"}, "*3\r\n$3\r\n0-0\r\n*0\r\n*0\r\n"},
+		{[]string{"XCLAIM", "xs", "g", "c2", forever, "1-1"}, "*0\r\n"},
+		{[]string{"XPENDING", "xs", "g", "IDLE", forever, "-", "+", "10"}, "*0\r\n"},
+	}
+	for _, tt := range tests {
+		if got := reply(client, conn, store, "", tt.command...); got != tt.want {
+			t.Errorf("%v failed. Expected: %q, got: %q", tt.command, tt.want, got)
+		}
+	}
+
+	// an IDLE out of range means delivered now
+	for _, idle := range []string{"-9223372036854775808", forever} {
+		command := []string{"XCLAIM", "xs", "g", "c2", "0", "1-1", "IDLE", idle, "JUSTID"}
+		if got := reply(client, conn, store, "", command...); got != "*1\r\n$3\r\n1-1\r\n" {
+			t.Errorf("%v failed. Expected the entry claimed, got: %q", command, got)
+		}
+		pending, _ := store.XPending("xs", "g", cache.StreamID{}, cache.MaxStreamID, 10, "", 0)
+		if len(pending) != 1 || pending[0].Idle > time.Second {
+			t.Errorf("%v failed. Expected an idle time of 0, got: %v", command, pending)
+		}
+	}
+}
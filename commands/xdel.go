@@ -0,0 +1,28 @@
+package commands
+
+import (
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+func (ch *CommandHandler) HandleXDel() {
+	key := ch.Command[1]
+
+	ids := make([]cache.StreamID, 0, len(ch.Command)-2)
+	for _, arg := range ch.Command[2:] {
+		id, err := cache.ParseStreamID(arg, 0)
+		if err != nil {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	deleted, err := ch.MemoryStore.XDel(key, ids...)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.SendInteger(ch.Conn, deleted)
+}
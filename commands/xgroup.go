@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// noGroupError is the NOGROUP reply most consumer group commands send when
+// the key or group does not exist.
+func noGroupError(key, group string) string {
+	return fmt.Sprintf("NOGROUP No such key '%s' or consumer group '%s'", key, group)
+}
+
+// sendGroupError replies with err, naming key and group if it is NOGROUP.
+func (ch *CommandHandler) sendGroupError(err error, key, group string) {
+	if err == cache.ErrNoGroup {
+		response.SendError(ch.Conn, noGroupError(key, group))
+		return
+	}
+	response.SendError(ch.Conn, err.Error())
+}
+
+// parseGroupPosition parses the "id|$ [ENTRIESREAD entries-read]" arguments
+// of XGROUP CREATE and SETID starting at args[i]. CREATE also accepts
+// MKSTREAM, which is reported when allowMkStream is set.
+func parseGroupPosition(args []string, i int, allowMkStream bool) (cache.GroupPosition, bool, string) {
+	var pos cache.GroupPosition
+	if args[i] == "$" {
+		pos.Last = true
+	} else {
+		id, err := cache.ParseStreamID(args[i], 0)
+		if err != nil {
+			return pos, false, err.Error()
+		}
+		pos.ID = id
+	}
+
+	mkStream := false
+	for i++; i < len(args); i++ {
+		switch option := strings.ToUpper(args[i]); {
+		case option == "MKSTREAM" && allowMkStream:
+			mkStream = true
+		case option == "ENTRIESREAD" && i+1 < len(args):
+			n, err := strconv.ParseInt(args[i+1], 10, 64)
+			if err != nil {
+				return pos, false, errNotInteger
+			}
+			pos.EntriesRead = n
+			pos.SetEntriesRead = true
+			i++
+		default:
+			return pos, false, errSyntax
+		}
+	}
+	return pos, mkStream, ""
+}
+
+// XGROUP CREATE key group id|$ [MKSTREAM] [ENTRIESREAD entries-read]
+// XGROUP SETID key group id|$ [ENTRIESREAD entries-read]
+// XGROUP DESTROY key group
+// XGROUP CREATECONSUMER key group consumer
+// XGROUP DELCONSUMER key group consumer
+func (ch *CommandHandler) HandleXGroup() {
+	subcommand := strings.ToUpper(ch.Command[1])
+
+	arity := map[string]int{
+		"CREATE":         -5,
+		"SETID":          -5,
+		"DESTROY":        4,
+		"CREATECONSUMER": 5,
+		"DELCONSUMER":    5,
+	}[subcommand]
+	if arity == 0 {
+		response.SendError(ch.Conn, fmt.Sprintf("ERR unknown subcommand '%s'", ch.Command[1]))
+		return
+	}
+	if (arity > 0 && len(ch.Command) != arity) || (arity < 0 && len(ch.Command) < -arity) {
+		response.SendError(ch.Conn, errWrongArgs(ch.Command[0]+"|"+subcommand))
+		return
+	}
+
+	key, group := ch.Command[2], ch.Command[3]
+	sendNoGroup := func(err error) {
+		if err == cache.ErrNoGroup {
+			response.SendError(ch.Conn, fmt.Sprintf("NOGROUP No such consumer group '%s' for key name '%s'", group, key))
+			return
+		}
+		response.SendError(ch.Conn, err.Error())
+	}
+
+	switch subcommand {
+	case "CREATE", "SETID":
+		pos, mkStream, errMsg := parseGroupPosition(ch.Command, 4, subcommand == "CREATE")
+		if errMsg != "" {
+			response.SendError(ch.Conn, errMsg)
+			return
+		}
+		var err error
+		if subcommand == "CREATE" {
+			err = ch.MemoryStore.XGroupCreate(key, group, pos, mkStream)
+		} else {
+			err = ch.MemoryStore.XGroupSetID(key, group, pos)
+		}
+		if err != nil {
+			sendNoGroup(err)
+			return
+		}
+		response.SendSimpleString(ch.Conn, "OK")
+
+	case "DESTROY":
+		destroyed, err := ch.MemoryStore.XGroupDestroy(key, group)
+		if err != nil {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+		if destroyed {
+			response.SendInteger(ch.Conn, 1)
+		} else {
+			response.SendInteger(ch.Conn, 0)
+		}
+
+	case "CREATECONSUMER":
+		created, err := ch.MemoryStore.XGroupCreateConsumer(key, group, ch.Command[4])
+		if err != nil {
+			sendNoGroup(err)
+			return
+		}
+		if created {
+			response.SendInteger(ch.Conn, 1)
+		} else {
+			response.SendInteger(ch.Conn, 0)
+		}
+
+	case "DELCONSUMER":
+		pending, err := ch.MemoryStore.XGroupDelConsumer(key, group, ch.Command[4])
+		if err != nil {
+			sendNoGroup(err)
+			return
+		}
+		response.SendInteger(ch.Conn, pending)
+	}
+}
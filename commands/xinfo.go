@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// entryReply converts a single entry to RESP, or a null array if it is nil.
+func entryReply(entry *cache.StreamEntry) response.DataType {
+	if entry == nil {
+		return response.ArrayType(nil)
+	}
+	return streamEntries([]cache.StreamEntry{*entry})[0]
+}
+
+// counterReply sends counters upstream may not know, such as a group's lag,
+// as a null bulk string when negative.
+func counterReply(n int64) response.DataType {
+	if n < 0 {
+		return response.NullBulkString{}
+	}
+	return response.IntegerType(n)
+}
+
+// XINFO STREAM key
+// XINFO GROUPS key
+// XINFO CONSUMERS key group
+func (ch *CommandHandler) HandleXInfo() {
+	subcommand := strings.ToUpper(ch.Command[1])
+	key := ch.Command[2]
+
+	switch {
+	case subcommand == "STREAM" && len(ch.Command) == 3:
+		info, err := ch.MemoryStore.XInfoStream(key)
+		if err != nil {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+		response.Send(ch.Conn, response.MapType{
+			{Key: response.BulkStringType("length"), Value: response.IntegerType(info.Length)},
+			{Key: response.BulkStringType("last-generated-id"), Value: response.BulkStringType(info.LastGeneratedID.String())},
+			{Key: response.BulkStringType("max-deleted-entry-id"), Value: response.BulkStringType(info.MaxDeletedEntryID.String())},
+			{Key: response.BulkStringType("entries-added"), Value: response.IntegerType(info.EntriesAdded)},
+			{Key: response.BulkStringType("recorded-first-entry-id"), Value: response.BulkStringType(info.RecordedFirstEntryID.String())},
+			{Key: response.BulkStringType("groups"), Value: response.IntegerType(info.Groups)},
+			{Key: response.BulkStringType("first-entry"), Value: entryReply(info.FirstEntry)},
+			{Key: response.BulkStringType("last-entry"), Value: entryReply(info.LastEntry)},
+		})
+
+	case subcommand == "GROUPS" && len(ch.Command) == 3:
+		groups, err := ch.MemoryStore.XInfoGroups(key)
+		if err != nil {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+		reply := make(response.ArrayType, len(groups))
+		for i, group := range groups {
+			reply[i] = response.MapType{
+				{Key: response.BulkStringType("name"), Value: response.BulkStringType(group.Name)},
+				{Key: response.BulkStringType("consumers"), Value: response.IntegerType(group.Consumers)},
+				{Key: response.BulkStringType("pending"), Value: response.IntegerType(group.Pending)},
+				{Key: response.BulkStringType("last-delivered-id"), Value: response.BulkStringType(group.LastDeliveredID.String())},
+				{Key: response.BulkStringType("entries-read"), Value: counterReply(group.EntriesRead)},
+				{Key: response.BulkStringType("lag"), Value: counterReply(group.Lag)},
+			}
+		}
+		response.SendArray(ch.Conn, reply)
+
+	case subcommand == "CONSUMERS" && len(ch.Command) == 4:
+		group := ch.Command[3]
+		consumers, err := ch.MemoryStore.XInfoConsumers(key, group)
+		if err == cache.ErrNoGroup {
+			response.SendError(ch.Conn, fmt.Sprintf("NOGROUP No such consumer group '%s' for key name '%s'", group, key))
+			return
+		}
+		if err != nil {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+		reply := make(response.ArrayType, len(consumers))
+		for i, consumer := range consumers {
+			inactive := int64(-1)
+			if consumer.Inactive >= 0 {
+				inactive = consumer.Inactive.Milliseconds()
+			}
+			reply[i] = response.MapType{
+				{Key: response.BulkStringType("name"), Value: response.BulkStringType(consumer.Name)},
+				{Key: response.BulkStringType("pending"), Value: response.IntegerType(consumer.Pending)},
+				{Key: response.BulkStringType("idle"), Value: response.IntegerType(consumer.Idle.Milliseconds())},
+				{Key: response.BulkStringType("inactive"), Value: response.IntegerType(inactive)},
+			}
+		}
+		response.SendArray(ch.Conn, reply)
+
+	case subcommand == "STREAM" || subcommand == "GROUPS" || subcommand == "CONSUMERS":
+		response.SendError(ch.Conn, errWrongArgs(ch.Command[0]+"|"+subcommand))
+
+	default:
+		response.SendError(ch.Conn, fmt.Sprintf("ERR unknown subcommand '%s'", ch.Command[1]))
+	}
+}
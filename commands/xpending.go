@@ -0,0 +1,96 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// XPENDING key group [[IDLE min-idle-time] start end count [consumer]]
+func (ch *CommandHandler) HandleXPending() {
+	key, group := ch.Command[1], ch.Command[2]
+
+	if len(ch.Command) == 3 {
+		summary, err := ch.MemoryStore.XPendingSummary(key, group)
+		if err != nil {
+			ch.sendGroupError(err, key, group)
+			return
+		}
+		if summary.Count == 0 {
+			response.SendArray(ch.Conn, response.ArrayType{
+				response.IntegerType(0), response.NullBulkString{}, response.NullBulkString{}, response.ArrayType(nil),
+			})
+			return
+		}
+
+		consumers := make(response.ArrayType, len(summary.Consumers))
+		for i, consumer := range summary.Consumers {
+			consumers[i] = response.BulkStrings([]string{consumer.Name, strconv.Itoa(consumer.Count)})
+		}
+		response.SendArray(ch.Conn, response.ArrayType{
+			response.IntegerType(summary.Count),
+			response.BulkStringType(summary.First.String()),
+			response.BulkStringType(summary.Last.String()),
+			consumers,
+		})
+		return
+	}
+
+	args := ch.Command[3:]
+	var minIdle int64 // milliseconds
+	if strings.ToUpper(args[0]) == "IDLE" {
+		if len(args) < 2 {
+			response.SendError(ch.Conn, errSyntax)
+			return
+		}
+		ms, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			response.SendError(ch.Conn, errNotInteger)
+			return
+		}
+		minIdle = ms
+		args = args[2:]
+	}
+	if len(args) != 3 && len(args) != 4 {
+		response.SendError(ch.Conn, errSyntax)
+		return
+	}
+
+	start, errMsg := parseRangeStart(args[0])
+	if errMsg != "" {
+		response.SendError(ch.Conn, errMsg)
+		return
+	}
+	end, errMsg := parseRangeEnd(args[1])
+	if errMsg != "" {
+		response.SendError(ch.Conn, errMsg)
+		return
+	}
+	count, err := strconv.Atoi(args[2])
+	if err != nil {
+		response.SendError(ch.Conn, errNotInteger)
+		return
+	}
+	consumer := ""
+	if len(args) == 4 {
+		consumer = args[3]
+	}
+
+	entries, err := ch.MemoryStore.XPending(key, group, start, end, max(count, 0), consumer, minIdle)
+	if err != nil {
+		ch.sendGroupError(err, key, group)
+		return
+	}
+
+	reply := make(response.ArrayType, len(entries))
+	for i, entry := range entries {
+		reply[i] = response.ArrayType{
+			response.BulkStringType(entry.ID.String()),
+			response.BulkStringType(entry.Consumer),
+			response.IntegerType(entry.Idle.Milliseconds()),
+			response.IntegerType(entry.DeliveryCount),
+		}
+	}
+	response.SendArray(ch.Conn, reply)
+}
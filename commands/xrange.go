@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// streamEntries converts entries to their RESP form: an array of
+// [id, [field, value, ...]] pairs.
+func streamEntries(entries []cache.StreamEntry) response.ArrayType {
+	reply := make(response.ArrayType, len(entries))
+	for i, entry := range entries {
+		// entries read back from a PEL may have been deleted since, which
+		// upstream reports as a nil field list
+		var fields response.ArrayType
+		if entry.Fields != nil {
+			fields = response.BulkStrings(entry.Fields)
+		}
+		reply[i] = response.ArrayType{response.BulkStringType(entry.ID.String()), fields}
+	}
+	return reply
+}
+
+// parseRangeStart parses the start of an XRANGE interval: "-", an ID, or an
+// ID prefixed with '(' to exclude it.
+func parseRangeStart(arg string) (cache.StreamID, string) {
+	if arg == "-" {
+		return cache.StreamID{}, ""
+	}
+	if exclusive, found := strings.CutPrefix(arg, "("); found {
+		id, err := cache.ParseStreamID(exclusive, 0)
+		if err != nil {
+			return id, err.Error()
+		}
+		next, ok := id.Next()
+		if !ok {
+			return id, "ERR invalid start ID for the interval"
+		}
+		return next, ""
+	}
+	id, err := cache.ParseStreamID(arg, 0)
+	if err != nil {
+		return id, err.Error()
+	}
+	return id, ""
+}
+
+// parseRangeEnd is parseRangeStart for the end of an interval, where "+" is
+// the largest ID and a bare timestamp covers every sequence number.
+func parseRangeEnd(arg string) (cache.StreamID, string) {
+	if arg == "+" {
+		return cache.MaxStreamID, ""
+	}
+	if exclusive, found := strings.CutPrefix(arg, "("); found {
+		id, err := cache.ParseStreamID(exclusive, math.MaxUint64)
+		if err != nil {
+			return id, err.Error()
+		}
+		prev, ok := id.Prev()
+		if !ok {
+			return id, "ERR invalid end ID for the interval"
+		}
+		return prev, ""
+	}
+	id, err := cache.ParseStreamID(arg, math.MaxUint64)
+	if err != nil {
+		return id, err.Error()
+	}
+	return id, ""
+}
+
+// XRANGE key start end [COUNT count]
+func (ch *CommandHandler) HandleXRange() {
+	ch.xrange(ch.Command[2], ch.Command[3], false)
+}
+
+// xrange implements XRANGE and XREVRANGE, which takes end before start.
+func (ch *CommandHandler) xrange(startArg, endArg string, rev bool) {
+	key := ch.Command[1]
+
+	count := -1
+	if len(ch.Command) > 4 {
+		if len(ch.Command) != 6 || strings.ToUpper(ch.Command[4]) != "COUNT" {
+			response.SendError(ch.Conn, errSyntax)
+			return
+		}
+		n, err := strconv.Atoi(ch.Command[5])
+		if err != nil {
+			response.SendError(ch.Conn, errNotInteger)
+			return
+		}
+		count = max(n, 0)
+	}
+
+	start, errMsg := parseRangeStart(startArg)
+	if errMsg != "" {
+		response.SendError(ch.Conn, errMsg)
+		return
+	}
+	end, errMsg := parseRangeEnd(endArg)
+	if errMsg != "" {
+		response.SendError(ch.Conn, errMsg)
+		return
+	}
+
+	if count == 0 {
+		response.SendArray(ch.Conn, response.ArrayType{})
+		return
+	}
+
+	entries, err := ch.MemoryStore.XRange(key, start, end, rev, count)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.SendArray(ch.Conn, streamEntries(entries))
+}
@@ -0,0 +1,156 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// splitStreams finds the STREAMS option at or after args[from] and splits
+// what follows into keys and IDs. ok is false if STREAMS is missing or
+// unbalanced.
+func splitStreams(args []string, from int) (keys, ids []string, streamsAt int, ok bool) {
+	for i := from; i < len(args); i++ {
+		if strings.ToUpper(args[i]) != "STREAMS" {
+			continue
+		}
+		rest := args[i+1:]
+		if len(rest) == 0 || len(rest)%2 != 0 {
+			return nil, nil, i, false
+		}
+		return rest[:len(rest)/2], rest[len(rest)/2:], i, true
+	}
+	return nil, nil, -1, false
+}
+
+// xreadKeys returns the keys of XREAD, which follow STREAMS.
+func xreadKeys(args []string) []string {
+	keys, _, _, _ := splitStreams(args, 1)
+	return keys
+}
+
+// streamsReply is the reply of XREAD and XREADGROUP: a map of each key to its
+// entries for RESP3 clients, and an array of [key, entries] for RESP2 ones.
+type streamsReply response.MapType
+
+func (s streamsReply) Serialize() string {
+	reply := make(response.ArrayType, len(s))
+	for i, pair := range s {
+		reply[i] = response.ArrayType{pair.Key, pair.Value}
+	}
+	return reply.Serialize()
+}
+
+func (s streamsReply) SerializeRESP3() string {
+	return response.MapType(s).SerializeRESP3()
+}
+
+// streamReadReply converts XREAD results to RESP.
+func streamReadReply(results []cache.StreamReadResult) streamsReply {
+	reply := make(streamsReply, len(results))
+	for i, result := range results {
+		reply[i] = response.Pair{Key: response.BulkStringType(result.Key), Value: streamEntries(result.Entries)}
+	}
+	return reply
+}
+
+// XREAD [COUNT count] [BLOCK milliseconds] STREAMS key [key ...] id [id ...]
+func (ch *CommandHandler) HandleXRead() {
+	keys, idArgs, streamsAt, ok := splitStreams(ch.Command, 1)
+	if streamsAt < 0 {
+		response.SendError(ch.Conn, errSyntax)
+		return
+	}
+	if !ok {
+		response.SendError(ch.Conn, "ERR Unbalanced 'xread' list of streams: for each stream key an ID or '$' must be specified.")
+		return
+	}
+
+	count := 0
+	blocking := false
+	var timeout time.Duration
+	for i := 1; i < streamsAt; i++ {
+		switch option := strings.ToUpper(ch.Command[i]); {
+		case option == "COUNT" && i+1 < streamsAt:
+			n, err := strconv.Atoi(ch.Command[i+1])
+			if err != nil {
+				response.SendError(ch.Conn, errNotInteger)
+				return
+			}
+			count = max(n, 0)
+			i++
+		case option == "BLOCK" && i+1 < streamsAt:
+			ms, err := strconv.ParseInt(ch.Command[i+1], 10, 64)
+			if err != nil {
+				response.SendError(ch.Conn, "ERR timeout is not an integer or out of range")
+				return
+			}
+			if ms < 0 {
+				response.SendError(ch.Conn, "ERR timeout is negative")
+				return
+			}
+			blocking = true
+			timeout = time.Duration(ms) * time.Millisecond
+			i++
+		default:
+			response.SendError(ch.Conn, errSyntax)
+			return
+		}
+	}
+
+	// "$" means only entries added after this call, so resolve it up front
+	ids := make([]cache.StreamID, len(keys))
+	for i, arg := range idArgs {
+		var err error
+		if arg == "$" {
+			ids[i], err = ch.MemoryStore.XLastID(keys[i])
+		} else {
+			ids[i], err = cache.ParseStreamID(arg, 0)
+		}
+		if err != nil {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+	}
+
+	if !blocking {
+		results, err := ch.MemoryStore.XRead(keys, ids, count)
+		if err != nil {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+		if len(results) == 0 {
+			response.SendNullArray(ch.Conn)
+			return
+		}
+		response.Send(ch.Conn, streamReadReply(results))
+		return
+	}
+
+	// register before reading so an XADD in between still wakes us
+	wake, cancel := ch.MemoryStore.WaitKeys(keys...)
+	defer cancel()
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	for {
+		results, err := ch.MemoryStore.XRead(keys, ids, count)
+		if err != nil {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+		if len(results) > 0 {
+			response.Send(ch.Conn, streamReadReply(results))
+			return
+		}
+		if !ch.block(wake, deadline) {
+			response.SendNullArray(ch.Conn)
+			return
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// xreadgroupKeys returns the keys of XREADGROUP, which follow STREAMS after
+// the group and consumer names.
+func xreadgroupKeys(args []string) []string {
+	keys, _, _, _ := splitStreams(args, 4)
+	return keys
+}
+
+// XREADGROUP GROUP group consumer [COUNT count] [BLOCK milliseconds] [NOACK] STREAMS key [key ...] id [id ...]
+func (ch *CommandHandler) HandleXReadGroup() {
+	if strings.ToUpper(ch.Command[1]) != "GROUP" {
+		response.SendError(ch.Conn, errSyntax)
+		return
+	}
+	group, consumer := ch.Command[2], ch.Command[3]
+
+	keys, idArgs, streamsAt, ok := splitStreams(ch.Command, 4)
+	if streamsAt < 0 {
+		response.SendError(ch.Conn, errSyntax)
+		return
+	}
+	if !ok {
+		response.SendError(ch.Conn, "ERR Unbalanced 'xreadgroup' list of streams: for each stream key an ID or '>' must be specified.")
+		return
+	}
+
+	count := 0
+	blocking := false
+	noAck := false
+	var timeout time.Duration
+	for i := 4; i < streamsAt; i++ {
+		switch option := strings.ToUpper(ch.Command[i]); {
+		case option == "COUNT" && i+1 < streamsAt:
+			n, err := strconv.Atoi(ch.Command[i+1])
+			if err != nil {
+				response.SendError(ch.Conn, errNotInteger)
+				return
+			}
+			count = max(n, 0)
+			i++
+		case option == "BLOCK" && i+1 < streamsAt:
+			ms, err := strconv.ParseInt(ch.Command[i+1], 10, 64)
+			if err != nil {
+				response.SendError(ch.Conn, "ERR timeout is not an integer or out of range")
+				return
+			}
+			if ms < 0 {
+				response.SendError(ch.Conn, "ERR timeout is negative")
+				return
+			}
+			blocking = true
+			timeout = time.Duration(ms) * time.Millisecond
+			i++
+		case option == "NOACK":
+			noAck = true
+		default:
+			response.SendError(ch.Conn, errSyntax)
+			return
+		}
+	}
+
+	ids := make([]cache.GroupReadID, len(keys))
+	for i, arg := range idArgs {
+		switch arg {
+		case ">":
+			ids[i].New = true
+		case "$":
+			response.SendError(ch.Conn, "ERR The $ ID is meaningless in the context of XREADGROUP: you want to read the history of this consumer by specifying a proper ID, or use the > ID to get new messages. The $ ID would just return an empty result set.")
+			return
+		default:
+			id, err := cache.ParseStreamID(arg, 0)
+			if err != nil {
+				response.SendError(ch.Conn, err.Error())
+				return
+			}
+			ids[i].ID = id
+		}
+	}
+
+	// register before reading so an XADD in between still wakes us
+	var wake <-chan struct{}
+	if blocking {
+		var cancel func()
+		wake, cancel = ch.MemoryStore.WaitKeys(keys...)
+		defer cancel()
+	}
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	for {
+		results, err := ch.MemoryStore.XReadGroup(group, consumer, keys, ids, count, noAck)
+		var noGroup *cache.NoGroupError
+		if errors.As(err, &noGroup) {
+			response.SendError(ch.Conn, fmt.Sprintf("NOGROUP No such key '%s' or consumer group '%s' in XREADGROUP with GROUP option", noGroup.Key, group))
+			return
+		}
+		if err != nil {
+			response.SendError(ch.Conn, err.Error())
+			return
+		}
+		// reading history always replies, so only reads of new entries block
+		if len(results) > 0 {
+			response.Send(ch.Conn, streamReadReply(results))
+			return
+		}
+		if !blocking || !ch.block(wake, deadline) {
+			response.SendNullArray(ch.Conn)
+			return
+		}
+	}
+}
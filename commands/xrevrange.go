@@ -0,0 +1,6 @@
+package commands
+
+// XREVRANGE key end start [COUNT count]
+func (ch *CommandHandler) HandleXRevRange() {
+	ch.xrange(ch.Command[3], ch.Command[2], true)
+}
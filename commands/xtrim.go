@@ -0,0 +1,34 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// XTRIM key MAXLEN|MINID [=|~] threshold [LIMIT count]
+func (ch *CommandHandler) HandleXTrim() {
+	key := ch.Command[1]
+
+	if strategy := strings.ToUpper(ch.Command[2]); strategy != "MAXLEN" && strategy != "MINID" {
+		response.SendError(ch.Conn, errSyntax)
+		return
+	}
+	trim, next, errMsg := parseStreamTrim(ch.Command, 2)
+	if errMsg != "" {
+		response.SendError(ch.Conn, errMsg)
+		return
+	}
+	if next != len(ch.Command) {
+		response.SendError(ch.Conn, errSyntax)
+		return
+	}
+
+	removed, err := ch.MemoryStore.XTrim(key, trim)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.SendInteger(ch.Conn, removed)
+}
@@ -0,0 +1,97 @@
+package commands
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// parseScore parses a sorted set score. Infinities are allowed, NaN is not.
+func parseScore(s string) (float64, bool) {
+	score, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(score) {
+		return 0, false
+	}
+	return score, true
+}
+
+// ZADD key [NX|XX] [GT|LT] [CH] [INCR] score member [score member ...]
+func (ch *CommandHandler) HandleZAdd() {
+	key := ch.Command[1]
+
+	var flags cache.ZAddFlags
+	changed := false
+
+	i := 2
+options:
+	for ; i < len(ch.Command); i++ {
+		switch strings.ToUpper(ch.Command[i]) {
+		case "NX":
+			flags.NX = true
+		case "XX":
+			flags.XX = true
+		case "GT":
+			flags.GT = true
+		case "LT":
+			flags.LT = true
+		case "CH":
+			changed = true
+		case "INCR":
+			flags.Incr = true
+		default:
+			break options
+		}
+	}
+
+	pairs := ch.Command[i:]
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		response.SendError(ch.Conn, errSyntax)
+		return
+	}
+	if flags.NX && flags.XX {
+		response.SendError(ch.Conn, "ERR XX and NX options at the same time are not compatible")
+		return
+	}
+	if (flags.GT && flags.NX) || (flags.LT && flags.NX) || (flags.GT && flags.LT) {
+		response.SendError(ch.Conn, "ERR GT, LT, and/or NX options at the same time are not compatible")
+		return
+	}
+	if flags.Incr && len(pairs) > 2 {
+		response.SendError(ch.Conn, "ERR INCR option supports a single increment-element pair")
+		return
+	}
+
+	members := make([]cache.ZMember, 0, len(pairs)/2)
+	for j := 0; j < len(pairs); j += 2 {
+		score, ok := parseScore(pairs[j])
+		if !ok {
+			response.SendError(ch.Conn, errNotFloat)
+			return
+		}
+		members = append(members, cache.ZMember{Member: pairs[j+1], Score: score})
+	}
+
+	result, err := ch.MemoryStore.ZAdd(key, flags, members...)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	if flags.Incr {
+		if !result.Applied {
+			response.SendNullString(ch.Conn)
+			return
+		}
+		response.SendDouble(ch.Conn, result.Score)
+		return
+	}
+
+	if changed {
+		response.SendInteger(ch.Conn, result.Added+result.Updated)
+		return
+	}
+	response.SendInteger(ch.Conn, result.Added)
+}
@@ -0,0 +1,25 @@
+package commands
+
+import (
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+func (ch *CommandHandler) HandleZIncrBy() {
+	key := ch.Command[1]
+	member := ch.Command[3]
+
+	increment, ok := parseScore(ch.Command[2])
+	if !ok {
+		response.SendError(ch.Conn, errNotFloat)
+		return
+	}
+
+	result, err := ch.MemoryStore.ZAdd(key, cache.ZAddFlags{Incr: true}, cache.ZMember{Member: member, Score: increment})
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.SendDouble(ch.Conn, result.Score)
+}
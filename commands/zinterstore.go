@@ -0,0 +1,5 @@
+package commands
+
+func (ch *CommandHandler) HandleZInterStore() {
+	ch.zstore(true)
+}
@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"strconv"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+func (ch *CommandHandler) HandleZPopMin() {
+	ch.zpop(false)
+}
+
+// zpop implements ZPOPMIN and ZPOPMAX key [count]. RESP2 clients always get a
+// flat array of members and scores; RESP3 clients get a pair for each member
+// when a count is given, and the member and score alone when it is not.
+func (ch *CommandHandler) zpop(max bool) {
+	if len(ch.Command) > 3 {
+		response.SendError(ch.Conn, errSyntax)
+		return
+	}
+
+	key := ch.Command[1]
+
+	count := 1
+	if len(ch.Command) == 3 {
+		n, err := strconv.Atoi(ch.Command[2])
+		if err != nil || n < 0 {
+			response.SendError(ch.Conn, errNotPositive)
+			return
+		}
+		count = n
+	}
+
+	popped, err := ch.MemoryStore.ZPop(key, count, max)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	if len(ch.Command) == 2 && len(popped) == 1 {
+		response.Send(ch.Conn, response.ArrayType{response.BulkStringType(popped[0].Member), response.DoubleType(popped[0].Score)})
+		return
+	}
+	sendZMembers(ch, popped, true)
+}
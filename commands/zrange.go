@@ -0,0 +1,165 @@
+package commands
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+type zrangeKind int
+
+const (
+	zrangeByRank zrangeKind = iota
+	zrangeByScore
+	zrangeByLex
+)
+
+// parseScoreBound parses one end of a score range: a float, optionally
+// prefixed with '(' to make it exclusive.
+func parseScoreBound(s string) (float64, bool, bool) {
+	exclusive := strings.HasPrefix(s, "(")
+	if exclusive {
+		s = s[1:]
+	}
+	score, ok := parseScore(s)
+	return score, exclusive, ok
+}
+
+// parseLexBound parses one end of a lexicographical range: "-", "+", or a
+// value prefixed with '[' (inclusive) or '(' (exclusive).
+func parseLexBound(s string) (cache.LexBound, bool) {
+	switch {
+	case s == "-":
+		return cache.LexBound{Inf: -1}, true
+	case s == "+":
+		return cache.LexBound{Inf: 1}, true
+	case strings.HasPrefix(s, "["):
+		return cache.LexBound{Value: s[1:]}, true
+	case strings.HasPrefix(s, "("):
+		return cache.LexBound{Value: s[1:], Exclusive: true}, true
+	default:
+		return cache.LexBound{}, false
+	}
+}
+
+// sendZMembers replies with members, paired with their scores when
+// withScores is set.
+func sendZMembers(ch *CommandHandler, members []cache.ZMember, withScores bool) {
+	if withScores {
+		response.Send(ch.Conn, zmemberPairs(members))
+		return
+	}
+	reply := make([]string, len(members))
+	for i, m := range members {
+		reply[i] = m.Member
+	}
+	response.SendStringArray(ch.Conn, reply)
+}
+
+// zmemberPairs pairs members with their scores, which RESP3 clients get as
+// doubles.
+func zmemberPairs(members []cache.ZMember) response.PairsType {
+	pairs := make(response.PairsType, len(members))
+	for i, m := range members {
+		pairs[i] = response.Pair{Key: response.BulkStringType(m.Member), Value: response.DoubleType(m.Score)}
+	}
+	return pairs
+}
+
+// ZRANGE key start stop [BYSCORE|BYLEX] [REV] [LIMIT offset count] [WITHSCORES]
+func (ch *CommandHandler) HandleZRange() {
+	ch.zrange(zrangeByRank, false, true)
+}
+
+// zrange implements ZRANGE and the older range commands it replaces. The
+// older commands fix kind and rev and only accept LIMIT and WITHSCORES, which
+// is what allowKindOptions turns off.
+func (ch *CommandHandler) zrange(kind zrangeKind, rev bool, allowKindOptions bool) {
+	key := ch.Command[1]
+
+	withScores := false
+	limited := false
+	offset, count := 0, -1
+
+	for i := 4; i < len(ch.Command); i++ {
+		switch option := strings.ToUpper(ch.Command[i]); {
+		case option == "WITHSCORES":
+			withScores = true
+		case option == "LIMIT" && i+2 < len(ch.Command):
+			var err1, err2 error
+			offset, err1 = strconv.Atoi(ch.Command[i+1])
+			count, err2 = strconv.Atoi(ch.Command[i+2])
+			if err1 != nil || err2 != nil {
+				response.SendError(ch.Conn, errNotInteger)
+				return
+			}
+			limited = true
+			i += 2
+		case option == "BYSCORE" && allowKindOptions:
+			kind = zrangeByScore
+		case option == "BYLEX" && allowKindOptions:
+			kind = zrangeByLex
+		case option == "REV" && allowKindOptions:
+			rev = true
+		default:
+			response.SendError(ch.Conn, errSyntax)
+			return
+		}
+	}
+
+	if limited && kind == zrangeByRank {
+		response.SendError(ch.Conn, "ERR syntax error, LIMIT is only supported in combination with either BYSCORE or BYLEX")
+		return
+	}
+	if withScores && kind == zrangeByLex {
+		response.SendError(ch.Conn, "ERR syntax error, WITHSCORES not supported in combination with BYLEX")
+		return
+	}
+
+	// reversed score and lex ranges are given as max then min
+	minArg, maxArg := ch.Command[2], ch.Command[3]
+	if rev && kind != zrangeByRank {
+		minArg, maxArg = maxArg, minArg
+	}
+
+	var members []cache.ZMember
+	var err error
+	switch kind {
+	case zrangeByRank:
+		start, err1 := strconv.Atoi(minArg)
+		stop, err2 := strconv.Atoi(maxArg)
+		if err1 != nil || err2 != nil {
+			response.SendError(ch.Conn, errNotInteger)
+			return
+		}
+		members, err = ch.MemoryStore.ZRangeByRank(key, start, stop, rev)
+	case zrangeByScore:
+		var r cache.ScoreRange
+		var ok1, ok2 bool
+		r.Min, r.MinEx, ok1 = parseScoreBound(minArg)
+		r.Max, r.MaxEx, ok2 = parseScoreBound(maxArg)
+		if !ok1 || !ok2 {
+			response.SendError(ch.Conn, "ERR min or max is not a float")
+			return
+		}
+		members, err = ch.MemoryStore.ZRangeByScore(key, r, rev, offset, count)
+	case zrangeByLex:
+		var r cache.LexRange
+		var ok1, ok2 bool
+		r.Min, ok1 = parseLexBound(minArg)
+		r.Max, ok2 = parseLexBound(maxArg)
+		if !ok1 || !ok2 {
+			response.SendError(ch.Conn, "ERR min or max not valid string range item")
+			return
+		}
+		members, err = ch.MemoryStore.ZRangeByLex(key, r, rev, offset, count)
+	}
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	sendZMembers(ch, members, withScores)
+}
@@ -0,0 +1,5 @@
+package commands
+
+func (ch *CommandHandler) HandleZRangeByLex() {
+	ch.zrange(zrangeByLex, false, false)
+}
@@ -0,0 +1,5 @@
+package commands
+
+func (ch *CommandHandler) HandleZRangeByScore() {
+	ch.zrange(zrangeByScore, false, false)
+}
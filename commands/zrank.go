@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"strings"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+func (ch *CommandHandler) HandleZRank() {
+	ch.rank(false)
+}
+
+// rank implements ZRANK and ZREVRANK key member [WITHSCORE].
+func (ch *CommandHandler) rank(rev bool) {
+	if len(ch.Command) > 4 {
+		response.SendError(ch.Conn, errWrongArgs(ch.Command[0]))
+		return
+	}
+
+	key := ch.Command[1]
+	member := ch.Command[2]
+
+	withScore := false
+	if len(ch.Command) == 4 {
+		if strings.ToUpper(ch.Command[3]) != "WITHSCORE" {
+			response.SendError(ch.Conn, errSyntax)
+			return
+		}
+		withScore = true
+	}
+
+	rank, ok, err := ch.MemoryStore.ZRank(key, member, rev)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	if !withScore {
+		if !ok {
+			response.SendNullString(ch.Conn)
+			return
+		}
+		response.SendInteger(ch.Conn, rank)
+		return
+	}
+
+	if !ok {
+		response.SendNullArray(ch.Conn)
+		return
+	}
+	score, _, _ := ch.MemoryStore.ZScore(key, member)
+	response.SendArray(ch.Conn, response.ArrayType{
+		response.IntegerType(rank),
+		response.DoubleType(score),
+	})
+}
@@ -0,0 +1,5 @@
+package commands
+
+func (ch *CommandHandler) HandleZRevRange() {
+	ch.zrange(zrangeByRank, true, false)
+}
@@ -0,0 +1,5 @@
+package commands
+
+func (ch *CommandHandler) HandleZRevRangeByLex() {
+	ch.zrange(zrangeByLex, true, false)
+}
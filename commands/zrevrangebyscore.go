@@ -0,0 +1,5 @@
+package commands
+
+func (ch *CommandHandler) HandleZRevRangeByScore() {
+	ch.zrange(zrangeByScore, true, false)
+}
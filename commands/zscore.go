@@ -0,0 +1,22 @@
+package commands
+
+import (
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+func (ch *CommandHandler) HandleZScore() {
+	key := ch.Command[1]
+	member := ch.Command[2]
+
+	score, ok, err := ch.MemoryStore.ZScore(key, member)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+	if !ok {
+		response.SendNullString(ch.Conn)
+		return
+	}
+
+	response.SendDouble(ch.Conn, score)
+}
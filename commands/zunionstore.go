@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+func (ch *CommandHandler) HandleZUnionStore() {
+	ch.zstore(false)
+}
+
+// zstoreKeys returns the destination and input keys of ZUNIONSTORE and
+// ZINTERSTORE: destination numkeys key [key ...].
+func zstoreKeys(args []string) []string {
+	numKeys, err := strconv.Atoi(args[2])
+	if err != nil || numKeys < 1 || numKeys > len(args)-3 {
+		return []string{args[1]}
+	}
+	return append([]string{args[1]}, args[3:3+numKeys]...)
+}
+
+// zstore implements ZUNIONSTORE and ZINTERSTORE:
+// destination numkeys key [key ...] [WEIGHTS weight [weight ...]] [AGGREGATE SUM|MIN|MAX]
+func (ch *CommandHandler) zstore(inter bool) {
+	dest := ch.Command[1]
+
+	numKeys, err := strconv.Atoi(ch.Command[2])
+	if err != nil {
+		response.SendError(ch.Conn, errNotInteger)
+		return
+	}
+	if numKeys < 1 {
+		response.SendError(ch.Conn, "ERR at least 1 input key is needed for '"+strings.ToLower(ch.Command[0])+"' command")
+		return
+	}
+	if numKeys > len(ch.Command)-3 {
+		response.SendError(ch.Conn, errSyntax)
+		return
+	}
+	keys := ch.Command[3 : 3+numKeys]
+
+	weights := make([]float64, numKeys)
+	for i := range weights {
+		weights[i] = 1
+	}
+	aggregate := cache.AggregateSum
+
+	for i := 3 + numKeys; i < len(ch.Command); i++ {
+		switch option := strings.ToUpper(ch.Command[i]); {
+		case option == "WEIGHTS" && i+numKeys < len(ch.Command):
+			for j := range weights {
+				weight, err := strconv.ParseFloat(ch.Command[i+1+j], 64)
+				if err != nil || math.IsNaN(weight) {
+					response.SendError(ch.Conn, "ERR weight value is not a float")
+					return
+				}
+				weights[j] = weight
+			}
+			i += numKeys
+		case option == "AGGREGATE" && i+1 < len(ch.Command):
+			switch strings.ToUpper(ch.Command[i+1]) {
+			case "SUM":
+				aggregate = cache.AggregateSum
+			case "MIN":
+				aggregate = cache.AggregateMin
+			case "MAX":
+				aggregate = cache.AggregateMax
+			default:
+				response.SendError(ch.Conn, errSyntax)
+				return
+			}
+			i++
+		default:
+			response.SendError(ch.Conn, errSyntax)
+			return
+		}
+	}
+
+	size, err := ch.MemoryStore.ZStore(dest, keys, weights, aggregate, inter)
+	if err != nil {
+		response.SendError(ch.Conn, err.Error())
+		return
+	}
+
+	response.SendInteger(ch.Conn, size)
+}
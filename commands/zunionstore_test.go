@@ -0,0 +1,29 @@
+package commands
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/pubsub"
+)
+
+func TestZStoreNumKeys(t *testing.T) {
+	store := cache.NewValueStore(time.Minute)
+	conn := &recordConn{}
+	client := NewClient(conn, pubsub.NewBroker())
+
+	const syntax = "-" + errSyntax + "\r\n"
+	for _, command := range [][]string{
+		{"ZUNIONSTORE", "d", "9223372036854775806", "a"},
+		{"ZINTERSTORE", "d", "9223372036854775807", "a"},
+		{"ZUNIONSTORE", "d", "2", "a"},
+	} {
+		if got := reply(client, conn, store, "", command...); got != syntax {
+			t.Errorf("%v failed. Expected: %q, got: %q", command, syntax, got)
+		}
+		if keys := zstoreKeys(command); len(keys) != 1 || keys[0] != "d" {
+			t.Errorf("zstoreKeys() failed for %v. Expected: [d], got: %v", command, keys)
+		}
+	}
+}
@@ -1,13 +1,67 @@
 package config
 
 import (
+	"fmt"
+	"math"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
 	RedisPassword *string
 	RedisPort     *int
+
+	// Persistence, named after upstream's dir, dbfilename and save directives.
+	// Save is a list of "<seconds> <changes>" pairs; empty disables saving.
+	Dir        string
+	DBFilename string
+	Save       string
+
+	// The append only file, after appendonly, appendfilename and appendfsync.
+	AppendOnly     bool
+	AppendFilename string
+	AppendFsync    string
+
+	// Replication, after replicaof, masterauth, replica-read-only and
+	// repl-backlog-size. ReplicaOf is "<host> <port>", or empty.
+	ReplicaOf       string
+	MasterAuth      string
+	ReplicaReadOnly bool
+	ReplBacklogSize int
+
+	// Cluster mode, after cluster-enabled and cluster-announce-ip.
+	// ClusterNodes optionally lays out the cluster statically, as pairs of
+	// "<host>:<port> <slots>" such as "127.0.0.1:7000 0-8191 127.0.0.1:7001
+	// 8192-16383"; without it nodes start empty and are joined with CLUSTER
+	// MEET and ADDSLOTS.
+	ClusterEnabled    bool
+	ClusterAnnounceIP string
+	ClusterNodes      string
+
+	// LuaTimeLimit is how long a script runs before other clients are told
+	// the server is busy and SCRIPT KILL may stop it, after lua-time-limit.
+	LuaTimeLimit time.Duration
+
+	// Network is how connections are served: "goroutine", a goroutine for
+	// each, or "epoll", an event loop for all of them, on Linux only.
+	Network string
+
+	// ProtoMaxBulkLen is the longest argument a client may send, in bytes,
+	// after proto-max-bulk-len.
+	ProtoMaxBulkLen int
+
+	// Hz is how many times a second expired keys are sampled and removed,
+	// after hz, from 1 to 500.
+	Hz int
+
+	// The memory limit, after maxmemory, maxmemory-policy and
+	// maxmemory-samples. MaxMemory is a number of bytes, 0 for no limit,
+	// which may be given with a unit, as in "100mb"; see ParseMemory.
+	MaxMemory        string
+	MaxMemoryPolicy  string
+	MaxMemorySamples int
 }
 
 func LoadConfig() *Config {
@@ -29,5 +83,82 @@ func LoadConfig() *Config {
 		cfg.RedisPort = nil
 	}
 
+	cfg.Dir = lookupDefault("REDIS_DIR", ".")
+	cfg.DBFilename = lookupDefault("REDIS_DBFILENAME", "dump.rdb")
+	cfg.Save = lookupDefault("REDIS_SAVE", "3600 1 300 100 60 10000")
+	cfg.AppendOnly = lookupDefault("REDIS_APPENDONLY", "no") == "yes"
+	cfg.AppendFilename = lookupDefault("REDIS_APPENDFILENAME", "appendonly.aof")
+	cfg.AppendFsync = lookupDefault("REDIS_APPENDFSYNC", "everysec")
+	cfg.ReplicaOf = lookupDefault("REDIS_REPLICAOF", "")
+	cfg.MasterAuth = lookupDefault("REDIS_MASTERAUTH", "")
+	cfg.ReplicaReadOnly = lookupDefault("REDIS_REPLICA_READ_ONLY", "yes") == "yes"
+	cfg.ReplBacklogSize = 1 << 20
+	if size, err := strconv.Atoi(lookupDefault("REDIS_REPL_BACKLOG_SIZE", "")); err == nil && size > 0 {
+		cfg.ReplBacklogSize = size
+	}
+
+	cfg.ClusterEnabled = lookupDefault("REDIS_CLUSTER_ENABLED", "no") == "yes"
+	cfg.ClusterAnnounceIP = lookupDefault("REDIS_CLUSTER_ANNOUNCE_IP", "127.0.0.1")
+	cfg.ClusterNodes = lookupDefault("REDIS_CLUSTER_NODES", "")
+	cfg.LuaTimeLimit = 5 * time.Second
+	if ms, err := strconv.Atoi(lookupDefault("REDIS_LUA_TIME_LIMIT", "")); err == nil && ms > 0 {
+		cfg.LuaTimeLimit = time.Duration(ms) * time.Millisecond
+	}
+
+	cfg.Network = lookupDefault("REDIS_NETWORK", "goroutine")
+	cfg.ProtoMaxBulkLen = 512 * 1024 * 1024
+	if size, err := strconv.Atoi(lookupDefault("REDIS_PROTO_MAX_BULK_LEN", "")); err == nil && size > 0 {
+		cfg.ProtoMaxBulkLen = size
+	}
+	cfg.Hz = 10
+	if hz, err := strconv.Atoi(lookupDefault("REDIS_HZ", "")); err == nil {
+		cfg.Hz = min(max(hz, 1), 500)
+	}
+	cfg.MaxMemory = lookupDefault("REDIS_MAXMEMORY", "0")
+	cfg.MaxMemoryPolicy = lookupDefault("REDIS_MAXMEMORY_POLICY", "noeviction")
+	cfg.MaxMemorySamples = 5
+	if samples, err := strconv.Atoi(lookupDefault("REDIS_MAXMEMORY_SAMPLES", "")); err == nil && samples > 0 {
+		cfg.MaxMemorySamples = samples
+	}
+
 	return &cfg
 }
+
+// ParseMemory parses a size in bytes, with an optional unit as upstream's
+// config file takes: k, kb, m, mb, g or gb, in any case, where k is 1000 and
+// kb 1024. Like upstream, it refuses anything else rather than take it for
+// no limit.
+func ParseMemory(value string) (int64, error) {
+	s := strings.ToLower(strings.TrimSpace(value))
+	units := []struct {
+		suffix string
+		scale  int64
+	}{
+		{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30},
+		{"k", 1000}, {"m", 1000 * 1000}, {"g", 1000 * 1000 * 1000},
+	}
+	scale := int64(1)
+	for _, unit := range units {
+		if number, ok := strings.CutSuffix(s, unit.suffix); ok {
+			s, scale = number, unit.scale
+			break
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid memory value %q", value)
+	}
+	if n > math.MaxInt64/scale {
+		return 0, fmt.Errorf("memory value %q is too large", value)
+	}
+	return n * scale, nil
+}
+
+// lookupDefault returns the environment variable key, or def if it is unset.
+// A variable set to the empty string is kept, so REDIS_SAVE="" disables saving.
+func lookupDefault(key, def string) string {
+	if value, exists := os.LookupEnv(key); exists {
+		return value
+	}
+	return def
+}
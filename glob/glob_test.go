@@ -0,0 +1,35 @@
+package glob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, str string
+		expected     bool
+	}{
+		{"*", "", true},
+		{"*", "anything", true},
+		{"h?llo", "hello", true},
+		{"h?llo", "hllo", false},
+		{"h*llo", "heeeello", true},
+		{"h*llo", "hello world", false},
+		{"h[ae]llo", "hallo", true},
+		{"h[ae]llo", "hillo", false},
+		{"h[^e]llo", "hallo", true},
+		{"h[^e]llo", "hello", false},
+		{"h[a-b]llo", "hbllo", true},
+		{"h[b-a]llo", "hallo", true},
+		{"h[a-b]llo", "hcllo", false},
+		{`h\*llo`, "h*llo", true},
+		{`h\*llo`, "hello", false},
+		{"news.*", "news.sport", true},
+		{"news.*", "news/sport", false},
+		{"a/*/c", "a/b/c", true},
+		{"**b", "aab", true},
+	}
+	for _, tt := range tests {
+		if actual := Match(tt.pattern, tt.str); actual != tt.expected {
+			t.Errorf("Match(%q, %q) failed. Expected: %v, got: %v", tt.pattern, tt.str, tt.expected, actual)
+		}
+	}
+}
@@ -2,6 +2,8 @@ module github.com/Ryan-DL/go-redis-server
 
 go 1.23.4
 
+require github.com/yuin/gopher-lua v1.1.2
+
 require (
 	dario.cat/mergo v1.0.0 // indirect
 	github.com/Azure/go-ansiterm v0.0.0-20210617225240-d185dfc1b5a1 // indirect
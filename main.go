@@ -1,159 +1,191 @@
 package main
 
 import (
-	"bufio"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/cluster"
 	"github.com/Ryan-DL/go-redis-server/commands"
 	"github.com/Ryan-DL/go-redis-server/config"
+	"github.com/Ryan-DL/go-redis-server/network"
+	"github.com/Ryan-DL/go-redis-server/persist"
+	"github.com/Ryan-DL/go-redis-server/protocol"
+	"github.com/Ryan-DL/go-redis-server/pubsub"
+	"github.com/Ryan-DL/go-redis-server/replication"
 	"github.com/Ryan-DL/go-redis-server/response"
+	"github.com/Ryan-DL/go-redis-server/scripting"
 )
 
-func handleConnection(conn net.Conn, memoryStore *cache.ValueStore, password string) {
-	defer func() {
-		log.Printf("Closing connection from %s", conn.RemoteAddr())
-		conn.Close()
-	}()
+// server holds what every connection shares.
+type server struct {
+	store       *cache.ValueStore
+	broker      *pubsub.Broker
+	saver       *persist.Saver
+	aof         *persist.AOF
+	replication *replication.Node
+	cluster     *cluster.Cluster
+	scripts     *scripting.Engine
+	propagator  commands.Propagator
+	password    string
+}
 
-	reader := bufio.NewReader(conn)
+// Connect starts serving a client that connected.
+func (s *server) Connect(conn net.Conn) network.Conn {
+	log.Printf("Accepted connection from %s", conn.RemoteAddr())
+	// replies go through the client so they stay ordered with pub/sub pushes
+	return &connection{server: s, conn: conn, client: commands.NewClient(conn, s.broker)}
+}
 
-	authenticated := false
+// connection is a client's connection, served by the network layer.
+type connection struct {
+	*server
+	conn   net.Conn
+	client *commands.Client
+}
 
+func (c *connection) Serve(reader *protocol.Reader) error {
 	for {
-		prefix, err := reader.ReadByte()
+		args, err := reader.ReadCommand()
 		if err != nil {
-			if err != io.EOF {
-				log.Printf("Error reading prefix from %s: %v", conn.RemoteAddr(), err)
+			var protoErr protocol.Error
+			if errors.As(err, &protoErr) {
+				response.SendError(c.client, err.Error())
+			} else if err != io.EOF && err != io.ErrUnexpectedEOF {
+				log.Printf("Error reading command from %s: %v", c.conn.RemoteAddr(), err)
 			}
-			return
+			return err
 		}
 
-		if prefix != '*' { // all redis commands are of an array type
-			response.SendError(conn, "Protocol error: expected '*', got '"+string(prefix)+"'")
-			return
-		}
+		// the arguments are reused by the next read, and commands keep them
+		c.dispatch(c.client, c.conn, reader, protocol.Strings(args))
 
-		// extract number of args
-		line, err := reader.ReadString('\n')
-		if err != nil {
-			response.SendError(conn, "Protocol error: unable to read array length for command")
-			return
-		}
-		line = strings.TrimSpace(line)
-		numArgs, err := strconv.Atoi(line)
-		if err != nil {
-			response.SendError(conn, "Protocol error: invalid array length")
-			return
+		// replies are sent once every command the client pipelined has run
+		if reader.Buffered() == 0 {
+			if err := c.client.Flush(); err != nil {
+				log.Printf("Error sending replies to %s: %v", c.conn.RemoteAddr(), err)
+				return err
+			}
+			return nil
 		}
+	}
+}
 
-		command := make([]string, 0, numArgs)
-		for i := 0; i < numArgs; i++ {
-			bulkPrefix, err := reader.ReadByte()
-			if err != nil {
-				response.SendError(conn, "Protocol error: unable to read bulk string prefix")
-				return
-			}
-			if bulkPrefix != '$' {
-				response.SendError(conn, "Protocol error: expected '$', got '"+string(bulkPrefix)+"'")
-				return
-			}
+func (c *connection) Close() {
+	log.Printf("Closing connection from %s", c.conn.RemoteAddr())
+	c.replication.Disconnected(c.conn)
+	c.client.Close()
+}
 
-			bulkLenStr, err := reader.ReadString('\n')
-			if err != nil {
-				response.SendError(conn, "Protocol error: unable to read bulk string length")
-				return
-			}
-			bulkLenStr = strings.TrimSpace(bulkLenStr)
-			bulkLen, err := strconv.Atoi(bulkLenStr)
-			if err != nil {
-				response.SendError(conn, "Protocol error: invalid bulk string length")
-				return
-			}
+// dispatch runs a command read from a client's connection.
+func (s *server) dispatch(client *commands.Client, conn net.Conn, reader *protocol.Reader, command []string) {
+	commandHandler := commands.NewCommandHandler(client, command, s.store)
+	commandHandler.Client = client
+	commandHandler.Password = s.password
+	commandHandler.WatchClose = func() (<-chan struct{}, func()) {
+		return watchClose(conn, reader)
+	}
+	commandHandler.Saver = s.saver
+	commandHandler.AOF = s.aof
+	commandHandler.Replication = s.replication
+	commandHandler.Cluster = s.cluster
+	commandHandler.Scripts = s.scripts
+	commandHandler.Propagator = s.propagator
+	commandHandler.Dispatch()
+}
 
-			// read the actual string
-			buf := make([]byte, bulkLen+2) // +2 for \r\n
-			_, err = io.ReadFull(reader, buf)
-			if err != nil {
-				response.SendError(conn, "Protocol error: unable to read bulk string")
-				return
-			}
-			arg := string(buf[:bulkLen])
-			command = append(command, arg)
-		}
+// watchClose lets a blocked command, such as XREAD BLOCK, notice the client
+// hanging up. It peeks at the connection in the background; stop interrupts
+// the peek with a read deadline and must be called before the connection loop
+// reads again. Pipelined data that arrives meanwhile stays in the reader.
+func watchClose(conn net.Conn, reader *protocol.Reader) (<-chan struct{}, func()) {
+	closed := make(chan struct{})
+	done := make(chan struct{})
 
-		// if the connection is not authenticated, and the password is set.
-		if !authenticated && password != "" {
-			if len(command) == 2 && strings.ToUpper(command[0]) == "AUTH" {
-				if command[1] == password {
-					authenticated = true
-					response.SendSimpleString(conn, "OK")
-				} else {
-					response.SendError(conn, "ERR invalid password")
-				}
-				continue
-			} else {
-				response.SendError(conn, "NOAUTH Authentication required.")
-				continue
+	go func() {
+		defer close(done)
+		if _, err := reader.Peek(1); err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+				return
 			}
+			close(closed)
 		}
+	}()
 
-		// handle other commands after authentication
-		commandHandler := commands.NewCommandHandler(conn, command, memoryStore)
-		handleCommand(commandHandler)
-	}
-}
-
-func handleCommand(cmd *commands.CommandHandler) {
-	cmd.Command[0] = strings.ToUpper(cmd.Command[0])
-
-	switch command := cmd.Command[0]; command {
-	case "PING":
-		fmt.Printf("DEBUG: cmd.Command[0] = %q\n", cmd.Command[0])
-		cmd.HandlePing()
-	case "SET":
-		cmd.HandleSet()
-	case "GET":
-		cmd.HandleGet()
-	case "DEL":
-		cmd.HandleDelete()
-	case "EXISTS":
-		cmd.HandleExists()
-	case "EXPIRE":
-		cmd.HandleExpire()
-	case "TTL":
-		cmd.HandleTTL()
-	case "RENAME":
-		cmd.HandleRename()
-	case "APPEND":
-		cmd.HandleAppend()
-	case "INCR":
-		cmd.HandleIncr()
-	case "DECR":
-		cmd.HandleDecr()
-	case "INFO":
-		cmd.HandleInfo()
-	default:
-		response.SendError(cmd.Conn, "Unknown command: "+command)
+	stop := func() {
+		conn.SetReadDeadline(time.Now())
+		<-done
+		conn.SetReadDeadline(time.Time{})
 	}
+	return closed, stop
 }
 
 func main() {
 	cfg := config.LoadConfig()
+	// the append only file, primaries and cluster peers are held to the
+	// same limit as clients
+	persist.MaxBulkLen = cfg.ProtoMaxBulkLen
 
-	memoryStore := cache.NewValueStore(10 * time.Second)
+	memoryStore := cache.NewValueStore(time.Second / time.Duration(cfg.Hz))
+	policy, err := cache.ParseEvictionPolicy(cfg.MaxMemoryPolicy)
+	if err != nil {
+		log.Fatalf("Failed to parse maxmemory policy: %v", err)
+	}
+	maxMemory, err := config.ParseMemory(cfg.MaxMemory)
+	if err != nil {
+		log.Fatalf("Failed to parse maxmemory: %v", err)
+	}
+	memoryStore.SetMaxMemory(maxMemory, policy, cfg.MaxMemorySamples)
+	broker := pubsub.NewBroker()
+
+	saveRules, err := persist.ParseSaveRules(cfg.Save)
+	if err != nil {
+		log.Fatalf("Failed to parse save rules: %v", err)
+	}
+	saver := persist.NewSaver(memoryStore, filepath.Join(cfg.Dir, cfg.DBFilename), saveRules)
+	saver.Locker = commands.ExecLocker()
 
-	var port string
+	// load the data before accepting clients, as upstream does. The append
+	// only file, being the more complete, wins over the snapshot.
+	var aof *persist.AOF
+	start := time.Now()
+	if cfg.AppendOnly {
+		aof, err = loadAOF(cfg, memoryStore, saver)
+		if err != nil {
+			log.Fatalf("Failed to load %s: %v", aof.Path(), err)
+		}
+	} else if err := saver.Load(); err != nil {
+		log.Fatalf("Failed to load %s: %v", saver.Path(), err)
+	}
+	log.Printf("DB loaded from disk: %.3f seconds", time.Since(start).Seconds())
+	go saver.Run(time.Second)
+	go shutdownOnSignal(saver, aof)
+
+	portNumber := 6379
 	if cfg.RedisPort != nil {
-		port = fmt.Sprintf(":%d", *cfg.RedisPort)
-	} else {
-		port = ":6379"
+		portNumber = *cfg.RedisPort
+	}
+	port := fmt.Sprintf(":%d", portNumber)
+
+	node := newReplication(cfg, memoryStore, aof, portNumber)
+	propagator := commands.Propagator(node)
+	if aof != nil {
+		propagator = commands.Propagators{aof, node}
+	}
+
+	layer, err := network.New(cfg.Network, cfg.ProtoMaxBulkLen)
+	if err != nil {
+		log.Fatalf("Failed to set up the network layer: %v", err)
 	}
 	listener, err := net.Listen("tcp", port)
 	if err != nil {
@@ -170,15 +202,128 @@ func main() {
 		password = ""
 	}
 
-	for {
-		conn, err := listener.Accept()
+	var clusterState *cluster.Cluster
+	if cfg.ClusterEnabled {
+		clusterState, err = newCluster(cfg, portNumber, password)
 		if err != nil {
-			log.Printf("Failed to accept connection: %v", err)
-			continue
+			log.Fatalf("Failed to set up the cluster: %v", err)
+		}
+	}
+
+	srv := &server{
+		store:       memoryStore,
+		broker:      broker,
+		saver:       saver,
+		aof:         aof,
+		replication: node,
+		cluster:     clusterState,
+		scripts:     scripting.NewEngine(cfg.LuaTimeLimit),
+		propagator:  propagator,
+		password:    password,
+	}
+	if err := layer.Serve(listener, srv); err != nil {
+		log.Fatalf("Failed to accept connections: %v", err)
+	}
+}
+
+// newReplication sets up this server's side of replication, and starts
+// following a primary if REDIS_REPLICAOF names one. What a primary sends is
+// applied like a replayed AOF, and logged to our own AOF if there is one.
+func newReplication(cfg *config.Config, memoryStore *cache.ValueStore, aof *persist.AOF, port int) *replication.Node {
+	node := replication.NewNode(memoryStore, port, cfg.ReplBacklogSize)
+	node.Locker = commands.ExclusiveLocker()
+	node.MasterAuth = cfg.MasterAuth
+	node.ReadOnly = cfg.ReplicaReadOnly
+	node.Apply = func(batch [][]string) {
+		for _, args := range batch {
+			if err := commands.Replay(memoryStore, args); err != nil {
+				log.Printf("Error applying a command from MASTER: %v", err)
+			}
+		}
+		if aof != nil {
+			aof.Propagate(batch)
+		}
+	}
+	if aof != nil {
+		node.OnFullSync = func() {
+			if err := aof.Rewrite(); err != nil {
+				log.Printf("Failed to rewrite the AOF after a full resync: %v", err)
+			}
+		}
+	}
+	go node.Run()
+
+	if cfg.ReplicaOf != "" {
+		host, portStr, _ := strings.Cut(cfg.ReplicaOf, " ")
+		masterPort, err := strconv.Atoi(strings.TrimSpace(portStr))
+		if err != nil {
+			log.Fatalf("Failed to parse REDIS_REPLICAOF %q: expected \"<host> <port>\"", cfg.ReplicaOf)
 		}
+		node.ReplicaOf(host, masterPort)
+	}
+	return node
+}
 
-		log.Printf("Accepted connection from %s", conn.RemoteAddr())
+// newCluster sets up cluster mode, laid out by REDIS_CLUSTER_NODES if set,
+// and starts gossiping with the other nodes.
+func newCluster(cfg *config.Config, port int, password string) (*cluster.Cluster, error) {
+	c := cluster.New(cfg.ClusterAnnounceIP, port)
+	if cfg.ClusterNodes != "" {
+		var err error
+		if c, err = cluster.NewStatic(cfg.ClusterAnnounceIP, port, cfg.ClusterNodes); err != nil {
+			return nil, err
+		}
+	}
+	c.Password = password
+	log.Printf("Cluster node ID: %s", c.MyID())
+	go c.Run()
+	return c, nil
+}
 
-		go handleConnection(conn, memoryStore, password)
+// loadAOF replays the append only file into the store and opens it for
+// writing. When there is no file yet, as when appendonly has just been turned
+// on, the snapshot is loaded instead and a rewrite creates the file from it.
+func loadAOF(cfg *config.Config, memoryStore *cache.ValueStore, saver *persist.Saver) (*persist.AOF, error) {
+	fsync, err := persist.ParseFsyncPolicy(cfg.AppendFsync)
+	if err != nil {
+		log.Fatalf("Failed to parse appendfsync: %v", err)
+	}
+	aof := persist.NewAOF(memoryStore, filepath.Join(cfg.Dir, cfg.AppendFilename), fsync)
+
+	found, err := aof.Load(func(args []string) error {
+		return commands.Replay(memoryStore, args)
+	})
+	if err != nil {
+		return aof, err
+	}
+	if !found {
+		if err := saver.Load(); err != nil {
+			return aof, err
+		}
+	}
+	if err := aof.Open(); err != nil {
+		return aof, err
+	}
+	if !found {
+		if err := aof.Rewrite(); err != nil {
+			return aof, err
+		}
+	}
+	go aof.Run()
+	return aof, nil
+}
+
+// shutdownOnSignal saves the store and exits on SIGINT or SIGTERM, so a
+// restart loses nothing when save rules are configured. Like upstream, it
+// refuses to exit if that save fails.
+func shutdownOnSignal(saver *persist.Saver, aof *persist.AOF) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	for sig := range signals {
+		log.Printf("Received %s, scheduling shutdown...", sig)
+		if err := commands.Shutdown(saver, aof, false, false); err != nil {
+			continue
+		}
+		os.Exit(0)
 	}
 }
@@ -1,10 +1,15 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"log"
+	"net"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -204,6 +209,43 @@ func TestExpireAndTTL(t *testing.T) {
 	t.Logf("TTL for key '%s' is %s", key, ttl)
 }
 
+// expiredKeys reads expired_keys from INFO.
+func expiredKeys(t *testing.T) int {
+	info, err := redisClient.Info(ctx, "stats").Result()
+	if err != nil {
+		t.Fatalf("Failed to get info: %s", err)
+	}
+	for _, line := range strings.Split(info, "\n") {
+		if value, ok := strings.CutPrefix(line, "expired_keys: "); ok {
+			n, _ := strconv.Atoi(strings.TrimSpace(value))
+			return n
+		}
+	}
+	t.Fatalf("Expected INFO to report expired_keys, got: %s", info)
+	return 0
+}
+
+func TestActiveExpire(t *testing.T) {
+	before := expiredKeys(t)
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("testActiveExpireKey%d", i)
+		if err := redisClient.Set(ctx, key, "value", 100*time.Millisecond).Err(); err != nil {
+			t.Fatalf("Failed to set key '%s': %s", key, err)
+		}
+	}
+
+	// the keys are never touched again, so only the expire cycle removes them
+	deadline := time.Now().Add(5 * time.Second)
+	for expiredKeys(t)-before < 100 {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected 100 keys to expire without being accessed, got: %d", expiredKeys(t)-before)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	t.Logf("Successfully expired keys in the background")
+}
+
 func TestRename(t *testing.T) {
 	key := "testRenameKey"
 	newKey := "renamedKey"
@@ -299,3 +341,533 @@ func TestDecr(t *testing.T) {
 
 	t.Logf("Successfully decremented key '%s'. New value: %d", key, newValue)
 }
+
+func TestListPushAndRange(t *testing.T) {
+	key := "testListKey"
+
+	length, err := redisClient.RPush(ctx, key, "b", "c").Result()
+	if err != nil {
+		t.Fatalf("Failed to push to list '%s': %s", key, err)
+	}
+	if length != 2 {
+		t.Fatalf("Expected length 2 after RPUSH, got %d", length)
+	}
+
+	if err := redisClient.LPush(ctx, key, "a").Err(); err != nil {
+		t.Fatalf("Failed to push to list '%s': %s", key, err)
+	}
+
+	values, err := redisClient.LRange(ctx, key, 0, -1).Result()
+	if err != nil {
+		t.Fatalf("Failed to get range of list '%s': %s", key, err)
+	}
+	if fmt.Sprint(values) != "[a b c]" {
+		t.Fatalf("Expected [a b c], got %v", values)
+	}
+
+	popped, err := redisClient.RPop(ctx, key).Result()
+	if err != nil {
+		t.Fatalf("Failed to pop from list '%s': %s", key, err)
+	}
+	if popped != "c" {
+		t.Fatalf("Expected 'c' from RPOP, got '%s'", popped)
+	}
+
+	t.Logf("Successfully pushed, ranged and popped list '%s'", key)
+}
+
+func TestListWrongType(t *testing.T) {
+	key := "testListWrongTypeKey"
+
+	if err := redisClient.Set(ctx, key, "value", 0).Err(); err != nil {
+		t.Fatalf("Failed to set key '%s': %s", key, err)
+	}
+
+	err := redisClient.LPush(ctx, key, "a").Err()
+	if err == nil || !strings.HasPrefix(err.Error(), "WRONGTYPE") {
+		t.Fatalf("Expected WRONGTYPE error pushing to string key '%s', got: %v", key, err)
+	}
+
+	t.Logf("Correctly received WRONGTYPE for list command on string key '%s'", key)
+}
+
+func TestHashSetAndGetAll(t *testing.T) {
+	key := "testHashKey"
+
+	added, err := redisClient.HSet(ctx, key, "name", "alice", "visits", "1").Result()
+	if err != nil {
+		t.Fatalf("Failed to set hash '%s': %s", key, err)
+	}
+	if added != 2 {
+		t.Fatalf("Expected 2 new fields, got %d", added)
+	}
+
+	visits, err := redisClient.HIncrBy(ctx, key, "visits", 2).Result()
+	if err != nil {
+		t.Fatalf("Failed to increment field of hash '%s': %s", key, err)
+	}
+	if visits != 3 {
+		t.Fatalf("Expected visits 3 after HINCRBY, got %d", visits)
+	}
+
+	all, err := redisClient.HGetAll(ctx, key).Result()
+	if err != nil {
+		t.Fatalf("Failed to get hash '%s': %s", key, err)
+	}
+	if all["name"] != "alice" || all["visits"] != "3" {
+		t.Fatalf("Unexpected hash contents: %v", all)
+	}
+
+	t.Logf("Successfully set and retrieved hash '%s': %v", key, all)
+}
+
+func TestHashKeepsTTL(t *testing.T) {
+	key := "testHashTTLKey"
+
+	if err := redisClient.HSet(ctx, key, "field", "value").Err(); err != nil {
+		t.Fatalf("Failed to set hash '%s': %s", key, err)
+	}
+	if err := redisClient.Expire(ctx, key, 100*time.Second).Err(); err != nil {
+		t.Fatalf("Failed to set expiration for key '%s': %s", key, err)
+	}
+	if err := redisClient.HSet(ctx, key, "other", "value").Err(); err != nil {
+		t.Fatalf("Failed to set hash '%s': %s", key, err)
+	}
+
+	ttl, err := redisClient.TTL(ctx, key).Result()
+	if err != nil {
+		t.Fatalf("Failed to get TTL for key '%s': %s", key, err)
+	}
+	if ttl <= 0 {
+		t.Fatalf("Expected HSET to keep the TTL of key '%s', got %s", key, ttl)
+	}
+
+	t.Logf("TTL for hash '%s' kept after HSET: %s", key, ttl)
+}
+
+func TestSetAlgebra(t *testing.T) {
+	first := "testSetFirstKey"
+	second := "testSetSecondKey"
+	dest := "testSetDestKey"
+
+	if err := redisClient.SAdd(ctx, first, "a", "b", "c").Err(); err != nil {
+		t.Fatalf("Failed to add to set '%s': %s", first, err)
+	}
+	if err := redisClient.SAdd(ctx, second, "b", "c", "d").Err(); err != nil {
+		t.Fatalf("Failed to add to set '%s': %s", second, err)
+	}
+
+	inter, err := redisClient.SInter(ctx, first, second).Result()
+	if err != nil {
+		t.Fatalf("Failed to intersect sets: %s", err)
+	}
+	sort.Strings(inter)
+	if fmt.Sprint(inter) != "[b c]" {
+		t.Fatalf("Expected [b c], got %v", inter)
+	}
+
+	size, err := redisClient.SUnionStore(ctx, dest, first, second).Result()
+	if err != nil {
+		t.Fatalf("Failed to store union of sets: %s", err)
+	}
+	if size != 4 {
+		t.Fatalf("Expected union of size 4, got %d", size)
+	}
+
+	isMember, err := redisClient.SIsMember(ctx, dest, "d").Result()
+	if err != nil {
+		t.Fatalf("Failed to check membership of set '%s': %s", dest, err)
+	}
+	if !isMember {
+		t.Fatalf("Expected 'd' to be a member of '%s'", dest)
+	}
+
+	t.Logf("Successfully intersected and stored the union of sets '%s' and '%s'", first, second)
+}
+
+func TestSortedSetLeaderboard(t *testing.T) {
+	key := "testSortedSetKey"
+
+	err := redisClient.ZAdd(ctx, key,
+		&redis.Z{Score: 10, Member: "alice"},
+		&redis.Z{Score: 30, Member: "bob"},
+		&redis.Z{Score: 20, Member: "carol"},
+	).Err()
+	if err != nil {
+		t.Fatalf("Failed to add to sorted set '%s': %s", key, err)
+	}
+
+	if err := redisClient.ZIncrBy(ctx, key, 15, "alice").Err(); err != nil {
+		t.Fatalf("Failed to increment member of sorted set '%s': %s", key, err)
+	}
+
+	top, err := redisClient.ZRevRangeWithScores(ctx, key, 0, 1).Result()
+	if err != nil {
+		t.Fatalf("Failed to get range of sorted set '%s': %s", key, err)
+	}
+	if len(top) != 2 || top[0].Member != "bob" || top[1].Member != "alice" || top[1].Score != 25 {
+		t.Fatalf("Unexpected top two members: %v", top)
+	}
+
+	rank, err := redisClient.ZRank(ctx, key, "carol").Result()
+	if err != nil {
+		t.Fatalf("Failed to get rank in sorted set '%s': %s", key, err)
+	}
+	if rank != 0 {
+		t.Fatalf("Expected carol to have rank 0, got %d", rank)
+	}
+
+	t.Logf("Successfully ranked members of sorted set '%s': %v", key, top)
+}
+
+func TestStreamAddAndRange(t *testing.T) {
+	key := "testStreamKey"
+
+	for i := 1; i <= 3; i++ {
+		err := redisClient.XAdd(ctx, &redis.XAddArgs{
+			Stream: key,
+			ID:     fmt.Sprintf("1-%d", i),
+			Values: []string{"n", strconv.Itoa(i)},
+		}).Err()
+		if err != nil {
+			t.Fatalf("Failed to add to stream '%s': %s", key, err)
+		}
+	}
+
+	if err := redisClient.XAdd(ctx, &redis.XAddArgs{Stream: key, ID: "1-1", Values: []string{"n", "0"}}).Err(); err == nil {
+		t.Fatalf("Expected an error adding a smaller ID to stream '%s'", key)
+	}
+
+	entries, err := redisClient.XRangeN(ctx, key, "(1-1", "+", 1).Result()
+	if err != nil {
+		t.Fatalf("Failed to get range of stream '%s': %s", key, err)
+	}
+	if len(entries) != 1 || entries[0].ID != "1-2" || entries[0].Values["n"] != "2" {
+		t.Fatalf("Unexpected entries: %v", entries)
+	}
+
+	t.Logf("Successfully added to and ranged over stream '%s': %v", key, entries)
+}
+
+func TestStreamBlockingRead(t *testing.T) {
+	key := "testStreamBlockKey"
+
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		redisClient.XAdd(ctx, &redis.XAddArgs{Stream: key, Values: []string{"event", "woken"}})
+	}()
+
+	streams, err := redisClient.XRead(ctx, &redis.XReadArgs{
+		Streams: []string{key, "$"},
+		Block:   5 * time.Second,
+	}).Result()
+	if err != nil {
+		t.Fatalf("Failed to read from stream '%s': %s", key, err)
+	}
+	if len(streams) != 1 || len(streams[0].Messages) != 1 || streams[0].Messages[0].Values["event"] != "woken" {
+		t.Fatalf("Unexpected read result: %v", streams)
+	}
+
+	t.Logf("Successfully blocked on stream '%s' until an entry was added", key)
+}
+
+func TestStreamConsumerGroup(t *testing.T) {
+	key := "testStreamGroupKey"
+	group := "workers"
+
+	if err := redisClient.XGroupCreateMkStream(ctx, key, group, "$").Err(); err != nil {
+		t.Fatalf("Failed to create consumer group '%s': %s", group, err)
+	}
+	if err := redisClient.XAdd(ctx, &redis.XAddArgs{Stream: key, Values: []string{"job", "1"}}).Err(); err != nil {
+		t.Fatalf("Failed to add to stream '%s': %s", key, err)
+	}
+
+	streams, err := redisClient.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    group,
+		Consumer: "alice",
+		Streams:  []string{key, ">"},
+	}).Result()
+	if err != nil {
+		t.Fatalf("Failed to read from consumer group '%s': %s", group, err)
+	}
+	if len(streams) != 1 || len(streams[0].Messages) != 1 {
+		t.Fatalf("Unexpected read result: %v", streams)
+	}
+	id := streams[0].Messages[0].ID
+
+	claimed, err := redisClient.XClaimJustID(ctx, &redis.XClaimArgs{
+		Stream:   key,
+		Group:    group,
+		Consumer: "bob",
+		Messages: []string{id},
+	}).Result()
+	if err != nil || len(claimed) != 1 || claimed[0] != id {
+		t.Fatalf("Failed to claim '%s' for bob: %v %v", id, claimed, err)
+	}
+
+	pending, err := redisClient.XPending(ctx, key, group).Result()
+	if err != nil {
+		t.Fatalf("Failed to get pending entries of group '%s': %s", group, err)
+	}
+	if pending.Count != 1 || pending.Consumers["bob"] != 1 {
+		t.Fatalf("Unexpected pending summary: %v", pending)
+	}
+
+	acked, err := redisClient.XAck(ctx, key, group, id).Result()
+	if err != nil || acked != 1 {
+		t.Fatalf("Failed to acknowledge '%s': %v %v", id, acked, err)
+	}
+
+	t.Logf("Successfully read, claimed and acknowledged '%s' in group '%s'", id, group)
+}
+
+func TestPubSub(t *testing.T) {
+	channel := "testChannel"
+
+	pubsub := redisClient.Subscribe(ctx, channel)
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		t.Fatalf("Failed to subscribe to '%s': %s", channel, err)
+	}
+
+	receivers, err := redisClient.Publish(ctx, channel, "hello").Result()
+	if err != nil {
+		t.Fatalf("Failed to publish to '%s': %s", channel, err)
+	}
+	if receivers != 1 {
+		t.Fatalf("Expected 1 receiver on '%s', got %d", channel, receivers)
+	}
+
+	msg, err := pubsub.ReceiveMessage(ctx)
+	if err != nil {
+		t.Fatalf("Failed to receive message on '%s': %s", channel, err)
+	}
+	if msg.Channel != channel || msg.Payload != "hello" {
+		t.Fatalf("Unexpected message: %v", msg)
+	}
+
+	t.Logf("Successfully published and received '%s' on '%s'", msg.Payload, channel)
+}
+
+func TestTransactionWatch(t *testing.T) {
+	key := "testWatchKey"
+
+	if err := redisClient.Set(ctx, key, "0", 0).Err(); err != nil {
+		t.Fatalf("Failed to set key '%s': %s", key, err)
+	}
+
+	// a transaction whose watched key is changed by another client must fail
+	err := redisClient.Watch(ctx, func(tx *redis.Tx) error {
+		if err := redisClient.Set(ctx, key, "changed", 0).Err(); err != nil {
+			return err
+		}
+		_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Incr(ctx, key)
+			return nil
+		})
+		return err
+	}, key)
+	if err != redis.TxFailedErr {
+		t.Fatalf("Expected the transaction on '%s' to fail, got: %v", key, err)
+	}
+
+	err = redisClient.Watch(ctx, func(tx *redis.Tx) error {
+		_, err := tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, "41", 0)
+			pipe.Incr(ctx, key)
+			return nil
+		})
+		return err
+	}, key)
+	if err != nil {
+		t.Fatalf("Failed to run transaction on '%s': %s", key, err)
+	}
+
+	val, err := redisClient.Get(ctx, key).Result()
+	if err != nil || val != "42" {
+		t.Fatalf("Expected '%s' to be 42, got: %v %v", key, val, err)
+	}
+
+	t.Logf("Successfully ran a watched transaction on '%s'", key)
+}
+
+func TestBackgroundSave(t *testing.T) {
+	before, err := redisClient.LastSave(ctx).Result()
+	if err != nil {
+		t.Fatalf("Failed to get last save time: %s", err)
+	}
+
+	if err := redisClient.Set(ctx, "testSaveKey", "value", 0).Err(); err != nil {
+		t.Fatalf("Failed to set key '%s': %s", "testSaveKey", err)
+	}
+	if err := redisClient.BgSave(ctx).Err(); err != nil {
+		t.Fatalf("Failed to start background save: %s", err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		info, err := redisClient.Info(ctx).Result()
+		if err != nil {
+			t.Fatalf("Failed to get info: %s", err)
+		}
+		if strings.Contains(info, "rdb_bgsave_in_progress: 0") {
+			if !strings.Contains(info, "rdb_last_bgsave_status: ok") {
+				t.Fatalf("Expected the background save to succeed, got: %s", info)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Background save did not finish in time")
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	after, err := redisClient.LastSave(ctx).Result()
+	if err != nil || after < before {
+		t.Fatalf("Expected LASTSAVE to be at least %d, got: %d %v", before, after, err)
+	}
+
+	t.Logf("Successfully saved a snapshot in the background")
+}
+
+func TestRole(t *testing.T) {
+	role, err := redisClient.Do(ctx, "ROLE").Slice()
+	if err != nil {
+		t.Fatalf("Failed to get role: %s", err)
+	}
+	if len(role) != 3 || role[0] != "master" {
+		t.Fatalf("Expected the server to be a primary, got: %v", role)
+	}
+
+	info, err := redisClient.Info(ctx, "replication").Result()
+	if err != nil {
+		t.Fatalf("Failed to get info: %s", err)
+	}
+	if !strings.Contains(info, "role: master") {
+		t.Fatalf("Expected INFO to report the primary role, got: %s", info)
+	}
+
+	t.Logf("Successfully got the replication role")
+}
+
+func TestEval(t *testing.T) {
+	// go-redis tries EVALSHA first and falls back to EVAL on NOSCRIPT
+	incrBy := redis.NewScript(`
+local n = tonumber(redis.call("GET", KEYS[1]) or "0") + ARGV[1]
+redis.call("SET", KEYS[1], n)
+return n`)
+	for i := 0; i < 2; i++ {
+		if _, err := incrBy.Run(ctx, redisClient, []string{"scriptcounter"}, 5).Int(); err != nil {
+			t.Fatalf("Failed to run script: %s", err)
+		}
+	}
+	value, err := redisClient.Get(ctx, "scriptcounter").Int()
+	if err != nil {
+		t.Fatalf("Failed to get scriptcounter: %s", err)
+	}
+	if value != 10 {
+		t.Fatalf("Expected scriptcounter to be 10, got: %d", value)
+	}
+
+	t.Logf("Successfully ran a script")
+}
+
+func TestFunction(t *testing.T) {
+	library := `#!lua name=testlib
+redis.register_function('setget', function(keys, args)
+	redis.call('SET', keys[1], args[1])
+	return redis.call('GET', keys[1])
+end)
+redis.register_function{function_name = 'get', callback = function(keys) return redis.call('GET', keys[1]) end, flags = {'no-writes'}}`
+	if err := redisClient.Do(ctx, "FUNCTION", "LOAD", "REPLACE", library).Err(); err != nil {
+		t.Fatalf("Failed to load library: %s", err)
+	}
+
+	value, err := redisClient.Do(ctx, "FCALL", "setget", 1, "fnkey", "hello").Text()
+	if err != nil {
+		t.Fatalf("Failed to call function: %s", err)
+	}
+	if value != "hello" {
+		t.Fatalf("Expected setget to return hello, got: %s", value)
+	}
+	if value, err := redisClient.Do(ctx, "FCALL_RO", "get", 1, "fnkey").Text(); err != nil || value != "hello" {
+		t.Fatalf("Failed to call read only function: %v %s", err, value)
+	}
+	if err := redisClient.Do(ctx, "FCALL_RO", "setget", 1, "fnkey", "x").Err(); err == nil {
+		t.Fatalf("Expected FCALL_RO of a function with writes to fail")
+	}
+
+	t.Logf("Successfully called functions")
+}
+
+func TestHello(t *testing.T) {
+	// go-redis v8 only speaks RESP2, so this checks the RESP2 form of HELLO
+	reply, err := redisClient.Do(ctx, "HELLO", 2).Slice()
+	if err != nil {
+		t.Fatalf("Failed to send HELLO: %s", err)
+	}
+	fields := map[string]interface{}{}
+	for i := 0; i+1 < len(reply); i += 2 {
+		fields[reply[i].(string)] = reply[i+1]
+	}
+	if fields["proto"] != int64(2) || fields["server"] != "redis" {
+		t.Fatalf("Expected HELLO to report protocol 2, got: %v", reply)
+	}
+	if err := redisClient.Do(ctx, "HELLO", 4).Err(); err == nil || !strings.HasPrefix(err.Error(), "NOPROTO") {
+		t.Fatalf("Expected HELLO 4 to fail with NOPROTO, got: %v", err)
+	}
+
+	t.Logf("Successfully negotiated the protocol")
+}
+
+func TestInline(t *testing.T) {
+	conn, err := net.Dial("tcp", redisClient.Options().Addr)
+	if err != nil {
+		t.Fatalf("Failed to connect: %s", err)
+	}
+	defer conn.Close()
+
+	// commands typed as text, as telnet and health checks send them
+	fmt.Fprint(conn, "AUTH securepassword\r\nSET inlinekey \"a \\x41\\n\"\r\nGET inlinekey\n")
+	reader := bufio.NewReader(conn)
+	expected := []string{"+OK\r\n", "+OK\r\n", "$4\r\n", "a A\n", "\r\n"}
+	for _, line := range expected {
+		got, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read reply: %s", err)
+		}
+		if got != line {
+			t.Fatalf("Expected %q, got: %q", line, got)
+		}
+	}
+
+	fmt.Fprint(conn, "GET \"unbalanced\r\n")
+	if got, _ := reader.ReadString('\n'); got != "-Protocol error: unbalanced quotes in request\r\n" {
+		t.Fatalf("Expected a protocol error, got: %q", got)
+	}
+
+	t.Logf("Successfully sent inline commands")
+}
+
+// benchmarkPipeline sends b.N GETs in pipelines of batch commands, so ns/op
+// is the time per command. Run with: go test -run '^$' -bench Pipeline
+func benchmarkPipeline(b *testing.B, batch int) {
+	if err := redisClient.Set(ctx, "benchkey", "value", 0).Err(); err != nil {
+		b.Fatalf("Failed to set benchkey: %s", err)
+	}
+	b.ResetTimer()
+	for sent := 0; sent < b.N; sent += batch {
+		pipe := redisClient.Pipeline()
+		for i := 0; i < batch && sent+i < b.N; i++ {
+			pipe.Get(ctx, "benchkey")
+		}
+		if _, err := pipe.Exec(ctx); err != nil {
+			b.Fatalf("Failed to run pipeline: %s", err)
+		}
+	}
+}
+
+func BenchmarkPipeline1(b *testing.B)    { benchmarkPipeline(b, 1) }
+func BenchmarkPipeline10(b *testing.B)   { benchmarkPipeline(b, 10) }
+func BenchmarkPipeline100(b *testing.B)  { benchmarkPipeline(b, 100) }
+func BenchmarkPipeline1000(b *testing.B) { benchmarkPipeline(b, 1000) }
@@ -0,0 +1,94 @@
+//go:build unix
+
+package network
+
+import (
+	"bufio"
+	"net"
+	"runtime"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+// benchConns is how many idle clients the benchmark connects, as far as the
+// limit on open files allows: each takes a descriptor on both ends.
+const benchConns = 50000
+
+// BenchmarkConnections connects benchConns clients to each network layer
+// and reports the memory they take, then has ns/op be the time for a PING
+// sent by one of them, round-robin, from 64 goroutines. Run with:
+// go test -run '^$' -bench Connections -benchtime 200000x ./network
+func BenchmarkConnections(b *testing.B) {
+	n := benchConns
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err == nil {
+		limit.Cur = limit.Max
+		syscall.Setrlimit(syscall.RLIMIT_NOFILE, &limit)
+		if max := int(limit.Cur/2) - 100; max < n {
+			b.Logf("Connecting %d clients, as the limit on open files is %d", max, limit.Cur)
+			n = max
+		}
+	}
+
+	for name, server := range layers(b) {
+		b.Run(name, func(b *testing.B) {
+			addr := listen(b, server, newPingHandler())
+
+			before := memoryInUse()
+			conns := make([]net.Conn, n)
+			readers := make([]*bufio.Reader, n)
+			for i := range conns {
+				conn, err := net.Dial("tcp", addr)
+				if err != nil {
+					b.Fatalf("Dial() failed after %d connections: %v", i, err)
+				}
+				conns[i], readers[i] = conn, bufio.NewReaderSize(conn, 16)
+			}
+			defer func() {
+				for _, conn := range conns {
+					conn.Close()
+				}
+			}()
+			// every connection is served once, as it would be after a client's
+			// first command, before it goes idle
+			for i, conn := range conns {
+				conn.Write([]byte("PING\r\n"))
+				readers[i].ReadString('\n')
+			}
+			perConn := float64(memoryInUse()-before) / float64(n)
+
+			// each worker has its own connections: every workers-th one
+			const workers = 64
+			var wg sync.WaitGroup
+			b.ResetTimer()
+			for w := 0; w < workers; w++ {
+				wg.Add(1)
+				go func(w int) {
+					defer wg.Done()
+					c := w
+					for i := w; i < b.N; i += workers {
+						conns[c].Write([]byte("PING\r\n"))
+						if _, err := readers[c].ReadString('\n'); err != nil {
+							b.Errorf("PING failed: %v", err)
+							return
+						}
+						if c += workers; c >= n {
+							c = w
+						}
+					}
+				}(w)
+			}
+			wg.Wait()
+			b.ReportMetric(perConn, "bytes/conn")
+		})
+	}
+}
+
+// memoryInUse returns the heap and goroutine stacks in use, after a GC.
+func memoryInUse() uint64 {
+	runtime.GC()
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapInuse + stats.StackInuse
+}
@@ -0,0 +1,42 @@
+package network
+
+import (
+	"errors"
+	"log"
+	"net"
+
+	"github.com/Ryan-DL/go-redis-server/protocol"
+)
+
+// Goroutines serves each connection from its own goroutine, which blocks
+// reading the connection while the client is idle.
+type Goroutines struct {
+	// MaxBulkLen is the longest argument a client may send.
+	MaxBulkLen int
+}
+
+func (g *Goroutines) Serve(l net.Listener, h Handler) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return err
+			}
+			log.Printf("Failed to accept connection: %v", err)
+			continue
+		}
+		go g.serve(conn, h)
+	}
+}
+
+func (g *Goroutines) serve(conn net.Conn, h Handler) {
+	c := h.Connect(conn)
+	defer c.Close()
+
+	r := protocol.NewReader(conn)
+	if g.MaxBulkLen > 0 {
+		r.MaxBulkLen = g.MaxBulkLen
+	}
+	for c.Serve(r) == nil {
+	}
+}
@@ -0,0 +1,49 @@
+// Package network accepts client connections and reads their commands,
+// leaving what the commands do to a Handler. How connections are waited on
+// is up to the Server: Goroutines gives each its own goroutine, as Go servers
+// usually do, while the Reactor waits on all of them with one epoll instance
+// and only spends a goroutine and buffers on those with commands to run,
+// which matters with many idle clients.
+package network
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/Ryan-DL/go-redis-server/protocol"
+)
+
+// Handler serves the connections a Server accepts.
+type Handler interface {
+	// Connect is called for each new connection and returns what serves it.
+	Connect(conn net.Conn) Conn
+}
+
+// Conn serves one connection.
+type Conn interface {
+	// Serve waits for a command from r, runs it and keeps going while r has
+	// more buffered, so that a pipeline runs as a whole; then it returns nil.
+	// It returns an error once the connection is to be closed.
+	Serve(r *protocol.Reader) error
+
+	// Close ends the connection, after Serve failed or the connection was
+	// closed while idle.
+	Close()
+}
+
+// Server accepts connections from a listener until it fails.
+type Server interface {
+	Serve(l net.Listener, h Handler) error
+}
+
+// New returns the server called name: "goroutine" or "epoll".
+func New(name string, maxBulkLen int) (Server, error) {
+	switch name {
+	case "goroutine":
+		return &Goroutines{MaxBulkLen: maxBulkLen}, nil
+	case "epoll":
+		return NewReactor(maxBulkLen)
+	default:
+		return nil, fmt.Errorf("unknown network layer %q", name)
+	}
+}
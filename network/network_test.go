@@ -0,0 +1,222 @@
+package network
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/protocol"
+	"github.com/Ryan-DL/go-redis-server/pubsub"
+)
+
+// pingHandler replies +PONG to every command, flushing once a pipeline has
+// been read, and counts the connections it has closed.
+type pingHandler struct {
+	mu     sync.Mutex
+	conns  []net.Conn
+	closed chan struct{}
+}
+
+func newPingHandler() *pingHandler {
+	return &pingHandler{closed: make(chan struct{}, 1024)}
+}
+
+func (h *pingHandler) Connect(conn net.Conn) Conn {
+	h.mu.Lock()
+	h.conns = append(h.conns, conn)
+	h.mu.Unlock()
+	return &pingConn{conn: conn, out: bufio.NewWriter(conn), closed: h.closed}
+}
+
+type pingConn struct {
+	conn   net.Conn
+	out    *bufio.Writer
+	closed chan struct{}
+}
+
+func (c *pingConn) Serve(r *protocol.Reader) error {
+	for {
+		if _, err := r.ReadCommand(); err != nil {
+			return err
+		}
+		c.out.WriteString("+PONG\r\n")
+		if r.Buffered() == 0 {
+			return c.out.Flush()
+		}
+	}
+}
+
+func (c *pingConn) Close() {
+	c.conn.Close()
+	c.closed <- struct{}{}
+}
+
+// layers returns the network layers this platform has, by name.
+func layers(t testing.TB) map[string]Server {
+	servers := map[string]Server{"goroutine": &Goroutines{}}
+	if reactor, err := NewReactor(0); err == nil {
+		servers["epoll"] = reactor
+	} else {
+		t.Logf("Skipping the reactor: %v", err)
+	}
+	return servers
+}
+
+// listen serves h with server on a free port and returns its address.
+func listen(t testing.TB, server Server, h Handler) string {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen() failed: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go server.Serve(l, h)
+	return l.Addr().String()
+}
+
+func waitClosed(t *testing.T, h *pingHandler, n int) {
+	for i := 0; i < n; i++ {
+		select {
+		case <-h.closed:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Close() failed. Expected %d connections closed, got: %d", n, i)
+		}
+	}
+}
+
+func TestServe(t *testing.T) {
+	for name, server := range layers(t) {
+		t.Run(name, func(t *testing.T) {
+			h := newPingHandler()
+			addr := listen(t, server, h)
+
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					conn, err := net.Dial("tcp", addr)
+					if err != nil {
+						t.Errorf("Dial() failed: %v", err)
+						return
+					}
+					defer conn.Close()
+					reader := bufio.NewReader(conn)
+					for round := 0; round < 20; round++ {
+						// a pipeline, with a command split across writes
+						conn.Write([]byte(strings.Repeat("PING\r\n", 50) + "*1\r\n$4\r\nPI"))
+						time.Sleep(time.Millisecond)
+						conn.Write([]byte("NG\r\n"))
+						for j := 0; j < 51; j++ {
+							if line, err := reader.ReadString('\n'); err != nil || line != "+PONG\r\n" {
+								t.Errorf("Serve() failed. Expected: +PONG, got: %q, %v", line, err)
+								return
+							}
+						}
+					}
+				}()
+			}
+			wg.Wait()
+			waitClosed(t, h, 10)
+		})
+	}
+}
+
+func TestCloseIdle(t *testing.T) {
+	for name, server := range layers(t) {
+		t.Run(name, func(t *testing.T) {
+			h := newPingHandler()
+			addr := listen(t, server, h)
+
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatalf("Dial() failed: %v", err)
+			}
+			defer conn.Close()
+			conn.Write([]byte("PING\r\n"))
+			bufio.NewReader(conn).ReadString('\n')
+
+			// the server closing an idle connection, as a slow subscriber's is,
+			// ends it as a client hanging up does
+			h.mu.Lock()
+			h.conns[0].Close()
+			h.mu.Unlock()
+			waitClosed(t, h, 1)
+			if _, err := conn.Read(make([]byte, 1)); err == nil {
+				t.Errorf("Close() failed. Expected the connection to be closed")
+			}
+		})
+	}
+}
+
+// subscribeHandler subscribes each connection to a channel, and closes it
+// when it falls behind, as the server does with a slow subscriber.
+type subscribeHandler struct {
+	broker *pubsub.Broker
+	closed chan struct{}
+}
+
+func (h *subscribeHandler) Connect(conn net.Conn) Conn {
+	c := &subscribeConn{broker: h.broker, closed: h.closed}
+	c.sub = h.broker.NewSubscriber(conn, func() { conn.Close() })
+	h.broker.Subscribe(c.sub, "channel")
+	return c
+}
+
+type subscribeConn struct {
+	broker *pubsub.Broker
+	sub    *pubsub.Subscriber
+	closed chan struct{}
+}
+
+func (c *subscribeConn) Serve(r *protocol.Reader) error {
+	_, err := r.ReadCommand()
+	return err
+}
+
+func (c *subscribeConn) Close() {
+	c.broker.Close(c.sub)
+	c.closed <- struct{}{}
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	for name, server := range layers(t) {
+		t.Run(name, func(t *testing.T) {
+			h := &subscribeHandler{broker: pubsub.NewBroker(), closed: make(chan struct{}, 1)}
+			addr := listen(t, server, h)
+
+			// a client that never reads, so its connection stays idle
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatalf("Dial() failed: %v", err)
+			}
+			defer conn.Close()
+			for h.broker.NumSub("channel")[0] == 0 {
+				time.Sleep(time.Millisecond)
+			}
+
+			// closing the connection from within Publish must not wait for
+			// the broker's lock Publish holds
+			published := make(chan struct{})
+			go func() {
+				defer close(published)
+				message := strings.Repeat("x", 64<<10)
+				for i := 0; i < 4*pubsub.QueueSize && h.broker.NumSub("channel")[0] > 0; i++ {
+					h.broker.Publish("channel", message)
+				}
+			}()
+			select {
+			case <-published:
+			case <-time.After(10 * time.Second):
+				t.Fatalf("Publish() failed. Expected the slow subscriber to be dropped, got: a deadlock")
+			}
+			select {
+			case <-h.closed:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Close() failed. Expected the slow subscriber's connection closed")
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+//go:build linux
+
+package network
+
+import (
+	"errors"
+	"log"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+
+	"github.com/Ryan-DL/go-redis-server/protocol"
+)
+
+// Reactor waits on every connection with one epoll instance. A connection is
+// registered one-shot: when it becomes readable it is handed to a goroutine,
+// with a Reader from a pool, that runs the commands it sent and registers it
+// again once it is drained. An idle connection so costs neither a goroutine
+// nor a read buffer. Commands still run on their own goroutine, so one that
+// blocks, such as BLPOP, holds up only its own client.
+type Reactor struct {
+	// MaxBulkLen is the longest argument a client may send.
+	MaxBulkLen int
+
+	epfd    int
+	poller  syscall.RawConn // epfd, waited on by the runtime's own poller
+	mu      sync.Mutex
+	conns   map[int]*reactorConn // by file descriptor
+	readers sync.Pool
+}
+
+const armEvents = syscall.EPOLLIN | syscall.EPOLLRDHUP | syscall.EPOLLONESHOT
+
+// NewReactor creates the epoll instance and starts waiting on it.
+func NewReactor(maxBulkLen int) (*Reactor, error) {
+	epfd, err := syscall.EpollCreate1(syscall.EPOLL_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	// a goroutine blocked in epoll_wait would hold on to its thread, and the
+	// goroutines it starts could wait for the scheduler to notice. An epoll
+	// instance is itself pollable, so the runtime waits on it instead.
+	if err := syscall.SetNonblock(epfd, true); err != nil {
+		syscall.Close(epfd)
+		return nil, err
+	}
+	poller, err := os.NewFile(uintptr(epfd), "epoll").SyscallConn()
+	if err != nil {
+		return nil, err
+	}
+	r := &Reactor{MaxBulkLen: maxBulkLen, epfd: epfd, poller: poller, conns: make(map[int]*reactorConn)}
+	go r.wait()
+	return r, nil
+}
+
+func (r *Reactor) Serve(l net.Listener, h Handler) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return err
+			}
+			log.Printf("Failed to accept connection: %v", err)
+			continue
+		}
+		r.add(conn, h)
+	}
+}
+
+// add registers a new connection.
+func (r *Reactor) add(conn net.Conn, h Handler) {
+	fd, err := fileDescriptor(conn)
+	if err != nil {
+		// not a socket epoll can wait on
+		go (&Goroutines{MaxBulkLen: r.MaxBulkLen}).serve(conn, h)
+		return
+	}
+	c := &reactorConn{Conn: conn, reactor: r, fd: fd}
+	// the handler gets the wrapper, so closing the connection unregisters it
+	c.handler = h.Connect(c)
+
+	r.mu.Lock()
+	r.conns[fd] = c
+	r.mu.Unlock()
+	event := syscall.EpollEvent{Events: armEvents, Fd: int32(fd)}
+	if err := syscall.EpollCtl(r.epfd, syscall.EPOLL_CTL_ADD, fd, &event); err != nil {
+		log.Printf("Failed to watch connection from %s: %v", conn.RemoteAddr(), err)
+		c.Close()
+	}
+}
+
+// wait hands each connection that becomes readable to a goroutine.
+func (r *Reactor) wait() {
+	events := make([]syscall.EpollEvent, 256)
+	for {
+		var n int
+		var err error
+		waitErr := r.poller.Read(func(uintptr) bool {
+			n, err = syscall.EpollWait(r.epfd, events, 0)
+			// with nothing ready, wait for the epoll instance to be readable
+			return n > 0 || (err != nil && err != syscall.EINTR)
+		})
+		if err == nil {
+			err = waitErr
+		}
+		if err != nil {
+			log.Printf("Failed to wait for connections: %v", err)
+			return
+		}
+		for _, event := range events[:n] {
+			r.mu.Lock()
+			c := r.conns[int(event.Fd)]
+			r.mu.Unlock()
+			if c != nil {
+				go c.serve()
+			}
+		}
+	}
+}
+
+// reader returns a Reader for conn from the pool.
+func (r *Reactor) reader(conn net.Conn) *protocol.Reader {
+	rd, _ := r.readers.Get().(*protocol.Reader)
+	if rd == nil {
+		rd = protocol.NewReader(conn)
+	} else {
+		rd.Reset(conn)
+	}
+	if r.MaxBulkLen > 0 {
+		rd.MaxBulkLen = r.MaxBulkLen
+	}
+	return rd
+}
+
+// reactorConn is a connection registered with a Reactor.
+type reactorConn struct {
+	net.Conn
+	reactor *Reactor
+	fd      int
+	handler Conn
+
+	mu     sync.Mutex
+	busy   bool // a goroutine is serving the connection
+	closed bool
+	ended  sync.Once
+}
+
+// serve runs the commands the client sent, then waits for more.
+func (c *reactorConn) serve() {
+	c.mu.Lock()
+	// an event may be stale: the connection is being served, or its file
+	// descriptor was closed and reused by the time the event was read
+	if c.busy || c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.busy = true
+	c.mu.Unlock()
+
+	rd := c.reactor.reader(c.Conn)
+	err := c.handler.Serve(rd)
+	c.reactor.readers.Put(rd)
+
+	c.mu.Lock()
+	c.busy = false
+	if err == nil && !c.closed {
+		event := syscall.EpollEvent{Events: armEvents, Fd: int32(c.fd)}
+		err = syscall.EpollCtl(c.reactor.epfd, syscall.EPOLL_CTL_MOD, c.fd, &event)
+	}
+	closed := c.closed
+	c.mu.Unlock()
+
+	if err != nil || closed {
+		c.Close()
+		c.end()
+	}
+}
+
+// Close unregisters and closes the connection. If it is idle, the handler
+// is told on a goroutine of its own; otherwise the goroutine serving it tells
+// it. Close may be called with locks held that the handler's Close takes, as
+// a slow subscriber's connection is closed from within PUBLISH.
+func (c *reactorConn) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	idle := !c.busy
+
+	// unregister while the file descriptor is still ours
+	syscall.EpollCtl(c.reactor.epfd, syscall.EPOLL_CTL_DEL, c.fd, nil)
+	c.reactor.mu.Lock()
+	if c.reactor.conns[c.fd] == c {
+		delete(c.reactor.conns, c.fd)
+	}
+	c.reactor.mu.Unlock()
+	err := c.Conn.Close()
+	c.mu.Unlock()
+
+	if idle {
+		go c.end()
+	}
+	return err
+}
+
+func (c *reactorConn) end() {
+	c.ended.Do(c.handler.Close)
+}
+
+// fileDescriptor returns the socket behind conn.
+func fileDescriptor(conn net.Conn) (int, error) {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return 0, errors.New("connection has no file descriptor")
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+	fd := -1
+	if err := raw.Control(func(f uintptr) { fd = int(f) }); err != nil {
+		return 0, err
+	}
+	return fd, nil
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+
+package network
+
+import (
+	"errors"
+	"net"
+)
+
+// Reactor is only implemented on Linux, where epoll is.
+type Reactor struct{}
+
+func NewReactor(maxBulkLen int) (*Reactor, error) {
+	return nil, errors.New("the epoll network layer is only available on Linux")
+}
+
+func (r *Reactor) Serve(l net.Listener, h Handler) error {
+	return errors.New("the epoll network layer is only available on Linux")
+}
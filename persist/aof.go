@@ -0,0 +1,461 @@
+package persist
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/protocol"
+)
+
+var ErrRewriteInProgress = errors.New("ERR Background append only file rewriting already in progress")
+
+// FsyncPolicy is upstream's appendfsync: how often the append only file is
+// flushed to disk.
+type FsyncPolicy int
+
+const (
+	FsyncAlways   FsyncPolicy = iota // after every write, before replying
+	FsyncEverySec                    // once a second in the background
+	FsyncNo                          // whenever the operating system likes
+)
+
+func ParseFsyncPolicy(s string) (FsyncPolicy, error) {
+	switch strings.ToLower(s) {
+	case "always":
+		return FsyncAlways, nil
+	case "everysec":
+		return FsyncEverySec, nil
+	case "no":
+		return FsyncNo, nil
+	}
+	return 0, fmt.Errorf("invalid appendfsync policy %q", s)
+}
+
+func (p FsyncPolicy) String() string {
+	switch p {
+	case FsyncAlways:
+		return "always"
+	case FsyncEverySec:
+		return "everysec"
+	default:
+		return "no"
+	}
+}
+
+// AOF is the append only file: every write command, in the RESP form clients
+// send, so replaying the file rebuilds the store. Rewriting replaces the log
+// with an RDB snapshot of the store followed by the writes made since.
+type AOF struct {
+	store *cache.ValueStore
+	path  string
+	fsync FsyncPolicy
+
+	mu       sync.Mutex
+	file     *os.File
+	buf      []byte
+	unsynced bool // written since the last fsync
+	writeErr error
+
+	rewriting      bool
+	rewriteBuf     []byte // writes made while the rewrite runs
+	lastRewriteErr error
+}
+
+func NewAOF(store *cache.ValueStore, path string, fsync FsyncPolicy) *AOF {
+	return &AOF{store: store, path: path, fsync: fsync}
+}
+
+func (a *AOF) Path() string {
+	return a.path
+}
+
+// Open opens the file for appending, creating it if needed. Load it first.
+func (a *AOF) Open() error {
+	f, err := os.OpenFile(a.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	a.file = f
+	a.mu.Unlock()
+	return nil
+}
+
+// Propagate appends a batch of write commands. A batch of more than one comes
+// from a transaction and is wrapped in MULTI and EXEC, so it is replayed
+// whole or not at all. With appendfsync always it returns once the batch is
+// on disk.
+func (a *AOF) Propagate(batch [][]string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil {
+		return // closed for shutdown
+	}
+
+	buf := AppendBatch(a.buf[:0], batch)
+	a.buf = buf
+
+	if a.rewriting {
+		a.rewriteBuf = append(a.rewriteBuf, buf...)
+	}
+
+	_, err := a.file.Write(buf)
+	if err == nil && a.fsync == FsyncAlways {
+		err = a.file.Sync()
+	}
+	if err != nil {
+		// a client has been told nothing yet, but it would be lied to
+		if a.fsync == FsyncAlways {
+			log.Fatalf("Can't recover from AOF write error when the AOF fsync policy is 'always': %v. Exiting...", err)
+		}
+		log.Printf("Error writing to the AOF file: %v", err)
+	}
+	a.writeErr = err
+	a.unsynced = a.fsync != FsyncAlways
+}
+
+// AppendBatch appends a batch of commands to buf, wrapped in MULTI and EXEC
+// if there is more than one, as they are written to the AOF and sent to
+// replicas.
+func AppendBatch(buf []byte, batch [][]string) []byte {
+	if len(batch) > 1 {
+		buf = AppendCommand(buf, []string{"MULTI"})
+	}
+	for _, args := range batch {
+		buf = AppendCommand(buf, args)
+	}
+	if len(batch) > 1 {
+		buf = AppendCommand(buf, []string{"EXEC"})
+	}
+	return buf
+}
+
+// AppendCommand appends args to buf as a RESP array of bulk strings.
+func AppendCommand(buf []byte, args []string) []byte {
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	return buf
+}
+
+// Run fsyncs the file once a second under appendfsync everysec. It never
+// returns.
+func (a *AOF) Run() {
+	if a.fsync != FsyncEverySec {
+		return
+	}
+	for {
+		time.Sleep(time.Second)
+		a.mu.Lock()
+		if a.unsynced && a.file != nil {
+			if err := a.file.Sync(); err != nil {
+				log.Printf("Error syncing the AOF file: %v", err)
+			}
+			a.unsynced = false
+		}
+		a.mu.Unlock()
+	}
+}
+
+// Close flushes the file to disk and closes it, for shutdown. A rewrite in
+// progress is abandoned.
+func (a *AOF) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Sync()
+	if closeErr := a.file.Close(); err == nil {
+		err = closeErr
+	}
+	a.file = nil
+	return err
+}
+
+// Rewrite compacts the file in the background, like BGREWRITEAOF. The
+// snapshot is taken before Rewrite returns, and the caller must keep writes
+// from being propagated meanwhile or they would be counted twice.
+func (a *AOF) Rewrite() error {
+	a.mu.Lock()
+	if a.rewriting {
+		a.mu.Unlock()
+		return ErrRewriteInProgress
+	}
+	a.rewriting = true
+	a.rewriteBuf = nil
+	a.mu.Unlock()
+
+	snap := a.store.Snapshot()
+	log.Printf("Background append only file rewriting started")
+	go a.rewrite(snap)
+	return nil
+}
+
+// rewrite writes snap to a temporary file, appends what was written since it
+// was taken and swaps the new file in.
+func (a *AOF) rewrite(snap *cache.Snapshot) {
+	tmp := filepath.Join(filepath.Dir(a.path), fmt.Sprintf("temp-rewriteaof-bg-%d.aof", os.Getpid()))
+	f, err := os.Create(tmp)
+	if err == nil {
+		err = snap.WriteRDB(f)
+	}
+
+	// writes wait from here until the new file takes over, so none is lost
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if err == nil {
+		_, err = f.Write(a.rewriteBuf)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmp, a.path)
+	}
+
+	if err == nil {
+		if a.file != nil {
+			a.file.Close()
+		}
+		a.file = f
+		// the rename only survives a crash once the directory is synced
+		if err = syncDir(a.path); err == nil {
+			log.Printf("Background AOF rewrite finished successfully")
+		} else {
+			log.Printf("Background AOF rewrite failed to sync the directory: %v", err)
+		}
+	} else {
+		if f != nil {
+			f.Close()
+			os.Remove(tmp)
+		}
+		log.Printf("Background AOF rewrite failed: %v", err)
+	}
+	a.rewriting = false
+	a.rewriteBuf = nil
+	a.lastRewriteErr = err
+}
+
+// syncDir flushes the directory holding path to disk, so a file renamed to
+// path is found there after a crash.
+func syncDir(path string) error {
+	dir, err := os.Open(filepath.Dir(path))
+	if err != nil {
+		return err
+	}
+	err = dir.Sync()
+	if closeErr := dir.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Load replays the file into the store with apply, reporting whether the
+// file exists. A command cut short at the end of the file, as a crash
+// mid-write leaves behind, is dropped and the file truncated before it; so
+// is a transaction missing its EXEC.
+func (a *AOF) Load(apply func(args []string) error) (bool, error) {
+	f, err := os.Open(a.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	counter := &countingReader{r: f}
+	r := bufio.NewReader(counter)
+	offset := func() int64 {
+		return counter.n - int64(r.Buffered())
+	}
+
+	// a rewritten file starts with a snapshot
+	if head, err := r.Peek(5); err == nil && string(head) == "REDIS" {
+		if err := a.store.LoadRDB(r); err != nil {
+			return true, fmt.Errorf("loading the RDB preamble: %w", err)
+		}
+	}
+
+	var multi [][]string
+	valid := offset() // end of the last command or transaction applied
+	for {
+		args, err := ReadCommand(r)
+		if err == io.EOF {
+			break
+		}
+		if err == io.ErrUnexpectedEOF {
+			log.Printf("!!! Warning: short read while loading the AOF file %s !!!", a.path)
+			break
+		}
+		if err != nil {
+			return true, fmt.Errorf("bad file format reading the append only file at offset %d: %w", offset(), err)
+		}
+
+		switch strings.ToUpper(args[0]) {
+		case "MULTI":
+			multi = [][]string{}
+			continue
+		case "EXEC":
+			for _, queued := range multi {
+				if err := apply(queued); err != nil {
+					return true, err
+				}
+			}
+			multi = nil
+		default:
+			if multi != nil {
+				multi = append(multi, args)
+				continue
+			}
+			if err := apply(args); err != nil {
+				return true, err
+			}
+		}
+		valid = offset()
+	}
+
+	if multi != nil {
+		log.Printf("Revert incomplete MULTI/EXEC transaction in AOF file %s", a.path)
+	}
+	if valid < offset() || multi != nil {
+		log.Printf("AOF %s loaded anyway because aof-load-truncated is enabled. Truncating to offset %d", a.path, valid)
+		if err := os.Truncate(a.path, valid); err != nil {
+			return true, err
+		}
+	}
+	return true, nil
+}
+
+// MaxBulkLen and MaxMultiBulkLen bound the lengths ReadCommand accepts, as
+// the protocol Reader's do for clients, so a corrupt file or a broken peer is
+// reported rather than making it allocate without limit. The server sets
+// MaxBulkLen to proto-max-bulk-len.
+var (
+	MaxBulkLen      = protocol.DefaultMaxBulkLen
+	MaxMultiBulkLen = protocol.DefaultMaxMultiBulkLen
+)
+
+// readChunk is how much of a bulk string is read, and allocated for, at once.
+const readChunk = 64 * 1024
+
+// ReadCommand reads one RESP array of bulk strings. It returns io.EOF at a
+// clean end of file and io.ErrUnexpectedEOF if the command is cut short.
+func ReadCommand(r *bufio.Reader) ([]string, error) {
+	if _, err := r.Peek(1); err != nil {
+		return nil, err
+	}
+	n, err := readLength(r, '*')
+	if err != nil {
+		return nil, err
+	}
+	if n < 1 {
+		return nil, errors.New("empty command")
+	}
+	if n > MaxMultiBulkLen {
+		return nil, fmt.Errorf("too many arguments: %d", n)
+	}
+	args := make([]string, n)
+	for i := range args {
+		size, err := readLength(r, '$')
+		if err != nil {
+			return nil, err
+		}
+		if size > MaxBulkLen {
+			return nil, fmt.Errorf("bulk string too long: %d bytes", size)
+		}
+		if args[i], err = readBulk(r, size); err != nil {
+			return nil, err
+		}
+	}
+	return args, nil
+}
+
+// readBulk reads a bulk string of size bytes and the CRLF after it. The
+// buffer grows as the bytes arrive, so a length that promises more than is
+// there costs no more than what is.
+func readBulk(r *bufio.Reader, size int) (string, error) {
+	buf := make([]byte, 0, min(size, readChunk))
+	for len(buf) < size {
+		chunk := min(size-len(buf), readChunk)
+		buf = slices.Grow(buf, chunk)
+		if _, err := io.ReadFull(r, buf[len(buf):len(buf)+chunk]); err != nil {
+			return "", io.ErrUnexpectedEOF
+		}
+		buf = buf[:len(buf)+chunk]
+	}
+	crlf, err := r.Peek(2)
+	if err != nil {
+		return "", io.ErrUnexpectedEOF
+	}
+	if string(crlf) != "\r\n" {
+		return "", errors.New("missing CRLF after bulk string")
+	}
+	r.Discard(2)
+	return string(buf), nil
+}
+
+// readLength reads a line such as "*3\r\n" and returns its number.
+func readLength(r *bufio.Reader, prefix byte) (int, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if len(line) < 3 || line[0] != prefix || line[len(line)-2] != '\r' {
+		return 0, fmt.Errorf("expected '%c' line, got %q", prefix, line)
+	}
+	n, err := strconv.Atoi(line[1 : len(line)-2])
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid length %q", line)
+	}
+	return n, nil
+}
+
+// countingReader counts the bytes read through it, so Load knows where in
+// the file each command ends.
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// AOFStatus describes the append only file for INFO persistence.
+type AOFStatus struct {
+	Rewriting         bool
+	LastRewriteFailed bool
+	LastWriteFailed   bool
+}
+
+func (a *AOF) Status() AOFStatus {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return AOFStatus{
+		Rewriting:         a.rewriting,
+		LastRewriteFailed: a.lastRewriteErr != nil,
+		LastWriteFailed:   a.writeErr != nil,
+	}
+}
@@ -0,0 +1,140 @@
+package persist
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+)
+
+// applySet replays SET commands, which is all these tests log.
+func applySet(store *cache.ValueStore) func(args []string) error {
+	return func(args []string) error {
+		store.Set(args[1], args[2], 0)
+		return nil
+	}
+}
+
+func openAOF(t *testing.T, store *cache.ValueStore, path string) *AOF {
+	t.Helper()
+	aof := NewAOF(store, path, FsyncAlways)
+	if err := aof.Open(); err != nil {
+		t.Fatalf("Open() failed: %s", err)
+	}
+	t.Cleanup(func() { aof.Close() })
+	return aof
+}
+
+func TestAOFLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	aof := openAOF(t, cache.NewValueStore(time.Minute), path)
+	aof.Propagate([][]string{{"SET", "a", "1"}})
+	aof.Propagate([][]string{{"SET", "b", "2"}, {"SET", "c", "3"}})
+	aof.Close()
+
+	store := cache.NewValueStore(time.Minute)
+	found, err := NewAOF(store, path, FsyncAlways).Load(applySet(store))
+	if !found || err != nil {
+		t.Fatalf("Load() failed. Expected the file to load, got: %v (%v)", found, err)
+	}
+	for key, expected := range map[string]string{"a": "1", "b": "2", "c": "3"} {
+		if value, _, _ := store.Get(key); value != expected {
+			t.Errorf("Load() failed. Expected %s: %s, got: %s", key, expected, value)
+		}
+	}
+
+	if found, err := NewAOF(store, filepath.Join(t.TempDir(), "missing.aof"), FsyncAlways).Load(applySet(store)); found || err != nil {
+		t.Errorf("Load() failed. Expected a missing file to be ignored, got: %v (%v)", found, err)
+	}
+}
+
+func TestAOFLoadTruncated(t *testing.T) {
+	for name, tail := range map[string]string{
+		"command":     "*3\r\n$3\r\nSET\r\n$1\r\nz\r\n$2\r\n2",
+		"transaction": "*1\r\n$5\r\nMULTI\r\n*3\r\n$3\r\nSET\r\n$1\r\nz\r\n$1\r\n2\r\n",
+	} {
+		path := filepath.Join(t.TempDir(), "appendonly.aof")
+		good := "*3\r\n$3\r\nSET\r\n$1\r\na\r\n$1\r\n1\r\n"
+		if err := os.WriteFile(path, []byte(good+tail), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		store := cache.NewValueStore(time.Minute)
+		if _, err := NewAOF(store, path, FsyncAlways).Load(applySet(store)); err != nil {
+			t.Fatalf("Load() failed for a truncated %s: %s", name, err)
+		}
+		if !store.Exists("a") || store.Exists("z") {
+			t.Errorf("Load() failed for a truncated %s. Expected only the complete command, got keys: %v", name, store.GetKeys())
+		}
+		if data, _ := os.ReadFile(path); string(data) != good {
+			t.Errorf("Load() failed for a truncated %s. Expected the tail to be cut, got: %q", name, data)
+		}
+	}
+}
+
+func TestAOFLoadCorrupt(t *testing.T) {
+	good := "*3\r\n$3\r\nSET\r\n$1\r\na\r\n$1\r\n1\r\n"
+	for _, tail := range []string{
+		"*9223372036854775807\r\n",
+		"*1\r\n$9223372036854775807\r\n",
+		"*1\r\n$9223372036854775806\r\nSET\r\n",
+	} {
+		path := filepath.Join(t.TempDir(), "appendonly.aof")
+		if err := os.WriteFile(path, []byte(good+tail), 0644); err != nil {
+			t.Fatal(err)
+		}
+		store := cache.NewValueStore(time.Minute)
+		if _, err := NewAOF(store, path, FsyncAlways).Load(applySet(store)); err == nil {
+			t.Errorf("Load() failed for %q. Expected a bad file format error", tail)
+		}
+	}
+
+	// a length within the limit that the file does not hold is a truncation
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	if err := os.WriteFile(path, []byte(good+"*1\r\n$100000000\r\nSET"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	store := cache.NewValueStore(time.Minute)
+	if _, err := NewAOF(store, path, FsyncAlways).Load(applySet(store)); err != nil || !store.Exists("a") {
+		t.Errorf("Load() failed. Expected a truncated long bulk string to be cut, got: %v", err)
+	}
+}
+
+func TestAOFRewrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "appendonly.aof")
+	store := cache.NewValueStore(time.Minute)
+	aof := openAOF(t, store, path)
+	for i := 0; i < 10; i++ {
+		store.Set("key", "old", 0)
+		aof.Propagate([][]string{{"SET", "key", "old"}})
+	}
+	store.Set("key", "new", 0)
+	aof.Propagate([][]string{{"SET", "key", "new"}})
+
+	if err := aof.Rewrite(); err != nil {
+		t.Fatalf("Rewrite() failed: %s", err)
+	}
+	// made while the rewrite runs, so it must be carried over
+	store.Set("later", "value", 0)
+	aof.Propagate([][]string{{"SET", "later", "value"}})
+	for aof.Status().Rewriting {
+		time.Sleep(time.Millisecond)
+	}
+	if aof.Status().LastRewriteFailed {
+		t.Fatal("Rewrite() failed. Expected the rewrite to succeed")
+	}
+	aof.Propagate([][]string{{"SET", "last", "value"}})
+	aof.Close()
+
+	loaded := cache.NewValueStore(time.Minute)
+	if _, err := NewAOF(loaded, path, FsyncAlways).Load(applySet(loaded)); err != nil {
+		t.Fatalf("Load() failed: %s", err)
+	}
+	for key, expected := range map[string]string{"key": "new", "later": "value", "last": "value"} {
+		if value, _, _ := loaded.Get(key); value != expected {
+			t.Errorf("Rewrite() failed. Expected %s: %s, got: %s", key, expected, value)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+// Package persist saves the store to disk and loads it back on startup.
+package persist
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+)
+
+var ErrSaveInProgress = errors.New("ERR Background save already in progress")
+
+// bgsaveRetryDelay is how long automatic saves wait after a failed one, like
+// upstream's CONFIG_BGSAVE_RETRY_DELAY.
+const bgsaveRetryDelay = 5 * time.Second
+
+// SaveRule triggers a background save once Changes writes have been made
+// and Seconds have passed since the last save, like "save 300 100".
+type SaveRule struct {
+	Seconds int
+	Changes int64
+}
+
+// ParseSaveRules parses upstream's save directive: pairs of seconds and
+// changes, such as "3600 1 300 100 60 10000". An empty string disables
+// automatic saving.
+func ParseSaveRules(s string) ([]SaveRule, error) {
+	fields := strings.Fields(s)
+	if len(fields)%2 != 0 {
+		return nil, fmt.Errorf("invalid save rules %q", s)
+	}
+	rules := make([]SaveRule, 0, len(fields)/2)
+	for i := 0; i < len(fields); i += 2 {
+		seconds, err := strconv.Atoi(fields[i])
+		if err != nil || seconds < 1 {
+			return nil, fmt.Errorf("invalid save rules %q", s)
+		}
+		changes, err := strconv.ParseInt(fields[i+1], 10, 64)
+		if err != nil || changes < 0 {
+			return nil, fmt.Errorf("invalid save rules %q", s)
+		}
+		rules = append(rules, SaveRule{Seconds: seconds, Changes: changes})
+	}
+	return rules, nil
+}
+
+// Saver writes RDB snapshots of a store to a file.
+type Saver struct {
+	store *cache.ValueStore
+	path  string
+	rules []SaveRule
+
+	// Locker, if set, is held while automatic saves take their snapshot so
+	// they never land in the middle of a transaction. Commands already run
+	// with it held and must not use it.
+	Locker sync.Locker
+
+	mu             sync.Mutex
+	saving         bool // a background save is running
+	scheduled      bool // BGSAVE SCHEDULE is waiting for it
+	lastSave       time.Time
+	lastSaveFailed time.Time
+	lastBGSaveErr  error
+}
+
+func NewSaver(store *cache.ValueStore, path string, rules []SaveRule) *Saver {
+	return &Saver{
+		store:    store,
+		path:     path,
+		rules:    rules,
+		lastSave: time.Now(), // at startup the store counts as saved
+	}
+}
+
+func (s *Saver) Path() string {
+	return s.path
+}
+
+// Load reads the file into the store. A missing file is not an error: the
+// server simply starts empty.
+func (s *Saver) Load() error {
+	f, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return s.store.LoadRDB(f)
+}
+
+// write writes snap to a temporary file and renames it over the target, so
+// a crash mid-save never leaves a truncated file behind.
+func (s *Saver) write(snap *cache.Snapshot) error {
+	tmp := filepath.Join(filepath.Dir(s.path), fmt.Sprintf("temp-%d-%d.rdb", os.Getpid(), time.Now().UnixNano()))
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	err = snap.WriteRDB(f)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, s.path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// saved records a successful save of snap.
+func (s *Saver) saved(snap *cache.Snapshot) {
+	s.store.Saved(snap)
+	s.mu.Lock()
+	s.lastSave = time.Now()
+	s.mu.Unlock()
+}
+
+// Save writes a snapshot in the foreground, like SAVE.
+func (s *Saver) Save() error {
+	s.mu.Lock()
+	if s.saving {
+		s.mu.Unlock()
+		return ErrSaveInProgress
+	}
+	s.mu.Unlock()
+
+	snap := s.store.Snapshot()
+	if err := s.write(snap); err != nil {
+		log.Printf("Error saving DB on disk: %v", err)
+		return err
+	}
+	s.saved(snap)
+	log.Printf("DB saved on disk")
+	return nil
+}
+
+// BGSave takes a snapshot and writes it in the background, like BGSAVE. The
+// snapshot is taken before BGSave returns.
+func (s *Saver) BGSave() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.saving {
+		return ErrSaveInProgress
+	}
+	s.startBGSave()
+	return nil
+}
+
+// ScheduleBGSave is BGSAVE SCHEDULE: it starts a background save, or if one
+// is running, starts another once it finishes. It reports whether the save
+// was scheduled rather than started.
+func (s *Saver) ScheduleBGSave() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.saving {
+		s.scheduled = true
+		return true
+	}
+	s.startBGSave()
+	return false
+}
+
+// startBGSave snapshots the store and saves it in a goroutine. Caller must
+// hold mu.
+func (s *Saver) startBGSave() {
+	s.saving = true
+	snap := s.store.Snapshot()
+	log.Printf("Background saving started")
+
+	go func() {
+		err := s.write(snap)
+		if err == nil {
+			s.store.Saved(snap)
+			log.Printf("Background saving terminated with success")
+		} else {
+			log.Printf("Background saving error: %v", err)
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.saving = false
+		s.lastBGSaveErr = err
+		if err == nil {
+			s.lastSave = time.Now()
+		} else {
+			s.lastSaveFailed = time.Now()
+		}
+		if s.scheduled {
+			s.scheduled = false
+			s.startBGSave()
+		}
+	}()
+}
+
+// LastSave returns when the store was last saved successfully.
+func (s *Saver) LastSave() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.lastSave
+}
+
+// Status describes the saver for INFO persistence.
+type Status struct {
+	Dirty          int64
+	InProgress     bool
+	LastSave       time.Time
+	LastBGSaveFail bool
+}
+
+func (s *Saver) Status() Status {
+	dirty := s.store.Dirty()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return Status{
+		Dirty:          dirty,
+		InProgress:     s.saving,
+		LastSave:       s.lastSave,
+		LastBGSaveFail: s.lastBGSaveErr != nil,
+	}
+}
+
+// Run checks the save rules every interval and starts a background save when
+// one is met. It never returns.
+func (s *Saver) Run(interval time.Duration) {
+	for {
+		time.Sleep(interval)
+		if s.due() {
+			s.lock()
+			s.BGSave()
+			s.unlock()
+		}
+	}
+}
+
+// due reports whether a save rule is met. After a failed save it waits a
+// little before trying again rather than retrying every tick.
+func (s *Saver) due() bool {
+	dirty := s.store.Dirty()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.saving || (s.lastBGSaveErr != nil && time.Since(s.lastSaveFailed) < bgsaveRetryDelay) {
+		return false
+	}
+	for _, rule := range s.rules {
+		if dirty >= rule.Changes && time.Since(s.lastSave) >= time.Duration(rule.Seconds)*time.Second {
+			return true
+		}
+	}
+	return false
+}
+
+// Shutdown saves in the foreground if any save rules are configured, or force
+// is set, as upstream does when it is asked to stop.
+func (s *Saver) Shutdown(force bool) error {
+	if len(s.rules) == 0 && !force {
+		return nil
+	}
+	s.lock()
+	defer s.unlock()
+	s.waitBGSave()
+	return s.Save()
+}
+
+func (s *Saver) waitBGSave() {
+	for {
+		s.mu.Lock()
+		saving := s.saving
+		s.mu.Unlock()
+		if !saving {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func (s *Saver) lock() {
+	if s.Locker != nil {
+		s.Locker.Lock()
+	}
+}
+
+func (s *Saver) unlock() {
+	if s.Locker != nil {
+		s.Locker.Unlock()
+	}
+}
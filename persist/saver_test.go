@@ -0,0 +1,53 @@
+package persist
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+)
+
+func TestParseSaveRules(t *testing.T) {
+	rules, err := ParseSaveRules("3600 1 300 100")
+	expected := []SaveRule{{Seconds: 3600, Changes: 1}, {Seconds: 300, Changes: 100}}
+	if err != nil || !reflect.DeepEqual(rules, expected) {
+		t.Errorf("ParseSaveRules() failed. Expected: %v, got: %v (%v)", expected, rules, err)
+	}
+	if rules, err := ParseSaveRules(""); err != nil || len(rules) != 0 {
+		t.Errorf("ParseSaveRules() failed. Expected no rules, got: %v (%v)", rules, err)
+	}
+	if _, err := ParseSaveRules("60"); err == nil {
+		t.Error("ParseSaveRules() failed. Expected an error for an odd number of fields")
+	}
+}
+
+func TestBGSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.rdb")
+	store := cache.NewValueStore(time.Minute)
+	store.Set("key", "value", 0)
+
+	saver := NewSaver(store, path, nil)
+	if err := saver.BGSave(); err != nil {
+		t.Fatalf("BGSave() failed: %s", err)
+	}
+	store.Set("later", "value", 0) // after the snapshot, so still dirty
+	saver.waitBGSave()
+
+	if dirty := store.Dirty(); dirty != 1 {
+		t.Errorf("BGSave() failed. Expected changes since the snapshot: 1, got: %d", dirty)
+	}
+
+	loaded := cache.NewValueStore(time.Minute)
+	if err := NewSaver(loaded, path, nil).Load(); err != nil {
+		t.Fatalf("Load() failed: %s", err)
+	}
+	if value, _, _ := loaded.Get("key"); value != "value" || loaded.Exists("later") {
+		t.Errorf("Load() failed. Expected only the snapshotted key, got keys: %v", loaded.GetKeys())
+	}
+
+	if err := NewSaver(loaded, filepath.Join(t.TempDir(), "missing.rdb"), nil).Load(); err != nil {
+		t.Errorf("Load() failed. Expected a missing file to be ignored, got: %s", err)
+	}
+}
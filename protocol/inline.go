@@ -0,0 +1,115 @@
+package protocol
+
+// Besides arrays of bulk strings, clients may send a command as one line of
+// text, as telnet users and health checks such as `printf 'PING\r\n' | nc` do.
+// The arguments are split the way redis-cli splits what is typed at it.
+
+// splitArgs splits line into arguments at whitespace, appending them to buf.
+// An argument may be quoted: in double quotes, \n, \r, \t, \b, \a and \xHH
+// escapes are understood and a backslash keeps any other character as is; in
+// single quotes only \' is. A closing quote must end the argument. It
+// reports false if the quotes are unbalanced.
+func (r *Reader) splitArgs(line []byte) bool {
+	i := 0
+	for {
+		for i < len(line) && isSpace(line[i]) {
+			i++
+		}
+		if i == len(line) {
+			return true
+		}
+
+		inDouble, inSingle := false, false
+		for done := false; !done; {
+			switch {
+			case inDouble:
+				if i == len(line) {
+					return false
+				}
+				switch c := line[i]; {
+				case c == '\\' && i+3 < len(line) && line[i+1] == 'x' && isHex(line[i+2]) && isHex(line[i+3]):
+					r.buf = append(r.buf, hexValue(line[i+2])<<4|hexValue(line[i+3]))
+					i += 3
+				case c == '\\' && i+1 < len(line):
+					i++
+					switch line[i] {
+					case 'n':
+						r.buf = append(r.buf, '\n')
+					case 'r':
+						r.buf = append(r.buf, '\r')
+					case 't':
+						r.buf = append(r.buf, '\t')
+					case 'b':
+						r.buf = append(r.buf, '\b')
+					case 'a':
+						r.buf = append(r.buf, '\a')
+					default:
+						r.buf = append(r.buf, line[i])
+					}
+				case c == '"':
+					// the closing quote must be followed by a space or nothing
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return false
+					}
+					done = true
+				default:
+					r.buf = append(r.buf, c)
+				}
+			case inSingle:
+				if i == len(line) {
+					return false
+				}
+				switch c := line[i]; {
+				case c == '\\' && i+1 < len(line) && line[i+1] == '\'':
+					i++
+					r.buf = append(r.buf, '\'')
+				case c == '\'':
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return false
+					}
+					done = true
+				default:
+					r.buf = append(r.buf, c)
+				}
+			default:
+				if i == len(line) {
+					done = true
+					continue
+				}
+				switch c := line[i]; {
+				case isSpace(c):
+					done = true
+				case c == '"':
+					inDouble = true
+				case c == '\'':
+					inSingle = true
+				default:
+					r.buf = append(r.buf, c)
+				}
+			}
+			if i < len(line) {
+				i++
+			}
+		}
+		r.ends = append(r.ends, len(r.buf))
+	}
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func hexValue(c byte) byte {
+	switch {
+	case c >= 'a':
+		return c - 'a' + 10
+	case c >= 'A':
+		return c - 'A' + 10
+	default:
+		return c - '0'
+	}
+}
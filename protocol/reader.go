@@ -0,0 +1,269 @@
+// Package protocol reads the commands clients send: arrays of bulk strings,
+// as RESP has them, or inline commands, a line of text each.
+//
+// Lengths in a request come from the client, so nothing is allocated up
+// front from them: they are checked against the Reader's limits, and a bulk
+// string's buffer grows as its bytes actually arrive.
+package protocol
+
+import (
+	"bufio"
+	"io"
+	"slices"
+)
+
+// The default limits, as upstream's.
+const (
+	DefaultMaxBulkLen      = 512 * 1024 * 1024 // proto-max-bulk-len
+	DefaultMaxMultiBulkLen = 1024 * 1024
+	MaxInlineSize          = 64 * 1024
+)
+
+// readChunk is how much of a bulk string is read, and allocated for, at once.
+const readChunk = 64 * 1024
+
+// maxKeptBuffer is the largest buffer kept for the next command; a larger
+// one, left by a big request, is dropped rather than held on to.
+const maxKeptBuffer = 1024 * 1024
+
+// Error is a protocol error: the client sent something that is not a
+// command. It is replied with, and the connection closed, as upstream does.
+type Error string
+
+func (e Error) Error() string {
+	return string(e)
+}
+
+const (
+	errMultiBulkLen Error = "Protocol error: invalid multibulk length"
+	errBulkLen      Error = "Protocol error: invalid bulk length"
+	errTooBigCount  Error = "Protocol error: too big mbulk count string"
+	errTooBigInline Error = "Protocol error: too big inline request"
+	errUnbalanced   Error = "Protocol error: unbalanced quotes in request"
+	errMissingCRLF  Error = "Protocol error: expected CRLF after bulk string"
+)
+
+// Reader reads commands from a connection.
+type Reader struct {
+	// MaxBulkLen is the longest argument, in bytes, and MaxMultiBulkLen the
+	// most arguments, a command may have.
+	MaxBulkLen      int
+	MaxMultiBulkLen int
+
+	r    *bufio.Reader
+	buf  []byte // the arguments of the last command, one after the other
+	ends []int  // where each argument ends in buf
+	args [][]byte
+	line []byte // an inline command being read
+}
+
+// NewReader returns a Reader with the default limits.
+func NewReader(rd io.Reader) *Reader {
+	return &Reader{
+		MaxBulkLen:      DefaultMaxBulkLen,
+		MaxMultiBulkLen: DefaultMaxMultiBulkLen,
+		r:               bufio.NewReader(rd),
+	}
+}
+
+// ReadCommand reads the next command. Empty arrays and blank lines are
+// skipped, as upstream does, so the command always has a name. The
+// arguments share a buffer the Reader reuses: they are only valid until the
+// next call, and must be copied to be kept.
+//
+// It returns an Error if the client broke the protocol, io.EOF if the
+// connection was closed between commands and io.ErrUnexpectedEOF if it was
+// closed in the middle of one.
+func (r *Reader) ReadCommand() ([][]byte, error) {
+	for {
+		if cap(r.buf) > maxKeptBuffer {
+			r.buf = nil
+		}
+		r.buf, r.ends = r.buf[:0], r.ends[:0]
+
+		prefix, err := r.r.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+		if prefix[0] == '*' {
+			err = r.readMultiBulk()
+		} else {
+			err = r.readInline()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(r.ends) > 0 {
+			break
+		}
+	}
+
+	r.args = r.args[:0]
+	start := 0
+	for _, end := range r.ends {
+		r.args = append(r.args, r.buf[start:end:end])
+		start = end
+	}
+	return r.args, nil
+}
+
+// Reset makes r read from rd instead, dropping anything buffered and
+// restoring the default limits, so idle connections can share Readers.
+func (r *Reader) Reset(rd io.Reader) {
+	r.r.Reset(rd)
+	r.MaxBulkLen, r.MaxMultiBulkLen = DefaultMaxBulkLen, DefaultMaxMultiBulkLen
+	r.buf, r.ends, r.args = r.buf[:0], r.ends[:0], r.args[:0]
+}
+
+// Buffered returns how many bytes have been received but not read yet: if
+// there are none, the client has no more commands pipelined.
+func (r *Reader) Buffered() int {
+	return r.r.Buffered()
+}
+
+// Peek returns the next n bytes without reading them, waiting for them to
+// arrive if need be.
+func (r *Reader) Peek(n int) ([]byte, error) {
+	return r.r.Peek(n)
+}
+
+// readMultiBulk reads an array of bulk strings.
+func (r *Reader) readMultiBulk() error {
+	n, err := r.readLength('*')
+	if err != nil {
+		return err
+	}
+	if n > r.MaxMultiBulkLen {
+		return errMultiBulkLen
+	}
+	for i := 0; i < n; i++ {
+		prefix, err := r.r.ReadByte()
+		if err != nil {
+			return io.ErrUnexpectedEOF
+		}
+		if prefix != '$' {
+			return Error("Protocol error: expected '$', got '" + string(prefix) + "'")
+		}
+		size, err := r.readLength('$')
+		if err != nil {
+			return err
+		}
+		if size < 0 || size > r.MaxBulkLen {
+			return errBulkLen
+		}
+		if err := r.readBulk(size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readLength reads the number after a '*' or '$' up to the end of its line.
+// The prefix itself has been read already for a '$'.
+func (r *Reader) readLength(prefix byte) (int, error) {
+	line, err := r.r.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		return 0, errTooBigCount
+	}
+	if err != nil {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if prefix == '*' {
+		line = line[1:]
+	}
+	n, ok := parseInt(trimEOL(line))
+	if !ok {
+		if prefix == '*' {
+			return 0, errMultiBulkLen
+		}
+		return 0, errBulkLen
+	}
+	return n, nil
+}
+
+// parseInt parses a decimal number, which may be negative, without the
+// allocation strconv.Atoi of a []byte costs.
+func parseInt(b []byte) (int, bool) {
+	neg := len(b) > 0 && b[0] == '-'
+	if neg {
+		b = b[1:]
+	}
+	// more digits than this could overflow, and no limit needs them
+	if len(b) == 0 || len(b) > 18 {
+		return 0, false
+	}
+	n := 0
+	for _, c := range b {
+		if c < '0' || c > '9' {
+			return 0, false
+		}
+		n = n*10 + int(c-'0')
+	}
+	if neg {
+		n = -n
+	}
+	return n, true
+}
+
+// readBulk reads size bytes, and the CRLF after them, into buf.
+func (r *Reader) readBulk(size int) error {
+	for size > 0 {
+		chunk := min(size, readChunk)
+		r.buf = slices.Grow(r.buf, chunk)
+		start := len(r.buf)
+		n, err := io.ReadFull(r.r, r.buf[start:start+chunk])
+		r.buf = r.buf[:start+n]
+		if err != nil {
+			return io.ErrUnexpectedEOF
+		}
+		size -= n
+	}
+	r.ends = append(r.ends, len(r.buf))
+
+	crlf, err := r.r.Peek(2)
+	if err != nil {
+		return io.ErrUnexpectedEOF
+	}
+	if crlf[0] != '\r' || crlf[1] != '\n' {
+		return errMissingCRLF
+	}
+	r.r.Discard(2)
+	return nil
+}
+
+// readInline reads an inline command up to its newline and splits it into
+// arguments.
+func (r *Reader) readInline() error {
+	r.line = r.line[:0]
+	for {
+		chunk, err := r.r.ReadSlice('\n')
+		r.line = append(r.line, chunk...)
+		if len(r.line) > MaxInlineSize {
+			return errTooBigInline
+		}
+		if err == nil {
+			break
+		}
+		if err != bufio.ErrBufferFull {
+			if len(r.line) == 0 {
+				return err
+			}
+			return io.ErrUnexpectedEOF
+		}
+	}
+	if !r.splitArgs(trimEOL(r.line)) {
+		return errUnbalanced
+	}
+	return nil
+}
+
+// trimEOL strips the "\n" or "\r\n" a line ends with.
+func trimEOL(line []byte) []byte {
+	if n := len(line); n > 0 && line[n-1] == '\n' {
+		line = line[:n-1]
+	}
+	if n := len(line); n > 0 && line[n-1] == '\r' {
+		line = line[:n-1]
+	}
+	return line
+}
@@ -0,0 +1,177 @@
+package protocol
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// readAll reads every command in input, copying the arguments.
+func readAll(r *Reader) ([][]string, error) {
+	var commands [][]string
+	for {
+		args, err := r.ReadCommand()
+		if err != nil {
+			return commands, err
+		}
+		command := make([]string, len(args))
+		for i, arg := range args {
+			command[i] = string(arg)
+		}
+		commands = append(commands, command)
+	}
+}
+
+// multiBulk encodes args as a client sends them.
+func multiBulk(args ...string) string {
+	s := "*" + strconv.Itoa(len(args)) + "\r\n"
+	for _, arg := range args {
+		s += "$" + strconv.Itoa(len(arg)) + "\r\n" + arg + "\r\n"
+	}
+	return s
+}
+
+func TestReadCommand(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected [][]string
+		err      error
+	}{
+		{multiBulk("SET", "k", "v") + multiBulk("GET", "k"), [][]string{{"SET", "k", "v"}, {"GET", "k"}}, io.EOF},
+		{multiBulk("SET", "k", "") + multiBulk("GET", "a\r\nb"), [][]string{{"SET", "k", ""}, {"GET", "a\r\nb"}}, io.EOF},
+		{"*0\r\n*-1\r\n" + multiBulk("PING"), [][]string{{"PING"}}, io.EOF},
+		{"PING\r\nSET k v\n\r\n   \r\nGET k\r\n", [][]string{{"PING"}, {"SET", "k", "v"}, {"GET", "k"}}, io.EOF},
+		{`SET "a b" "x\x41\n\"y" 'it\'s' ""` + "\r\n", [][]string{{"SET", "a b", "xA\n\"y", "it's", ""}}, io.EOF},
+		{`SET a"b c" 'd'` + "\n", [][]string{{"SET", "ab c", "d"}}, io.EOF},
+		{`GET "k` + "\r\n", nil, errUnbalanced},
+		{`GET "k"x` + "\r\n", nil, errUnbalanced},
+		{`GET 'k` + "\r\n", nil, errUnbalanced},
+		{"*x\r\n", nil, errMultiBulkLen},
+		{"*2000000\r\n", nil, errMultiBulkLen},
+		{"*1\r\n$-1\r\n", nil, errBulkLen},
+		{"*1\r\n$2000000000\r\n", nil, errBulkLen},
+		{"*1\r\n$x\r\n", nil, errBulkLen},
+		{"*1\r\n+PING\r\n", nil, Error("Protocol error: expected '$', got '+'")},
+		{"*1\r\n$4\r\nPINGXX", nil, errMissingCRLF},
+		{"*2\r\n$4\r\nPING\r\n", nil, io.ErrUnexpectedEOF},
+		{"*1\r\n$4\r\nPI", nil, io.ErrUnexpectedEOF},
+		{"PING", nil, io.ErrUnexpectedEOF},
+		{strings.Repeat("A", MaxInlineSize+1) + "\r\n", nil, errTooBigInline},
+		{"*" + strings.Repeat("1", 5000) + "\r\n", nil, errTooBigCount},
+	}
+	for _, tt := range tests {
+		commands, err := readAll(NewReader(strings.NewReader(tt.input)))
+		if err != tt.err || !reflect.DeepEqual(commands, tt.expected) {
+			t.Errorf("ReadCommand(%.40q) failed. Expected: %q, %v, got: %q, %v", tt.input, tt.expected, tt.err, commands, err)
+		}
+	}
+}
+
+func TestReadCommandLimits(t *testing.T) {
+	r := NewReader(strings.NewReader(multiBulk("SET", "k", "12345") + multiBulk("A", "B", "C")))
+	r.MaxBulkLen = 4
+	if _, err := r.ReadCommand(); err != errBulkLen {
+		t.Errorf("ReadCommand() failed. Expected: %v, got: %v", errBulkLen, err)
+	}
+
+	r = NewReader(strings.NewReader(multiBulk("A", "B", "C")))
+	r.MaxMultiBulkLen = 2
+	if _, err := r.ReadCommand(); err != errMultiBulkLen {
+		t.Errorf("ReadCommand() failed. Expected: %v, got: %v", errMultiBulkLen, err)
+	}
+
+	// a length a client claims is not allocated before the data arrives
+	r = NewReader(strings.NewReader("*1\r\n$500000000\r\nabc"))
+	if _, err := r.ReadCommand(); err != io.ErrUnexpectedEOF {
+		t.Errorf("ReadCommand() failed. Expected: %v, got: %v", io.ErrUnexpectedEOF, err)
+	}
+	if cap(r.buf) > readChunk {
+		t.Errorf("ReadCommand() failed. Expected at most %d bytes allocated, got: %d", readChunk, cap(r.buf))
+	}
+}
+
+func TestReadCommandReusesBuffer(t *testing.T) {
+	input := strings.Repeat(multiBulk("SET", "key", "value"), 100)
+	r := NewReader(strings.NewReader(input))
+	r.ReadCommand()
+	allocs := testing.AllocsPerRun(50, func() {
+		if _, err := r.ReadCommand(); err != nil {
+			t.Fatalf("ReadCommand() failed: %v", err)
+		}
+	})
+	if allocs > 0 {
+		t.Errorf("ReadCommand() failed. Expected: no allocations, got: %v", allocs)
+	}
+}
+
+// inline encodes args as an inline command, quoting every argument.
+func inline(args []string) string {
+	var b strings.Builder
+	for i, arg := range args {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteByte('"')
+		for _, c := range []byte(arg) {
+			switch {
+			case c == '"' || c == '\\':
+				b.WriteByte('\\')
+				b.WriteByte(c)
+			case c < ' ' || c > '~':
+				fmt.Fprintf(&b, "\\x%02x", c)
+			default:
+				b.WriteByte(c)
+			}
+		}
+		b.WriteByte('"')
+	}
+	return b.String() + "\r\n"
+}
+
+func FuzzReadCommand(f *testing.F) {
+	f.Add([]byte(multiBulk("SET", "k", "v")))
+	f.Add([]byte("*1\r\n$-1\r\n"))
+	f.Add([]byte(`SET "a\x41" 'b\'c'` + "\r\n"))
+	f.Add([]byte("*2\r\n$3\r\nGET\r\n$99999999999\r\n"))
+	f.Fuzz(func(t *testing.T, input []byte) {
+		r := NewReader(bytes.NewReader(input))
+		r.MaxBulkLen = 1024
+		r.MaxMultiBulkLen = 16
+		for {
+			args, err := r.ReadCommand()
+			if err != nil {
+				if _, ok := err.(Error); !ok && err != io.EOF && err != io.ErrUnexpectedEOF {
+					t.Fatalf("ReadCommand() failed. Unexpected error: %v", err)
+				}
+				return
+			}
+			if len(args) == 0 {
+				t.Fatalf("ReadCommand() failed. Expected a command, got none")
+			}
+			if len(args) > 16 && input[0] == '*' {
+				t.Fatalf("ReadCommand() failed. Expected at most 16 arguments, got: %d", len(args))
+			}
+		}
+	})
+}
+
+func FuzzRoundTrip(f *testing.F) {
+	f.Add("SET", "key", "value")
+	f.Add("", "a b", "\"quoted\"\r\n")
+	f.Add("x", "\\x41", "'")
+	f.Fuzz(func(t *testing.T, a, b, c string) {
+		args := []string{a, b, c}
+		commands, err := readAll(NewReader(strings.NewReader(multiBulk(args...) + inline(args))))
+		if err != io.EOF {
+			t.Fatalf("ReadCommand() failed: %v", err)
+		}
+		expected := [][]string{args, args}
+		if !reflect.DeepEqual(commands, expected) {
+			t.Fatalf("ReadCommand() failed. Expected: %q, got: %q", expected, commands)
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package protocol
+
+import "unsafe"
+
+// Arguments up to maxPacked bytes are packed together, into allocations of
+// at most packSize bytes, when converted to strings. A kept argument, such as
+// a key, then holds on to at most packSize bytes of the others; a larger
+// argument gets an allocation of its own so nothing is kept alive with it.
+const (
+	maxPacked = 64
+	packSize  = 256
+)
+
+// Strings converts the arguments of a command to strings commands may keep,
+// with a few allocations rather than one for each argument.
+func Strings(args [][]byte) []string {
+	command := make([]string, len(args))
+
+	packed := 0 // bytes of the small arguments not converted yet
+	for _, arg := range args {
+		if len(arg) <= maxPacked {
+			packed += len(arg)
+		}
+	}
+
+	var pack []byte
+	for i, arg := range args {
+		switch {
+		case len(arg) == 0:
+		case len(arg) > maxPacked:
+			command[i] = string(arg)
+		default:
+			if cap(pack)-len(pack) < len(arg) {
+				pack = make([]byte, 0, min(packed, packSize))
+			}
+			start := len(pack)
+			pack = append(pack, arg...)
+			packed -= len(arg)
+			// the bytes are never written again once appended
+			command[i] = unsafe.String(&pack[start], len(arg))
+		}
+	}
+	return command
+}
@@ -0,0 +1,38 @@
+package protocol
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStrings(t *testing.T) {
+	large := strings.Repeat("v", maxPacked+1)
+	tests := [][]string{
+		{"GET", "key"},
+		{"SET", "key", "", large},
+		append([]string{"MSET"}, strings.Split(strings.Repeat("k,"+strings.Repeat("x", maxPacked)+",", 10), ",")...),
+	}
+	for _, want := range tests {
+		args := make([][]byte, len(want))
+		for i, arg := range want {
+			args[i] = []byte(arg)
+		}
+		got := Strings(args)
+		// the strings must not change with the buffer they were read into
+		for _, arg := range args {
+			clear(arg)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Strings(%q) failed. Expected: %q, got: %q", want, want, got)
+		}
+	}
+}
+
+func TestStringsAllocs(t *testing.T) {
+	args := [][]byte{[]byte("SET"), []byte("key"), []byte("value")}
+	// one for the slice and one for the arguments
+	if allocs := testing.AllocsPerRun(100, func() { Strings(args) }); allocs != 2 {
+		t.Errorf("Strings() failed. Expected: 2 allocations, got: %v", allocs)
+	}
+}
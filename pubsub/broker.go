@@ -0,0 +1,308 @@
+// Package pubsub implements the broker behind SUBSCRIBE, PSUBSCRIBE and
+// PUBLISH. Each subscriber gets a bounded queue drained by its own writer
+// goroutine, so PUBLISH never waits on a slow client: a subscriber whose queue
+// fills up is dropped, like upstream's client-output-buffer-limit for pubsub.
+package pubsub
+
+import (
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Ryan-DL/go-redis-server/glob"
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// QueueSize is how many messages may be waiting for a subscriber before it is
+// considered too slow and disconnected.
+const QueueSize = 8192
+
+type Broker struct {
+	mu       sync.RWMutex
+	channels map[string]map[*Subscriber]struct{}
+	patterns map[string]map[*Subscriber]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		channels: make(map[string]map[*Subscriber]struct{}),
+		patterns: make(map[string]map[*Subscriber]struct{}),
+	}
+}
+
+// Subscriber is one connection's side of the broker. Once a connection has a
+// subscriber, everything it sends must go through Write so replies and pushed
+// messages stay in order.
+type Subscriber struct {
+	out      chan []byte
+	dead     chan struct{}
+	finished chan struct{}
+	kill     sync.Once
+	onKill   func()
+	proto    atomic.Int32 // the connection's protocol version, see SetProto
+
+	// guarded by the broker's mu
+	channels map[string]struct{}
+	patterns map[string]struct{}
+}
+
+// NewSubscriber starts a subscriber writing to w. onKill is called, once, if
+// the subscriber falls too far behind; it should close the connection.
+func (b *Broker) NewSubscriber(w io.Writer, onKill func()) *Subscriber {
+	s := &Subscriber{
+		out:      make(chan []byte, QueueSize),
+		dead:     make(chan struct{}),
+		finished: make(chan struct{}),
+		onKill:   onKill,
+		channels: make(map[string]struct{}),
+		patterns: make(map[string]struct{}),
+	}
+	s.proto.Store(2)
+	go s.writeLoop(w)
+	return s
+}
+
+// SetProto sets the protocol version messages are pushed in. RESP3 clients
+// get them as push messages, which they can tell apart from replies.
+func (s *Subscriber) SetProto(proto int) {
+	s.proto.Store(int32(proto))
+}
+
+func (s *Subscriber) writeLoop(w io.Writer) {
+	defer close(s.finished)
+	failed := false
+	for msg := range s.out {
+		// keep draining after a failed write so senders never block
+		if !failed {
+			_, err := w.Write(msg)
+			failed = err != nil
+		}
+	}
+}
+
+// Write queues p for the connection, waiting for room if necessary. It is
+// meant for the connection's own replies; the broker never waits.
+func (s *Subscriber) Write(p []byte) (int, error) {
+	msg := append([]byte(nil), p...)
+	select {
+	case s.out <- msg:
+		return len(p), nil
+	case <-s.dead:
+		return 0, net.ErrClosed
+	}
+}
+
+// push queues msg without waiting, dropping the subscriber if it is full.
+// Caller must hold the broker's mu.
+func (s *Subscriber) push(msg []byte) {
+	select {
+	case s.out <- msg:
+	default:
+		s.kill.Do(func() {
+			close(s.dead)
+			if s.onKill != nil {
+				s.onKill()
+			}
+		})
+	}
+}
+
+// count returns how many channels and patterns s is subscribed to. Caller
+// must hold the broker's mu.
+func (s *Subscriber) count() int {
+	return len(s.channels) + len(s.patterns)
+}
+
+// Count returns how many channels and patterns s is subscribed to.
+func (b *Broker) Count(s *Subscriber) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return s.count()
+}
+
+// confirm queues the reply to a (un)subscribe for one channel or pattern.
+// target is nil when unsubscribing from nothing. Caller must hold mu.
+func (s *Subscriber) confirm(kind string, target *string) {
+	var name response.DataType = response.NullBulkString{}
+	if target != nil {
+		name = response.BulkStringType(*target)
+	}
+	reply := response.PushType{response.BulkStringType(kind), name, response.IntegerType(s.count())}
+	s.push([]byte(response.Serialize(reply, int(s.proto.Load()))))
+}
+
+// message is a push serialized once for each protocol version its
+// receivers speak.
+type message struct {
+	push       response.PushType
+	serialized [2][]byte // RESP2 and RESP3
+}
+
+func newMessage(parts ...string) *message {
+	push := make(response.PushType, len(parts))
+	for i, part := range parts {
+		push[i] = response.BulkStringType(part)
+	}
+	return &message{push: push}
+}
+
+func (m *message) to(s *Subscriber) []byte {
+	proto := int(s.proto.Load())
+	if m.serialized[proto-2] == nil {
+		m.serialized[proto-2] = []byte(response.Serialize(m.push, proto))
+	}
+	return m.serialized[proto-2]
+}
+
+// Subscribe subscribes s to channels and confirms each one. Confirmations are
+// queued under the lock so they always precede messages on the channel.
+func (b *Broker) Subscribe(s *Subscriber, channels ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, channel := range channels {
+		subscribe(b.channels, s.channels, s, channel)
+		s.confirm("subscribe", &channel)
+	}
+}
+
+// PSubscribe is Subscribe for glob-style patterns.
+func (b *Broker) PSubscribe(s *Subscriber, patterns ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, pattern := range patterns {
+		subscribe(b.patterns, s.patterns, s, pattern)
+		s.confirm("psubscribe", &pattern)
+	}
+}
+
+// Unsubscribe unsubscribes s from channels, or from every channel if none are
+// given, and confirms each one.
+func (b *Broker) Unsubscribe(s *Subscriber, channels ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	unsubscribe(b.channels, s.channels, s, channels, "unsubscribe")
+}
+
+// PUnsubscribe is Unsubscribe for patterns.
+func (b *Broker) PUnsubscribe(s *Subscriber, patterns ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	unsubscribe(b.patterns, s.patterns, s, patterns, "punsubscribe")
+}
+
+func subscribe(index map[string]map[*Subscriber]struct{}, own map[string]struct{}, s *Subscriber, name string) {
+	if index[name] == nil {
+		index[name] = make(map[*Subscriber]struct{})
+	}
+	index[name][s] = struct{}{}
+	own[name] = struct{}{}
+}
+
+func unsubscribe(index map[string]map[*Subscriber]struct{}, own map[string]struct{}, s *Subscriber, names []string, kind string) {
+	if len(names) == 0 {
+		if len(own) == 0 {
+			s.confirm(kind, nil)
+			return
+		}
+		for name := range own {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	for _, name := range names {
+		delete(own, name)
+		delete(index[name], s)
+		if len(index[name]) == 0 {
+			delete(index, name)
+		}
+		s.confirm(kind, &name)
+	}
+}
+
+// Close removes s from every channel and pattern, stops its writer and waits
+// for queued replies to be written or dropped.
+func (b *Broker) Close(s *Subscriber) {
+	b.mu.Lock()
+	for channel := range s.channels {
+		delete(b.channels[channel], s)
+		if len(b.channels[channel]) == 0 {
+			delete(b.channels, channel)
+		}
+	}
+	for pattern := range s.patterns {
+		delete(b.patterns[pattern], s)
+		if len(b.patterns[pattern]) == 0 {
+			delete(b.patterns, pattern)
+		}
+	}
+	clear(s.channels)
+	clear(s.patterns)
+	close(s.out)
+	b.mu.Unlock()
+
+	<-s.finished
+}
+
+// Publish sends message to every subscriber of channel and of a pattern
+// matching it, returning how many received it.
+func (b *Broker) Publish(channel, message string) int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	receivers := 0
+	if subscribers := b.channels[channel]; len(subscribers) > 0 {
+		msg := newMessage("message", channel, message)
+		for s := range subscribers {
+			s.push(msg.to(s))
+			receivers++
+		}
+	}
+	for pattern, subscribers := range b.patterns {
+		if !glob.Match(pattern, channel) {
+			continue
+		}
+		msg := newMessage("pmessage", pattern, channel, message)
+		for s := range subscribers {
+			s.push(msg.to(s))
+			receivers++
+		}
+	}
+	return receivers
+}
+
+// Channels returns the channels with at least one subscriber, filtered by
+// pattern unless it is empty.
+func (b *Broker) Channels(pattern string) []string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	channels := []string{}
+	for channel := range b.channels {
+		if pattern == "" || glob.Match(pattern, channel) {
+			channels = append(channels, channel)
+		}
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// NumSub returns the number of subscribers of each channel.
+func (b *Broker) NumSub(channels ...string) []int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	counts := make([]int, len(channels))
+	for i, channel := range channels {
+		counts[i] = len(b.channels[channel])
+	}
+	return counts
+}
+
+// NumPat returns the number of patterns subscribed to by any client.
+func (b *Broker) NumPat() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.patterns)
+}
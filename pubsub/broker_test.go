@@ -0,0 +1,93 @@
+package pubsub
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder collects everything a subscriber writes.
+type recorder struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (r *recorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.buf.Write(p)
+}
+
+func (r *recorder) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.buf.String()
+}
+
+func TestPublishToChannelsAndPatterns(t *testing.T) {
+	b := NewBroker()
+	out := &recorder{}
+	s := b.NewSubscriber(out, nil)
+	b.Subscribe(s, "news")
+	b.PSubscribe(s, "n*")
+
+	if receivers := b.Publish("news", "hi"); receivers != 2 {
+		t.Errorf("Publish() failed. Expected 2 receivers, got %d", receivers)
+	}
+	if receivers := b.Publish("other", "hi"); receivers != 0 {
+		t.Errorf("Publish() failed. Expected 0 receivers, got %d", receivers)
+	}
+	b.Close(s)
+
+	expected := "*3\r\n$9\r\nsubscribe\r\n$4\r\nnews\r\n:1\r\n" +
+		"*3\r\n$10\r\npsubscribe\r\n$2\r\nn*\r\n:2\r\n" +
+		"*3\r\n$7\r\nmessage\r\n$4\r\nnews\r\n$2\r\nhi\r\n" +
+		"*4\r\n$8\r\npmessage\r\n$2\r\nn*\r\n$4\r\nnews\r\n$2\r\nhi\r\n"
+	if actual := out.String(); actual != expected {
+		t.Errorf("Subscriber output failed. Expected: %q, got: %q", expected, actual)
+	}
+	if channels := b.Channels(""); len(channels) != 0 {
+		t.Errorf("Channels() after Close() failed. Expected none, got %v", channels)
+	}
+}
+
+// blockingWriter never returns, like a client that stopped reading.
+type blockingWriter struct{ release chan struct{} }
+
+func (w blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	return len(p), nil
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	b := NewBroker()
+	w := blockingWriter{release: make(chan struct{})}
+	killed := make(chan struct{})
+	s := b.NewSubscriber(w, func() { close(killed) })
+	b.Subscribe(s, "bulk")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		message := strings.Repeat("x", 16)
+		for i := 0; i < QueueSize+10; i++ {
+			b.Publish("bulk", message)
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish() blocked on a slow subscriber")
+	}
+	select {
+	case <-killed:
+	default:
+		t.Error("Slow subscriber was not dropped")
+	}
+
+	close(w.release)
+	b.Close(s)
+}
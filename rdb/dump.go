@@ -0,0 +1,57 @@
+package rdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// DumpFunctions returns the payload of FUNCTION DUMP: a function record for
+// each library's code, framed as DUMP frames a value, with the RDB version
+// and a checksum of the whole at the end.
+func DumpFunctions(codes []string) []byte {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, code := range codes {
+		w.Function(code)
+	}
+	binary.LittleEndian.PutUint16(w.buf[:], Version)
+	w.Raw(w.buf[:2])
+	binary.LittleEndian.PutUint64(w.buf[:], w.crc)
+	w.w.Write(w.buf[:8])
+	w.w.Flush() // writing to a bytes.Buffer does not fail
+	return buf.Bytes()
+}
+
+// LoadFunctions returns the libraries' code in a FUNCTION DUMP payload. It
+// fails with ErrBadChecksum if the footer does not match.
+func LoadFunctions(payload []byte) ([]string, error) {
+	n := len(payload)
+	if n < 10 {
+		return nil, ErrBadChecksum
+	}
+	version := binary.LittleEndian.Uint16(payload[n-10:])
+	if version > MaxVersion || crcUpdate(0, payload[:n-8]) != binary.LittleEndian.Uint64(payload[n-8:]) {
+		return nil, ErrBadChecksum
+	}
+
+	var codes []string
+	r := NewReader(bytes.NewReader(payload[:n-10]))
+	for {
+		if _, err := r.r.Peek(1); err == io.EOF {
+			return codes, nil
+		}
+		op, err := r.Byte()
+		if err != nil {
+			return nil, err
+		}
+		if op != OpFunction2 {
+			return nil, ErrCorrupt
+		}
+		code, err := r.String()
+		if err != nil {
+			return nil, err
+		}
+		codes = append(codes, code)
+	}
+}
@@ -0,0 +1,225 @@
+package rdb
+
+import (
+	"encoding/binary"
+	"math"
+	"strconv"
+)
+
+// Listpack builds a listpack, the compact list encoding upstream uses for
+// small aggregates and stream nodes.
+type Listpack struct {
+	entries []byte
+	count   int
+}
+
+// AppendString appends s. Unlike upstream it never converts strings that look
+// like integers; readers accept either.
+func (lp *Listpack) AppendString(s string) {
+	start := len(lp.entries)
+	switch n := len(s); {
+	case n < 64:
+		lp.entries = append(lp.entries, 0x80|byte(n))
+	case n < 4096:
+		lp.entries = append(lp.entries, 0xe0|byte(n>>8), byte(n))
+	default:
+		lp.entries = append(lp.entries, 0xf0)
+		lp.entries = binary.LittleEndian.AppendUint32(lp.entries, uint32(n))
+	}
+	lp.entries = append(lp.entries, s...)
+	lp.finish(start)
+}
+
+// AppendInt appends v using the smallest integer encoding that holds it.
+func (lp *Listpack) AppendInt(v int64) {
+	start := len(lp.entries)
+	switch {
+	case v >= 0 && v <= 127:
+		lp.entries = append(lp.entries, byte(v))
+	case v >= -4096 && v <= 4095:
+		u := uint16(v) & 0x1fff
+		lp.entries = append(lp.entries, 0xc0|byte(u>>8), byte(u))
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		lp.entries = append(lp.entries, 0xf1)
+		lp.entries = binary.LittleEndian.AppendUint16(lp.entries, uint16(v))
+	case v >= -1<<23 && v < 1<<23:
+		u := uint32(v)
+		lp.entries = append(lp.entries, 0xf2, byte(u), byte(u>>8), byte(u>>16))
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		lp.entries = append(lp.entries, 0xf3)
+		lp.entries = binary.LittleEndian.AppendUint32(lp.entries, uint32(v))
+	default:
+		lp.entries = append(lp.entries, 0xf4)
+		lp.entries = binary.LittleEndian.AppendUint64(lp.entries, uint64(v))
+	}
+	lp.finish(start)
+}
+
+// finish appends the back length of the entry starting at start, which lets
+// upstream walk a listpack backwards.
+func (lp *Listpack) finish(start int) {
+	n := uint64(len(lp.entries) - start)
+	switch {
+	case n <= 127:
+		lp.entries = append(lp.entries, byte(n))
+	case n < 16383:
+		lp.entries = append(lp.entries, byte(n>>7), byte(n&127)|128)
+	case n < 2097151:
+		lp.entries = append(lp.entries, byte(n>>14), byte((n>>7)&127)|128, byte(n&127)|128)
+	case n < 268435455:
+		lp.entries = append(lp.entries, byte(n>>21), byte((n>>14)&127)|128, byte((n>>7)&127)|128, byte(n&127)|128)
+	default:
+		lp.entries = append(lp.entries, byte(n>>28), byte((n>>21)&127)|128, byte((n>>14)&127)|128, byte((n>>7)&127)|128, byte(n&127)|128)
+	}
+	lp.count++
+}
+
+func (lp *Listpack) Len() int {
+	return lp.count
+}
+
+// Bytes returns the encoded listpack: header, entries and terminator.
+func (lp *Listpack) Bytes() []byte {
+	total := 6 + len(lp.entries) + 1
+	b := make([]byte, 6, total)
+	binary.LittleEndian.PutUint32(b, uint32(total))
+	count := lp.count
+	if count >= math.MaxUint16 {
+		count = math.MaxUint16 // unknown, readers must count
+	}
+	binary.LittleEndian.PutUint16(b[4:], uint16(count))
+	b = append(b, lp.entries...)
+	return append(b, 0xff)
+}
+
+func backlenSize(n int) int {
+	switch {
+	case n <= 127:
+		return 1
+	case n < 16383:
+		return 2
+	case n < 2097151:
+		return 3
+	case n < 268435455:
+		return 4
+	default:
+		return 5
+	}
+}
+
+// ParseListpack returns the entries of a listpack. Integers are returned in
+// their decimal form.
+func ParseListpack(b []byte) ([]string, error) {
+	if len(b) < 7 || int(binary.LittleEndian.Uint32(b)) != len(b) {
+		return nil, ErrCorrupt
+	}
+	var entries []string
+	p := b[6:]
+	for {
+		if len(p) == 0 {
+			return nil, ErrCorrupt
+		}
+		if p[0] == 0xff {
+			return entries, nil
+		}
+
+		value, size, err := listpackEntry(p)
+		if err != nil {
+			return nil, err
+		}
+		size += backlenSize(size)
+		if size > len(p) {
+			return nil, ErrCorrupt
+		}
+		entries = append(entries, value)
+		p = p[size:]
+	}
+}
+
+// listpackEntry decodes the entry at the start of p, returning its value and
+// encoded size without the back length.
+func listpackEntry(p []byte) (string, int, error) {
+	str := func(header, n int) (string, int, error) {
+		if header+n > len(p) {
+			return "", 0, ErrCorrupt
+		}
+		return string(p[header : header+n]), header + n, nil
+	}
+	integer := func(n int) (string, int, error) {
+		if 1+n > len(p) {
+			return "", 0, ErrCorrupt
+		}
+		var u uint64
+		for i := n; i >= 1; i-- {
+			u = u<<8 | uint64(p[i])
+		}
+		shift := 64 - 8*n
+		return strconv.FormatInt(int64(u<<shift)>>shift, 10), 1 + n, nil
+	}
+
+	b := p[0]
+	switch {
+	case b&0x80 == 0:
+		return strconv.Itoa(int(b)), 1, nil
+	case b&0xc0 == 0x80:
+		return str(1, int(b&0x3f))
+	case b&0xe0 == 0xc0:
+		if len(p) < 2 {
+			return "", 0, ErrCorrupt
+		}
+		v := int64(b&0x1f)<<8 | int64(p[1])
+		if v >= 1<<12 {
+			v -= 1 << 13
+		}
+		return strconv.FormatInt(v, 10), 2, nil
+	case b&0xf0 == 0xe0:
+		if len(p) < 2 {
+			return "", 0, ErrCorrupt
+		}
+		return str(2, int(b&0x0f)<<8|int(p[1]))
+	}
+	switch b {
+	case 0xf0:
+		if len(p) < 5 {
+			return "", 0, ErrCorrupt
+		}
+		return str(5, int(binary.LittleEndian.Uint32(p[1:])))
+	case 0xf1:
+		return integer(2)
+	case 0xf2:
+		return integer(3)
+	case 0xf3:
+		return integer(4)
+	case 0xf4:
+		return integer(8)
+	}
+	return "", 0, ErrCorrupt
+}
+
+// ParseIntset returns the members of an intset, the encoding upstream uses
+// for small sets of integers.
+func ParseIntset(b []byte) ([]string, error) {
+	if len(b) < 8 {
+		return nil, ErrCorrupt
+	}
+	width := int(binary.LittleEndian.Uint32(b))
+	n := int(binary.LittleEndian.Uint32(b[4:]))
+	if (width != 2 && width != 4 && width != 8) || len(b) != 8+n*width {
+		return nil, ErrCorrupt
+	}
+	members := make([]string, n)
+	for i := range members {
+		p := b[8+i*width:]
+		var v int64
+		switch width {
+		case 2:
+			v = int64(int16(binary.LittleEndian.Uint16(p)))
+		case 4:
+			v = int64(int32(binary.LittleEndian.Uint32(p)))
+		default:
+			v = int64(binary.LittleEndian.Uint64(p))
+		}
+		members[i] = strconv.FormatInt(v, 10)
+	}
+	return members, nil
+}
@@ -0,0 +1,79 @@
+// Package rdb reads and writes the building blocks of upstream's RDB snapshot
+// format: lengths, strings, doubles, listpacks and the file framing. It knows
+// nothing about the store; cache builds snapshots out of these pieces.
+//
+// Files are written as RDB version 11, the format of Redis 7.2, so they can be
+// checked with redis-check-rdb and loaded by a real server.
+package rdb
+
+import (
+	"errors"
+	"hash/crc64"
+)
+
+// Version is the RDB version written by Writer. Reader accepts files up to
+// and including MaxVersion.
+const (
+	Version    = 11
+	MaxVersion = 12
+)
+
+// Value types, written before each key.
+const (
+	TypeString           = 0
+	TypeList             = 1
+	TypeSet              = 2
+	TypeZSet             = 3
+	TypeHash             = 4
+	TypeZSet2            = 5
+	TypeSetIntset        = 11
+	TypeListQuicklist    = 14
+	TypeStreamListpacks  = 15
+	TypeHashListpack     = 16
+	TypeZSetListpack     = 17
+	TypeListQuicklist2   = 18
+	TypeStreamListpacks2 = 19
+	TypeSetListpack      = 20
+	TypeStreamListpacks3 = 21
+)
+
+// Opcodes, which share the type byte's position in the file.
+const (
+	OpFunction2    = 245
+	OpModuleAux    = 247
+	OpIdle         = 248
+	OpFreq         = 249
+	OpAux          = 250
+	OpResizeDB     = 251
+	OpExpireTimeMs = 252
+	OpExpireTime   = 253
+	OpSelectDB     = 254
+	OpEOF          = 255
+)
+
+// Quicklist node containers in a TypeListQuicklist2 value.
+const (
+	QuicklistPlain  = 1
+	QuicklistPacked = 2
+)
+
+// Stream entry flags inside a stream listpack.
+const (
+	StreamItemDeleted    = 1
+	StreamItemSameFields = 2
+)
+
+var (
+	ErrBadHeader   = errors.New("rdb: wrong signature trying to load DB from file")
+	ErrBadChecksum = errors.New("rdb: checksum mismatch")
+	ErrCorrupt     = errors.New("rdb: corrupt file")
+)
+
+// crcTable is upstream's CRC-64 (Jones polynomial, reflected). The standard
+// library inverts the register before and after, which upstream does not, so
+// updates go through crcUpdate.
+var crcTable = crc64.MakeTable(0x95ac9329ac4bc9b5)
+
+func crcUpdate(crc uint64, p []byte) uint64 {
+	return ^crc64.Update(^crc, crcTable, p)
+}
@@ -0,0 +1,137 @@
+package rdb
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestCRC64(t *testing.T) {
+	// the check value from upstream's crc64 self test
+	if crc := crcUpdate(0, []byte("123456789")); crc != 0xe9c6d914c4b8d9ca {
+		t.Errorf("crcUpdate() failed. Expected: %x, got: %x", uint64(0xe9c6d914c4b8d9ca), crc)
+	}
+}
+
+func TestListpackRoundTrip(t *testing.T) {
+	ints := []int64{0, 127, 128, -1, 4095, -4096, 4096, math.MaxInt16, math.MinInt16, 1 << 20, -1 << 23, math.MaxInt32, math.MinInt32, math.MaxInt64, math.MinInt64}
+	strs := []string{"", "a", strings.Repeat("b", 63), strings.Repeat("c", 64), strings.Repeat("d", 4095), strings.Repeat("e", 4096)}
+
+	var lp Listpack
+	var expected []string
+	for _, v := range ints {
+		lp.AppendInt(v)
+		expected = append(expected, strconv.FormatInt(v, 10))
+	}
+	for _, s := range strs {
+		lp.AppendString(s)
+		expected = append(expected, s)
+	}
+
+	got, err := ParseListpack(lp.Bytes())
+	if err != nil {
+		t.Fatalf("ParseListpack() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("ParseListpack() failed. Expected: %q, got: %q", expected, got)
+	}
+}
+
+func TestListpackEncoding(t *testing.T) {
+	// upstream's encoding of ["a", 1, -1]: header, entries with back lengths,
+	// terminator
+	var lp Listpack
+	lp.AppendString("a")
+	lp.AppendInt(1)
+	lp.AppendInt(-1)
+	expected := []byte{
+		15, 0, 0, 0, 3, 0,
+		0x81, 'a', 2,
+		0x01, 1,
+		0xdf, 0xff, 2,
+		0xff,
+	}
+	if got := lp.Bytes(); !bytes.Equal(got, expected) {
+		t.Errorf("Listpack.Bytes() failed. Expected: %x, got: %x", expected, got)
+	}
+}
+
+func TestWriterReaderRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	w.Header()
+	lengths := []uint64{0, 63, 64, 16383, 16384, math.MaxUint32, math.MaxUint32 + 1}
+	for _, n := range lengths {
+		w.Length(n)
+	}
+	strs := []string{"", "hello", "12", "-129", "65536", "2147483648", "007", strings.Repeat("x", 300)}
+	for _, s := range strs {
+		w.String(s)
+	}
+	w.Double(math.Inf(-1))
+	w.Millis(1700000000123)
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() failed: %v", err)
+	}
+
+	r := NewReader(&buf)
+	version, err := r.Header()
+	if err != nil || version != Version {
+		t.Fatalf("Header() failed. Expected: %d, got: %d (%v)", Version, version, err)
+	}
+	for _, n := range lengths {
+		if got, err := r.Length(); err != nil || got != n {
+			t.Errorf("Length() failed. Expected: %d, got: %d (%v)", n, got, err)
+		}
+	}
+	for _, s := range strs {
+		if got, err := r.String(); err != nil || got != s {
+			t.Errorf("String() failed. Expected: %q, got: %q (%v)", s, got, err)
+		}
+	}
+	if got, _ := r.Double(); !math.IsInf(got, -1) {
+		t.Errorf("Double() failed. Expected: -inf, got: %v", got)
+	}
+	if got, _ := r.Millis(); got != 1700000000123 {
+		t.Errorf("Millis() failed. Expected: %d, got: %d", 1700000000123, got)
+	}
+	if op, _ := r.Byte(); op != OpEOF {
+		t.Fatalf("Byte() failed. Expected EOF opcode, got: %d", op)
+	}
+	if err := r.Checksum(version); err != nil {
+		t.Errorf("Checksum() failed: %v", err)
+	}
+}
+
+func TestLZFDecompress(t *testing.T) {
+	// a literal run of "abc" then a back reference repeating it four times
+	in := []byte{2, 'a', 'b', 'c', 0xe0, 3, 2}
+	got, err := lzfDecompress(in, 15)
+	if err != nil || string(got) != "abcabcabcabcabc" {
+		t.Errorf("lzfDecompress() failed. Expected: %q, got: %q (%v)", "abcabcabcabcabc", got, err)
+	}
+}
+
+func TestDumpFunctions(t *testing.T) {
+	codes := []string{"#!lua name=a\nredis.register_function('f', function() return 1 end)", "#!lua name=b\n"}
+	payload := DumpFunctions(codes)
+
+	got, err := LoadFunctions(payload)
+	if err != nil {
+		t.Fatalf("LoadFunctions() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, codes) {
+		t.Errorf("LoadFunctions() failed. Expected: %q, got: %q", codes, got)
+	}
+
+	payload[1] ^= 1
+	if _, err := LoadFunctions(payload); err != ErrBadChecksum {
+		t.Errorf("LoadFunctions() failed. Expected: %v, got: %v", ErrBadChecksum, err)
+	}
+	if _, err := LoadFunctions([]byte("short")); err != ErrBadChecksum {
+		t.Errorf("LoadFunctions() failed. Expected: %v, got: %v", ErrBadChecksum, err)
+	}
+}
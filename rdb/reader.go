@@ -0,0 +1,188 @@
+package rdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Reader reads an RDB file written by Writer or by upstream.
+type Reader struct {
+	r   *bufio.Reader
+	crc uint64
+	buf [8]byte
+}
+
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+func (r *Reader) Raw(p []byte) error {
+	if _, err := io.ReadFull(r.r, p); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	r.crc = crcUpdate(r.crc, p)
+	return nil
+}
+
+func (r *Reader) Byte() (byte, error) {
+	err := r.Raw(r.buf[:1])
+	return r.buf[0], err
+}
+
+// Header reads the magic string and returns the file's version.
+func (r *Reader) Header() (int, error) {
+	magic := make([]byte, 9)
+	if err := r.Raw(magic); err != nil {
+		return 0, err
+	}
+	if string(magic[:5]) != "REDIS" {
+		return 0, ErrBadHeader
+	}
+	version, err := strconv.Atoi(string(magic[5:]))
+	if err != nil {
+		return 0, ErrBadHeader
+	}
+	if version < 1 || version > MaxVersion {
+		return 0, fmt.Errorf("rdb: can't handle RDB format version %d", version)
+	}
+	return version, nil
+}
+
+// length reads a length. If encoded is set the value is one of the special
+// string encodings, 0xc0 to 0xc3 with the top bits cleared.
+func (r *Reader) length() (n uint64, encoded bool, err error) {
+	b, err := r.Byte()
+	if err != nil {
+		return 0, false, err
+	}
+	switch b >> 6 {
+	case 0:
+		return uint64(b & 0x3f), false, nil
+	case 1:
+		next, err := r.Byte()
+		return uint64(b&0x3f)<<8 | uint64(next), false, err
+	case 3:
+		return uint64(b & 0x3f), true, nil
+	}
+	switch b {
+	case 0x80:
+		err = r.Raw(r.buf[:4])
+		return uint64(binary.BigEndian.Uint32(r.buf[:4])), false, err
+	case 0x81:
+		err = r.Raw(r.buf[:8])
+		return binary.BigEndian.Uint64(r.buf[:8]), false, err
+	}
+	return 0, false, ErrCorrupt
+}
+
+func (r *Reader) Length() (uint64, error) {
+	n, encoded, err := r.length()
+	if err == nil && encoded {
+		err = ErrCorrupt
+	}
+	return n, err
+}
+
+// String reads a string in any of its encodings.
+func (r *Reader) String() (string, error) {
+	n, encoded, err := r.length()
+	if err != nil {
+		return "", err
+	}
+	if !encoded {
+		p := make([]byte, n)
+		err := r.Raw(p)
+		return string(p), err
+	}
+
+	switch n {
+	case 0:
+		err = r.Raw(r.buf[:1])
+		return strconv.Itoa(int(int8(r.buf[0]))), err
+	case 1:
+		err = r.Raw(r.buf[:2])
+		return strconv.Itoa(int(int16(binary.LittleEndian.Uint16(r.buf[:2])))), err
+	case 2:
+		err = r.Raw(r.buf[:4])
+		return strconv.Itoa(int(int32(binary.LittleEndian.Uint32(r.buf[:4])))), err
+	case 3:
+		compressed, err := r.Length()
+		if err != nil {
+			return "", err
+		}
+		size, err := r.Length()
+		if err != nil {
+			return "", err
+		}
+		p := make([]byte, compressed)
+		if err := r.Raw(p); err != nil {
+			return "", err
+		}
+		out, err := lzfDecompress(p, int(size))
+		return string(out), err
+	}
+	return "", ErrCorrupt
+}
+
+// Double reads a float64 in the binary form used by TypeZSet2.
+func (r *Reader) Double() (float64, error) {
+	err := r.Raw(r.buf[:8])
+	return math.Float64frombits(binary.LittleEndian.Uint64(r.buf[:8])), err
+}
+
+// OldDouble reads a float64 in the string form used by TypeZSet.
+func (r *Reader) OldDouble() (float64, error) {
+	n, err := r.Byte()
+	if err != nil {
+		return 0, err
+	}
+	switch n {
+	case 253:
+		return math.NaN(), nil
+	case 254:
+		return math.Inf(1), nil
+	case 255:
+		return math.Inf(-1), nil
+	}
+	p := make([]byte, n)
+	if err := r.Raw(p); err != nil {
+		return 0, err
+	}
+	return strconv.ParseFloat(string(p), 64)
+}
+
+// Millis reads a millisecond timestamp.
+func (r *Reader) Millis() (int64, error) {
+	err := r.Raw(r.buf[:8])
+	return int64(binary.LittleEndian.Uint64(r.buf[:8])), err
+}
+
+// Seconds reads the 32 bit timestamp that follows OpExpireTime.
+func (r *Reader) Seconds() (int64, error) {
+	err := r.Raw(r.buf[:4])
+	return int64(binary.LittleEndian.Uint32(r.buf[:4])), err
+}
+
+// Checksum reads the checksum that follows OpEOF and checks it against the
+// file. Files written with checksums disabled store 0, which is accepted.
+func (r *Reader) Checksum(version int) error {
+	if version < 5 {
+		return nil
+	}
+	crc := r.crc
+	if err := r.Raw(r.buf[:8]); err != nil {
+		return err
+	}
+	stored := binary.LittleEndian.Uint64(r.buf[:8])
+	if stored != 0 && stored != crc {
+		return ErrBadChecksum
+	}
+	return nil
+}
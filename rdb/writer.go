@@ -0,0 +1,154 @@
+package rdb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+)
+
+// Writer writes an RDB file. Like bufio.Writer it remembers the first error,
+// so callers can write a whole file and check Close once.
+type Writer struct {
+	w   *bufio.Writer
+	crc uint64
+	err error
+	buf [9]byte
+}
+
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: bufio.NewWriter(w)}
+}
+
+func (w *Writer) Raw(p []byte) {
+	if w.err != nil {
+		return
+	}
+	w.crc = crcUpdate(w.crc, p)
+	_, w.err = w.w.Write(p)
+}
+
+func (w *Writer) byte(b byte) {
+	w.buf[0] = b
+	w.Raw(w.buf[:1])
+}
+
+// Header writes the magic string and version.
+func (w *Writer) Header() {
+	w.Raw([]byte(fmt.Sprintf("REDIS%04d", Version)))
+}
+
+// Aux writes an auxiliary field, such as redis-ver or ctime.
+func (w *Writer) Aux(key, value string) {
+	w.byte(OpAux)
+	w.String(key)
+	w.String(value)
+}
+
+// Function writes the code of a function library.
+func (w *Writer) Function(code string) {
+	w.byte(OpFunction2)
+	w.String(code)
+}
+
+func (w *Writer) SelectDB(db int) {
+	w.byte(OpSelectDB)
+	w.Length(uint64(db))
+}
+
+// ResizeDB hints how many keys, and keys with an expiry, follow.
+func (w *Writer) ResizeDB(keys, expires int) {
+	w.byte(OpResizeDB)
+	w.Length(uint64(keys))
+	w.Length(uint64(expires))
+}
+
+// ExpireAt sets the expiry, in unix milliseconds, of the next key.
+func (w *Writer) ExpireAt(ms int64) {
+	w.byte(OpExpireTimeMs)
+	w.Millis(ms)
+}
+
+// Key starts a key of the given value type. The value is written next.
+func (w *Writer) Key(valueType byte, key string) {
+	w.byte(valueType)
+	w.String(key)
+}
+
+// Length writes n in upstream's variable length encoding.
+func (w *Writer) Length(n uint64) {
+	switch {
+	case n < 1<<6:
+		w.byte(byte(n))
+	case n < 1<<14:
+		w.buf[0] = byte(n>>8) | 0x40
+		w.buf[1] = byte(n)
+		w.Raw(w.buf[:2])
+	case n <= math.MaxUint32:
+		w.buf[0] = 0x80
+		binary.BigEndian.PutUint32(w.buf[1:], uint32(n))
+		w.Raw(w.buf[:5])
+	default:
+		w.buf[0] = 0x81
+		binary.BigEndian.PutUint64(w.buf[1:], n)
+		w.Raw(w.buf[:9])
+	}
+}
+
+// String writes a string. Short strings holding a canonical integer are
+// stored as integers, as upstream does.
+func (w *Writer) String(s string) {
+	if len(s) <= 11 {
+		if n, err := strconv.ParseInt(s, 10, 32); err == nil && strconv.FormatInt(n, 10) == s {
+			w.integer(n)
+			return
+		}
+	}
+	w.Length(uint64(len(s)))
+	w.Raw([]byte(s))
+}
+
+func (w *Writer) integer(n int64) {
+	switch {
+	case n >= math.MinInt8 && n <= math.MaxInt8:
+		w.buf[0] = 0xc0
+		w.buf[1] = byte(n)
+		w.Raw(w.buf[:2])
+	case n >= math.MinInt16 && n <= math.MaxInt16:
+		w.buf[0] = 0xc1
+		binary.LittleEndian.PutUint16(w.buf[1:], uint16(n))
+		w.Raw(w.buf[:3])
+	default:
+		w.buf[0] = 0xc2
+		binary.LittleEndian.PutUint32(w.buf[1:], uint32(n))
+		w.Raw(w.buf[:5])
+	}
+}
+
+// Double writes a float64 in the binary form used by TypeZSet2.
+func (w *Writer) Double(f float64) {
+	binary.LittleEndian.PutUint64(w.buf[:], math.Float64bits(f))
+	w.Raw(w.buf[:8])
+}
+
+// Millis writes a millisecond timestamp.
+func (w *Writer) Millis(ms int64) {
+	binary.LittleEndian.PutUint64(w.buf[:], uint64(ms))
+	w.Raw(w.buf[:8])
+}
+
+// Close writes the EOF marker and checksum and flushes the file. It returns
+// the first error met while writing.
+func (w *Writer) Close() error {
+	w.byte(OpEOF)
+	if w.err != nil {
+		return w.err
+	}
+	binary.LittleEndian.PutUint64(w.buf[:], w.crc)
+	if _, err := w.w.Write(w.buf[:8]); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
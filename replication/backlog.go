@@ -0,0 +1,61 @@
+package replication
+
+// Backlog keeps the tail of the replication stream in a ring buffer, so a
+// replica that reconnects after a short break can be sent just what it
+// missed. Offsets count bytes of the stream from 1, as upstream's do.
+type Backlog struct {
+	buf     []byte
+	pos     int   // where the next byte goes
+	histlen int   // bytes held
+	end     int64 // offset of the last byte written
+}
+
+// NewBacklog returns an empty backlog of size bytes whose next byte will be
+// at offset+1.
+func NewBacklog(size int, offset int64) *Backlog {
+	return &Backlog{buf: make([]byte, size), end: offset}
+}
+
+func (b *Backlog) Write(p []byte) {
+	b.end += int64(len(p))
+	if len(p) >= len(b.buf) {
+		// only the tail fits
+		copy(b.buf, p[len(p)-len(b.buf):])
+		b.pos = 0
+		b.histlen = len(b.buf)
+		return
+	}
+	n := copy(b.buf[b.pos:], p)
+	copy(b.buf, p[n:])
+	b.pos = (b.pos + len(p)) % len(b.buf)
+	b.histlen = min(b.histlen+len(p), len(b.buf))
+}
+
+// First returns the offset of the oldest byte held.
+func (b *Backlog) First() int64 {
+	return b.end - int64(b.histlen) + 1
+}
+
+func (b *Backlog) Len() int {
+	return b.histlen
+}
+
+func (b *Backlog) Size() int {
+	return len(b.buf)
+}
+
+// Since returns the stream from offset on. ok is false if the backlog no
+// longer holds offset, or never did.
+func (b *Backlog) Since(offset int64) (data []byte, ok bool) {
+	if offset < b.First() || offset > b.end+1 {
+		return nil, false
+	}
+	n := int(b.end - offset + 1)
+	start := (b.pos - n + len(b.buf)) % len(b.buf)
+	data = make([]byte, 0, n)
+	if start+n <= len(b.buf) {
+		return append(data, b.buf[start:start+n]...), true
+	}
+	data = append(data, b.buf[start:]...)
+	return append(data, b.buf[:n-(len(b.buf)-start)]...), true
+}
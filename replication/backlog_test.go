@@ -0,0 +1,37 @@
+package replication
+
+import (
+	"testing"
+)
+
+func TestBacklog(t *testing.T) {
+	b := NewBacklog(8, 100)
+	if data, ok := b.Since(101); !ok || len(data) != 0 {
+		t.Errorf("Since() failed. Expected an empty stream at the next offset, got: %q %v", data, ok)
+	}
+
+	b.Write([]byte("abcde"))
+	if data, ok := b.Since(103); !ok || string(data) != "cde" {
+		t.Errorf("Since() failed. Expected: cde, got: %q %v", data, ok)
+	}
+
+	// wraps around, dropping "ab"
+	b.Write([]byte("fghij"))
+	if first := b.First(); first != 103 {
+		t.Errorf("First() failed. Expected: 103, got: %d", first)
+	}
+	if data, ok := b.Since(104); !ok || string(data) != "defghij" {
+		t.Errorf("Since() failed. Expected: defghij, got: %q %v", data, ok)
+	}
+	if _, ok := b.Since(102); ok {
+		t.Error("Since() failed. Expected an offset that was dropped to be refused")
+	}
+	if _, ok := b.Since(112); ok {
+		t.Error("Since() failed. Expected an offset past the end to be refused")
+	}
+
+	b.Write([]byte("0123456789"))
+	if data, ok := b.Since(b.First()); !ok || string(data) != "23456789" || b.Len() != 8 {
+		t.Errorf("Write() failed. Expected a long write to keep its tail, got: %q %v", data, ok)
+	}
+}
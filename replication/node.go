@@ -0,0 +1,192 @@
+// Package replication keeps replicas in step with a primary: a full resync
+// sends them a snapshot, then every write is streamed to them, with a backlog
+// so a replica that briefly loses its link can pick up where it left off.
+package replication
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+	"github.com/Ryan-DL/go-redis-server/persist"
+)
+
+// pingPeriod is how often a primary pings its replicas, which lets them tell
+// a quiet primary from a dead link. Upstream's repl-ping-replica-period.
+const pingPeriod = 10 * time.Second
+
+// Node is this server's place in replication: a primary with its replicas,
+// or a replica of another server, which may have replicas of its own.
+type Node struct {
+	store       *cache.ValueStore
+	port        int // ours, which replicas report to their primary
+	backlogSize int
+
+	// Locker, when held, keeps every command from running. A replica holds
+	// it while it loads a snapshot or applies what its primary sends.
+	Locker sync.Locker
+
+	// Apply runs a command, or a transaction, sent by the primary. It is
+	// called with Locker held.
+	Apply func(batch [][]string)
+
+	// OnFullSync is called with Locker held once a full resync has replaced
+	// the data set, so the AOF can be rewritten to match.
+	OnFullSync func()
+
+	// MasterAuth is the password to authenticate to the primary with.
+	MasterAuth string
+
+	// ReadOnly makes a replica refuse writes from clients, as upstream's
+	// replica-read-only does.
+	ReadOnly bool
+
+	mu           sync.Mutex
+	replID       string
+	replID2      string // the primary's ID before a failover, kept for PSYNC
+	offset       int64  // bytes of the stream produced, or received as a replica
+	secondOffset int64  // up to where replID2 is valid, -1 if it is not
+	backlog      *Backlog
+	replicas     map[net.Conn]*replica
+	lastPing     time.Time
+	link         *link // the link to our primary, nil on a primary
+
+	// whether link and backlog are set, which every write checks, so read
+	// without mu
+	replica    atomic.Bool
+	hasBacklog atomic.Bool
+}
+
+func NewNode(store *cache.ValueStore, port, backlogSize int) *Node {
+	return &Node{
+		store:        store,
+		port:         port,
+		backlogSize:  backlogSize,
+		ReadOnly:     true,
+		replID:       newReplID(),
+		replID2:      strings.Repeat("0", 40),
+		secondOffset: -1,
+		replicas:     make(map[net.Conn]*replica),
+	}
+}
+
+// newReplID returns a random replication ID of 40 hex characters.
+func newReplID() string {
+	b := make([]byte, 20)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// IsReplica reports whether the node replicates another server.
+func (n *Node) IsReplica() bool {
+	return n.replica.Load()
+}
+
+// RefusesWrites reports whether clients' write commands must be refused.
+func (n *Node) RefusesWrites() bool {
+	return n.ReadOnly && n.IsReplica()
+}
+
+// Idle reports whether there is no backlog yet, so Propagate does nothing
+// and writes need not be ordered for it. On a primary it only turns false in
+// Sync, which the caller must keep every command from running across; on a
+// replica Propagate does nothing either way.
+func (n *Node) Idle() bool {
+	return !n.hasBacklog.Load()
+}
+
+// Propagate sends a batch of writes made by clients to the replicas. Writes
+// a replica makes itself are not passed on; its replicas follow its primary.
+// Until the first replica connects there is no backlog, and nothing to do.
+func (n *Node) Propagate(batch [][]string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.link != nil || n.backlog == nil {
+		return
+	}
+	n.feed(persist.AppendBatch(nil, batch))
+}
+
+// feed appends p to the stream. Caller must hold mu.
+func (n *Node) feed(p []byte) {
+	n.offset += int64(len(p))
+	n.backlog.Write(p)
+	for _, r := range n.replicas {
+		r.send(p)
+	}
+}
+
+// Run pings replicas now and then, and lets a replica acknowledge what it
+// has processed. It never returns.
+func (n *Node) Run() {
+	for {
+		time.Sleep(time.Second)
+
+		n.mu.Lock()
+		if n.link == nil && len(n.replicas) > 0 && time.Since(n.lastPing) >= pingPeriod {
+			n.feed(persist.AppendCommand(nil, []string{"PING"}))
+			n.lastPing = time.Now()
+		}
+		l := n.link
+		n.mu.Unlock()
+
+		if l != nil {
+			l.ack()
+		}
+	}
+}
+
+// ReplicaOf makes the node a replica of host:port. It reports false if it
+// already is one. The link is made in the background.
+func (n *Node) ReplicaOf(host string, port int) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.link != nil && n.link.host == host && n.link.port == port {
+		return false
+	}
+	if n.link != nil {
+		n.link.stop()
+	}
+	// our replicas must resync to follow the new primary's history
+	n.dropReplicas()
+	n.link = newLink(n, host, port)
+	n.replica.Store(true)
+	go n.link.run()
+	return true
+}
+
+// ReplicaOfNoOne promotes a replica to primary. It starts a new history but
+// remembers the old one, so replicas of the same primary can still resync
+// partially from it.
+func (n *Node) ReplicaOfNoOne() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.link == nil {
+		return
+	}
+	n.link.stop()
+	n.link = nil
+	n.replica.Store(false)
+	n.shiftReplID(newReplID())
+	n.dropReplicas()
+}
+
+// shiftReplID adopts a new replication ID from the next byte on, keeping the
+// current one as the secondary. Caller must hold mu.
+func (n *Node) shiftReplID(id string) {
+	n.replID2 = n.replID
+	n.secondOffset = n.offset + 1
+	n.replID = id
+}
+
+// dropReplicas disconnects every replica. Caller must hold mu.
+func (n *Node) dropReplicas() {
+	for _, r := range n.replicas {
+		n.drop(r)
+	}
+}
@@ -0,0 +1,72 @@
+package replication
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+)
+
+// attach runs PSYNC replID offset against n over a pipe and returns the
+// replica's end and the first line of the reply.
+func attach(t *testing.T, n *Node, replID string, offset int64) (*bufio.Reader, string) {
+	t.Helper()
+	primary, replica := net.Pipe()
+	t.Cleanup(func() { replica.Close() })
+
+	n.Sync(primary, 6380, replID, offset)
+	replica.SetReadDeadline(time.Now().Add(time.Second))
+	r := bufio.NewReader(replica)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatalf("Sync() failed: %s", err)
+	}
+	return r, strings.TrimRight(line, "\r\n")
+}
+
+func TestSync(t *testing.T) {
+	store := cache.NewValueStore(time.Minute)
+	store.Set("key", "value", 0)
+	n := NewNode(store, 6379, 1024)
+
+	// an unknown history gets a snapshot, then the stream
+	r, line := attach(t, n, "?", -1)
+	if line != "+FULLRESYNC "+n.replID+" 0" {
+		t.Fatalf("Sync() failed. Expected a full resync, got: %s", line)
+	}
+	if header, _ := r.ReadString('\n'); !strings.HasPrefix(header, "$") {
+		t.Fatalf("Sync() failed. Expected the snapshot, got: %q", header)
+	}
+
+	n.Propagate([][]string{{"SET", "a", "1"}})
+	n.Propagate([][]string{{"SET", "b", "2"}})
+	if n.offset != 2*int64(len("*3\r\n$3\r\nSET\r\n$1\r\na\r\n$1\r\n1\r\n")) {
+		t.Errorf("Propagate() failed. Expected the offset to count the stream, got: %d", n.offset)
+	}
+
+	// a replica that has seen the first write gets just the second
+	r, line = attach(t, n, n.replID, n.offset/2+1)
+	if line != "+CONTINUE "+n.replID {
+		t.Fatalf("Sync() failed. Expected a partial resync, got: %s", line)
+	}
+	for _, expected := range []string{"*3", "$3", "SET", "$1", "b"} {
+		if got, _ := r.ReadString('\n'); strings.TrimRight(got, "\r\n") != expected {
+			t.Fatalf("Sync() failed. Expected the backlog line %q, got: %q", expected, got)
+		}
+	}
+
+	// after a failover the old history is still accepted up to where it ended
+	old, end := n.replID, n.offset
+	n.mu.Lock()
+	n.shiftReplID(newReplID())
+	n.mu.Unlock()
+	if _, line := attach(t, n, old, end+1); line != "+CONTINUE "+n.replID {
+		t.Errorf("Sync() failed. Expected a partial resync on the old history, got: %s", line)
+	}
+	if _, line := attach(t, n, old, end+2); !strings.HasPrefix(line, "+FULLRESYNC") {
+		t.Errorf("Sync() failed. Expected a full resync past the old history, got: %s", line)
+	}
+}
@@ -0,0 +1,177 @@
+package replication
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/cache"
+)
+
+// replicaOutputLimit is how many chunks of the stream may queue up for a
+// replica before it is dropped as too slow, like upstream's
+// client-output-buffer-limit for replicas.
+const replicaOutputLimit = 16384
+
+// Replica states, as INFO replication reports them.
+const (
+	stateSendBulk = "send_bulk"
+	stateOnline   = "online"
+)
+
+// replica is a connection that has asked, with PSYNC, to follow our stream.
+type replica struct {
+	conn net.Conn
+	ip   string
+	port int // the port it listens on, from REPLCONF listening-port
+
+	out  chan []byte
+	done chan struct{}
+	once sync.Once
+
+	// guarded by the node's mu
+	state     string
+	ackOffset int64
+	ackTime   time.Time
+}
+
+// send queues p for the replica, dropping it if it has fallen too far
+// behind. Caller must hold the node's mu.
+func (r *replica) send(p []byte) {
+	select {
+	case r.out <- p:
+	default:
+		log.Printf("Client %s scheduled to be closed ASAP for overcoming of output buffer limits.", r.conn.RemoteAddr())
+		r.close()
+	}
+}
+
+func (r *replica) close() {
+	r.once.Do(func() {
+		close(r.done)
+		r.conn.Close()
+	})
+}
+
+// Sync answers PSYNC from conn. If the backlog still holds everything after
+// offset, and replID is the history it belongs to, only that is sent;
+// otherwise a snapshot is, and the stream follows it. listeningPort is the
+// port the replica gave with REPLCONF. The caller must keep writes from being
+// propagated meanwhile, so the snapshot and the stream line up, and while the
+// node is Idle keep every command from running, as the backlog starts here.
+func (n *Node) Sync(conn net.Conn, listeningPort int, replID string, offset int64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.backlog == nil {
+		n.backlog = NewBacklog(n.backlogSize, n.offset)
+		n.hasBacklog.Store(true)
+	}
+
+	r := &replica{
+		conn:    conn,
+		port:    listeningPort,
+		out:     make(chan []byte, replicaOutputLimit),
+		done:    make(chan struct{}),
+		ackTime: time.Now(),
+	}
+	if addr, ok := conn.RemoteAddr().(*net.TCPAddr); ok {
+		r.ip = addr.IP.String()
+	}
+
+	if backlog, ok := n.partialSync(replID, offset); ok {
+		log.Printf("Partial resynchronization request from %s accepted. Sending %d bytes of backlog starting from offset %d.", conn.RemoteAddr(), len(backlog), offset)
+		r.state = stateOnline
+		r.ackOffset = offset - 1
+		n.replicas[conn] = r
+		go n.serve(r, fmt.Sprintf("+CONTINUE %s\r\n", n.replID), backlog, nil)
+		return
+	}
+
+	log.Printf("Replica %s asks for synchronization. Starting full resync with offset %d.", conn.RemoteAddr(), n.offset)
+	r.state = stateSendBulk
+	n.replicas[conn] = r
+	go n.serve(r, fmt.Sprintf("+FULLRESYNC %s %d\r\n", n.replID, n.offset), nil, n.store.Snapshot())
+}
+
+// partialSync returns what a replica needs to continue from offset, if it
+// can. Caller must hold mu.
+func (n *Node) partialSync(replID string, offset int64) ([]byte, bool) {
+	if replID != n.replID && (replID != n.replID2 || offset > n.secondOffset) {
+		return nil, false
+	}
+	return n.backlog.Since(offset)
+}
+
+// serve writes the reply to PSYNC, then the stream as it is queued, until
+// the replica goes away.
+func (n *Node) serve(r *replica, reply string, backlog []byte, snap *cache.Snapshot) {
+	defer n.Disconnected(r.conn)
+
+	if _, err := r.conn.Write([]byte(reply)); err != nil {
+		return
+	}
+	if snap != nil {
+		var rdb bytes.Buffer
+		if err := snap.WriteRDB(&rdb); err != nil {
+			log.Printf("Error writing the snapshot for replica %s: %v", r.conn.RemoteAddr(), err)
+			return
+		}
+		if _, err := fmt.Fprintf(r.conn, "$%d\r\n", rdb.Len()); err != nil {
+			return
+		}
+		if _, err := r.conn.Write(rdb.Bytes()); err != nil {
+			return
+		}
+		n.mu.Lock()
+		r.state = stateOnline
+		n.mu.Unlock()
+		log.Printf("Synchronization with replica %s succeeded", r.conn.RemoteAddr())
+	}
+	if _, err := r.conn.Write(backlog); err != nil {
+		return
+	}
+
+	for {
+		select {
+		case p := <-r.out:
+			if _, err := r.conn.Write(p); err != nil {
+				return
+			}
+		case <-r.done:
+			return
+		}
+	}
+}
+
+// Ack records the offset a replica reports with REPLCONF ACK.
+func (n *Node) Ack(conn net.Conn, offset int64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if r, ok := n.replicas[conn]; ok {
+		r.ackOffset = offset
+		r.ackTime = time.Now()
+	}
+}
+
+// Disconnected forgets conn if it was a replica. The connection loop calls
+// it when a client goes away.
+func (n *Node) Disconnected(conn net.Conn) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if r, ok := n.replicas[conn]; ok {
+		n.drop(r)
+	}
+}
+
+// drop disconnects a replica. Caller must hold mu.
+func (n *Node) drop(r *replica) {
+	if n.replicas[r.conn] == r {
+		delete(n.replicas, r.conn)
+		log.Printf("Connection with replica %s lost.", r.conn.RemoteAddr())
+	}
+	r.close()
+}
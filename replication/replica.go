@@ -0,0 +1,360 @@
+package replication
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/persist"
+)
+
+// replTimeout is how long a replica waits to hear from its primary before
+// dropping the link, like upstream's repl-timeout.
+const replTimeout = 60 * time.Second
+
+// Link states, as ROLE reports them.
+const (
+	linkConnect    = "connect"
+	linkConnecting = "connecting"
+	linkSync       = "sync"
+	linkConnected  = "connected"
+)
+
+// link is a replica's connection to its primary. It reconnects until it is
+// stopped.
+type link struct {
+	node *Node
+	host string
+	port int
+
+	done chan struct{}
+
+	// guarded by the node's mu
+	stopped bool
+	state   string
+	conn    net.Conn
+	lastIO  time.Time
+
+	writeMu sync.Mutex // orders ACKs written from Run and the link
+}
+
+func newLink(n *Node, host string, port int) *link {
+	return &link{node: n, host: host, port: port, done: make(chan struct{}), state: linkConnect}
+}
+
+// stop ends the link. Commands it has read but not applied are dropped.
+// Caller must hold the node's mu.
+func (l *link) stop() {
+	l.stopped = true
+	close(l.done)
+	if l.conn != nil {
+		l.conn.Close()
+	}
+}
+
+func (l *link) addr() string {
+	return net.JoinHostPort(l.host, strconv.Itoa(l.port))
+}
+
+// run keeps the link up, reconnecting a second after it fails.
+func (l *link) run() {
+	log.Printf("Connecting to MASTER %s", l.addr())
+	for {
+		err := l.sync()
+
+		n := l.node
+		n.mu.Lock()
+		stopped := l.stopped
+		l.state = linkConnect
+		l.conn = nil
+		n.mu.Unlock()
+		if stopped {
+			return
+		}
+		log.Printf("Error condition on socket for SYNC: %v", err)
+
+		select {
+		case <-time.After(time.Second):
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// sync connects, resyncs and then applies the stream until the link fails.
+func (l *link) sync() error {
+	n := l.node
+	conn, err := net.DialTimeout("tcp", l.addr(), 5*time.Second)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	n.mu.Lock()
+	if l.stopped {
+		n.mu.Unlock()
+		return nil
+	}
+	l.conn = conn
+	l.state = linkConnecting
+	l.lastIO = time.Now()
+	n.mu.Unlock()
+
+	r := bufio.NewReader(conn)
+	if n.MasterAuth != "" {
+		if err := l.handshake(conn, r, "AUTH", n.MasterAuth); err != nil {
+			return err
+		}
+	}
+	if err := l.handshake(conn, r, "PING"); err != nil {
+		return err
+	}
+	if err := l.handshake(conn, r, "REPLCONF", "listening-port", strconv.Itoa(n.port)); err != nil {
+		return err
+	}
+	if err := l.handshake(conn, r, "REPLCONF", "capa", "psync2"); err != nil {
+		return err
+	}
+
+	// ask to carry on from where our copy of the history ends
+	n.mu.Lock()
+	replID, offset := n.replID, n.offset+1
+	l.state = linkSync
+	n.mu.Unlock()
+	log.Printf("Trying a partial resynchronization (request %s:%d).", replID, offset)
+	if err := l.write(conn, "PSYNC", replID, strconv.FormatInt(offset, 10)); err != nil {
+		return err
+	}
+
+	line, err := l.readLine(conn, r)
+	if err != nil {
+		return err
+	}
+	switch {
+	case strings.HasPrefix(line, "+FULLRESYNC "):
+		if err := l.fullSync(conn, r, line); err != nil {
+			return err
+		}
+	case strings.HasPrefix(line, "+CONTINUE"):
+		l.continueSync(strings.TrimSpace(strings.TrimPrefix(line, "+CONTINUE")))
+	default:
+		return fmt.Errorf("unexpected reply to PSYNC: %s", line)
+	}
+
+	n.mu.Lock()
+	l.state = linkConnected
+	n.mu.Unlock()
+	l.ack()
+	return l.stream(conn, r)
+}
+
+// handshake sends a command and fails unless the reply is a status reply.
+func (l *link) handshake(conn net.Conn, r *bufio.Reader, args ...string) error {
+	if err := l.write(conn, args...); err != nil {
+		return err
+	}
+	line, err := l.readLine(conn, r)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "+") {
+		return fmt.Errorf("error reply to %s: %s", args[0], line)
+	}
+	return nil
+}
+
+func (l *link) write(conn net.Conn, args ...string) error {
+	l.writeMu.Lock()
+	defer l.writeMu.Unlock()
+	_, err := conn.Write(persist.AppendCommand(nil, args))
+	return err
+}
+
+// readLine reads a reply line. Upstream sends empty lines to keep the link
+// alive while it prepares a snapshot, so those are skipped.
+func (l *link) readLine(conn net.Conn, r *bufio.Reader) (string, error) {
+	for {
+		conn.SetReadDeadline(time.Now().Add(replTimeout))
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		l.touch()
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			return line, nil
+		}
+	}
+}
+
+func (l *link) touch() {
+	l.node.mu.Lock()
+	l.lastIO = time.Now()
+	l.node.mu.Unlock()
+}
+
+// fullSync loads the snapshot that follows +FULLRESYNC <replid> <offset> and
+// adopts the primary's history.
+func (l *link) fullSync(conn net.Conn, r *bufio.Reader, line string) error {
+	n := l.node
+	fields := strings.Fields(line)
+	if len(fields) != 3 {
+		return fmt.Errorf("bad FULLRESYNC reply: %s", line)
+	}
+	replID := fields[1]
+	offset, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return fmt.Errorf("bad FULLRESYNC reply: %s", line)
+	}
+
+	header, err := l.readLine(conn, r)
+	if err != nil {
+		return err
+	}
+	size, err := strconv.Atoi(strings.TrimPrefix(header, "$"))
+	if !strings.HasPrefix(header, "$") || err != nil || size < 0 {
+		return fmt.Errorf("bad snapshot header from MASTER: %s", header)
+	}
+	log.Printf("MASTER <-> REPLICA sync: receiving %d bytes from master to disk", size)
+	rdb := make([]byte, size)
+	conn.SetReadDeadline(time.Now().Add(replTimeout))
+	if _, err := io.ReadFull(r, rdb); err != nil {
+		return err
+	}
+
+	n.Locker.Lock()
+	defer n.Locker.Unlock()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if l.stopped {
+		return errors.New("replication stopped")
+	}
+
+	log.Printf("MASTER <-> REPLICA sync: Flushing old data")
+	n.store.Flush()
+	log.Printf("MASTER <-> REPLICA sync: Loading DB in memory")
+	if err := n.store.LoadRDB(bytes.NewReader(rdb)); err != nil {
+		return fmt.Errorf("loading the snapshot from MASTER: %w", err)
+	}
+
+	// a new history, which our own replicas must start over on
+	n.replID = replID
+	n.replID2 = strings.Repeat("0", 40)
+	n.secondOffset = -1
+	n.offset = offset
+	n.backlog = NewBacklog(n.backlogSize, offset)
+	n.hasBacklog.Store(true)
+	n.dropReplicas()
+	if n.OnFullSync != nil {
+		n.OnFullSync()
+	}
+	log.Printf("MASTER <-> REPLICA sync: Finished with success")
+	return nil
+}
+
+// continueSync handles +CONTINUE. If the primary has a new ID, as after a
+// failover, our history carries on under it.
+func (l *link) continueSync(replID string) {
+	n := l.node
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.backlog == nil {
+		n.backlog = NewBacklog(n.backlogSize, n.offset)
+		n.hasBacklog.Store(true)
+	}
+	if replID != "" && replID != n.replID {
+		n.shiftReplID(replID)
+		n.dropReplicas()
+	}
+	log.Printf("Successful partial resynchronization with master.")
+}
+
+// stream applies the primary's writes as they arrive. A transaction is held
+// back until its EXEC, and only complete commands count towards our offset,
+// so after a break we ask for a transaction again from its start.
+func (l *link) stream(conn net.Conn, r *bufio.Reader) error {
+	var multi [][]string
+	var pending []byte // the transaction so far, as received
+	for {
+		conn.SetReadDeadline(time.Now().Add(replTimeout))
+		args, err := persist.ReadCommand(r)
+		if err != nil {
+			return err
+		}
+		l.touch()
+		raw := persist.AppendCommand(nil, args)
+
+		switch strings.ToUpper(args[0]) {
+		case "MULTI":
+			multi = [][]string{}
+			pending = raw
+			continue
+		case "EXEC":
+			raw = append(pending, raw...)
+			l.apply(multi, raw)
+			multi, pending = nil, nil
+			continue
+		}
+		if multi != nil {
+			multi = append(multi, args)
+			pending = append(pending, raw...)
+			continue
+		}
+
+		switch strings.ToUpper(args[0]) {
+		case "PING", "SELECT":
+			l.apply(nil, raw)
+		case "REPLCONF":
+			// REPLCONF GETACK asks for an ACK now; it counts as processed
+			// before we send it
+			l.apply(nil, raw)
+			if len(args) > 1 && strings.EqualFold(args[1], "GETACK") {
+				l.ack()
+			}
+		default:
+			l.apply([][]string{args}, raw)
+		}
+	}
+}
+
+// apply runs a batch from the primary, then passes it on to our own replicas
+// and counts it towards our offset.
+func (l *link) apply(batch [][]string, raw []byte) {
+	n := l.node
+	n.Locker.Lock()
+	defer n.Locker.Unlock()
+
+	n.mu.Lock()
+	stopped := l.stopped
+	n.mu.Unlock()
+	if stopped {
+		return
+	}
+
+	if len(batch) > 0 {
+		n.Apply(batch)
+	}
+
+	n.mu.Lock()
+	n.feed(raw)
+	n.mu.Unlock()
+}
+
+// ack tells the primary how much of the stream has been processed.
+func (l *link) ack() {
+	n := l.node
+	n.mu.Lock()
+	conn, offset := l.conn, n.offset
+	connected := l.state == linkConnected
+	n.mu.Unlock()
+	if conn != nil && connected {
+		l.write(conn, "REPLCONF", "ACK", strconv.FormatInt(offset, 10))
+	}
+}
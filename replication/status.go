@@ -0,0 +1,87 @@
+package replication
+
+import (
+	"cmp"
+	"slices"
+	"time"
+)
+
+// Status describes the node for ROLE and INFO replication.
+type Status struct {
+	Replica      bool
+	ReplID       string
+	ReplID2      string
+	Offset       int64
+	SecondOffset int64
+
+	BacklogActive bool
+	BacklogSize   int
+	BacklogFirst  int64
+	BacklogLen    int
+
+	Replicas []ReplicaStatus
+
+	// set on a replica
+	MasterHost     string
+	MasterPort     int
+	LinkState      string // connect, connecting, sync or connected
+	LastIO         time.Time
+	SyncInProgress bool
+	ReadOnly       bool
+}
+
+// ReplicaStatus describes one connected replica.
+type ReplicaStatus struct {
+	IP     string
+	Port   int
+	State  string
+	Offset int64
+	Lag    time.Duration
+}
+
+// LinkUp reports whether a replica's link to its primary is working.
+func (s Status) LinkUp() bool {
+	return s.LinkState == linkConnected
+}
+
+func (n *Node) Status() Status {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	s := Status{
+		ReplID:       n.replID,
+		ReplID2:      n.replID2,
+		Offset:       n.offset,
+		SecondOffset: n.secondOffset,
+		ReadOnly:     n.ReadOnly,
+	}
+	if n.backlog != nil {
+		s.BacklogActive = true
+		s.BacklogSize = n.backlog.Size()
+		s.BacklogFirst = n.backlog.First()
+		s.BacklogLen = n.backlog.Len()
+	} else {
+		s.BacklogSize = n.backlogSize
+	}
+	for _, r := range n.replicas {
+		s.Replicas = append(s.Replicas, ReplicaStatus{
+			IP:     r.ip,
+			Port:   r.port,
+			State:  r.state,
+			Offset: r.ackOffset,
+			Lag:    time.Since(r.ackTime),
+		})
+	}
+	slices.SortFunc(s.Replicas, func(a, b ReplicaStatus) int {
+		return cmp.Or(cmp.Compare(a.IP, b.IP), cmp.Compare(a.Port, b.Port))
+	})
+	if l := n.link; l != nil {
+		s.Replica = true
+		s.MasterHost = l.host
+		s.MasterPort = l.port
+		s.LinkState = l.state
+		s.LastIO = l.lastIO
+		s.SyncInProgress = l.state == linkSync
+	}
+	return s
+}
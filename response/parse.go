@@ -0,0 +1,132 @@
+package response
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Send writes any reply.
+func Send(conn net.Conn, resp DataType) {
+	writeResponse(conn, resp)
+}
+
+// Parse reads one reply, in either protocol version, the inverse of
+// Serialize. Scripts use it to turn the replies of the commands they call
+// back into values. A RESP3 null is read as Null, whatever it stood for.
+func Parse(r *bufio.Reader) (DataType, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("invalid reply line %q", line)
+	}
+	prefix, line := line[0], line[1:len(line)-2]
+
+	switch prefix {
+	case '+':
+		return SimpleString(line), nil
+	case '-':
+		return ErrorType(line), nil
+	case ':':
+		n, err := strconv.Atoi(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer reply %q", line)
+		}
+		return IntegerType(n), nil
+	case '$':
+		size, err := strconv.Atoi(line)
+		if err != nil || size < -1 {
+			return nil, fmt.Errorf("invalid bulk length %q", line)
+		}
+		if size == -1 {
+			return NullBulkString{}, nil
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return BulkStringType(buf[:size]), nil
+	case '*', '~', '>':
+		n, err := strconv.Atoi(line)
+		if err != nil || n < -1 {
+			return nil, fmt.Errorf("invalid array length %q", line)
+		}
+		if n == -1 {
+			return ArrayType(nil), nil
+		}
+		elems, err := parseElems(r, n)
+		switch {
+		case err != nil:
+			return nil, err
+		case prefix == '~':
+			return SetType(elems), nil
+		case prefix == '>':
+			return PushType(elems), nil
+		}
+		return ArrayType(elems), nil
+	case '%', '|':
+		n, err := strconv.Atoi(line)
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("invalid map length %q", line)
+		}
+		elems, err := parseElems(r, 2*n)
+		if err != nil {
+			return nil, err
+		}
+		m := make(MapType, n)
+		for i := range m {
+			m[i] = Pair{elems[2*i], elems[2*i+1]}
+		}
+		if prefix == '%' {
+			return m, nil
+		}
+		reply, err := Parse(r)
+		if err != nil {
+			return nil, err
+		}
+		return AttributeType{Attributes: m, Reply: reply}, nil
+	case '_':
+		return Null{}, nil
+	case '#':
+		if line != "t" && line != "f" {
+			return nil, fmt.Errorf("invalid boolean reply %q", line)
+		}
+		return BooleanType(line == "t"), nil
+	case ',':
+		f, err := strconv.ParseFloat(line, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid double reply %q", line)
+		}
+		return DoubleType(f), nil
+	case '(':
+		return BigNumberType(line), nil
+	case '=':
+		size, err := strconv.Atoi(line)
+		if err != nil || size < 4 {
+			return nil, fmt.Errorf("invalid verbatim string length %q", line)
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return VerbatimStringType{Format: string(buf[:3]), Text: string(buf[4:size])}, nil
+	}
+	return nil, errors.New("unknown reply type " + strconv.QuoteRune(rune(prefix)))
+}
+
+func parseElems(r *bufio.Reader, n int) ([]DataType, error) {
+	elems := make([]DataType, n)
+	for i := range elems {
+		var err error
+		if elems[i], err = Parse(r); err != nil {
+			return nil, err
+		}
+	}
+	return elems, nil
+}
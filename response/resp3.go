@@ -0,0 +1,289 @@
+package response
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+)
+
+// RESP3, which a client switches to with HELLO 3, adds reply types that say
+// more about the data: maps, sets, doubles and so on. Each of them also has a
+// RESP2 form, which is what Serialize returns, so a command builds one reply
+// and every client gets the shape it understands. SerializeRESP3 is only
+// implemented by the types whose RESP3 form differs.
+
+// RESP3 is implemented by the replies RESP3 clients are sent differently.
+type RESP3 interface {
+	SerializeRESP3() string
+}
+
+// appender is implemented by the replies that append themselves to a buffer
+// in protocol version proto rather than return a string: aggregates, so that
+// one costs time in proportion to its size however deeply it nests, and the
+// bulk strings they are mostly made of.
+type appender interface {
+	appendTo(buf []byte, proto int) []byte
+}
+
+// appendReply appends resp, in protocol version proto, to buf.
+func appendReply(buf []byte, resp DataType, proto int) []byte {
+	if a, ok := resp.(appender); ok {
+		return a.appendTo(buf, proto)
+	}
+	return append(buf, Serialize(resp, proto)...)
+}
+
+// appendHeader appends the type prefix and length of a reply.
+func appendHeader(buf []byte, prefix byte, n int) []byte {
+	buf = append(buf, prefix)
+	buf = strconv.AppendInt(buf, int64(n), 10)
+	return append(buf, "\r\n"...)
+}
+
+// Serialize returns resp in protocol version proto, 2 or 3.
+func Serialize(resp DataType, proto int) string {
+	if a, ok := resp.(appender); ok {
+		return string(a.appendTo(nil, proto))
+	}
+	if proto == 3 {
+		if r, ok := resp.(RESP3); ok {
+			return r.SerializeRESP3()
+		}
+	}
+	return resp.Serialize()
+}
+
+// Proto returns the protocol version conn's client speaks: 3 if it switched
+// with HELLO, as connections implementing Proto() report, 2 otherwise.
+func Proto(conn net.Conn) int {
+	if c, ok := conn.(interface{ Proto() int }); ok {
+		return c.Proto()
+	}
+	return 2
+}
+
+func (n NullBulkString) SerializeRESP3() string {
+	return "_\r\n"
+}
+
+func (a ArrayType) SerializeRESP3() string {
+	return string(a.appendTo(nil, 3))
+}
+
+// appendAggregate appends the header and elements of an array-like reply.
+func appendAggregate(buf []byte, prefix byte, elems []DataType, proto int) []byte {
+	buf = appendHeader(buf, prefix, len(elems))
+	for _, elem := range elems {
+		buf = appendReply(buf, elem, proto)
+	}
+	return buf
+}
+
+// appendPairs appends the keys and values of pairs in turn.
+func appendPairs(buf []byte, pairs []Pair, proto int) []byte {
+	for _, p := range pairs {
+		buf = appendReply(buf, p.Key, proto)
+		buf = appendReply(buf, p.Value, proto)
+	}
+	return buf
+}
+
+// Null is RESP3's null; RESP2 clients get a null bulk string.
+type Null struct{}
+
+func (Null) Serialize() string {
+	return "$-1\r\n"
+}
+
+func (Null) SerializeRESP3() string {
+	return "_\r\n"
+}
+
+// Pair is an entry of a map, or two values a reply pairs up, such as a
+// sorted set member and its score.
+type Pair struct {
+	Key, Value DataType
+}
+
+// MapType is a map with its entries in order; RESP2 clients get its keys and
+// values as one flat array, as HGETALL always replied.
+type MapType []Pair
+
+// StringMap builds a map of bulk strings from alternating keys and values.
+func StringMap(pairs ...string) MapType {
+	m := make(MapType, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		m = append(m, Pair{BulkStringType(pairs[i]), BulkStringType(pairs[i+1])})
+	}
+	return m
+}
+
+func (m MapType) Serialize() string {
+	return string(m.appendTo(nil, 2))
+}
+
+func (m MapType) SerializeRESP3() string {
+	return string(m.appendTo(nil, 3))
+}
+
+func (m MapType) appendTo(buf []byte, proto int) []byte {
+	if proto == 3 {
+		buf = appendHeader(buf, '%', len(m))
+	} else {
+		buf = appendHeader(buf, '*', len(m)*2)
+	}
+	return appendPairs(buf, m, proto)
+}
+
+// PairsType is an array of pairs, such as the members and scores of ZRANGE
+// WITHSCORES. RESP3 clients get each pair as an array of two; RESP2 clients
+// get one flat array, as upstream replied before RESP3.
+type PairsType []Pair
+
+func (p PairsType) Serialize() string {
+	return string(p.appendTo(nil, 2))
+}
+
+func (p PairsType) SerializeRESP3() string {
+	return string(p.appendTo(nil, 3))
+}
+
+func (p PairsType) appendTo(buf []byte, proto int) []byte {
+	if proto != 3 {
+		buf = appendHeader(buf, '*', len(p)*2)
+		return appendPairs(buf, p, proto)
+	}
+	buf = appendHeader(buf, '*', len(p))
+	for i := range p {
+		buf = appendHeader(buf, '*', 2)
+		buf = appendPairs(buf, p[i:i+1], proto)
+	}
+	return buf
+}
+
+// SetType is an unordered collection of distinct elements, such as SMEMBERS
+// replies with; RESP2 clients get an array.
+type SetType []DataType
+
+func (s SetType) Serialize() string {
+	return string(s.appendTo(nil, 2))
+}
+
+func (s SetType) SerializeRESP3() string {
+	return string(s.appendTo(nil, 3))
+}
+
+func (s SetType) appendTo(buf []byte, proto int) []byte {
+	if proto == 3 {
+		return appendAggregate(buf, '~', s, proto)
+	}
+	return appendAggregate(buf, '*', s, proto)
+}
+
+// PushType is data the server sends unasked, such as a published message;
+// RESP2 clients get an array.
+type PushType []DataType
+
+func (p PushType) Serialize() string {
+	return string(p.appendTo(nil, 2))
+}
+
+func (p PushType) SerializeRESP3() string {
+	return string(p.appendTo(nil, 3))
+}
+
+func (p PushType) appendTo(buf []byte, proto int) []byte {
+	if proto == 3 {
+		return appendAggregate(buf, '>', p, proto)
+	}
+	return appendAggregate(buf, '*', p, proto)
+}
+
+// DoubleType is a floating point reply; RESP2 clients get it as a bulk
+// string, formatted as sorted set scores are.
+type DoubleType float64
+
+func (d DoubleType) Serialize() string {
+	return BulkStringType(formatDouble(float64(d))).Serialize()
+}
+
+func (d DoubleType) SerializeRESP3() string {
+	return "," + formatDouble(float64(d)) + "\r\n"
+}
+
+// formatDouble writes integers without a decimal point and infinities as
+// "inf" and "-inf", as upstream does.
+func formatDouble(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "inf"
+	case math.IsInf(f, -1):
+		return "-inf"
+	case math.IsNaN(f):
+		return "nan"
+	case f == math.Trunc(f) && math.Abs(f) < 1<<53:
+		return strconv.FormatInt(int64(f), 10)
+	default:
+		return strconv.FormatFloat(f, 'g', -1, 64)
+	}
+}
+
+// BooleanType is a true or false reply; RESP2 clients get 1 or 0.
+type BooleanType bool
+
+func (b BooleanType) Serialize() string {
+	if b {
+		return IntegerType(1).Serialize()
+	}
+	return IntegerType(0).Serialize()
+}
+
+func (b BooleanType) SerializeRESP3() string {
+	if b {
+		return "#t\r\n"
+	}
+	return "#f\r\n"
+}
+
+// BigNumberType is an integer too large for an integer reply, in decimal;
+// RESP2 clients get it as a bulk string.
+type BigNumberType string
+
+func (n BigNumberType) Serialize() string {
+	return BulkStringType(n).Serialize()
+}
+
+func (n BigNumberType) SerializeRESP3() string {
+	return "(" + string(n) + "\r\n"
+}
+
+// VerbatimStringType is text meant to be shown as is, such as INFO's, with
+// its three letter format: "txt" or "mkd". RESP2 clients get a bulk string.
+type VerbatimStringType struct {
+	Format string
+	Text   string
+}
+
+func (v VerbatimStringType) Serialize() string {
+	return BulkStringType(v.Text).Serialize()
+}
+
+func (v VerbatimStringType) SerializeRESP3() string {
+	return fmt.Sprintf("=%d\r\n%s:%s\r\n", len(v.Text)+4, v.Format, v.Text)
+}
+
+// AttributeType is a reply with attributes attached, extra information a
+// client may ignore. RESP2 clients only get the reply.
+type AttributeType struct {
+	Attributes MapType
+	Reply      DataType
+}
+
+func (a AttributeType) Serialize() string {
+	return a.Reply.Serialize()
+}
+
+func (a AttributeType) SerializeRESP3() string {
+	return "|" + a.Attributes.SerializeRESP3()[1:] + Serialize(a.Reply, 3)
+}
@@ -1,10 +1,10 @@
 package response
 
 import (
-	"fmt"
 	"log"
 	"net"
 	"strconv"
+	"sync"
 )
 
 type DataType interface {
@@ -32,7 +32,13 @@ func (i IntegerType) Serialize() string {
 type BulkStringType string
 
 func (b BulkStringType) Serialize() string {
-	return fmt.Sprintf("$%d\r\n%s\r\n", len(b), string(b))
+	return string(b.appendTo(nil, 2))
+}
+
+func (b BulkStringType) appendTo(buf []byte, _ int) []byte {
+	buf = appendHeader(buf, '$', len(b))
+	buf = append(buf, b...)
+	return append(buf, "\r\n"...)
 }
 
 type NullBulkString struct{}
@@ -44,22 +50,38 @@ func (n NullBulkString) Serialize() string {
 type ArrayType []DataType
 
 func (a ArrayType) Serialize() string {
+	return string(a.appendTo(nil, 2))
+}
+
+func (a ArrayType) appendTo(buf []byte, proto int) []byte {
 	if a == nil {
-		return "*-1\r\n"
+		if proto == 3 {
+			return append(buf, "_\r\n"...)
+		}
+		return append(buf, "*-1\r\n"...)
 	}
-	response := "*" + strconv.Itoa(len(a)) + "\r\n"
-	for _, elem := range a {
-		response += elem.Serialize()
-	}
-	return response
+	return appendAggregate(buf, '*', a, proto)
 }
 
+// Replies are serialized into a buffer from a pool and written in one go, so
+// a large one costs no more than its size and no garbage once the pool is
+// warm. Buffers grown past maxPooledBuffer are left to the garbage collector
+// rather than kept around for every later reply.
+const maxPooledBuffer = 64 << 10
+
+var buffers = sync.Pool{New: func() any { return new([]byte) }}
+
 func writeResponse(conn net.Conn, resp DataType) {
-	response := resp.Serialize()
-	_, err := conn.Write([]byte(response))
+	pooled := buffers.Get().(*[]byte)
+	buf := appendReply((*pooled)[:0], resp, Proto(conn))
+	_, err := conn.Write(buf)
 	if err != nil {
 		log.Printf("Error sending RESP: %v", err)
 	}
+	if cap(buf) <= maxPooledBuffer {
+		*pooled = buf
+		buffers.Put(pooled)
+	}
 }
 
 // Helper Functions to write responses
@@ -83,7 +105,62 @@ func SendBulkString(conn net.Conn, msg string) {
 	writeResponse(conn, response)
 }
 
+// SendDouble sends a double, a bulk string to RESP2 clients.
+func SendDouble(conn net.Conn, value float64) {
+	writeResponse(conn, DoubleType(value))
+}
+
 func SendNullString(conn net.Conn) {
 	response := NullBulkString{}
 	writeResponse(conn, response)
 }
+
+func SendArray(conn net.Conn, values ArrayType) {
+	if values == nil {
+		values = ArrayType{}
+	}
+	writeResponse(conn, values)
+}
+
+// SendStringArray sends values as an array of bulk strings.
+func SendStringArray(conn net.Conn, values []string) {
+	writeResponse(conn, stringArray(values))
+}
+
+// stringArray is BulkStrings without an element boxed for each value.
+type stringArray []string
+
+func (a stringArray) Serialize() string {
+	return string(a.appendTo(nil, 2))
+}
+
+func (a stringArray) appendTo(buf []byte, _ int) []byte {
+	buf = appendHeader(buf, '*', len(a))
+	for _, value := range a {
+		buf = BulkStringType(value).appendTo(buf, 0)
+	}
+	return buf
+}
+
+// BulkStrings converts values to an array of bulk strings.
+func BulkStrings(values []string) ArrayType {
+	array := make(ArrayType, len(values))
+	for i, value := range values {
+		array[i] = BulkStringType(value)
+	}
+	return array
+}
+
+// SendArrayHeader starts an array of n elements, which the caller then sends
+// one by one. EXEC uses it to stream the replies of queued commands.
+func SendArrayHeader(conn net.Conn, n int) {
+	_, err := conn.Write([]byte("*" + strconv.Itoa(n) + "\r\n"))
+	if err != nil {
+		log.Printf("Error sending RESP: %v", err)
+	}
+}
+
+func SendNullArray(conn net.Conn) {
+	var response ArrayType
+	writeResponse(conn, response)
+}
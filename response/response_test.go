@@ -1,6 +1,13 @@
 package response
 
 import (
+	"bufio"
+	"bytes"
+	"math"
+	"net"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -73,3 +80,135 @@ func TestArrayTypeSerialize(t *testing.T) {
 		t.Errorf("ArrayType Serialize() failed for null array. Expected: %q, got: %q", expected, actual)
 	}
 }
+
+func TestParse(t *testing.T) {
+	replies := []DataType{
+		SimpleString("OK"),
+		ErrorType("ERR boom"),
+		IntegerType(-42),
+		BulkStringType("line\r\nbreak"),
+		NullBulkString{},
+		ArrayType(nil),
+		ArrayType{IntegerType(1), ArrayType{BulkStringType("a"), NullBulkString{}}, ArrayType{}},
+	}
+	for _, expected := range replies {
+		r := bufio.NewReader(strings.NewReader(expected.Serialize()))
+		actual, err := Parse(r)
+		if err != nil {
+			t.Fatalf("Parse() failed for %q: %v", expected.Serialize(), err)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Parse() failed. Expected: %#v, got: %#v", expected, actual)
+		}
+	}
+
+	if _, err := Parse(bufio.NewReader(strings.NewReader("$5\r\nab"))); err == nil {
+		t.Errorf("Parse() failed. Expected an error for a short bulk string")
+	}
+}
+
+func TestSerializeRESP3(t *testing.T) {
+	tests := []struct {
+		reply        DataType
+		resp2, resp3 string
+	}{
+		{Null{}, "$-1\r\n", "_\r\n"},
+		{NullBulkString{}, "$-1\r\n", "_\r\n"},
+		{ArrayType(nil), "*-1\r\n", "_\r\n"},
+		{StringMap("a", "1"), "*2\r\n$1\r\na\r\n$1\r\n1\r\n", "%1\r\n$1\r\na\r\n$1\r\n1\r\n"},
+		{PairsType{{Key: BulkStringType("m"), Value: DoubleType(2)}}, "*2\r\n$1\r\nm\r\n$1\r\n2\r\n", "*1\r\n*2\r\n$1\r\nm\r\n,2\r\n"},
+		{SetType{BulkStringType("x")}, "*1\r\n$1\r\nx\r\n", "~1\r\n$1\r\nx\r\n"},
+		{PushType{BulkStringType("message")}, "*1\r\n$7\r\nmessage\r\n", ">1\r\n$7\r\nmessage\r\n"},
+		{DoubleType(1.5), "$3\r\n1.5\r\n", ",1.5\r\n"},
+		{DoubleType(3), "$1\r\n3\r\n", ",3\r\n"},
+		{DoubleType(math.Inf(-1)), "$4\r\n-inf\r\n", ",-inf\r\n"},
+		{BooleanType(true), ":1\r\n", "#t\r\n"},
+		{BooleanType(false), ":0\r\n", "#f\r\n"},
+		{BigNumberType("3492890328409238509324850943850943825024385"), "$43\r\n3492890328409238509324850943850943825024385\r\n", "(3492890328409238509324850943850943825024385\r\n"},
+		{VerbatimStringType{Format: "txt", Text: "Some string"}, "$11\r\nSome string\r\n", "=15\r\ntxt:Some string\r\n"},
+		{AttributeType{Attributes: StringMap("ttl", "3600"), Reply: IntegerType(1)}, ":1\r\n", "|1\r\n$3\r\nttl\r\n$4\r\n3600\r\n:1\r\n"},
+		// RESP3 types nested in an array keep their RESP3 form
+		{ArrayType{DoubleType(0.5), NullBulkString{}}, "*2\r\n$3\r\n0.5\r\n$-1\r\n", "*2\r\n,0.5\r\n_\r\n"},
+	}
+	for _, tt := range tests {
+		if actual := Serialize(tt.reply, 2); actual != tt.resp2 {
+			t.Errorf("Serialize(%#v, 2) failed. Expected: %q, got: %q", tt.reply, tt.resp2, actual)
+		}
+		if actual := Serialize(tt.reply, 3); actual != tt.resp3 {
+			t.Errorf("Serialize(%#v, 3) failed. Expected: %q, got: %q", tt.reply, tt.resp3, actual)
+		}
+	}
+}
+
+func TestParseRESP3(t *testing.T) {
+	replies := []DataType{
+		Null{},
+		StringMap("a", "1", "b", "2"),
+		SetType{BulkStringType("x"), IntegerType(2)},
+		PushType{BulkStringType("message"), BulkStringType("ch")},
+		DoubleType(-2.25),
+		BooleanType(true),
+		BigNumberType("-12345678901234567890"),
+		VerbatimStringType{Format: "txt", Text: "a\r\nb"},
+		AttributeType{Attributes: StringMap("key", "value"), Reply: ArrayType{IntegerType(1)}},
+		MapType{{Key: SimpleString("nested"), Value: MapType{{Key: IntegerType(1), Value: SetType{}}}}},
+	}
+	for _, expected := range replies {
+		r := bufio.NewReader(strings.NewReader(Serialize(expected, 3)))
+		actual, err := Parse(r)
+		if err != nil {
+			t.Fatalf("Parse() failed for %q: %v", Serialize(expected, 3), err)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Parse() failed. Expected: %#v, got: %#v", expected, actual)
+		}
+	}
+}
+
+// recordConn keeps what is written to it.
+type recordConn struct {
+	net.Conn
+	buf bytes.Buffer
+}
+
+func (c *recordConn) Write(p []byte) (int, error) {
+	return c.buf.Write(p)
+}
+
+func TestSendLargeArray(t *testing.T) {
+	// each element used to copy the reply so far, which took minutes for
+	// arrays this size
+	values := make([]string, 200000)
+	for i := range values {
+		values[i] = "value"
+	}
+	conn := &recordConn{}
+	SendStringArray(conn, values)
+	expected := "*200000\r\n" + strings.Repeat("$5\r\nvalue\r\n", 200000)
+	if conn.buf.String() != expected {
+		t.Errorf("SendStringArray() failed. Expected %d bytes, got: %d", len(expected), conn.buf.Len())
+	}
+
+	nested := ArrayType{BulkStrings(values), PairsType{{Key: BulkStringType("m"), Value: DoubleType(1)}}}
+	expected = "*2\r\n" + expected + "*1\r\n*2\r\n$1\r\nm\r\n,1\r\n"
+	if actual := Serialize(nested, 3); actual != expected {
+		t.Errorf("Serialize() failed for a nested array. Expected %d bytes, got: %d", len(expected), len(actual))
+	}
+}
+
+func BenchmarkSendStringArray(b *testing.B) {
+	for _, n := range []int{10, 1000, 100000} {
+		values := make([]string, n)
+		for i := range values {
+			values[i] = "value:" + strconv.Itoa(i)
+		}
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			conn := &recordConn{}
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				conn.buf.Reset()
+				SendStringArray(conn, values)
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package scripting
+
+import (
+	"github.com/Ryan-DL/go-redis-server/response"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// toLua converts a command's reply to a Lua value, with upstream's rules:
+// integers become numbers, bulk strings strings, arrays tables, a status
+// reply a table with an ok field and an error reply one with an err field,
+// and a null bulk string or array false.
+func toLua(L *lua.LState, reply response.DataType) lua.LValue {
+	switch r := reply.(type) {
+	case response.IntegerType:
+		return lua.LNumber(r)
+	case response.BulkStringType:
+		return lua.LString(r)
+	case response.SimpleString:
+		return replyTable(L, "ok", string(r))
+	case response.ErrorType:
+		return replyTable(L, "err", string(r))
+	case response.ArrayType:
+		if r == nil {
+			return lua.LFalse
+		}
+		t := L.CreateTable(len(r), 0)
+		for _, elem := range r {
+			t.Append(toLua(L, elem))
+		}
+		return t
+	}
+	return lua.LFalse
+}
+
+// toReply converts a script's return value to a reply, the inverse of toLua:
+// numbers are truncated to integers, true becomes 1 and false or nil a null
+// bulk string. A table is an array up to its first nil, unless it has an ok
+// or err field.
+func toReply(v lua.LValue) response.DataType {
+	switch v := v.(type) {
+	case lua.LNumber:
+		return response.IntegerType(int64(v))
+	case lua.LString:
+		return response.BulkStringType(v)
+	case lua.LBool:
+		if v {
+			return response.IntegerType(1)
+		}
+		return response.NullBulkString{}
+	case *lua.LTable:
+		if ok, isStr := v.RawGetString("ok").(lua.LString); isStr {
+			return response.SimpleString(ok)
+		}
+		if err, isStr := v.RawGetString("err").(lua.LString); isStr {
+			return response.ErrorType(err)
+		}
+		array := response.ArrayType{}
+		for i := 1; ; i++ {
+			elem := v.RawGetInt(i)
+			if elem == lua.LNil {
+				break
+			}
+			array = append(array, toReply(elem))
+		}
+		return array
+	}
+	return response.NullBulkString{}
+}
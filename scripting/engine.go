@@ -0,0 +1,231 @@
+// Package scripting runs the Lua scripts of EVAL and EVALSHA, and the
+// function libraries of FUNCTION LOAD and FCALL, as upstream's embedded
+// interpreter does. Scripts reach the keyspace through redis.call, which the
+// caller of Eval or FCall implements by running the command.
+package scripting
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+var (
+	ErrNotBusy    = errors.New("NOTBUSY No scripts in execution right now.")
+	ErrUnkillable = errors.New("UNKILLABLE Sorry the script already executed write commands against the dataset. You can either wait the script termination or kill the server in a hard way using the SHUTDOWN NOSAVE command.")
+
+	errKilled = errors.New("ERR Script killed by user with SCRIPT KILL...")
+)
+
+// Caller runs a command a script calls and returns its reply.
+type Caller func(args []string) response.DataType
+
+// Engine caches scripts by SHA1, and loads function libraries, and runs them
+// one at a time.
+type Engine struct {
+	// TimeLimit is how long a script runs before the server reports it busy
+	// and lets SCRIPT KILL stop it, after lua-time-limit.
+	TimeLimit time.Duration
+
+	mu      sync.Mutex
+	scripts map[string]*lua.FunctionProto
+	running *run
+	// started is when the running script started, in Unix nanoseconds, or 0.
+	// Every command checks Busy, so it is read without taking mu.
+	started atomic.Int64
+
+	// funcsMu is apart from mu, which a running script takes
+	funcsMu sync.Mutex
+	funcs   *functions
+}
+
+// run is the script currently running.
+type run struct {
+	start  time.Time
+	cancel context.CancelFunc
+	wrote  bool
+	killed bool
+}
+
+func NewEngine(timeLimit time.Duration) *Engine {
+	return &Engine{TimeLimit: timeLimit, scripts: make(map[string]*lua.FunctionProto)}
+}
+
+// SHA1 returns the name a script is cached under.
+func SHA1(body string) string {
+	sum := sha1.Sum([]byte(body))
+	return hex.EncodeToString(sum[:])
+}
+
+// Load compiles a script and caches it, returning its SHA1.
+func (e *Engine) Load(body string) (string, error) {
+	sha := SHA1(body)
+	e.mu.Lock()
+	_, ok := e.scripts[sha]
+	e.mu.Unlock()
+	if ok {
+		return sha, nil
+	}
+
+	proto, err := compile(body, "user_script")
+	if err != nil {
+		return "", errors.New("ERR Error compiling script (new function): " + oneLine(err.Error()))
+	}
+	e.mu.Lock()
+	e.scripts[sha] = proto
+	e.mu.Unlock()
+	return sha, nil
+}
+
+func compile(body, name string) (*lua.FunctionProto, error) {
+	chunk, err := parse.Parse(strings.NewReader(body), name)
+	if err != nil {
+		return nil, err
+	}
+	return lua.Compile(chunk, name)
+}
+
+// Exists reports which of the scripts are cached.
+func (e *Engine) Exists(shas ...string) []bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	exists := make([]bool, len(shas))
+	for i, sha := range shas {
+		_, exists[i] = e.scripts[strings.ToLower(sha)]
+	}
+	return exists
+}
+
+// Flush empties the script cache.
+func (e *Engine) Flush() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.scripts = make(map[string]*lua.FunctionProto)
+}
+
+// Busy reports whether a script has been running longer than TimeLimit.
+func (e *Engine) Busy() bool {
+	started := e.started.Load()
+	return started != 0 && time.Since(time.Unix(0, started)) >= e.TimeLimit
+}
+
+// NoteWrite records that the running script has written to the keyspace,
+// after which it can no longer be killed.
+func (e *Engine) NoteWrite() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.running != nil {
+		e.running.wrote = true
+	}
+}
+
+// Kill stops the running script, unless it has written something.
+func (e *Engine) Kill() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.running == nil || e.running.killed {
+		return ErrNotBusy
+	}
+	if e.running.wrote {
+		return ErrUnkillable
+	}
+	e.running.killed = true
+	e.running.cancel()
+	return nil
+}
+
+// Eval runs the cached script sha with KEYS and ARGV set and returns its
+// reply. Errors, including a missing script, are error replies.
+func (e *Engine) Eval(sha string, keys, argv []string, call Caller) response.DataType {
+	sha = strings.ToLower(sha)
+	e.mu.Lock()
+	proto := e.scripts[sha]
+	e.mu.Unlock()
+	if proto == nil {
+		return response.ErrorType("NOSCRIPT No matching script. Please use EVAL.")
+	}
+
+	L := newState(call)
+	defer L.Close()
+	L.SetGlobal("KEYS", stringTable(L, keys))
+	L.SetGlobal("ARGV", stringTable(L, argv))
+	protectGlobals(L)
+
+	reply, err := e.run(L, L.NewFunctionFromProto(proto))
+	if err != nil {
+		return scriptError(err, sha)
+	}
+	return reply
+}
+
+// run calls fn as the running script, so it can be killed.
+func (e *Engine) run(L *lua.LState, fn *lua.LFunction, args ...lua.LValue) (response.DataType, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	L.SetContext(ctx)
+
+	r := &run{start: time.Now(), cancel: cancel}
+	e.mu.Lock()
+	e.running = r
+	e.started.Store(r.start.UnixNano())
+	e.mu.Unlock()
+	defer func() {
+		e.mu.Lock()
+		e.running = nil
+		e.started.Store(0)
+		e.mu.Unlock()
+	}()
+
+	L.Push(fn)
+	for _, arg := range args {
+		L.Push(arg)
+	}
+	err := L.PCall(len(args), 1, nil)
+
+	e.mu.Lock()
+	killed := r.killed
+	e.mu.Unlock()
+	if killed {
+		return nil, errKilled
+	}
+	if err != nil {
+		return nil, err
+	}
+	// function libraries keep their interpreter, so leave its stack empty
+	reply := toReply(L.Get(-1))
+	L.Pop(1)
+	return reply, nil
+}
+
+// scriptError turns an error raised by a script into its reply. An error
+// table, as redis.call raises, keeps its message; anything else is reported
+// with where it happened.
+func scriptError(err error, sha string) response.DataType {
+	if err == errKilled {
+		return response.ErrorType(err.Error())
+	}
+	apiErr, ok := err.(*lua.ApiError)
+	if !ok {
+		return response.ErrorType("ERR " + err.Error())
+	}
+	if t, ok := apiErr.Object.(*lua.LTable); ok {
+		if msg, ok := t.RawGetString("err").(lua.LString); ok {
+			return response.ErrorType(oneLine(string(msg)) + " script: " + sha)
+		}
+	}
+	return response.ErrorType("ERR " + oneLine(apiErr.Object.String()) + " script: " + sha)
+}
+
+// oneLine makes a message fit in an error reply.
+func oneLine(msg string) string {
+	return strings.Join(strings.Fields(msg), " ")
+}
@@ -0,0 +1,115 @@
+package scripting
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// eval loads and runs body against a store of one key, "k", whose value is
+// "v".
+func eval(t *testing.T, e *Engine, body string, keys, argv []string) response.DataType {
+	t.Helper()
+	sha, err := e.Load(body)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	return e.Eval(sha, keys, argv, func(args []string) response.DataType {
+		switch strings.ToUpper(args[0]) {
+		case "GET":
+			if args[1] == "k" {
+				return response.BulkStringType("v")
+			}
+			return response.NullBulkString{}
+		case "PING":
+			return response.SimpleString("PONG")
+		case "ECHO":
+			return response.BulkStringType(strings.Join(args[1:], " "))
+		}
+		return response.ErrorType("ERR unknown command")
+	})
+}
+
+func TestEval(t *testing.T) {
+	e := NewEngine(time.Second)
+	tests := []struct {
+		body     string
+		expected response.DataType
+	}{
+		// Lua to RESP
+		{"return 42", response.IntegerType(42)},
+		{"return 3.99", response.IntegerType(3)},
+		{"return 'hello'", response.BulkStringType("hello")},
+		{"return true", response.IntegerType(1)},
+		{"return false", response.NullBulkString{}},
+		{"return nil", response.NullBulkString{}},
+		{"return {1, 'two', {3}, nil, 5}", response.ArrayType{response.IntegerType(1), response.BulkStringType("two"), response.ArrayType{response.IntegerType(3)}}},
+		{"return {ok = 'FINE'}", response.SimpleString("FINE")},
+		{"return redis.error_reply('MY error')", response.ErrorType("MY error")},
+
+		// RESP to Lua
+		{"return redis.call('get', KEYS[1])", response.BulkStringType("v")},
+		{"return redis.call('get', 'missing') == false", response.IntegerType(1)},
+		{"return redis.call('ping').ok", response.BulkStringType("PONG")},
+		{"return redis.pcall('nosuch').err", response.BulkStringType("ERR unknown command")},
+		{"return redis.call('echo', ARGV[1], 2, 1.5)", response.BulkStringType("a 2 1.5")},
+	}
+	for _, tt := range tests {
+		if reply := eval(t, e, tt.body, []string{"k"}, []string{"a"}); !reflect.DeepEqual(reply, tt.expected) {
+			t.Errorf("Eval(%q) failed. Expected: %#v, got: %#v", tt.body, tt.expected, reply)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	e := NewEngine(time.Second)
+	tests := []struct {
+		body   string
+		prefix string
+	}{
+		{"return redis.call('nosuch')", "ERR unknown command script: "},
+		{"error('boom')", "ERR user_script:1: boom script: "},
+		{"x = 1", "ERR user_script:1: Attempt to modify a readonly table"},
+		{"return undefined", "ERR user_script:1: Script attempted to access nonexistent global variable 'undefined'"},
+		{"return redis.call('echo', {})", "ERR Lua redis lib command arguments must be strings or integers"},
+	}
+	for _, tt := range tests {
+		reply, ok := eval(t, e, tt.body, nil, nil).(response.ErrorType)
+		if !ok || !strings.HasPrefix(string(reply), tt.prefix) {
+			t.Errorf("Eval(%q) failed. Expected an error starting %q, got: %#v", tt.body, tt.prefix, reply)
+		}
+	}
+
+	if _, err := e.Load("return ("); err == nil || !strings.HasPrefix(err.Error(), "ERR Error compiling script") {
+		t.Errorf("Load() failed. Expected a compile error, got: %v", err)
+	}
+	if reply := e.Eval(SHA1("return 2"), nil, nil, nil); reply != response.ErrorType("NOSCRIPT No matching script. Please use EVAL.") {
+		t.Errorf("Eval() failed. Expected NOSCRIPT, got: %#v", reply)
+	}
+}
+
+func TestKill(t *testing.T) {
+	e := NewEngine(10 * time.Millisecond)
+	if err := e.Kill(); err != ErrNotBusy {
+		t.Errorf("Kill() failed. Expected: %v, got: %v", ErrNotBusy, err)
+	}
+
+	sha, _ := e.Load("while true do end")
+	done := make(chan response.DataType)
+	go func() { done <- e.Eval(sha, nil, nil, nil) }()
+	for !e.Busy() {
+		time.Sleep(time.Millisecond)
+	}
+	if err := e.Kill(); err != nil {
+		t.Fatalf("Kill() failed: %v", err)
+	}
+	if reply := <-done; reply != response.ErrorType(errKilled.Error()) {
+		t.Errorf("Kill() failed. Expected the script to be killed, got: %#v", reply)
+	}
+	if e.Busy() {
+		t.Errorf("Busy() failed. Expected: false, got: true")
+	}
+}
@@ -0,0 +1,358 @@
+package scripting
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+	lua "github.com/yuin/gopher-lua"
+)
+
+var (
+	ErrFunctionNotFound = errors.New("ERR Function not found")
+	ErrLibraryNotFound  = errors.New("ERR Library not found")
+)
+
+// loadTimeout is how long a library's code may run while it is loaded.
+const loadTimeout = 500 * time.Millisecond
+
+// functionFlags are the flags redis.register_function accepts.
+var functionFlags = map[string]bool{
+	"no-writes":             true,
+	"allow-oom":             true,
+	"allow-stale":           true,
+	"no-cluster":            true,
+	"allow-cross-slot-keys": true,
+}
+
+// Library is a function library of FUNCTION LOAD: Lua code, beginning with a
+// "#!lua name=<name>" line, that registers functions when it runs.
+type Library struct {
+	Name      string
+	Code      string
+	Functions []*Function // sorted by name
+}
+
+// Function is a function a library registered with redis.register_function.
+type Function struct {
+	Name        string
+	Description string
+	Flags       []string
+	Library     *Library
+
+	fn    *lua.LFunction
+	state *functions
+}
+
+// Has reports whether the function was registered with flag.
+func (f *Function) Has(flag string) bool {
+	return slices.Contains(f.Flags, flag)
+}
+
+// LibraryStore holds the code of the function libraries, which are part of
+// the dataset. cache.ValueStore implements it.
+type LibraryStore interface {
+	Libraries() (codes []string, version uint64)
+}
+
+// functions are the libraries of a LibraryStore, loaded into one interpreter
+// that their functions run in.
+type functions struct {
+	version   uint64
+	L         *lua.LState
+	call      Caller // redis.call of the function running
+	libraries []*Library
+	byName    map[string]*Function
+}
+
+// functions returns the libraries in store, loading them again if they have
+// changed since they were last loaded.
+func (e *Engine) functions(store LibraryStore) *functions {
+	codes, version := store.Libraries()
+	e.funcsMu.Lock()
+	defer e.funcsMu.Unlock()
+	if e.funcs != nil && e.funcs.version == version {
+		return e.funcs
+	}
+	if e.funcs != nil {
+		e.funcs.L.Close()
+	}
+
+	f := &functions{version: version, byName: make(map[string]*Function)}
+	f.L = newState(func(args []string) response.DataType { return f.call(args) })
+	protectGlobals(f.L)
+	for _, code := range codes {
+		lib, err := loadLibrary(f.L, code)
+		if err != nil {
+			// only a library from a file written elsewhere can fail here
+			log.Printf("Failed to load function library: %v", err)
+			continue
+		}
+		f.libraries = append(f.libraries, lib)
+		for _, fn := range lib.Functions {
+			fn.state = f
+			f.byName[fn.Name] = fn
+		}
+	}
+	sort.Slice(f.libraries, func(i, j int) bool { return f.libraries[i].Name < f.libraries[j].Name })
+	e.funcs = f
+	return f
+}
+
+// Libraries returns the libraries in store, sorted by name.
+func (e *Engine) Libraries(store LibraryStore) []*Library {
+	return e.functions(store).libraries
+}
+
+// Function looks up a function registered by one of the libraries in store.
+func (e *Engine) Function(store LibraryStore, name string) (*Function, bool) {
+	fn, ok := e.functions(store).byName[name]
+	return fn, ok
+}
+
+// FCall runs fn with the keys and args tables it is called with and returns
+// its reply. Errors are error replies.
+func (e *Engine) FCall(fn *Function, keys, args []string, call Caller) response.DataType {
+	f := fn.state
+	f.call = call
+	defer func() { f.call = nil }()
+
+	reply, err := e.run(f.L, fn.fn, stringTable(f.L, keys), stringTable(f.L, args))
+	if err != nil {
+		return scriptError(err, fn.Name)
+	}
+	return reply
+}
+
+// ParseLibrary runs a library's code, as FUNCTION LOAD does, to check it and
+// learn the functions it registers.
+func ParseLibrary(code string) (*Library, error) {
+	L := newState(nil)
+	defer L.Close()
+	protectGlobals(L)
+	return loadLibrary(L, code)
+}
+
+// AddLibrary returns codes with the library in code added, replacing the
+// library of the same name if replace is set, and the library's name. It
+// fails if the library exists, or if another library registers one of its
+// functions.
+func AddLibrary(codes []string, code string, replace bool) ([]string, string, error) {
+	lib, err := ParseLibrary(code)
+	if err != nil {
+		return nil, "", err
+	}
+
+	kept := make([]string, 0, len(codes)+1)
+	for _, other := range codes {
+		name, _, _ := parseMetadata(other)
+		if name == lib.Name {
+			if !replace {
+				return nil, "", fmt.Errorf("ERR Library '%s' already exists", lib.Name)
+			}
+			continue
+		}
+		otherLib, err := ParseLibrary(other)
+		if err == nil {
+			for _, fn := range otherLib.Functions {
+				if slices.ContainsFunc(lib.Functions, func(f *Function) bool { return f.Name == fn.Name }) {
+					return nil, "", fmt.Errorf("ERR Function %s already exists", fn.Name)
+				}
+			}
+		}
+		kept = append(kept, other)
+	}
+	return append(kept, code), lib.Name, nil
+}
+
+// DeleteLibrary returns codes without the library called name.
+func DeleteLibrary(codes []string, name string) ([]string, error) {
+	for i, code := range codes {
+		if libName, _, _ := parseMetadata(code); libName == name {
+			return slices.Delete(codes, i, i+1), nil
+		}
+	}
+	return nil, ErrLibraryNotFound
+}
+
+// parseMetadata reads the "#!<engine> name=<name>" line a library begins
+// with, and returns the library's name and the code after that line.
+func parseMetadata(code string) (name, body string, err error) {
+	line, body, _ := strings.Cut(code, "\n")
+	if !strings.HasPrefix(line, "#!") {
+		return "", "", errors.New("ERR Missing library metadata")
+	}
+	parts := strings.Fields(line[2:])
+	if len(parts) == 0 || !strings.EqualFold(parts[0], "lua") {
+		engine := ""
+		if len(parts) > 0 {
+			engine = parts[0]
+		}
+		return "", "", fmt.Errorf("ERR Engine '%s' not found", engine)
+	}
+	for _, part := range parts[1:] {
+		value, ok := strings.CutPrefix(part, "name=")
+		if !ok {
+			return "", "", fmt.Errorf("ERR Invalid metadata value given: %s", part)
+		}
+		name = value
+	}
+	if name == "" {
+		return "", "", errors.New("ERR Library name was not given")
+	}
+	if !validName(name) {
+		return "", "", errors.New("ERR Library names can only contain letters, numbers, or underscores(_) and must be at least one character long")
+	}
+	return name, body, nil
+}
+
+func validName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// loadLibrary runs a library's code in L with redis.register_function
+// available, and redis.call not, and returns the library it registered.
+func loadLibrary(L *lua.LState, code string) (*Library, error) {
+	name, body, err := parseMetadata(code)
+	if err != nil {
+		return nil, err
+	}
+	// the metadata line stays, blank, so line numbers in errors match
+	proto, err := compile("\n"+body, "@user_function")
+	if err != nil {
+		return nil, errors.New("ERR Error compiling function: " + oneLine(err.Error()))
+	}
+
+	lib := &Library{Name: name, Code: code}
+	redis := L.GetGlobal("redis").(*lua.LTable)
+	call, pcall := redis.RawGetString("call"), redis.RawGetString("pcall")
+	redis.RawSetString("call", lua.LNil)
+	redis.RawSetString("pcall", lua.LNil)
+	redis.RawSetString("register_function", L.NewFunction(func(L *lua.LState) int {
+		return registerFunction(L, lib)
+	}))
+	defer func() {
+		redis.RawSetString("call", call)
+		redis.RawSetString("pcall", pcall)
+		redis.RawSetString("register_function", L.NewFunction(func(L *lua.LState) int {
+			L.Error(replyTable(L, "err", "ERR redis.register_function can only be called on FUNCTION LOAD command"), 1)
+			return 0
+		}))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), loadTimeout)
+	defer cancel()
+	L.SetContext(ctx)
+	defer L.RemoveContext()
+	L.Push(L.NewFunctionFromProto(proto))
+	if err := L.PCall(0, 0, nil); err != nil {
+		msg := "FUNCTION LOAD timeout"
+		if ctx.Err() == nil {
+			msg = errorMessage(err)
+		}
+		return nil, errors.New("ERR Error registering functions: " + msg)
+	}
+
+	if len(lib.Functions) == 0 {
+		return nil, errors.New("ERR No functions registered")
+	}
+	sort.Slice(lib.Functions, func(i, j int) bool { return lib.Functions[i].Name < lib.Functions[j].Name })
+	return lib, nil
+}
+
+// registerFunction is redis.register_function, called either with a name and
+// a callback or with a table of named arguments:
+// function_name, callback, flags and description.
+func registerFunction(L *lua.LState, lib *Library) int {
+	fail := func(msg string) int {
+		L.Error(replyTable(L, "err", msg), 1)
+		return 0
+	}
+
+	fn := &Function{Library: lib}
+	var callback lua.LValue = lua.LNil
+	switch L.GetTop() {
+	case 1:
+		args, ok := L.Get(1).(*lua.LTable)
+		if !ok {
+			return fail("ERR calling redis.register_function with a single argument is only applicable to Lua table (representing named arguments).")
+		}
+		var bad string
+		args.ForEach(func(k, v lua.LValue) {
+			switch k.String() {
+			case "function_name":
+				fn.Name = lua.LVAsString(v)
+			case "callback":
+				callback = v
+			case "description":
+				fn.Description = lua.LVAsString(v)
+			case "flags":
+				flags, ok := v.(*lua.LTable)
+				if !ok {
+					bad = "ERR flags argument to redis.register_function must be a table representing function flags"
+					return
+				}
+				flags.ForEach(func(_, flag lua.LValue) {
+					if !functionFlags[flag.String()] {
+						bad = "ERR unknown flag given"
+					}
+					fn.Flags = append(fn.Flags, flag.String())
+				})
+			default:
+				bad = "ERR unknown argument given to redis.register_function"
+			}
+		})
+		if bad != "" {
+			return fail(bad)
+		}
+	case 2:
+		fn.Name = lua.LVAsString(L.Get(1))
+		callback = L.Get(2)
+	default:
+		return fail("ERR wrong number of arguments to redis.register_function")
+	}
+
+	if !validName(fn.Name) {
+		return fail("ERR Function names can only contain letters, numbers, or underscores(_) and must be at least one character long")
+	}
+	var ok bool
+	if fn.fn, ok = callback.(*lua.LFunction); !ok {
+		return fail("ERR callback argument given to redis.register_function must be a function")
+	}
+	for _, other := range lib.Functions {
+		if other.Name == fn.Name {
+			return fail("ERR Function already exists in the library")
+		}
+	}
+	lib.Functions = append(lib.Functions, fn)
+	return 0
+}
+
+// errorMessage is the message of an error raised by Lua, without the ERR an
+// error table's message begins with.
+func errorMessage(err error) string {
+	apiErr, ok := err.(*lua.ApiError)
+	if !ok {
+		return oneLine(err.Error())
+	}
+	if t, ok := apiErr.Object.(*lua.LTable); ok {
+		if msg, ok := t.RawGetString("err").(lua.LString); ok {
+			return oneLine(strings.TrimPrefix(string(msg), "ERR "))
+		}
+	}
+	return oneLine(apiErr.Object.String())
+}
@@ -0,0 +1,138 @@
+package scripting
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+)
+
+// libraries is a LibraryStore holding codes.
+type libraries struct {
+	codes   []string
+	version uint64
+}
+
+func (l *libraries) Libraries() ([]string, uint64) {
+	return l.codes, l.version
+}
+
+func (l *libraries) set(codes []string) {
+	l.codes = codes
+	l.version++
+}
+
+const testLibrary = `#!lua name=lib
+local function get(keys, args)
+	return redis.call('get', keys[1])
+end
+redis.register_function{function_name = 'get', callback = get, flags = {'no-writes'}, description = 'reads'}
+redis.register_function('echo', function(keys, args) return args end)
+redis.register_function('spin', function() while true do end end)`
+
+func TestParseLibrary(t *testing.T) {
+	lib, err := ParseLibrary(testLibrary)
+	if err != nil {
+		t.Fatalf("ParseLibrary() failed: %v", err)
+	}
+	if lib.Name != "lib" || len(lib.Functions) != 3 {
+		t.Fatalf("ParseLibrary() failed. Expected: lib with 3 functions, got: %s with %d", lib.Name, len(lib.Functions))
+	}
+	get := lib.Functions[1]
+	if get.Name != "get" || get.Description != "reads" || !get.Has("no-writes") {
+		t.Errorf("ParseLibrary() failed. Expected: get, reads, no-writes, got: %s, %s, %v", get.Name, get.Description, get.Flags)
+	}
+
+	tests := []struct {
+		code   string
+		prefix string
+	}{
+		{"return 1", "ERR Missing library metadata"},
+		{"#!js name=x\n", "ERR Engine 'js' not found"},
+		{"#!lua\n", "ERR Library name was not given"},
+		{"#!lua name=x version=1\n", "ERR Invalid metadata value given: version=1"},
+		{"#!lua name=x-y\n", "ERR Library names can only contain"},
+		{"#!lua name=x\nreturn 1", "ERR No functions registered"},
+		{"#!lua name=x\nredis.call('ping')", "ERR Error registering functions: @user_function:2:"},
+		{"#!lua name=x\nglobal = 1", "ERR Error registering functions: @user_function:2: Attempt to modify a readonly table"},
+		{"#!lua name=x\nredis.register_function('f', 1)", "ERR Error registering functions: callback argument given"},
+		{"#!lua name=x\nredis.register_function{function_name = 'f', callback = print, flags = {'fast'}}", "ERR Error registering functions: unknown flag given"},
+		{"#!lua name=x\nredis.register_function('f', print)\nredis.register_function('f', print)", "ERR Error registering functions: Function already exists in the library"},
+		{"#!lua name=x\nwhile true do end", "ERR Error registering functions: FUNCTION LOAD timeout"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseLibrary(tt.code); err == nil || !strings.HasPrefix(err.Error(), tt.prefix) {
+			t.Errorf("ParseLibrary(%q) failed. Expected: %s..., got: %v", tt.code, tt.prefix, err)
+		}
+	}
+}
+
+func TestAddLibrary(t *testing.T) {
+	codes, name, err := AddLibrary(nil, testLibrary, false)
+	if err != nil || name != "lib" || len(codes) != 1 {
+		t.Fatalf("AddLibrary() failed. Expected: lib, got: %s, %v", name, err)
+	}
+
+	if _, _, err := AddLibrary(codes, testLibrary, false); err == nil || err.Error() != "ERR Library 'lib' already exists" {
+		t.Errorf("AddLibrary() failed. Expected: library exists, got: %v", err)
+	}
+	other := "#!lua name=other\nredis.register_function('echo', print)"
+	if _, _, err := AddLibrary(codes, other, false); err == nil || err.Error() != "ERR Function echo already exists" {
+		t.Errorf("AddLibrary() failed. Expected: function exists, got: %v", err)
+	}
+	replaced := "#!lua name=lib\nredis.register_function('echo', print)"
+	if codes, _, err := AddLibrary(codes, replaced, true); err != nil || !reflect.DeepEqual(codes, []string{replaced}) {
+		t.Errorf("AddLibrary() failed. Expected: %q, got: %q, %v", []string{replaced}, codes, err)
+	}
+
+	if codes, err := DeleteLibrary(codes, "lib"); err != nil || len(codes) != 0 {
+		t.Errorf("DeleteLibrary() failed. Expected: no libraries, got: %q, %v", codes, err)
+	}
+	if _, err := DeleteLibrary(codes, "nosuch"); err != ErrLibraryNotFound {
+		t.Errorf("DeleteLibrary() failed. Expected: %v, got: %v", ErrLibraryNotFound, err)
+	}
+}
+
+func TestFCall(t *testing.T) {
+	e := NewEngine(10 * time.Millisecond)
+	store := &libraries{}
+	store.set([]string{testLibrary})
+	call := func(args []string) response.DataType {
+		return response.BulkStringType(strings.Join(args, " "))
+	}
+
+	fn, ok := e.Function(store, "get")
+	if !ok {
+		t.Fatalf("Function() failed. Expected: get, got: nothing")
+	}
+	if reply := e.FCall(fn, []string{"k"}, nil, call); reply != response.BulkStringType("get k") {
+		t.Errorf("FCall() failed. Expected: %q, got: %#v", "get k", reply)
+	}
+	fn, _ = e.Function(store, "echo")
+	expected := response.ArrayType{response.BulkStringType("a"), response.BulkStringType("b")}
+	if reply := e.FCall(fn, nil, []string{"a", "b"}, call); !reflect.DeepEqual(reply, expected) {
+		t.Errorf("FCall() failed. Expected: %#v, got: %#v", expected, reply)
+	}
+
+	// a killed function leaves its library usable
+	spin, _ := e.Function(store, "spin")
+	done := make(chan response.DataType)
+	go func() { done <- e.FCall(spin, nil, nil, call) }()
+	for !e.Busy() {
+		time.Sleep(time.Millisecond)
+	}
+	e.Kill()
+	if reply := <-done; reply != response.ErrorType(errKilled.Error()) {
+		t.Errorf("Kill() failed. Expected the function to be killed, got: %#v", reply)
+	}
+	if reply := e.FCall(fn, nil, []string{"c"}, call); !reflect.DeepEqual(reply, response.ArrayType{response.BulkStringType("c")}) {
+		t.Errorf("FCall() failed after Kill(). Got: %#v", reply)
+	}
+
+	store.set(nil)
+	if _, ok := e.Function(store, "get"); ok {
+		t.Errorf("Function() failed. Expected the deleted library's functions to be gone")
+	}
+}
@@ -0,0 +1,136 @@
+package scripting
+
+import (
+	"log"
+	"strings"
+
+	"github.com/Ryan-DL/go-redis-server/response"
+	lua "github.com/yuin/gopher-lua"
+)
+
+// newState returns an interpreter with the libraries upstream offers
+// scripts, bar cjson, cmsgpack, struct and bit, and the redis table wired to
+// call.
+func newState(call Caller) *lua.LState {
+	L := lua.NewState(lua.Options{SkipOpenLibs: true})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	// no reaching the filesystem
+	L.SetGlobal("dofile", lua.LNil)
+	L.SetGlobal("loadfile", lua.LNil)
+
+	redis := L.NewTable()
+	L.SetFuncs(redis, map[string]lua.LGFunction{
+		"call":  func(L *lua.LState) int { return redisCall(L, call, true) },
+		"pcall": func(L *lua.LState) int { return redisCall(L, call, false) },
+		"error_reply": func(L *lua.LState) int {
+			L.Push(replyTable(L, "err", L.CheckString(1)))
+			return 1
+		},
+		"status_reply": func(L *lua.LState) int {
+			L.Push(replyTable(L, "ok", L.CheckString(1)))
+			return 1
+		},
+		"sha1hex": func(L *lua.LState) int {
+			L.Push(lua.LString(SHA1(L.CheckString(1))))
+			return 1
+		},
+		"log": func(L *lua.LState) int {
+			L.CheckInt(1)
+			parts := make([]string, 0, L.GetTop()-1)
+			for i := 2; i <= L.GetTop(); i++ {
+				parts = append(parts, L.Get(i).String())
+			}
+			log.Printf("Script: %s", strings.Join(parts, " "))
+			return 0
+		},
+		// scripts are always replicated by their effects
+		"replicate_commands": func(L *lua.LState) int {
+			L.Push(lua.LTrue)
+			return 1
+		},
+	})
+	for i, level := range []string{"LOG_DEBUG", "LOG_VERBOSE", "LOG_NOTICE", "LOG_WARNING"} {
+		redis.RawSetString(level, lua.LNumber(i))
+	}
+	L.SetGlobal("redis", redis)
+	return L
+}
+
+// redisCall is redis.call and, unless raise is set, redis.pcall: it runs the
+// command given by the arguments and returns its reply. An error reply is
+// raised by redis.call and returned by redis.pcall.
+func redisCall(L *lua.LState, call Caller, raise bool) int {
+	n := L.GetTop()
+	fail := func(msg string) int {
+		if raise {
+			L.Error(replyTable(L, "err", msg), 1)
+			return 0
+		}
+		L.Push(replyTable(L, "err", msg))
+		return 1
+	}
+	if n == 0 {
+		return fail("ERR Please specify at least one argument for this redis lib call")
+	}
+
+	args := make([]string, n)
+	for i := range args {
+		switch v := L.Get(i + 1).(type) {
+		case lua.LString:
+			args[i] = string(v)
+		case lua.LNumber:
+			args[i] = v.String()
+		default:
+			return fail("ERR Lua redis lib command arguments must be strings or integers")
+		}
+	}
+
+	reply := call(args)
+	if err, ok := reply.(response.ErrorType); ok {
+		return fail(string(err))
+	}
+	L.Push(toLua(L, reply))
+	return 1
+}
+
+// protectGlobals stops a script from creating globals or reading ones that
+// do not exist, as upstream does, since they would otherwise be mistakes.
+func protectGlobals(L *lua.LState) {
+	mt := L.NewTable()
+	L.SetField(mt, "__newindex", L.NewFunction(func(L *lua.LState) int {
+		L.RaiseError("Attempt to modify a readonly table")
+		return 0
+	}))
+	L.SetField(mt, "__index", L.NewFunction(func(L *lua.LState) int {
+		L.RaiseError("Script attempted to access nonexistent global variable '%s'", L.Get(2).String())
+		return 0
+	}))
+	L.SetMetatable(L.G.Global, mt)
+}
+
+func stringTable(L *lua.LState, values []string) *lua.LTable {
+	t := L.CreateTable(len(values), 0)
+	for _, v := range values {
+		t.Append(lua.LString(v))
+	}
+	return t
+}
+
+// replyTable is a status ({ok=...}) or error ({err=...}) reply as Lua sees it.
+func replyTable(L *lua.LState, field, msg string) *lua.LTable {
+	t := L.CreateTable(0, 1)
+	t.RawSetString(field, lua.LString(msg))
+	return t
+}